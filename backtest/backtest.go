@@ -0,0 +1,244 @@
+package backtest
+
+import (
+	"binance_bot/bot"
+	"binance_bot/client"
+	sqlite "binance_bot/db"
+	"binance_bot/execution"
+	"binance_bot/exits"
+	"binance_bot/grid"
+	"binance_bot/interfaces"
+	"binance_bot/liquidity"
+	"binance_bot/logger"
+	"binance_bot/models"
+	"binance_bot/protection"
+	"binance_bot/risk"
+	"binance_bot/sizing"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// runs numbers the in-memory databases, so every run starts without the trades of an earlier one
+var runs atomic.Int64
+
+// Config holds the settings of a backtest run
+type Config struct {
+	Interval       string            // Candle interval of the data, passed to the bot
+	QuoteAsset     string            // Quote asset of every pair, e.g. USDT
+	InitialBalance float64           // Starting quote balance of the simulated wallet
+	FeeRate        float64           // Fee charged on every fill
+	Slippage       float64           // Fraction the fill price moves against taker orders
+	MinNotional    float64           // Minimum order value accepted by the simulated exchange
+	Window         int               // Number of candles handed to the strategy on each step
+	Risk           risk.Config       // Portfolio limits checked before every order, the zero value disables them
+	Sizers         *sizing.Table     // Position sizers of the pairs, nil uses the bot default
+	Exits          *exits.Config     // Exit rules of every position, nil uses the rules of the strategy
+	Protection     protection.Config // Protective orders resting for every position, the zero value keeps stops in the bot
+	Execution      execution.Config  // Repricing and timeouts of limit orders on the replayed clock, the zero value leaves them on the book
+	Liquidity      liquidity.Config  // Spread and slippage bounds checked against the simulated book, the zero value disables them
+	Grid           grid.Config       // Pairs traded by a grid of limit orders instead of the strategy
+}
+
+// DefaultConfig mirrors the live bot settings
+func DefaultConfig() Config {
+	return Config{
+		Interval:       "15m",
+		QuoteAsset:     "USDT",
+		InitialBalance: 1000,
+		FeeRate:        0.001,
+		Slippage:       0.0005,
+		MinNotional:    5,
+		Window:         100,
+	}
+}
+
+// EquityPoint is the wallet value after a replay step
+type EquityPoint struct {
+	Timestamp time.Time
+	Equity    float64
+}
+
+// Stats summarizes a backtest run
+type Stats struct {
+	InitialEquity float64
+	FinalEquity   float64
+	TotalReturn   float64 // Percent
+	MaxDrawdown   float64 // Percent, peak to trough
+	Fills         int
+	ClosedTrades  int // Number of SELL fills
+	Wins          int
+	Losses        int
+	Breakevens    int     // Closed trades without profit or loss
+	WinRate       float64 // Percent of closed trades with a profit
+	ProfitFactor  float64 // Gross profit divided by gross loss
+	RealizedPnL   float64
+	TotalFees     float64
+}
+
+// Result holds the outcome of a backtest run
+type Result struct {
+	Trades []client.PaperFill
+	Equity []EquityPoint
+	Stats  Stats
+}
+
+// Run replays the candles of every pair through MultiPairTradingBot using the given strategy.
+// Candles are evaluated once per step in timestamp order across all pairs and orders are
+// filled by a PaperClient against the replayed prices.
+func Run(cfg Config, strategy interfaces.Strategy, data map[string][]models.CandleStick) (*Result, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no candle data to backtest")
+	}
+	if cfg.Window <= 0 {
+		return nil, fmt.Errorf("invalid window size %d", cfg.Window)
+	}
+
+	// Strategies and the bot read positions from SQLite, keep them in memory and out of the live
+	// database. The memory is freed once the last connection closes.
+	if err := sqlite.InitDBAt(fmt.Sprintf("file:backtest%d?mode=memory&cache=shared", runs.Add(1))); err != nil {
+		return nil, fmt.Errorf("failed to initialize backtest database: %v", err)
+	}
+	defer sqlite.SQLiteDB.DB.Close()
+
+	market := NewMarket(data, cfg)
+	for symbol := range data {
+		if err := market.AddTradingPair(models.NewTradingPair(symbol)); err != nil {
+			return nil, err
+		}
+	}
+	exchange := client.NewPaperClient(market, client.PaperConfig{
+		Balances: map[string]float64{cfg.QuoteAsset: cfg.InitialBalance},
+		FeeRate:  cfg.FeeRate,
+		Slippage: cfg.Slippage,
+		Clock:    market.Now,
+	})
+	tradingBot := bot.NewMultiPairTradingBot(exchange, strategy, cfg.Interval)
+
+	// Completed trades are stamped with the wall clock, so the daily loss limit cannot be replayed
+	limits := cfg.Risk
+	limits.MaxDailyLoss = 0
+	tradingBot.SetRiskManager(risk.NewManager(limits, exchange))
+	if cfg.Sizers != nil {
+		tradingBot.SetPositionSizers(cfg.Sizers)
+	}
+	if cfg.Exits != nil {
+		tradingBot.SetExitRules(*cfg.Exits)
+	}
+	tradingBot.SetProtection(cfg.Protection)
+	tradingBot.SetExecutionPolicy(cfg.Execution, market.Now)
+	tradingBot.SetLiquidityFilters(cfg.Liquidity)
+	tradingBot.SetGrids(cfg.Grid)
+
+	symbols := make([]string, 0, len(data))
+	for symbol := range data {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	// Index candles by timestamp so every pair is evaluated on its own candle close
+	steps := make(map[time.Time][]string)
+	for _, symbol := range symbols {
+		for _, candle := range data[symbol] {
+			steps[candle.Timestamp] = append(steps[candle.Timestamp], symbol)
+		}
+	}
+	timeline := make([]time.Time, 0, len(steps))
+	for ts := range steps {
+		timeline = append(timeline, ts)
+	}
+	sort.Slice(timeline, func(i, j int) bool { return timeline[i].Before(timeline[j]) })
+
+	logger.Infof("Backtesting %d pairs over %d steps (%s - %s)", len(symbols), len(timeline), timeline[0].Format(time.RFC3339), timeline[len(timeline)-1].Format(time.RFC3339))
+
+	result := &Result{}
+	pairs := exchange.GetTradingPairs()
+	for _, ts := range timeline {
+		market.SetTime(ts)
+
+		// Fill orders left on the book by the previous steps and apply the fills to positions
+		for _, symbol := range steps[ts] {
+			if candle, err := market.CurrentCandle(symbol); err == nil {
+				exchange.MatchOrders(symbol, decimal.NewFromFloat(candle.Low), decimal.NewFromFloat(candle.High))
+			}
+		}
+		tradingBot.SyncOrders()
+
+		for _, symbol := range steps[ts] {
+			candles, err := exchange.FetchCandles(symbol, cfg.Interval, cfg.Window)
+			if err != nil || len(candles) < cfg.Window {
+				continue // Warming up
+			}
+			tradingBot.ProcessCandles(pairs[symbol], candles, ts)
+		}
+		result.Equity = append(result.Equity, EquityPoint{Timestamp: ts, Equity: exchange.Equity(cfg.QuoteAsset)})
+	}
+
+	result.Trades = exchange.Fills()
+	result.Stats = calculateStats(cfg.InitialBalance, result.Trades, result.Equity)
+	return result, nil
+}
+
+func calculateStats(initial float64, trades []client.PaperFill, equity []EquityPoint) Stats {
+	stats := Stats{InitialEquity: initial, FinalEquity: initial, Fills: len(trades)}
+
+	var grossProfit, grossLoss float64
+	for _, trade := range trades {
+		stats.TotalFees += trade.Fee.InexactFloat64()
+		if trade.Side != "SELL" {
+			continue
+		}
+		profitLoss := trade.ProfitLoss.InexactFloat64()
+		stats.ClosedTrades++
+		stats.RealizedPnL += profitLoss
+		switch {
+		case profitLoss > 0:
+			stats.Wins++
+			grossProfit += profitLoss
+		case profitLoss < 0:
+			stats.Losses++
+			grossLoss += -profitLoss
+		default:
+			stats.Breakevens++
+		}
+	}
+	if stats.ClosedTrades > 0 {
+		stats.WinRate = float64(stats.Wins) / float64(stats.ClosedTrades) * 100
+	}
+	if grossLoss > 0 {
+		stats.ProfitFactor = grossProfit / grossLoss
+	} else if grossProfit > 0 {
+		stats.ProfitFactor = math.Inf(1)
+	}
+
+	peak := initial
+	for _, point := range equity {
+		if point.Equity > peak {
+			peak = point.Equity
+		}
+		if peak > 0 {
+			stats.MaxDrawdown = math.Max(stats.MaxDrawdown, (peak-point.Equity)/peak*100)
+		}
+	}
+	if len(equity) > 0 {
+		stats.FinalEquity = equity[len(equity)-1].Equity
+	}
+	if initial > 0 {
+		stats.TotalReturn = (stats.FinalEquity - initial) / initial * 100
+	}
+
+	return stats
+}
+
+// LogSummary prints the summary stats of a run
+func (r *Result) LogSummary() {
+	s := r.Stats
+	logger.Infof("Backtest Summary:")
+	logger.Infof("Equity: %.2f -> %.2f (%.2f%%)", s.InitialEquity, s.FinalEquity, s.TotalReturn)
+	logger.Infof("Max drawdown: %.2f%%", s.MaxDrawdown)
+	logger.Infof("Fills: %d | Closed trades: %d | Wins: %d | Losses: %d | Breakevens: %d | Win rate: %.2f%%", s.Fills, s.ClosedTrades, s.Wins, s.Losses, s.Breakevens, s.WinRate)
+	logger.Infof("Realized P/L: %.2f | Profit factor: %.2f | Fees: %.2f", s.RealizedPnL, s.ProfitFactor, s.TotalFees)
+}
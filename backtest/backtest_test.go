@@ -0,0 +1,159 @@
+package backtest
+
+import (
+	"binance_bot/client"
+	"binance_bot/models"
+	"binance_bot/strategies"
+	"github.com/shopspring/decimal"
+	"math"
+	"testing"
+	"time"
+)
+
+// scripted signals BUY and SELL on the candles closing at given prices
+type scripted struct {
+	buy, sell float64
+}
+
+func (s scripted) GetStrategyType() strategies.StrategyType {
+	return strategies.RSIMACDStrategyType
+}
+
+func (s scripted) Calculate(candles []models.CandleStick, pair string, trend bool) (models.Signal, error) {
+	switch candles[len(candles)-1].Close {
+	case s.buy:
+		return models.Signal{Action: models.ActionBuy, SizeFraction: 0.5}, nil
+	case s.sell:
+		return models.Signal{Action: models.ActionSell}, nil
+	}
+	return models.HoldSignal(""), nil
+}
+
+// hourly returns hourly ETHUSDT candles closing at the given prices, each ranging 1 around its close
+func hourly(closes ...float64) map[string][]models.CandleStick {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make([]models.CandleStick, len(closes))
+	for i, c := range closes {
+		candles[i] = models.CandleStick{Timestamp: start.Add(time.Duration(i) * time.Hour), Open: c, High: c + 1, Low: c - 1, Close: c, Volume: 100}
+	}
+	return map[string][]models.CandleStick{"ETHUSDT": candles}
+}
+
+// replay runs the scripted strategy over hourly candles with a window of 3
+func replay(t *testing.T, closes ...float64) *Result {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Interval = "1h"
+	cfg.Window = 3
+
+	result, err := Run(cfg, scripted{buy: 100, sell: 120}, hourly(closes...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestRun(t *testing.T) {
+	// BUY half the wallet at 100 and SELL it at 120, both with 0.05% slippage and a 0.1% fee
+	result := replay(t, 101, 102, 103, 100, 110, 120, 115)
+
+	trades := []struct{ side, qty, price, fee, profitLoss string }{
+		{"BUY", "5", "100.05", "0.50025", "0"},
+		{"SELL", "5", "119.94", "0.5997", "98.35005"},
+	}
+	if len(result.Trades) != len(trades) {
+		t.Fatalf("got %d trades, want %d: %+v", len(result.Trades), len(trades), result.Trades)
+	}
+	for i, want := range trades {
+		got := result.Trades[i]
+		if got.Side != want.side || !got.Quantity.Equal(d(want.qty)) || !got.Price.Equal(d(want.price)) ||
+			!got.Fee.Equal(d(want.fee)) || !got.ProfitLoss.Equal(d(want.profitLoss)) {
+			t.Errorf("trade %d: got %+v, want %+v", i, got, want)
+		}
+	}
+
+	// The BUY fee and slippage cost 0.75 at once, the position then follows the closes
+	equity := []float64{1000, 1000, 1000, 999.24975, 1049.24975, 1098.35005, 1098.35005}
+	if len(result.Equity) != len(equity) {
+		t.Fatalf("got %d equity points, want %d", len(result.Equity), len(equity))
+	}
+	for i, want := range equity {
+		if got := result.Equity[i].Equity; math.Abs(got-want) > 1e-9 {
+			t.Errorf("equity %d: got %v, want %v", i, got, want)
+		}
+	}
+
+	want := Stats{
+		InitialEquity: 1000,
+		FinalEquity:   1098.35005,
+		TotalReturn:   9.835005,
+		MaxDrawdown:   0.075025,
+		Fills:         2,
+		ClosedTrades:  1,
+		Wins:          1,
+		WinRate:       100,
+		ProfitFactor:  math.Inf(1),
+		RealizedPnL:   98.35005,
+		TotalFees:     1.09995,
+	}
+	if !statsNear(result.Stats, want) {
+		t.Errorf("got stats %+v, want %+v", result.Stats, want)
+	}
+}
+
+func TestRunStartsClean(t *testing.T) {
+	// The first run ends holding the position it bought
+	if first := replay(t, 101, 102, 103, 100, 110); len(first.Trades) != 1 {
+		t.Fatalf("first run: got trades %+v, want the BUY", first.Trades)
+	}
+
+	// A second run sells only what it bought itself
+	second := replay(t, 101, 102, 103, 100, 110, 120, 115)
+	if len(second.Trades) != 2 || !second.Trades[1].Quantity.Equal(d("5")) || second.Stats.FinalEquity != 1098.35005 {
+		t.Errorf("second run: got trades %+v, final equity %v", second.Trades, second.Stats.FinalEquity)
+	}
+}
+
+func TestCalculateStats(t *testing.T) {
+	sell := func(profitLoss string) client.PaperFill {
+		return client.PaperFill{Side: "SELL", Fee: d("1"), ProfitLoss: d(profitLoss)}
+	}
+	trades := []client.PaperFill{
+		{Side: "BUY", Fee: d("1")},
+		sell("30"), sell("-10"), sell("0"), sell("-5"),
+	}
+	equity := []EquityPoint{{Equity: 1000}, {Equity: 1030}, {Equity: 1020}, {Equity: 1020}, {Equity: 1015}}
+
+	want := Stats{
+		InitialEquity: 1000,
+		FinalEquity:   1015,
+		TotalReturn:   1.5,
+		MaxDrawdown:   (1030.0 - 1015) / 1030 * 100,
+		Fills:         5,
+		ClosedTrades:  4,
+		Wins:          1,
+		Losses:        2,
+		Breakevens:    1,
+		WinRate:       25,
+		ProfitFactor:  2,
+		RealizedPnL:   15,
+		TotalFees:     5,
+	}
+	if got := calculateStats(1000, trades, equity); !statsNear(got, want) {
+		t.Errorf("got stats %+v, want %+v", got, want)
+	}
+}
+
+func d(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
+// statsNear compares stats, allowing for float rounding in the ratios
+func statsNear(got, want Stats) bool {
+	near := func(a, b float64) bool { return a == b || math.Abs(a-b) < 1e-9 }
+	return near(got.InitialEquity, want.InitialEquity) && near(got.FinalEquity, want.FinalEquity) &&
+		near(got.TotalReturn, want.TotalReturn) && near(got.MaxDrawdown, want.MaxDrawdown) &&
+		got.Fills == want.Fills && got.ClosedTrades == want.ClosedTrades && got.Wins == want.Wins &&
+		got.Losses == want.Losses && got.Breakevens == want.Breakevens && near(got.WinRate, want.WinRate) &&
+		near(got.ProfitFactor, want.ProfitFactor) && near(got.RealizedPnL, want.RealizedPnL) && near(got.TotalFees, want.TotalFees)
+}
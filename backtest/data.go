@@ -0,0 +1,101 @@
+package backtest
+
+import (
+	"binance_bot/interfaces"
+	"binance_bot/logger"
+	"binance_bot/models"
+	"binance_bot/utils"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LoadCandles reads every <SYMBOL>.csv file in dir
+func LoadCandles(dir string) (map[string][]models.CandleStick, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.csv"))
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string][]models.CandleStick)
+	for _, file := range files {
+		symbol := strings.TrimSuffix(filepath.Base(file), ".csv")
+		if strings.HasPrefix(symbol, "backtest_") {
+			continue // Skip reports of previous runs
+		}
+		candles, err := utils.ReadCandlesCSV(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load candles for %s: %v", symbol, err)
+		}
+		if len(candles) > 0 {
+			data[symbol] = candles
+		}
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no candle files found in %s", dir)
+	}
+	return data, nil
+}
+
+// DownloadCandles stores the latest candles of every pair in dir for later backtests
+func DownloadCandles(exchange interfaces.ExchangeClient, interval string, limit int, dir string) error {
+	for symbol := range exchange.GetTradingPairs() {
+		candles, err := exchange.FetchCandles(symbol, interval, limit)
+		if err != nil {
+			logger.Warnf("Failed to fetch candles for %s: %v", symbol, err)
+			continue
+		}
+		if err := utils.WriteCandlesCSV(filepath.Join(dir, symbol+".csv"), candles); err != nil {
+			return err
+		}
+		logger.Infof("Stored %d candles for %s", len(candles), symbol)
+	}
+	return nil
+}
+
+// WriteReport stores the trade list and equity curve of a run as CSV files in dir
+func (r *Result) WriteReport(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %v", dir, err)
+	}
+
+	trades := [][]string{{"Timestamp", "Symbol", "Side", "Quantity", "Price", "Fee", "ProfitLoss"}}
+	for _, t := range r.Trades {
+		trades = append(trades, []string{
+			t.Timestamp.Format(time.RFC3339),
+			t.Symbol,
+			t.Side,
+			t.Quantity.String(),
+			t.Price.String(),
+			t.Fee.StringFixed(8),
+			t.ProfitLoss.StringFixed(8),
+		})
+	}
+	if err := writeCSV(filepath.Join(dir, "backtest_trades.csv"), trades); err != nil {
+		return err
+	}
+
+	equity := [][]string{{"Timestamp", "Equity"}}
+	for _, p := range r.Equity {
+		equity = append(equity, []string{p.Timestamp.Format(time.RFC3339), fmt.Sprintf("%.2f", p.Equity)})
+	}
+	return writeCSV(filepath.Join(dir, "backtest_equity.csv"), equity)
+}
+
+func writeCSV(filename string, records [][]string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV file: %v", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write CSV file %s: %v", filename, err)
+	}
+	return nil
+}
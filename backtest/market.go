@@ -0,0 +1,127 @@
+package backtest
+
+import (
+	"binance_bot/models"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Market replays historical candles as a market data source.
+// It implements interfaces.MarketDataClient so a PaperClient can fill orders against it.
+type Market struct {
+	candles     map[string][]models.CandleStick
+	cursor      map[string]int // Index of the current candle per symbol, -1 before the first one
+	pairs       map[string]*models.TradingPair
+	pairsMutex  sync.RWMutex
+	quoteAsset  string
+	minNotional float64
+	now         time.Time
+	mu          sync.RWMutex
+}
+
+// NewMarket creates a replay market over the candles of every symbol
+func NewMarket(data map[string][]models.CandleStick, cfg Config) *Market {
+	m := &Market{
+		candles:     data,
+		cursor:      make(map[string]int),
+		pairs:       make(map[string]*models.TradingPair),
+		quoteAsset:  cfg.QuoteAsset,
+		minNotional: cfg.MinNotional,
+	}
+	for symbol := range data {
+		m.cursor[symbol] = -1
+	}
+	return m
+}
+
+// SetTime moves the replay clock, making every candle opened at or before t visible
+func (m *Market) SetTime(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = t
+	for symbol, candles := range m.candles {
+		c := m.cursor[symbol]
+		for c+1 < len(candles) && !candles[c+1].Timestamp.After(t) {
+			c++
+		}
+		m.cursor[symbol] = c
+	}
+}
+
+// Now returns the replay clock
+func (m *Market) Now() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.now
+}
+
+func (m *Market) GetTradingPairs() map[string]*models.TradingPair {
+	m.pairsMutex.RLock()
+	defer m.pairsMutex.RUnlock()
+	return m.pairs
+}
+
+// AddTradingPair registers a pair, deriving the base asset from the configured quote asset
+func (m *Market) AddTradingPair(pair models.TradingPair) error {
+	if _, ok := m.candles[pair.Symbol]; !ok {
+		return fmt.Errorf("no candle data for %s", pair.Symbol)
+	}
+	if !strings.HasSuffix(pair.Symbol, m.quoteAsset) {
+		return fmt.Errorf("symbol %s is not quoted in %s", pair.Symbol, m.quoteAsset)
+	}
+
+	pair.BaseAsset = strings.TrimSuffix(pair.Symbol, m.quoteAsset)
+	pair.QuoteAsset = m.quoteAsset
+	pair.ApplyRules(&models.SymbolRules{
+		Status:      "TRADING",
+		TickSize:    decimal.New(1, -8),
+		StepSize:    decimal.New(1, -8),
+		MinNotional: decimal.NewFromFloat(m.minNotional),
+	})
+
+	m.pairsMutex.Lock()
+	m.pairs[pair.Symbol] = &pair
+	m.pairsMutex.Unlock()
+	return nil
+}
+
+// GetCurrentPrice returns the close of the current candle
+func (m *Market) GetCurrentPrice(symbol string) (decimal.Decimal, error) {
+	candle, err := m.CurrentCandle(symbol)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return decimal.NewFromFloat(candle.Close), nil
+}
+
+// CurrentCandle returns the latest visible candle of a symbol
+func (m *Market) CurrentCandle(symbol string) (models.CandleStick, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.cursor[symbol]
+	if !ok || c < 0 {
+		return models.CandleStick{}, fmt.Errorf("no price data for %s at %s", symbol, m.now.Format(time.RFC3339))
+	}
+	return m.candles[symbol][c], nil
+}
+
+// FetchCandles returns up to limit candles ending with the current one
+func (m *Market) FetchCandles(symbol, _ string, limit int) ([]models.CandleStick, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.cursor[symbol]
+	if !ok || c < 0 {
+		return nil, fmt.Errorf("no candles for %s at %s", symbol, m.now.Format(time.RFC3339))
+	}
+	start := c + 1 - limit
+	if start < 0 {
+		start = 0
+	}
+	return append([]models.CandleStick(nil), m.candles[symbol][start:c+1]...), nil
+}
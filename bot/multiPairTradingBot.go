@@ -2,38 +2,172 @@ package bot
 
 import (
 	db2 "binance_bot/db"
+	"binance_bot/execution"
+	"binance_bot/exits"
+	"binance_bot/grid"
+	"binance_bot/indicators"
 	"binance_bot/interfaces"
+	"binance_bot/liquidity"
 	"binance_bot/logger"
 	"binance_bot/models"
+	"binance_bot/protection"
+	"binance_bot/risk"
+	"binance_bot/sizing"
 	"binance_bot/strategies"
+	"binance_bot/utils"
+	"context"
 	"fmt"
+	"github.com/shopspring/decimal"
 	"log"
 	"math"
-	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // MultiPairTradingBot manages multiple trading pairs
 type MultiPairTradingBot struct {
-	exchange interfaces.ExchangeClient
-	strategy interfaces.Strategy
-	interval string
-	pairs    map[string]*models.TradingPair
-	pairsMu  sync.RWMutex
-	wg       sync.WaitGroup
-	stopCh   chan struct{}
+	exchange   interfaces.ExchangeClient
+	strategy   interfaces.Strategy
+	interval   string
+	pairs      map[string]*models.TradingPair
+	pairsMu    sync.RWMutex
+	wg         sync.WaitGroup
+	stopCh     chan struct{}
+	states     map[string]*pairState
+	statesMu   sync.Mutex
+	store      *db2.StateStore // Checkpoints pairState across restarts
+	orders     *OrderManager
+	clockOff   atomic.Int64    // Exchange time minus local time in nanoseconds
+	risk       *risk.Manager   // Portfolio limits checked before every order, nil allows all orders
+	sizers     *sizing.Table   // Quote amount of a BUY per pair
+	exits      *exits.Manager  // Exit rules of the open positions
+	selling    map[string]bool // Pairs the candle or price path is selling, the other path leaves them alone
+	sellingMu  sync.Mutex
+	protection *protection.Manager   // Protective orders on the exchange, nil keeps stops in the bot only
+	executor   *execution.Executor   // Places limit orders and follows them with the execution policy
+	liquidity  *liquidity.Guard      // Spread and slippage bounds of signals, nil trades every book
+	grids      map[string]*grid.Grid // Pairs traded by a grid of limit orders instead of the strategy
+	ctx        context.Context       // Canceled when the bot stops
+	cancel     context.CancelFunc
+}
+
+// maxTradesPerDay caps the number of trades per pair per day
+const maxTradesPerDay = 25
+
+// orderPollInterval is how often open orders are checked for fills
+const orderPollInterval = 5 * time.Second
+
+// candleCloseDelay gives the exchange time to publish a closed candle before it is fetched
+const candleCloseDelay = 2 * time.Second
+
+// clockSyncInterval is how often the offset to the exchange clock is refreshed
+const clockSyncInterval = time.Hour
+
+// rulesRefreshInterval is how often the exchange trading rules of the pairs are fetched again
+const rulesRefreshInterval = time.Hour
+
+// equitySnapshotInterval is how often the account equity is stored for the drawdown kill switch
+const equitySnapshotInterval = 5 * time.Minute
+
+// executionInterval is how often limit orders under execution are checked for reprices and timeouts
+const executionInterval = time.Second
+
+// exitCheckInterval is how often the exit rules of open positions are checked against the price
+const exitCheckInterval = time.Second
+
+// gridCheckInterval is how often the grids are checked for filled orders and the price range
+const gridCheckInterval = orderPollInterval
+
+// pairState keeps the per-pair bookkeeping of the decision path
+type pairState struct {
+	tradesToday  int    // Number of trades placed today
+	lastResetDay string // UTC date of the last daily counter reset
 }
 
 // NewMultiPairTradingBot creates a new instance of MultiPairTradingBot
 func NewMultiPairTradingBot(exchange interfaces.ExchangeClient, strategy interfaces.Strategy, interval string) *MultiPairTradingBot {
-	return &MultiPairTradingBot{
+	ctx, cancel := context.WithCancel(context.Background())
+	orders := NewOrderManager(exchange)
+	bot := &MultiPairTradingBot{
 		exchange: exchange,
 		strategy: strategy,
 		interval: interval,
 		pairs:    make(map[string]*models.TradingPair),
 		stopCh:   make(chan struct{}),
+		states:   make(map[string]*pairState),
+		selling:  make(map[string]bool),
+		store:    db2.NewStateStore("bot"),
+		orders:   orders,
+		sizers:   sizing.NewTable(&sizing.FixedFraction{Fraction: 0.25}, nil),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	bot.executor = execution.NewExecutor(execution.Config{}, exchange, orders, bot.allowOrder, nil)
+	bot.exits = exits.NewManager(strategyExitRules(strategy), exchange, interval, bot.orderWorking)
+	return bot
+}
+
+// strategyExitRules returns the exit rules a strategy brings, none when it has no rules of its own
+func strategyExitRules(strategy interfaces.Strategy) exits.Config {
+	if provider, ok := strategy.(interfaces.ExitRuleProvider); ok {
+		return provider.ExitRules()
+	}
+	return exits.Config{}
+}
+
+// SetExitRules replaces the exit rules of the strategy
+func (bot *MultiPairTradingBot) SetExitRules(rules exits.Config) {
+	bot.exits = exits.NewManager(rules, bot.exchange, bot.interval, bot.orderWorking)
+}
+
+// orderWorking reports whether an order placed by the bot, or the execution that replaced it, can still fill
+func (bot *MultiPairTradingBot) orderWorking(symbol string, orderID int64) bool {
+	if bot.executor.Working(symbol, orderID) {
+		return true
 	}
+	order, err := db2.SQLiteDB.GetOrder(symbol, orderID)
+	if err != nil {
+		return false
+	}
+	return order.IsOpen()
+}
+
+// SetProtection places protective orders on the exchange for open positions, an empty type disables them
+func (bot *MultiPairTradingBot) SetProtection(cfg protection.Config) {
+	bot.protection = nil
+	if cfg.Type != "" {
+		bot.protection = protection.NewManager(cfg, bot.exchange, bot.orders, bot.availableBalance)
+	}
+}
+
+// SetExecutionPolicy sets how limit orders are repriced, timed out and replaced by market orders.
+// clock is the time source of the policy, nil uses the wall clock.
+func (bot *MultiPairTradingBot) SetExecutionPolicy(cfg execution.Config, clock func() time.Time) {
+	bot.executor = execution.NewExecutor(cfg, bot.exchange, bot.orders, bot.allowOrder, clock)
+}
+
+// SetLiquidityFilters skips signals of pairs whose spread or estimated slippage breaks the filters
+func (bot *MultiPairTradingBot) SetLiquidityFilters(cfg liquidity.Config) {
+	bot.liquidity = liquidity.NewGuard(cfg, bot.exchange)
+}
+
+// SetGrids sets the pairs traded by a grid of limit orders, the other pairs follow the strategy
+func (bot *MultiPairTradingBot) SetGrids(cfg grid.Config) {
+	bot.grids = make(map[string]*grid.Grid, len(cfg.Pairs))
+	for symbol, settings := range cfg.Pairs {
+		bot.grids[symbol] = grid.New(settings, bot.exchange, bot.orders, bot.availableBalance, bot.allowOrder)
+	}
+}
+
+// SetRiskManager sets the portfolio limits every order is checked against
+func (bot *MultiPairTradingBot) SetRiskManager(manager *risk.Manager) {
+	bot.risk = manager
+}
+
+// SetPositionSizers sets the sizers that decide the quote amount of a BUY
+func (bot *MultiPairTradingBot) SetPositionSizers(sizers *sizing.Table) {
+	bot.sizers = sizers
 }
 
 func (bot *MultiPairTradingBot) StartTrading() {
@@ -49,17 +183,66 @@ func (bot *MultiPairTradingBot) StartTrading() {
 		log.Fatalf("Invalid strategy type: %s", bot.strategy.GetStrategyType())
 	}
 
+	// Align candle boundaries to the exchange clock, keep the trading rules current and track the equity
+	bot.syncClock()
+	bot.snapshotEquity()
+	bot.wg.Add(1)
+	go func() {
+		defer bot.wg.Done()
+		ticker := time.NewTicker(clockSyncInterval)
+		defer ticker.Stop()
+		rulesTicker := time.NewTicker(rulesRefreshInterval)
+		defer rulesTicker.Stop()
+		equityTicker := time.NewTicker(equitySnapshotInterval)
+		defer equityTicker.Stop()
+		for {
+			select {
+			case <-bot.stopCh:
+				return
+			case <-ticker.C:
+				bot.syncClock()
+			case <-rulesTicker.C:
+				bot.refreshRules()
+			case <-equityTicker.C:
+				bot.snapshotEquity()
+			}
+		}
+	}()
+
+	// Follow orders placed before a restart as well as new ones
+	bot.wg.Add(1)
+	go func() {
+		defer bot.wg.Done()
+		bot.orders.Run(bot.stopCh, orderPollInterval)
+	}()
+
+	// Reprice and time out limit orders, the orders still under execution are canceled on stop
+	bot.wg.Add(1)
+	go func() {
+		defer bot.wg.Done()
+		bot.executor.Run(bot.ctx, executionInterval)
+	}()
+
+	def, _ := strategies.Lookup(bot.strategy.GetStrategyType().String())
 	for _, pair := range pairs {
 		bot.wg.Add(1)
-		switch bot.strategy.GetStrategyType() {
-		case strategies.RSIMACDStrategyType:
-			fmt.Println("Starting trading for", pair.Symbol, "using RSI-MACD strategy")
-			go bot.tradePair(pair) // Use RSI-MACD strategy
-		case strategies.SpikeDetectionStrategyType:
-			fmt.Println("Starting trading for", pair.Symbol, "using Spike Detection strategy")
-			go bot.monitorCurrentCandle(pair) // Use spike detection strategy
+		// Range-bound pairs with a grid trade its limit orders instead of the strategy
+		if g, ok := bot.grids[pair.Symbol]; ok {
+			fmt.Println("Starting grid trading for", pair.Symbol, "with limit orders")
+			go bot.tradeGrid(pair, g)
+			continue
+		}
+		switch def.Execution {
+		case strategies.CandleExecution:
+			fmt.Println("Starting trading for", pair.Symbol, "using", def.Name, "strategy")
+			go bot.tradePair(pair)
+			bot.wg.Add(1)
+			go bot.watchExits(pair)
+		case strategies.TickExecution:
+			fmt.Println("Starting trading for", pair.Symbol, "using", def.Name, "strategy on price ticks")
+			go bot.monitorCurrentCandle(pair)
 		default:
-			log.Printf("Unknown strategy type: %s. Skipping trading for %s", bot.strategy.GetStrategyType(), pair.Symbol)
+			log.Printf("Unknown execution model %s of strategy %s. Skipping trading for %s", def.Execution, def.Name, pair.Symbol)
 			bot.wg.Done()
 		}
 	}
@@ -68,6 +251,7 @@ func (bot *MultiPairTradingBot) StartTrading() {
 
 // Stop stops the trading bot
 func (bot *MultiPairTradingBot) Stop() {
+	bot.cancel()
 	close(bot.stopCh)
 	bot.wg.Wait()
 	fmt.Println("Trading bot stopped.")
@@ -80,68 +264,91 @@ func (bot *MultiPairTradingBot) isUptrend(candles []models.CandleStick) bool {
 	}
 
 	// Calculate the short-term and long-term SMAs
-	shortSMA := bot.calculateSMA(candles, 20) // 20-period SMA
-	longSMA := bot.calculateSMA(candles, 50)  // 50-period SMA
+	closes := indicators.Closes(candles)
+	shortSMA, _ := indicators.SMASeries(closes, 20) // 20-period SMA
+	longSMA, _ := indicators.SMASeries(closes, 50)  // 50-period SMA
 
 	// Compare the latest short-term SMA with the long-term SMA
 	return shortSMA[len(shortSMA)-1] > longSMA[len(longSMA)-1]
 }
 
-// Helper function to calculate SMA
-func (bot *MultiPairTradingBot) calculateSMA(candles []models.CandleStick, period int) []float64 {
-	if len(candles) < period {
-		return nil
-	}
-
-	sma := make([]float64, len(candles)-period+1)
-	for i := 0; i <= len(candles)-period; i++ {
-		sum := 0.0
-		for j := 0; j < period; j++ {
-			sum += candles[i+j].Close
+// calculateTradeAmount sizes a trade from the signal: the suggested fraction of the balance,
+// or the quote amount of the position sizer of the pair for a BUY and the whole position for a SELL.
+// BUY sizes are scaled down by the strength of the signal and capped at the quote balance.
+func (bot *MultiPairTradingBot) calculateTradeAmount(signal models.Signal, quoteBalance, position decimal.Decimal, pair *models.TradingPair, candles []models.CandleStick) decimal.Decimal {
+	fraction := signal.SizeFraction
+	switch signal.Action {
+	case models.ActionBuy:
+		amount := quoteBalance.Mul(decimal.NewFromFloat(math.Min(fraction, 1)))
+		if fraction <= 0 {
+			amount = bot.sizeBuy(pair, candles, quoteBalance)
 		}
-		sma[i] = sum / float64(period)
-	}
-	return sma
-}
-
-func (bot *MultiPairTradingBot) calculateTradeAmount(signal int, quoteBalance, baseBalance float64, pair string) float64 {
-	if signal > 0 { // BUY Signal
-		amount := math.Min(quoteBalance*0.25, quoteBalance)
-		logger.Infof("BUY %.2f %s \n", amount, pair)
-		return amount // Use 25% of quote balance
-	} else if signal < 0 { // SELL Signal
-		logger.Infof("SELL %s %.2f \n", pair, baseBalance)
-		return baseBalance // Sell all base balance
+		if signal.Strength > 0 && signal.Strength < 1 {
+			amount = amount.Mul(decimal.NewFromFloat(signal.Strength))
+		}
+		buy := decimal.Min(amount, quoteBalance)
+		logger.Infof("BUY %s %s \n", buy.StringFixed(2), pair.Symbol)
+		return buy
+	case models.ActionSell:
+		if fraction <= 0 {
+			fraction = 1
+		}
+		amount := position
+		if fraction < 1 {
+			amount = position.Mul(decimal.NewFromFloat(fraction))
+		}
+		logger.Infof("SELL %s %s \n", pair.Symbol, amount)
+		return amount
 	}
-	return 0
+	return decimal.Zero
 }
 
+// tradePair evaluates the strategy once per closed candle, or every 10 seconds on the
+// forming candle for strategies that opt in with IntraCandle
 func (bot *MultiPairTradingBot) tradePair(pair *models.TradingPair) {
 	defer bot.wg.Done()
 
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+	if s, ok := bot.strategy.(interfaces.IntraCandleStrategy); ok && s.IntraCandle() {
+		bot.tradePairIntraCandle(pair)
+		return
+	}
+
+	logger.Infof("Started trading %s on %s candle close", pair.Symbol, bot.interval)
+
+	for {
+		closeTime, err := utils.NextCandleClose(bot.interval, bot.serverNow())
+		if err != nil {
+			logger.Errorf("Cannot schedule %s candles for %s: %v", bot.interval, pair.Symbol, err)
+			return
+		}
+
+		select {
+		case <-bot.stopCh:
+			return
+		case <-time.After(closeTime.Sub(bot.serverNow()) + candleCloseDelay):
+		}
 
-	resetTicker := time.NewTicker(1 * time.Minute) // Check every minute for day change
-	defer resetTicker.Stop()
+		candles, err := bot.fetchClosedCandles(pair.Symbol, closeTime)
+		if err != nil {
+			logger.Infof("Error fetching candles for %s: %v", pair.Symbol, err)
+			continue
+		}
 
-	logger.Infof("Started trading %s", pair.Symbol)
+		bot.ProcessCandles(bot.latestPair(pair), candles, closeTime)
+	}
+}
 
-	tradesToday := 0                 // Track number of trades per day
-	lastResetDay := time.Now().Day() // Track the day of the last reset
+// tradePairIntraCandle re-evaluates the forming candle every 10 seconds
+func (bot *MultiPairTradingBot) tradePairIntraCandle(pair *models.TradingPair) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	logger.Infof("Started trading %s intra-candle", pair.Symbol)
 
 	for {
 		select {
 		case <-bot.stopCh:
 			return
-		case <-resetTicker.C:
-			// Reset daily trade counter at midnight
-			currentDay := time.Now().Day()
-			if currentDay != lastResetDay {
-				logger.Infof("Resetting daily trade counter for %s. Previous trades: %d", pair.Symbol, tradesToday)
-				tradesToday = 0
-				lastResetDay = currentDay
-			}
 		case <-ticker.C:
 			// Fetch candles
 			candles, err := bot.exchange.FetchCandles(pair.Symbol, bot.interval, 100)
@@ -150,259 +357,620 @@ func (bot *MultiPairTradingBot) tradePair(pair *models.TradingPair) {
 				continue
 			}
 
-			// Detect trend and calculate signal
-			isUptrend := bot.isUptrend(candles)
-			sngl, err := bot.strategy.Calculate(candles, pair.Symbol, isUptrend)
-			if err != nil {
-				logger.Infof("Error calculating strategy for %s: %v", pair.Symbol, err)
-				continue
-			}
-
-			if sngl == 0 {
-				// HOLD signal
-				continue
-			}
+			bot.ProcessCandles(bot.latestPair(pair), candles, time.Now())
+		}
+	}
+}
 
-			// Avoid overtrading
-			if tradesToday >= 25 {
-				logger.Infof("Max trades reached for %s today. Skipping further trades.", pair.Symbol)
-				continue
+// fetchClosedCandles returns the last 100 candles that closed at or before closeTime.
+// It retries briefly when the exchange has not published the candle closing at closeTime yet.
+func (bot *MultiPairTradingBot) fetchClosedCandles(symbol string, closeTime time.Time) ([]models.CandleStick, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-bot.stopCh:
+				return nil, fmt.Errorf("bot stopped")
+			case <-time.After(candleCloseDelay):
 			}
+		}
 
-			// Fetch balances
-			quoteBalance, err := bot.exchange.GetBalance(pair.QuoteAsset)
-			if err != nil {
-				logger.Infof("Error fetching %s balance: %v", pair.QuoteAsset, err)
-				continue
-			}
+		candles, err := bot.exchange.FetchCandles(symbol, bot.interval, 101)
+		if err != nil {
+			return nil, err
+		}
 
-			baseBalance, err := bot.exchange.GetBalance(pair.BaseAsset)
+		// Drop the candle that is still forming
+		for len(candles) > 0 {
+			end, err := utils.NextCandleClose(bot.interval, candles[len(candles)-1].Timestamp)
 			if err != nil {
-				logger.Infof("Error fetching %s balance: %v", pair.BaseAsset, err)
-				continue
+				return nil, err
 			}
-
-			// Current price
-			currentPrice := candles[len(candles)-1].Close
-
-			// Determine trade size
-			tradeAmount := bot.calculateTradeAmount(sngl, quoteBalance, baseBalance, pair.Symbol)
-			if tradeAmount == 0 {
-				logger.Infof("Insufficient balance for %s trade. Skipping trade.", pair.Symbol)
-				continue
-			}
-
-			// Handle BUY or SELL
-			if sngl > 0 { // BUY signal
-				trAmount := tradeAmount / currentPrice
-				fmt.Println("BUY signal", pair.Symbol, "Trade amount", trAmount, "Current price", currentPrice, "Base balance", baseBalance)
-				if !bot.handleBuy(pair, trAmount, currentPrice, quoteBalance) {
-					logger.Infof("Error handling BUY for %s\n", pair.Symbol)
-					continue
-				}
-			} else if sngl < 0 { // SELL signal
-				fmt.Println("SELL signal", pair.Symbol, "Trade amount", tradeAmount, "Current price", currentPrice, "Quote balance", quoteBalance)
-				if !bot.handleSell(pair, tradeAmount, currentPrice, baseBalance) {
-					logger.Infof("Error handling SELL for %s\n", pair.Symbol)
-					continue
+			if !end.After(closeTime) {
+				if end.Equal(closeTime) {
+					if len(candles) > 100 {
+						candles = candles[len(candles)-100:]
+					}
+					return candles, nil
 				}
+				break
 			}
-
-			tradesToday++
+			candles = candles[:len(candles)-1]
 		}
 	}
+	return nil, fmt.Errorf("candle closing at %s not available", closeTime.Format(time.RFC3339))
 }
 
-func (bot *MultiPairTradingBot) handleBuy(pair *models.TradingPair, tradeAmount, currentPrice, quoteBalance float64) bool {
-	if tradeAmount*currentPrice < pair.MinNotional {
-		logger.Infof("BUY amount too small for %s. Adjusting to minimum notional.", pair.Symbol)
-		tradeAmount = pair.MinNotional / currentPrice
+// syncClock measures the offset between the local and the exchange clock
+func (bot *MultiPairTradingBot) syncClock() {
+	clock, ok := bot.exchange.(interfaces.ServerClock)
+	if !ok {
+		return
+	}
 
-		if tradeAmount > quoteBalance {
-			logger.Infof("Skipping BUY for %s: Insufficient USDT balance. Need %.2f Have %.2f", pair.Symbol, tradeAmount, quoteBalance)
-			return false
-		}
+	before := time.Now()
+	serverTime, err := clock.GetServerTime()
+	if err != nil {
+		logger.Warnf("Failed to sync with exchange clock: %v", err)
+		return
 	}
+	// Assume the server read its clock halfway through the request
+	local := before.Add(time.Since(before) / 2)
+	offset := serverTime.Sub(local)
+	bot.clockOff.Store(int64(offset))
+	logger.Debugf("Exchange clock offset: %s", offset)
+}
 
-	// Place Limit BUY Order
-	limitPrice := currentPrice * 1.001 // 0.1% higher than current price
-	limitOrderPrice := strconv.FormatFloat(limitPrice, 'f', pair.PricePrecision, 64)
-	executedVolume := strconv.FormatFloat(tradeAmount, 'f', pair.QtyPrecision, 64)
+// refreshRules fetches the exchange trading rules of the pairs again when the client supports it
+func (bot *MultiPairTradingBot) refreshRules() {
+	refresher, ok := bot.exchange.(interfaces.RulesRefresher)
+	if !ok {
+		return
+	}
+	if err := refresher.RefreshRules(); err != nil {
+		logger.Warnf("Failed to refresh trading rules: %v", err)
+	}
+}
 
-	logger.Infof("Placing LIMIT BUY order for %s: Quantity=%.2f, Limit Price=%.2f", pair.Symbol, tradeAmount, limitPrice)
-	orderID, err := bot.exchange.CreateLimitOrder(pair.Symbol, "BUY", executedVolume, limitOrderPrice)
+// latestPair returns a pair with the trading rules of the last refresh, a refresh replaces the pair
+func (bot *MultiPairTradingBot) latestPair(pair *models.TradingPair) *models.TradingPair {
+	if latest, ok := bot.exchange.GetTradingPairs()[pair.Symbol]; ok {
+		return latest
+	}
+	return pair
+}
+
+// snapshotEquity stores the account equity when a risk manager is set
+func (bot *MultiPairTradingBot) snapshotEquity() {
+	if bot.risk == nil {
+		return
+	}
+	equity, err := bot.risk.Snapshot()
 	if err != nil {
-		logger.Infof("Error placing LIMIT BUY order for %s: %v", pair.Symbol, err)
-		return false
+		logger.Warnf("Failed to snapshot equity: %v", err)
+		return
 	}
+	logger.Debugf("Equity: %s", equity.StringFixed(2))
+}
 
-	// Log trade in database
-	err = db2.SQLiteDB.LogActiveTrade(pair.Symbol, limitPrice, tradeAmount)
+// allowOrder checks an order against the portfolio limits, the risk manager logs rejections. The
+// executor checks every order it places with it.
+func (bot *MultiPairTradingBot) allowOrder(pair *models.TradingPair, side, quantity, price string) bool {
+	if bot.risk == nil {
+		return true
+	}
+	qty, err := decimal.NewFromString(quantity)
 	if err != nil {
-		logger.Infof("Error logging BUY trade for %s: %v", pair.Symbol, err)
+		logger.Errorf("Invalid %s quantity %q for %s: %v", side, quantity, pair.Symbol, err)
+		return false
 	}
-	logger.Infof("Successfully placed LIMIT BUY order for %s. Order ID: %s", pair.Symbol, orderID)
-	return true
+	limit, err := decimal.NewFromString(price)
+	if err != nil {
+		logger.Errorf("Invalid %s price %q for %s: %v", side, price, pair.Symbol, err)
+		return false
+	}
+	return bot.risk.Check(pair, side, qty, limit) == nil
 }
 
-// handleSell processes a SELL order
-func (bot *MultiPairTradingBot) handleSell(pair *models.TradingPair, tradeAmount, currentPrice, baseBalance float64) bool {
-	if tradeAmount*currentPrice < pair.MinNotional {
-		logger.Infof("SELL amount too small for %s. Adjusting to minimum notional.", pair.Symbol)
-		tradeAmount = pair.MinNotional / currentPrice
+// liquidOrder reports whether the book of a pair takes an order of a notional in the quote asset
+// within the liquidity filters
+func (bot *MultiPairTradingBot) liquidOrder(pair *models.TradingPair, side string, notional decimal.Decimal) bool {
+	if bot.liquidity == nil {
+		return true
+	}
+	return bot.liquidity.Check(pair, side, notional) == nil
+}
 
-		if tradeAmount > baseBalance {
-			logger.Infof("Skipping SELL for %s: Insufficient balance. Need %.2f Have %.2f", pair.Symbol, tradeAmount, baseBalance)
-			return false
-		}
+// serverNow returns the current exchange time
+func (bot *MultiPairTradingBot) serverNow() time.Time {
+	return time.Now().Add(time.Duration(bot.clockOff.Load()))
+}
+
+// ProcessCandles runs a single pass of the trading decision path for a pair: trend filter,
+// strategy signal, daily trade cap, trade sizing and order placement.
+// now is used for the daily trade counter, which lets a backtest drive the bot with candle time.
+// It reports whether the strategy signal placed an order.
+func (bot *MultiPairTradingBot) ProcessCandles(pair *models.TradingPair, candles []models.CandleStick, now time.Time) bool {
+	if len(candles) == 0 {
+		return false
+	}
+	// Grids trade their limit orders instead of strategy signals
+	if g, ok := bot.grids[pair.Symbol]; ok {
+		bot.stepGrid(pair, g, decimal.NewFromFloat(candles[len(candles)-1].Close))
+		return false
 	}
 
-	// Place Limit SELL Order
-	limitPrice := currentPrice * 0.999 // 0.1% lower than current price
-	limitOrderPrice := strconv.FormatFloat(limitPrice, 'f', pair.PricePrecision, 64)
-	executedVolume := strconv.FormatFloat(tradeAmount, 'f', pair.QtyPrecision, 64)
+	state := bot.getPairState(pair.Symbol, now)
+	defer bot.saveState(pair.Symbol, state)
+
+	// Reset daily trade counter at midnight
+	if today := now.UTC().Format(time.DateOnly); today != state.lastResetDay {
+		logger.Infof("Resetting daily trade counter for %s. Previous trades: %d", pair.Symbol, state.tradesToday)
+		state.tradesToday = 0
+		state.lastResetDay = today
+	}
+
+	// Current price
+	currentPrice := decimal.NewFromFloat(candles[len(candles)-1].Close)
+
+	// Exit rules of the open position take precedence over the strategy
+	if bot.checkExits(pair, currentPrice, now) {
+		return false
+	}
 
-	logger.Infof("Placing LIMIT SELL order for %s: Quantity=%.2f, Limit Price=%.2f", pair.Symbol, tradeAmount, limitPrice)
-	orderID, err := bot.exchange.CreateLimitOrder(pair.Symbol, "SELL", executedVolume, limitOrderPrice)
+	// Detect trend and calculate signal
+	isUptrend := bot.isUptrend(candles)
+	signal, err := bot.strategy.Calculate(candles, pair.Symbol, isUptrend)
 	if err != nil {
-		logger.Infof("Error placing LIMIT SELL order for %s: %v", pair.Symbol, err)
+		logger.Infof("Error calculating strategy for %s: %v", pair.Symbol, err)
+		return false
+	}
+
+	if signal.Action == models.ActionHold {
+		// HOLD signal
+		return false
+	}
+	logger.Infof("%s signal for %s: %s", signal.Action, pair.Symbol, signal)
+
+	// Avoid overtrading
+	if state.tradesToday >= maxTradesPerDay {
+		logger.Infof("Max trades reached for %s today. Skipping further trades.", pair.Symbol)
 		return false
 	}
 
-	// Fetch active trade and log completed trade
-	activeTrades, err := db2.SQLiteDB.GetActiveTrades(pair.Symbol)
+	// Fetch balances
+	quoteBalance, err := bot.availableBalance(pair.QuoteAsset)
 	if err != nil {
-		logger.Infof("Error fetching active trade for %s: %v", pair.Symbol, err)
+		logger.Infof("Error fetching %s balance: %v", pair.QuoteAsset, err)
 		return false
 	}
-	for _, activeTrade := range activeTrades {
-		profitLoss := (limitPrice - activeTrade.BuyPrice) * tradeAmount
-		err = db2.SQLiteDB.LogCompletedTrade(pair.Symbol, activeTrade.BuyPrice, limitPrice, tradeAmount, profitLoss)
-		if err != nil {
-			logger.Infof("Error logging SELL trade for %s: %v", pair.Symbol, err)
+
+	if signal.Action == models.ActionSell {
+		if !bot.claimSell(pair.Symbol) {
+			logger.Infof("Skipping SELL for %s: its exit rules are being checked", pair.Symbol)
 			return false
 		}
+		defer bot.releaseSell(pair.Symbol)
+	}
 
-		// Remove the active trade
-		err = db2.SQLiteDB.RemoveActiveTrade(activeTrade.ID)
-		if err != nil {
-			logger.Infof("Error removing active trade for %s: %v", pair.Symbol, err)
+	position, err := bot.sellablePosition(pair)
+	if err != nil {
+		logger.Infof("%v", err)
+		return false
+	}
+
+	// Determine trade size
+	tradeAmount := bot.calculateTradeAmount(signal, quoteBalance, position, pair, candles)
+	if !tradeAmount.IsPositive() {
+		logger.Infof("Insufficient balance for %s trade. Skipping trade.", pair.Symbol)
+		return false
+	}
+
+	// Skip signals the book cannot take without a wide spread or slippage
+	notional, side := tradeAmount, "BUY"
+	if signal.Action == models.ActionSell {
+		notional, side = tradeAmount.Mul(currentPrice), "SELL"
+	}
+	if !bot.liquidOrder(pair, side, notional) {
+		return false
+	}
+
+	// Handle BUY or SELL
+	if signal.Action == models.ActionBuy {
+		trAmount := tradeAmount.Div(currentPrice)
+		logger.Debug("BUY signal", pair.Symbol, "Trade amount", trAmount, "Current price", currentPrice, "Position", position)
+		orderID, ok := bot.handleBuy(pair, trAmount, currentPrice, quoteBalance)
+		if !ok {
+			logger.Infof("Error handling BUY for %s\n", pair.Symbol)
+			return false
+		}
+		bot.exits.SetLevels(pair.Symbol, orderID, signal.StopLoss, signal.TakeProfit)
+	} else {
+		logger.Debug("SELL signal", pair.Symbol, "Trade amount", tradeAmount, "Current price", currentPrice, "Quote balance", quoteBalance)
+		// The protective order holds the position, take it off the exchange right before selling
+		if !bot.releaseProtection(pair) {
+			return false
+		}
+		if !bot.handleSell(pair, tradeAmount, currentPrice, position) {
+			logger.Infof("Error handling SELL for %s\n", pair.Symbol)
+			bot.protectPosition(pair, currentPrice)
 			return false
 		}
-		logger.Infof("Successfully completed SELL order for %s. Order ID: %s", pair.Symbol, orderID)
-
 	}
 
+	state.tradesToday++
 	return true
 }
 
-func (bot *MultiPairTradingBot) monitorCurrentCandle(pair *models.TradingPair) {
-	ticker := time.NewTicker(1 * time.Second) // Monitor every second
-	defer ticker.Stop()
+// getPairState returns the bookkeeping for a pair, creating it on first use
+func (bot *MultiPairTradingBot) getPairState(symbol string, now time.Time) *pairState {
+	bot.statesMu.Lock()
+	defer bot.statesMu.Unlock()
+
+	state, ok := bot.states[symbol]
+	if !ok {
+		// Restore the state checkpointed before a restart
+		state = &pairState{lastResetDay: now.UTC().Format(time.DateOnly)}
+		if day, ok := bot.store.Get(symbol, "last_reset_day"); ok {
+			state.lastResetDay = day
+		}
+		state.tradesToday, _ = bot.store.GetInt(symbol, "trades_today")
+		bot.states[symbol] = state
+	}
+	return state
+}
+
+// saveState checkpoints the bookkeeping of a pair, unchanged values are not written
+func (bot *MultiPairTradingBot) saveState(symbol string, state *pairState) {
+	bot.store.Set(symbol, "last_reset_day", state.lastResetDay)
+	bot.store.SetInt(symbol, "trades_today", state.tradesToday)
+}
+
+// availableBalance returns the free balance of an asset, without the funds of orders being repriced
+func (bot *MultiPairTradingBot) availableBalance(asset string) (decimal.Decimal, error) {
+	return bot.executor.Available(asset)
+}
+
+// sellablePosition returns what the bot may sell of a pair: the lots it bought that open SELL
+// orders and execution algorithms do not cover yet, at most the free balance. Holdings outside the ledger are left alone.
+// The protective order is released before a sell, so what it holds counts as sellable.
+func (bot *MultiPairTradingBot) sellablePosition(pair *models.TradingPair) (decimal.Decimal, error) {
+	baseBalance, err := bot.availableBalance(pair.BaseAsset)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("error fetching %s balance: %v", pair.BaseAsset, err)
+	}
+
+	position, err := db2.SQLiteDB.SellableQuantity(pair.Symbol)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("error fetching %s position: %v", pair.Symbol, err)
+	}
+	if bot.protection != nil {
+		protected, err := bot.protection.Protected(pair.Symbol)
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("error fetching the protected %s position: %v", pair.Symbol, err)
+		}
+		position, baseBalance = position.Add(protected), baseBalance.Add(protected)
+	}
+	position = position.Sub(bot.executor.Pending(pair.Symbol, "SELL"))
+	if position.GreaterThan(baseBalance) {
+		logger.Warnf("Position of %s (%s) exceeds the free %s balance (%s)", pair.Symbol, position, pair.BaseAsset, baseBalance)
+		position = baseBalance
+	}
+	return position, nil
+}
 
-	lastPrice := 0.0 // Track the last price for detecting spikes
+// watchExits checks the exit rules of the open position of a pair on every price update. Prices
+// come from the market data stream when the exchange client streams them, the price is polled
+// while no update arrives.
+func (bot *MultiPairTradingBot) watchExits(pair *models.TradingPair) {
+	defer bot.wg.Done()
 
+	var updates <-chan decimal.Decimal
+	if watcher, ok := bot.exchange.(interfaces.PriceWatcher); ok {
+		var stop func()
+		updates, stop = watcher.WatchPrice(pair.Symbol)
+		defer stop()
+	}
+
+	ticker := time.NewTicker(exitCheckInterval)
+	defer ticker.Stop()
+
+	streamed := false
 	for {
 		select {
 		case <-bot.stopCh:
 			return
+		case price := <-updates:
+			streamed = true
+			bot.checkExits(bot.latestPair(pair), price, time.Now())
 		case <-ticker.C:
-			// Fetch current price
-			currentPrice, err := bot.exchange.GetCurrentPrice(pair.Symbol)
+			if streamed {
+				streamed = false
+				continue
+			}
+			price, err := bot.exchange.GetCurrentPrice(pair.Symbol)
 			if err != nil {
-				logger.Infof("Error fetching current price for %s: %v", pair.Symbol, err)
+				logger.Debugf("Error fetching current price for %s: %v", pair.Symbol, err)
 				continue
 			}
+			bot.checkExits(bot.latestPair(pair), price, time.Now())
+		}
+	}
+}
 
-			// Calculate the price change percentage
-			priceChange := 0.0
-			if lastPrice > 0 {
-				priceChange = (currentPrice - lastPrice) / lastPrice * 100
-			}
+// checkExits sells the open position of a pair, or part of it, when its exit rules ask for it.
+// It reports whether an exit was due, also when the position is already being sold. Prices of a
+// pair the other path is selling are skipped.
+func (bot *MultiPairTradingBot) checkExits(pair *models.TradingPair, price decimal.Decimal, now time.Time) bool {
+	if !bot.claimSell(pair.Symbol) {
+		return false
+	}
+	defer bot.releaseSell(pair.Symbol)
 
-			lastPrice = currentPrice // Update the last price
+	exit, err := bot.exits.Evaluate(pair.Symbol, price, now)
+	if err != nil {
+		logger.Warnf("Error evaluating exit rules of %s: %v", pair.Symbol, err)
+		return false
+	}
+	if exit == nil {
+		bot.protectPosition(pair, price)
+		return false
+	}
 
-			log.Println(pair.Symbol, "Current Price:", currentPrice, "Price Change:", priceChange)
+	position, err := bot.sellablePosition(pair)
+	if err != nil {
+		logger.Infof("%v", err)
+		return true
+	}
+	quantity := decimal.Min(exit.Quantity, position)
+	if exit.Full {
+		quantity = position
+	}
+	if !quantity.IsPositive() {
+		// Open SELL orders already cover the position
+		return true
+	}
 
-			// Spike detection logic
-			if priceChange > 1.0 { // Spike up (e.g., >2%)
-				logger.Infof("Detected upward spike for %s: Price Change = %.2f%%", pair.Symbol, priceChange)
+	logger.Infof("Exit for %s: %s, selling %s", pair.Symbol, exit.Reason, quantity)
+	if !bot.releaseProtection(pair) {
+		return true
+	}
+	if !bot.handleSell(pair, quantity, price, position) {
+		bot.protectPosition(pair, price)
+		return true
+	}
+	bot.exits.Executed(exit)
+	return true
+}
 
-				// Fetch available USDT balance
-				quoteBalance, err := bot.exchange.GetBalance(pair.QuoteAsset)
-				if err != nil {
-					logger.Infof("Error fetching USDT balance for %s: %v", pair.Symbol, err)
-					continue
-				}
+// claimSell marks a pair as being sold, false when the candle or price path already sells it
+func (bot *MultiPairTradingBot) claimSell(symbol string) bool {
+	bot.sellingMu.Lock()
+	defer bot.sellingMu.Unlock()
 
-				// Calculate the trade amount
-				tradeAmount := pair.MinNotional / currentPrice
-				if tradeAmount*currentPrice < pair.MinNotional || tradeAmount > quoteBalance {
-					logger.Infof("Skipping BUY for %s: Insufficient USDT balance or below minNotional", pair.Symbol)
-					continue
-				}
+	if bot.selling[symbol] {
+		return false
+	}
+	bot.selling[symbol] = true
+	return true
+}
 
-				// Place a BUY order
-				quantity := strconv.FormatFloat(tradeAmount, 'f', pair.QtyPrecision, 64)
-				orderID, err := bot.exchange.CreateMarketOrder(pair.Symbol, "BUY", quantity)
-				if err != nil {
-					logger.Infof("Error executing BUY order for %s: %v", pair.Symbol, err)
-					continue
-				}
+// releaseSell ends the claim of claimSell
+func (bot *MultiPairTradingBot) releaseSell(symbol string) {
+	bot.sellingMu.Lock()
+	delete(bot.selling, symbol)
+	bot.sellingMu.Unlock()
+}
 
-				// Log the trade in the database
-				logger.Infof("Executed BUY order for %s. Order ID: %s", pair.Symbol, orderID)
-				err = db2.SQLiteDB.LogActiveTrade(pair.Symbol, currentPrice, tradeAmount)
-				if err != nil {
-					logger.Infof("Error logging BUY trade for %s: %v", pair.Symbol, err)
-				}
-			}
+// protectPosition keeps the protective order of a pair in line with the exit levels of its position
+func (bot *MultiPairTradingBot) protectPosition(pair *models.TradingPair, price decimal.Decimal) {
+	if bot.protection == nil {
+		return
+	}
+	levels, _ := bot.exits.Levels(pair.Symbol)
+	if err := bot.protection.Protect(pair, levels, price); err != nil {
+		logger.Warnf("Error protecting the position of %s: %v", pair.Symbol, err)
+	}
+}
+
+// releaseProtection cancels the protective order of a pair right before the bot sells the position
+// itself, false when the order is still on the exchange
+func (bot *MultiPairTradingBot) releaseProtection(pair *models.TradingPair) bool {
+	if bot.protection == nil {
+		return true
+	}
+	if err := bot.protection.Release(pair.Symbol); err != nil {
+		logger.Warnf("Error releasing the protective order of %s, not selling: %v", pair.Symbol, err)
+		return false
+	}
+	return true
+}
+
+// sizeBuy asks the position sizer of a pair for the quote amount of a BUY, 0 skips the BUY
+func (bot *MultiPairTradingBot) sizeBuy(pair *models.TradingPair, candles []models.CandleStick, quoteBalance decimal.Decimal) decimal.Decimal {
+	portfolio, err := risk.Valuate(bot.exchange)
+	if err != nil {
+		logger.Warnf("Error valuing the portfolio to size %s: %v", pair.Symbol, err)
+		return decimal.Zero
+	}
+
+	sizer := bot.sizers.For(pair.Symbol)
+	amount, err := sizer.Size(sizing.Context{
+		Symbol:       pair.Symbol,
+		Price:        decimal.NewFromFloat(candles[len(candles)-1].Close),
+		Candles:      candles,
+		QuoteBalance: quoteBalance,
+		Equity:       portfolio.Equity,
+		Pairs:        len(bot.exchange.GetTradingPairs()),
+	})
+	if err != nil {
+		logger.Warnf("Error sizing BUY for %s with %s: %v", pair.Symbol, sizer.Name(), err)
+		return decimal.Zero
+	}
+	logger.Debugf("%s sized BUY for %s at %s %s", sizer.Name(), pair.Symbol, amount.StringFixed(2), pair.QuoteAsset)
+	return amount
+}
+
+func (bot *MultiPairTradingBot) handleBuy(pair *models.TradingPair, tradeAmount, price, quoteBalance decimal.Decimal) (int64, bool) {
+	if tradeAmount.Mul(price).LessThan(pair.MinNotional) {
+		logger.Infof("BUY amount too small for %s. Adjusting to minimum notional.", pair.Symbol)
+		tradeAmount = pair.MinNotional.Div(price)
+
+		if pair.MinNotional.GreaterThan(quoteBalance) {
+			logger.Infof("Skipping BUY for %s: Insufficient USDT balance. Need %s Have %s", pair.Symbol, pair.MinNotional.StringFixed(2), quoteBalance.StringFixed(2))
+			return 0, false
+		}
+	}
+
+	// Place Limit BUY Order slightly above the current price
+	limitOrderPrice := bot.executor.LimitPrice(pair, "BUY", price)
+	executedVolume := pair.FormatQty(tradeAmount)
+
+	logger.Infof("Placing LIMIT BUY order for %s: Quantity=%s, Limit Price=%s", pair.Symbol, executedVolume, limitOrderPrice)
+	// The position is opened once the order fills
+	orderID, err := bot.executor.Submit(bot.ctx, pair, "BUY", executedVolume, limitOrderPrice)
+	if err != nil {
+		logger.Infof("Error placing LIMIT BUY order for %s: %v", pair.Symbol, err)
+		return 0, false
+	}
+	logger.Infof("Successfully placed LIMIT BUY order for %s. Order ID: %d", pair.Symbol, orderID)
+	return orderID, true
+}
+
+// handleSell processes a SELL order
+func (bot *MultiPairTradingBot) handleSell(pair *models.TradingPair, tradeAmount, price, position decimal.Decimal) bool {
+	if tradeAmount.Mul(price).LessThan(pair.MinNotional) {
+		logger.Infof("SELL amount too small for %s. Adjusting to minimum notional.", pair.Symbol)
+		tradeAmount = pair.MinNotional.Div(price)
+
+		if tradeAmount.GreaterThan(position) {
+			logger.Infof("Skipping SELL for %s: Insufficient position. Need %s Have %s", pair.Symbol, tradeAmount, position)
+			return false
+		}
+	}
+
+	// Place Limit SELL Order slightly below the current price
+	limitOrderPrice := bot.executor.LimitPrice(pair, "SELL", price)
+	executedVolume := pair.FormatQty(tradeAmount)
+
+	logger.Infof("Placing LIMIT SELL order for %s: Quantity=%s, Limit Price=%s", pair.Symbol, executedVolume, limitOrderPrice)
+	// Positions are closed once the order fills
+	orderID, err := bot.executor.Submit(bot.ctx, pair, "SELL", executedVolume, limitOrderPrice)
+	if err != nil {
+		logger.Infof("Error placing LIMIT SELL order for %s: %v", pair.Symbol, err)
+		return false
+	}
+	logger.Infof("Successfully placed LIMIT SELL order for %s. Order ID: %d", pair.Symbol, orderID)
+	return true
+}
+
+// SyncOrders applies the fills of open orders and moves the orders under execution along the
+// execution policy, backtests call it after every replay step
+func (bot *MultiPairTradingBot) SyncOrders() {
+	bot.orders.SyncOpenOrders()
+	bot.executor.Step()
+}
+
+// tradeGrid follows the grid of a pair with the price until the price leaves its range
+func (bot *MultiPairTradingBot) tradeGrid(pair *models.TradingPair, g *grid.Grid) {
+	defer bot.wg.Done()
+
+	logger.Infof("Started grid trading %s", pair.Symbol)
+
+	ticker := time.NewTicker(gridCheckInterval)
+	defer ticker.Stop()
 
-			activeTrades, err := db2.SQLiteDB.GetActiveTrades(pair.Symbol)
+	for {
+		select {
+		case <-bot.stopCh:
+			return
+		case <-ticker.C:
+			price, err := bot.exchange.GetCurrentPrice(pair.Symbol)
 			if err != nil {
-				logger.Infof("Error fetching active trades for %s: %v", pair.Symbol, err)
+				logger.Infof("Error fetching current price for %s: %v", pair.Symbol, err)
 				continue
 			}
+			bot.stepGrid(bot.latestPair(pair), g, price)
+			if g.Stopped() {
+				logger.Infof("Stopped grid trading %s", pair.Symbol)
+				return
+			}
+		}
+	}
+}
 
-			dropThreshold := 0.5 // Percentage drop to trigger a sell (e.g., 2%)
-
-			for _, trade := range activeTrades {
-				// Initialize maxPrice with the price at which the trade was executed (PriceOnBuy)
-				maxPrice := trade.BuyPrice
-
-				// Calculate breakeven price (include fees)
-				feeRate := 0.001 // Default fee rate
-				requiredPrice := trade.BuyPrice * (1 + feeRate)
+// stepGrid moves the grid of a pair along with the price
+func (bot *MultiPairTradingBot) stepGrid(pair *models.TradingPair, g *grid.Grid, price decimal.Decimal) {
+	if err := g.Step(pair, price); err != nil {
+		logger.Errorf("Error trading the grid of %s: %v", pair.Symbol, err)
+	}
+}
 
-				// Update the maximum price if current price exceeds it
-				if currentPrice > maxPrice {
-					maxPrice = currentPrice
-				}
+// monitorCurrentCandle evaluates the strategy of a pair on every price update, the forming candle
+// closes at the latest price. Prices come from the market data stream when the exchange client
+// streams them and are polled every second otherwise. A signal trades once per candle, the exit
+// rules are checked on every update.
+func (bot *MultiPairTradingBot) monitorCurrentCandle(pair *models.TradingPair) {
+	defer bot.wg.Done()
 
-				// Calculate the percentage drop from the maximum price
-				priceDrop := (maxPrice - currentPrice) / maxPrice * 100
+	var updates <-chan decimal.Decimal
+	if watcher, ok := bot.exchange.(interfaces.PriceWatcher); ok {
+		var stop func()
+		updates, stop = watcher.WatchPrice(pair.Symbol)
+		defer stop()
+	}
 
-				// Check if the price is below the breakeven price or if the drop exceeds the threshold
-				if currentPrice <= requiredPrice || priceDrop >= dropThreshold {
-					logger.Infof("Detected price drop below breakeven or spike reversal for %s. Reversing trade.", pair.Symbol)
-					logger.Infof("Current Price: %.8f, Max Price: %.8f, Price Drop: %.2f%%", currentPrice, maxPrice, priceDrop)
+	ticker := time.NewTicker(exitCheckInterval)
+	defer ticker.Stop()
 
-					// Sell immediately to avoid losses or secure profit
-					quantity := strconv.FormatFloat(trade.Quantity, 'f', pair.QtyPrecision, 64)
-					orderID, err := bot.exchange.CreateMarketOrder(pair.Symbol, "SELL", quantity)
-					if err != nil {
-						logger.Infof("Error executing SELL order for %s: %v", pair.Symbol, err)
-						continue
-					}
+	logger.Infof("Started trading %s on price ticks", pair.Symbol)
 
-					// Log and remove the trade
-					logger.Infof("Executed SELL order for %s. Order ID: %f", pair.Symbol, orderID)
-					err = db2.SQLiteDB.RemoveActiveTrade(trade.ID)
-					if err != nil {
-						logger.Infof("Error removing active trade for %s: %v", pair.Symbol, err)
-					}
-				}
+	var traded time.Time // Open time of the last candle a signal traded on
+	streamed := false
+	for {
+		var price decimal.Decimal
+		select {
+		case <-bot.stopCh:
+			return
+		case price = <-updates:
+			streamed = true
+		case <-ticker.C:
+			if streamed {
+				streamed = false
+				continue
+			}
+			var err error
+			price, err = bot.exchange.GetCurrentPrice(pair.Symbol)
+			if err != nil {
+				logger.Debugf("Error fetching current price for %s: %v", pair.Symbol, err)
+				continue
 			}
 		}
+		traded = bot.processTick(bot.latestPair(pair), price, traded, time.Now())
+	}
+}
+
+// processTick runs the strategy of a pair on its candles with the forming candle at price. A candle
+// a signal already traded on only gets its exit rules checked. It returns the open time of the last
+// candle a signal traded on.
+func (bot *MultiPairTradingBot) processTick(pair *models.TradingPair, price decimal.Decimal, traded, now time.Time) time.Time {
+	candles, err := bot.exchange.FetchCandles(pair.Symbol, bot.interval, 100)
+	if err != nil || len(candles) == 0 {
+		logger.Infof("Error fetching candles for %s: %v", pair.Symbol, err)
+		bot.checkExits(pair, price, now)
+		return traded
+	}
+
+	forming := &candles[len(candles)-1]
+	if forming.Timestamp.Equal(traded) {
+		bot.checkExits(pair, price, now)
+		return traded
+	}
+	forming.Close = price.InexactFloat64()
+	forming.High = max(forming.High, forming.Close)
+	forming.Low = min(forming.Low, forming.Close)
+
+	if bot.ProcessCandles(pair, candles, now) {
+		return forming.Timestamp
 	}
+	return traded
 }
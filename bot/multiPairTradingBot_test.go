@@ -0,0 +1,264 @@
+package bot
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/exits"
+	"binance_bot/interfaces"
+	"binance_bot/liquidity"
+	"binance_bot/models"
+	"binance_bot/protection"
+	"binance_bot/strategies"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+// tickExchange serves candles up to a forming candle and records the limit orders placed
+type tickExchange struct {
+	interfaces.ExchangeClient
+	forming time.Time // Open time of the forming candle
+	orders  []string  // Side, quantity and price of the placed orders
+}
+
+func (e *tickExchange) GetTradingPairs() map[string]*models.TradingPair {
+	return map[string]*models.TradingPair{"ETHUSDT": {
+		Symbol: "ETHUSDT", BaseAsset: "ETH", QuoteAsset: "USDT",
+		QtyPrecision: 3, PricePrecision: 2, MinNotional: d("5"),
+	}}
+}
+
+// FetchCandles returns flat candles at 100, the forming candle has not moved yet
+func (e *tickExchange) FetchCandles(symbol, interval string, limit int) ([]models.CandleStick, error) {
+	candles := make([]models.CandleStick, limit)
+	for i := range candles {
+		open := e.forming.Add(time.Duration(i-limit+1) * time.Minute)
+		candles[i] = models.CandleStick{Timestamp: open, Open: 100, High: 101, Low: 99, Close: 100}
+	}
+	return candles, nil
+}
+
+func (e *tickExchange) GetBalance(asset string) (decimal.Decimal, error) {
+	if asset == "USDT" {
+		return d("1000"), nil
+	}
+	return decimal.Zero, nil
+}
+
+func (e *tickExchange) CreateLimitOrder(symbol, side, quantity, price string) (int64, error) {
+	e.orders = append(e.orders, side+" "+quantity+" @ "+price)
+	return int64(len(e.orders)), nil
+}
+
+// breakout buys a tenth of the balance once the forming candle closes above 105
+type breakout struct {
+	seen []models.CandleStick // Forming candles the strategy was evaluated on
+}
+
+func (s *breakout) GetStrategyType() strategies.StrategyType {
+	return strategies.SpikeDetectionStrategyType
+}
+
+func (s *breakout) Calculate(candles []models.CandleStick, pair string, trend bool) (models.Signal, error) {
+	forming := candles[len(candles)-1]
+	s.seen = append(s.seen, forming)
+	if forming.Close > 105 {
+		return models.Signal{Action: models.ActionBuy, SizeFraction: 0.1}, nil
+	}
+	return models.Signal{Action: models.ActionHold}, nil
+}
+
+func TestProcessTick(t *testing.T) {
+	if err := db2.InitDBAt(t.TempDir() + "/tick.db"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db2.SQLiteDB.DB.Close() })
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	exchange := &tickExchange{forming: start}
+	strategy := &breakout{}
+	bot := NewMultiPairTradingBot(exchange, strategy, "1m")
+	pair := exchange.GetTradingPairs()["ETHUSDT"]
+
+	steps := []struct {
+		name   string
+		candle int // Minutes of the forming candle after start
+		price  string
+		close  float64 // Close of the forming candle the strategy sees, 0 when it is not evaluated
+		high   float64
+		low    float64
+		orders []string
+		traded int // Minutes of the candle last traded on after start, -1 for none
+	}{
+		{"price within the candle holds", 0, "100.5", 100.5, 101, 99, nil, -1},
+		{"dip extends the low", 0, "98", 98, 101, 98, nil, -1},
+		{"breakout buys on the forming candle", 0, "110", 110, 110, 99, []string{"BUY 0.909 @ 110.11"}, 0},
+		{"signal trades once per candle", 0, "111", 0, 0, 0, []string{"BUY 0.909 @ 110.11"}, 0},
+		{"next candle trades again", 1, "106", 106, 106, 99, []string{"BUY 0.909 @ 110.11", "BUY 0.943 @ 106.10"}, 1},
+	}
+
+	traded := time.Time{}
+	for _, step := range steps {
+		exchange.forming = start.Add(time.Duration(step.candle) * time.Minute)
+		evaluated := len(strategy.seen)
+		traded = bot.processTick(pair, d(step.price), traded, start)
+
+		if step.close == 0 {
+			if len(strategy.seen) != evaluated {
+				t.Errorf("%s: strategy evaluated on a candle it traded on", step.name)
+			}
+		} else if len(strategy.seen) != evaluated+1 {
+			t.Errorf("%s: strategy evaluated %d times, want once", step.name, len(strategy.seen)-evaluated)
+		} else if c := strategy.seen[evaluated]; c.Close != step.close || c.High != step.high || c.Low != step.low {
+			t.Errorf("%s: forming candle closes %v high %v low %v, want %v %v %v", step.name, c.Close, c.High, c.Low, step.close, step.high, step.low)
+		}
+
+		if len(exchange.orders) != len(step.orders) {
+			t.Fatalf("%s: got orders %v, want %v", step.name, exchange.orders, step.orders)
+		}
+		for i, order := range exchange.orders {
+			if order != step.orders[i] {
+				t.Errorf("%s: order %d is %s, want %s", step.name, i, order, step.orders[i])
+			}
+		}
+		want := time.Time{}
+		if step.traded >= 0 {
+			want = start.Add(time.Duration(step.traded) * time.Minute)
+		}
+		if !traded.Equal(want) {
+			t.Errorf("%s: last traded candle %s, want %s", step.name, traded, want)
+		}
+	}
+}
+
+// protectExchange holds 1 ETH, locked by its open SELL orders, and keeps the orders placed on it
+type protectExchange struct {
+	ledgerExchange
+	failSell bool // Reject limit SELL orders
+	cancels  int
+}
+
+func (e *protectExchange) GetTradingPairs() map[string]*models.TradingPair {
+	return map[string]*models.TradingPair{"ETHUSDT": {Symbol: "ETHUSDT", BaseAsset: "ETH", QuoteAsset: "USDT", QtyPrecision: 3, PricePrecision: 2, MinNotional: d("5")}}
+}
+
+func (e *protectExchange) GetBalance(asset string) (decimal.Decimal, error) {
+	if asset == "USDT" {
+		return d("1000"), nil
+	}
+	free := d("1")
+	for _, order := range e.orders {
+		if order.Side == "SELL" && order.IsOpen() {
+			free = free.Sub(order.Remaining())
+		}
+	}
+	return free, nil
+}
+
+// GetBookTicker fails, so a liquidity filter skips every signal
+func (e *protectExchange) GetBookTicker(symbol string) (*models.BookTicker, error) {
+	return nil, fmt.Errorf("book unavailable")
+}
+
+func (e *protectExchange) place(orderType, quantity, price string) int64 {
+	id := int64(len(e.orders) + 1)
+	e.orders[id] = &models.Order{OrderID: id, Symbol: "ETHUSDT", Side: "SELL", Type: orderType, Quantity: d(quantity), Price: d(price), Status: models.OrderStatusNew}
+	return id
+}
+
+func (e *protectExchange) CreateStopLossLimitOrder(symbol, side, quantity, price, stopLoss string) (int64, error) {
+	return e.place("STOP_LOSS_LIMIT", quantity, price), nil
+}
+
+func (e *protectExchange) CreateLimitOrder(symbol, side, quantity, price string) (int64, error) {
+	if e.failSell {
+		return 0, fmt.Errorf("rejected")
+	}
+	return e.place("LIMIT", quantity, price), nil
+}
+
+func (e *protectExchange) CancelOrder(symbol string, orderID int64) error {
+	e.cancels++
+	e.orders[orderID].Status = models.OrderStatusCanceled
+	return nil
+}
+
+// open returns the type and quantity of the open orders
+func (e *protectExchange) open() []string {
+	var open []string
+	for id := int64(1); id <= int64(len(e.orders)); id++ {
+		if order := e.orders[id]; order.IsOpen() {
+			open = append(open, order.Type+" "+order.Quantity.String())
+		}
+	}
+	return open
+}
+
+// fixedSignal returns the same signal on every evaluation
+type fixedSignal struct {
+	signal models.Signal
+}
+
+func (s fixedSignal) GetStrategyType() strategies.StrategyType {
+	return strategies.BollingerStrategyType
+}
+
+func (s fixedSignal) Calculate(candles []models.CandleStick, pair string, trend bool) (models.Signal, error) {
+	return s.signal, nil
+}
+
+func TestSellReleasesProtection(t *testing.T) {
+	if err := db2.InitDBAt(t.TempDir() + "/protect.db"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db2.SQLiteDB.DB.Close() })
+	if err := db2.SQLiteDB.LogActiveTrade("ETHUSDT", d("100"), d("1")); err != nil {
+		t.Fatal(err)
+	}
+
+	exchange := &protectExchange{ledgerExchange: ledgerExchange{orders: make(map[int64]*models.Order)}}
+	bot := NewMultiPairTradingBot(exchange, fixedSignal{models.Signal{Action: models.ActionSell}}, "1h")
+	bot.SetExitRules(exits.Config{StopLossPercent: 5})
+	bot.SetProtection(protection.Config{Type: protection.StopLossLimitType})
+	pair := exchange.GetTradingPairs()["ETHUSDT"]
+	candles := []models.CandleStick{{Open: 100, High: 101, Low: 99, Close: 100}}
+
+	// The stop 5% below the entry goes on the exchange while there is no exit
+	if bot.checkExits(pair, d("100"), time.Now()) {
+		t.Fatal("exit at the entry price")
+	}
+	if open := exchange.open(); len(open) != 1 || open[0] != "STOP_LOSS_LIMIT 1" {
+		t.Fatalf("got open orders %v, want the stop for 1", open)
+	}
+
+	steps := []struct {
+		name      string
+		liquidity bool // Skip the SELL with the liquidity filter
+		failSell  bool
+		traded    bool
+		cancels   int
+		open      []string
+	}{
+		{"SELL skipped by the liquidity filter keeps the stop", true, false, false, 0, []string{"STOP_LOSS_LIMIT 1"}},
+		{"failed SELL puts the stop back", false, true, false, 1, []string{"STOP_LOSS_LIMIT 1"}},
+		{"SELL sells the protected position", false, false, true, 2, []string{"LIMIT 1"}},
+	}
+	for _, step := range steps {
+		bot.liquidity = nil
+		if step.liquidity {
+			bot.SetLiquidityFilters(liquidity.Config{Filter: liquidity.Filter{MaxSpreadPercent: 1}})
+		}
+		exchange.failSell = step.failSell
+
+		if traded := bot.ProcessCandles(pair, candles, time.Now()); traded != step.traded {
+			t.Errorf("%s: traded %v, want %v", step.name, traded, step.traded)
+		}
+		if exchange.cancels != step.cancels {
+			t.Errorf("%s: got %d cancels, want %d", step.name, exchange.cancels, step.cancels)
+		}
+		open := exchange.open()
+		if fmt.Sprint(open) != fmt.Sprint(step.open) {
+			t.Errorf("%s: got open orders %v, want %v", step.name, open, step.open)
+		}
+	}
+}
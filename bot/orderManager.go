@@ -0,0 +1,164 @@
+package bot
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/interfaces"
+	"binance_bot/logger"
+	"binance_bot/models"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"sync"
+	"time"
+)
+
+// OrderManager persists every order placed by the bot and opens or closes positions
+// only from the fills reported by the exchange, including partial fills.
+type OrderManager struct {
+	exchange interfaces.ExchangeClient
+	mu       sync.Mutex
+}
+
+// NewOrderManager creates a new instance of OrderManager
+func NewOrderManager(exchange interfaces.ExchangeClient) *OrderManager {
+	return &OrderManager{exchange: exchange}
+}
+
+// Track stores a newly placed order and applies the fills it already carries
+func (om *OrderManager) Track(order *models.Order) error {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	placed := *order
+	placed.FilledQty, placed.AvgPrice, placed.Commission = decimal.Zero, decimal.Zero, decimal.Zero
+	if placed.Status == "" {
+		placed.Status = models.OrderStatusNew
+	}
+	if err := db2.SQLiteDB.LogOrder(&placed); err != nil {
+		return err
+	}
+
+	return om.apply(&placed, order)
+}
+
+// Run polls the exchange for updates of open orders until stopCh is closed
+func (om *OrderManager) Run(stopCh <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			om.SyncOpenOrders()
+		}
+	}
+}
+
+// SyncOpenOrders fetches the status of every stored open order once and applies new fills
+func (om *OrderManager) SyncOpenOrders() {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	orders, err := db2.SQLiteDB.GetOpenOrders()
+	if err != nil {
+		logger.Errorf("Error fetching open orders: %v", err)
+		return
+	}
+
+	for _, stored := range orders {
+		latest, err := om.exchange.GetOrder(stored.Symbol, stored.OrderID)
+		if err != nil {
+			logger.Warnf("Error fetching order %d for %s: %v", stored.OrderID, stored.Symbol, err)
+			continue
+		}
+		if err := om.apply(stored, latest); err != nil {
+			logger.Errorf("Error applying update of order %d for %s: %v", stored.OrderID, stored.Symbol, err)
+		}
+	}
+}
+
+// Refresh fetches the latest status of a stored order from the exchange and applies new fills
+func (om *OrderManager) Refresh(symbol string, orderID int64) (*models.Order, error) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	stored, err := db2.SQLiteDB.GetOrder(symbol, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !stored.IsOpen() {
+		return stored, nil
+	}
+
+	latest, err := om.exchange.GetOrder(symbol, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching order %d for %s: %v", orderID, symbol, err)
+	}
+	if err := om.apply(stored, latest); err != nil {
+		return nil, err
+	}
+	return latest, nil
+}
+
+// apply turns the fills between two snapshots of an order into position changes and stores the latest snapshot
+func (om *OrderManager) apply(prev, latest *models.Order) error {
+	// Keep the fields only known when the order was placed
+	latest.Symbol, latest.Side = prev.Symbol, prev.Side
+
+	if latest.Status != prev.Status && !latest.IsOpen() {
+		logger.Infof("Order %d for %s is %s. Filled %s of %s", latest.OrderID, latest.Symbol, latest.Status, latest.FilledQty, latest.Quantity)
+	}
+
+	delta := latest.FilledQty.Sub(prev.FilledQty)
+	if !delta.IsPositive() {
+		return db2.SQLiteDB.UpdateOrder(latest)
+	}
+
+	// The ledger stores the snapshot together with the lots, so a fill is applied once
+	fillPrice := latest.AvgPrice.Mul(latest.FilledQty).Sub(prev.AvgPrice.Mul(prev.FilledQty)).Div(delta)
+	commission := latest.Commission.Sub(prev.Commission)
+	logger.Infof("Order %d %s %s filled %s at %s (%s)", latest.OrderID, latest.Side, latest.Symbol, delta, fillPrice, latest.Status)
+	if latest.Side == "BUY" {
+		return om.openPosition(latest, delta, fillPrice, commission)
+	}
+	return om.closePositions(latest, delta, fillPrice, commission)
+}
+
+// openPosition records a BUY fill as a lot in the position ledger. Commission paid in the base asset
+// reduces the quantity, the fee is kept in the quote asset for the realized profit.
+func (om *OrderManager) openPosition(order *models.Order, qty, price, commission decimal.Decimal) error {
+	fee := om.quoteFee(order, price, commission)
+	if pair, ok := om.exchange.GetTradingPairs()[order.Symbol]; ok && order.CommissionAsset == pair.BaseAsset {
+		qty = qty.Sub(commission)
+	}
+	return db2.SQLiteDB.OpenLot(order, price, qty, fee)
+}
+
+// closePositions closes lots in the position ledger first in, first out for a SELL fill
+func (om *OrderManager) closePositions(order *models.Order, qty, price, commission decimal.Decimal) error {
+	untracked, err := db2.SQLiteDB.CloseLots(order, price, qty, om.quoteFee(order, price, commission))
+	if err != nil {
+		return err
+	}
+	if untracked.IsPositive() {
+		logger.Warnf("SELL order %d for %s filled %s more than the tracked positions", order.OrderID, order.Symbol, untracked)
+	}
+	return nil
+}
+
+// quoteFee converts the commission of a fill to the quote asset. Commission paid in another asset,
+// such as BNB, has no price in the pair and is left out of the realized profit.
+func (om *OrderManager) quoteFee(order *models.Order, price, commission decimal.Decimal) decimal.Decimal {
+	pair, ok := om.exchange.GetTradingPairs()[order.Symbol]
+	switch {
+	case !commission.IsPositive():
+		return decimal.Zero
+	case ok && order.CommissionAsset == pair.BaseAsset:
+		return commission.Mul(price)
+	case ok && order.CommissionAsset == pair.QuoteAsset:
+		return commission
+	}
+	logger.Warnf("Order %d for %s paid %s %s commission, which is not counted in the realized profit", order.OrderID, order.Symbol, commission, order.CommissionAsset)
+	return decimal.Zero
+}
@@ -0,0 +1,291 @@
+package bot
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/interfaces"
+	"binance_bot/models"
+	"github.com/shopspring/decimal"
+	"slices"
+	"testing"
+)
+
+// ledgerExchange reports the ETHUSDT pair and the latest snapshot of the orders of a test
+type ledgerExchange struct {
+	interfaces.ExchangeClient
+	orders map[int64]*models.Order
+}
+
+func (e *ledgerExchange) GetTradingPairs() map[string]*models.TradingPair {
+	return map[string]*models.TradingPair{"ETHUSDT": {Symbol: "ETHUSDT", BaseAsset: "ETH", QuoteAsset: "USDT"}}
+}
+
+func (e *ledgerExchange) GetOrder(symbol string, orderID int64) (*models.Order, error) {
+	order := *e.orders[orderID]
+	return &order, nil
+}
+
+// fill is a snapshot of an order as the exchange reports it
+func fill(id int64, side, qty, filled, avgPrice, commission, commissionAsset string) *models.Order {
+	status := models.OrderStatusFilled
+	if d(filled).IsZero() {
+		status = models.OrderStatusNew
+	} else if !d(filled).Equal(d(qty)) {
+		status = models.OrderStatusPartiallyFilled
+	}
+	return &models.Order{
+		OrderID:         id,
+		Symbol:          "ETHUSDT",
+		Side:            side,
+		Type:            "LIMIT",
+		Quantity:        d(qty),
+		Price:           d(avgPrice),
+		Status:          status,
+		FilledQty:       d(filled),
+		AvgPrice:        d(avgPrice),
+		Commission:      d(commission),
+		CommissionAsset: commissionAsset,
+	}
+}
+
+func d(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
+func TestLedgerFills(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates []*models.Order // Tracked when new, polled otherwise
+		lots    [][2]string     // Quantity and entry fee of the open lots, oldest first
+		profits []string        // Realized profit of the completed trades, oldest first
+	}{
+		{
+			name: "oldest lot closes first",
+			updates: []*models.Order{
+				fill(1, "BUY", "1", "1", "100", "0", ""),
+				fill(2, "BUY", "1", "1", "110", "0", ""),
+				fill(3, "SELL", "1", "1", "120", "0", ""),
+			},
+			lots:    [][2]string{{"1", "0"}},
+			profits: []string{"20"},
+		},
+		{
+			// The base-asset commission shrinks the first lot, the SELL fee is split by quantity
+			name: "partial close with base-asset commission",
+			updates: []*models.Order{
+				fill(1, "BUY", "1", "1", "100", "0.001", "ETH"),
+				fill(2, "BUY", "2", "2", "110", "0.22", "USDT"),
+				fill(3, "SELL", "3", "1.5", "120", "0.18", "USDT"),
+			},
+			lots:    [][2]string{{"1.499", "0.16489"}},
+			profits: []string{"19.76012", "4.89477"},
+		},
+		{
+			// Polling the same snapshot again must not close more lots
+			name: "fills across polls apply once",
+			updates: []*models.Order{
+				fill(1, "BUY", "2", "2", "100", "0", ""),
+				fill(2, "SELL", "2", "1", "110", "0.11", "USDT"),
+				fill(2, "SELL", "2", "1", "110", "0.11", "USDT"),
+				fill(2, "SELL", "2", "2", "115", "0.23", "USDT"),
+			},
+			profits: []string{"9.89", "19.88"},
+		},
+		{
+			name: "sell fee in the base asset at the fill price",
+			updates: []*models.Order{
+				fill(1, "BUY", "1", "1", "100", "0", ""),
+				fill(2, "SELL", "0.5", "0.5", "120", "0.0005", "ETH"),
+			},
+			lots:    [][2]string{{"0.5", "0"}},
+			profits: []string{"9.94"},
+		},
+		{
+			name: "sell beyond the lots",
+			updates: []*models.Order{
+				fill(1, "BUY", "1", "1", "100", "0", ""),
+				fill(2, "SELL", "1.5", "1.5", "90", "0", ""),
+			},
+			profits: []string{"-10"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := db2.InitDBAt(t.TempDir() + "/ledger.db"); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db2.SQLiteDB.DB.Close() })
+			exchange := &ledgerExchange{orders: make(map[int64]*models.Order)}
+			om := NewOrderManager(exchange)
+
+			for _, update := range tt.updates {
+				if _, tracked := exchange.orders[update.OrderID]; tracked {
+					exchange.orders[update.OrderID] = update
+					om.SyncOpenOrders()
+					continue
+				}
+				exchange.orders[update.OrderID] = update
+				if err := om.Track(update); err != nil {
+					t.Fatalf("track order %d: %v", update.OrderID, err)
+				}
+			}
+
+			lots, err := db2.SQLiteDB.GetActiveTrades("ETHUSDT")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lots) != len(tt.lots) {
+				t.Fatalf("got %d lots, want %d", len(lots), len(tt.lots))
+			}
+			for i, lot := range lots {
+				if !lot.Quantity.Equal(d(tt.lots[i][0])) || !lot.EntryFee.Equal(d(tt.lots[i][1])) {
+					t.Errorf("lot %d: got quantity %s fee %s, want %s fee %s", i, lot.Quantity, lot.EntryFee, tt.lots[i][0], tt.lots[i][1])
+				}
+			}
+
+			profits, err := db2.SQLiteDB.RecentProfits("ETHUSDT", 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Reverse(profits)
+			if len(profits) != len(tt.profits) {
+				t.Fatalf("got profits %v, want %v", profits, tt.profits)
+			}
+			for i, profit := range profits {
+				if !profit.Equal(d(tt.profits[i])) {
+					t.Errorf("trade %d: got profit %s, want %s", i, profit, tt.profits[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	if err := db2.InitDBAt(t.TempDir() + "/orders.db"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db2.SQLiteDB.DB.Close() })
+	exchange := &ledgerExchange{orders: make(map[int64]*models.Order)}
+	om := NewOrderManager(exchange)
+
+	canceled := func(order *models.Order) *models.Order {
+		order.Status = models.OrderStatusCanceled
+		return order
+	}
+	steps := []struct {
+		name   string
+		update *models.Order // Tracked when new, polled otherwise
+		status string        // Stored status of the order
+		lots   []string      // Quantity of the open lots, oldest first
+		open   int           // Stored open orders
+	}{
+		{"placing opens no lot", fill(1, "BUY", "1", "0", "0", "0", ""), models.OrderStatusNew, nil, 1},
+		{"partial fill opens a lot", fill(1, "BUY", "1", "0.4", "100", "0", ""), models.OrderStatusPartiallyFilled, []string{"0.4"}, 1},
+		{"second fill at its own price", fill(1, "BUY", "1", "1", "100.6", "0", ""), models.OrderStatusFilled, []string{"0.4", "0.6"}, 0},
+		{"placing another order", fill(2, "BUY", "1", "0", "0", "0", ""), models.OrderStatusNew, []string{"0.4", "0.6"}, 1},
+		{"cancel without a fill", canceled(fill(2, "BUY", "1", "0", "0", "0", "")), models.OrderStatusCanceled, []string{"0.4", "0.6"}, 0},
+	}
+
+	for _, step := range steps {
+		if _, tracked := exchange.orders[step.update.OrderID]; tracked {
+			exchange.orders[step.update.OrderID] = step.update
+			om.SyncOpenOrders()
+		} else {
+			exchange.orders[step.update.OrderID] = step.update
+			if err := om.Track(step.update); err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+		}
+
+		stored, err := db2.SQLiteDB.GetOrder("ETHUSDT", step.update.OrderID)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if stored.Status != step.status {
+			t.Errorf("%s: got status %s, want %s", step.name, stored.Status, step.status)
+		}
+		open, err := db2.SQLiteDB.GetOpenOrders()
+		if err != nil || len(open) != step.open {
+			t.Errorf("%s: got %d open orders %v, want %d", step.name, len(open), err, step.open)
+		}
+
+		lots, err := db2.SQLiteDB.GetActiveTrades("ETHUSDT")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lots) != len(step.lots) {
+			t.Fatalf("%s: got %d lots, want %d", step.name, len(lots), len(step.lots))
+		}
+		for i, lot := range lots {
+			if !lot.Quantity.Equal(d(step.lots[i])) {
+				t.Errorf("%s: lot %d has %s, want %s", step.name, i, lot.Quantity, step.lots[i])
+			}
+		}
+		if len(lots) == 2 && !lots[1].BuyPrice.Equal(d("101")) {
+			t.Errorf("%s: second lot bought at %s, want 101", step.name, lots[1].BuyPrice)
+		}
+	}
+}
+
+func TestSellableQuantity(t *testing.T) {
+	if err := db2.InitDBAt(t.TempDir() + "/sellable.db"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db2.SQLiteDB.DB.Close() })
+	exchange := &ledgerExchange{orders: make(map[int64]*models.Order)}
+	om := NewOrderManager(exchange)
+
+	leg := func(id int64, orderType, status string) *models.Order {
+		order := fill(id, "SELL", "1.5", "0", "0", "0", "")
+		order.Type, order.Status = orderType, status
+		return order
+	}
+	steps := []struct {
+		name     string
+		updates  []*models.Order // Tracked when new, polled otherwise
+		oco      bool            // Record orders 2 and 3 as the legs of an OCO
+		sellable string
+	}{
+		{"bought lot", []*models.Order{fill(1, "BUY", "2", "2", "100", "0", "")}, false, "2"},
+		{
+			name:     "OCO legs count once",
+			updates:  []*models.Order{leg(2, "STOP_LOSS_LIMIT", models.OrderStatusNew), leg(3, "LIMIT_MAKER", models.OrderStatusNew)},
+			oco:      true,
+			sellable: "0.5",
+		},
+		{"SELL order besides the OCO", []*models.Order{fill(4, "SELL", "0.25", "0", "0", "0", "")}, false, "0.25"},
+		{
+			name:     "expired OCO",
+			updates:  []*models.Order{leg(2, "STOP_LOSS_LIMIT", models.OrderStatusExpired), leg(3, "LIMIT_MAKER", models.OrderStatusExpired)},
+			sellable: "1.75",
+		},
+	}
+
+	for _, step := range steps {
+		for _, update := range step.updates {
+			if _, tracked := exchange.orders[update.OrderID]; tracked {
+				exchange.orders[update.OrderID] = update
+				continue
+			}
+			exchange.orders[update.OrderID] = update
+			if err := om.Track(update); err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+		}
+		om.SyncOpenOrders()
+		if step.oco {
+			oco := &models.ProtectiveOrder{Symbol: "ETHUSDT", Type: "OCO", StopOrderID: 2, LimitOrderID: 3, Position: d("2"), Quantity: d("1.5"), Status: models.ProtectionActive}
+			if err := db2.SQLiteDB.LogProtectiveOrder(oco); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		sellable, err := db2.SQLiteDB.SellableQuantity("ETHUSDT")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sellable.Equal(d(step.sellable)) {
+			t.Errorf("%s: got %s sellable, want %s", step.name, sellable, step.sellable)
+		}
+	}
+}
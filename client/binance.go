@@ -7,7 +7,8 @@ import (
 	"context"
 	"fmt"
 	"github.com/adshao/go-binance/v2"
-	"math"
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/shopspring/decimal"
 	"strconv"
 	"sync"
 	"time"
@@ -19,7 +20,12 @@ type BinanceClient struct {
 	pairs       map[string]*models.TradingPair
 	pairsMutex  sync.RWMutex
 	candleCache map[string][]models.CandleStick
+	tickerCache map[string]bookTicker
 	cacheMutex  sync.RWMutex
+	stream      *marketStream
+	streamMu    sync.RWMutex
+	watchers    map[string][]chan decimal.Decimal // Price watchers per symbol
+	watchMu     sync.RWMutex
 }
 
 // NewBinanceClient creates a new Binance client instance
@@ -30,11 +36,21 @@ func NewBinanceClient(apiKey, apiSecret string) (interfaces.ExchangeClient, erro
 		client:      client,
 		pairs:       make(map[string]*models.TradingPair),
 		candleCache: make(map[string][]models.CandleStick),
+		tickerCache: make(map[string]bookTicker),
+		watchers:    make(map[string][]chan decimal.Decimal),
 	}, nil
 }
 
+// GetTradingPairs returns the configured pairs as of now, the map is a copy
 func (b *BinanceClient) GetTradingPairs() map[string]*models.TradingPair {
-	return b.pairs
+	b.pairsMutex.RLock()
+	defer b.pairsMutex.RUnlock()
+
+	pairs := make(map[string]*models.TradingPair, len(b.pairs))
+	for symbol, pair := range b.pairs {
+		pairs[symbol] = pair
+	}
+	return pairs
 }
 
 // AddTradingPair adds a new trading pair to monitor
@@ -45,71 +61,164 @@ func (b *BinanceClient) AddTradingPair(pair models.TradingPair) error {
 		return fmt.Errorf("failed to get exchange info for %s: %v", pair.Symbol, err)
 	}
 
-	// Loop through symbols to find the matching one
-	var symbolFound bool
 	for _, symbol := range info.Symbols {
-		if symbol.Symbol == pair.Symbol {
-			symbolFound = true
-			pair.BaseAsset = symbol.BaseAsset
-			pair.QuoteAsset = symbol.QuoteAsset
-			pair.PricePrecision = symbol.QuotePrecision
-			pair.QtyPrecision = symbol.BaseAssetPrecision
-
-			// Parse filters to extract additional trading rules
-			for _, filter := range symbol.Filters {
-				if filter["filterType"] == "MIN_NOTIONAL" {
-					minNotionalStr, ok := filter["minNotional"].(string)
-					if !ok {
-						return fmt.Errorf("invalid minNotional format for %s", pair.Symbol)
-					}
-					pair.MinNotional, err = strconv.ParseFloat(minNotionalStr, 64)
-					if err != nil {
-						return fmt.Errorf("failed to parse minNotional for %s: %v", pair.Symbol, err)
-					}
-				}
-				// Add more filters here if needed
-			}
-
-			// Safely add the pair to the map
-			b.pairsMutex.Lock()
-			b.pairs[pair.Symbol] = &pair
-			b.pairsMutex.Unlock()
-
-			logger.Debugf("Successfully added trading pair: %s", pair.Symbol)
-			break
+		if symbol.Symbol != pair.Symbol {
+			continue
 		}
-	}
 
-	if !symbolFound {
-		return fmt.Errorf("symbol %s not found in exchange info", pair.Symbol)
+		rules, err := parseSymbolRules(symbol)
+		if err != nil {
+			return err
+		}
+		pair.BaseAsset = symbol.BaseAsset
+		pair.QuoteAsset = symbol.QuoteAsset
+		pair.ApplyRules(rules)
+
+		// Safely add the pair to the map
+		b.pairsMutex.Lock()
+		b.pairs[pair.Symbol] = &pair
+		b.pairsMutex.Unlock()
+
+		logger.Debugf("Successfully added trading pair: %s", pair.Symbol)
+		return nil
 	}
-	return nil
+
+	return fmt.Errorf("symbol %s not found in exchange info", pair.Symbol)
 }
 
 // GetCurrentPrice fetches the current price for a given symbol
-func (b *BinanceClient) GetCurrentPrice(symbol string) (float64, error) {
+func (b *BinanceClient) GetCurrentPrice(symbol string) (decimal.Decimal, error) {
+	// Use the close of the forming candle while the stream is connected
+	if price := b.streamedPrice(symbol); price.IsPositive() {
+		return price, nil
+	}
+
 	// Fetch the price from the Binance API
 	prices, err := b.client.NewListPricesService().Symbol(symbol).Do(context.Background())
 	if err != nil {
-		return 0, fmt.Errorf("failed to fetch current price for %s: %v", symbol, err)
+		return decimal.Zero, fmt.Errorf("failed to fetch current price for %s: %v", symbol, err)
 	}
 
 	if len(prices) == 0 {
-		return 0, fmt.Errorf("no price data returned for symbol %s", symbol)
+		return decimal.Zero, fmt.Errorf("no price data returned for symbol %s", symbol)
 	}
 
-	// Parse the price as a float
-	price, err := strconv.ParseFloat(prices[0].Price, 64)
+	// Parse the price exactly as the exchange sent it
+	price, err := decimal.NewFromString(prices[0].Price)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse price for %s: %v", symbol, err)
+		return decimal.Zero, fmt.Errorf("failed to parse price for %s: %v", symbol, err)
 	}
 
-	logger.Infof("Current price for %s: %.8f", symbol, price)
+	logger.Infof("Current price for %s: %s", symbol, price)
 	return price, nil
 }
 
-// FetchCandles implements the Exchange interface
+// GetOrderBook fetches a depth snapshot with up to limit levels per side
+func (b *BinanceClient) GetOrderBook(symbol string, limit int) (*models.OrderBook, error) {
+	res, err := b.client.NewDepthService().Symbol(symbol).Limit(limit).Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch order book for %s: %v", symbol, err)
+	}
+
+	book := &models.OrderBook{Symbol: symbol, UpdatedAt: time.Now()}
+	if book.Bids, err = bookLevels(res.Bids); err != nil {
+		return nil, fmt.Errorf("failed to parse bids for %s: %v", symbol, err)
+	}
+	if book.Asks, err = bookLevels(res.Asks); err != nil {
+		return nil, fmt.Errorf("failed to parse asks for %s: %v", symbol, err)
+	}
+	return book, nil
+}
+
+// GetBookTicker returns the best bid and ask, streamed while the stream is connected
+func (b *BinanceClient) GetBookTicker(symbol string) (*models.BookTicker, error) {
+	if ticker, ok := b.streamedTicker(symbol); ok {
+		return ticker, nil
+	}
+
+	res, err := b.client.NewListBookTickersService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch book ticker for %s: %v", symbol, err)
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no book ticker returned for symbol %s", symbol)
+	}
+
+	ticker := &models.BookTicker{Symbol: symbol}
+	for field, value := range map[*decimal.Decimal]string{
+		&ticker.BidPrice: res[0].BidPrice,
+		&ticker.BidQty:   res[0].BidQuantity,
+		&ticker.AskPrice: res[0].AskPrice,
+		&ticker.AskQty:   res[0].AskQuantity,
+	} {
+		if *field, err = decimal.NewFromString(value); err != nil {
+			return nil, fmt.Errorf("failed to parse book ticker for %s: %v", symbol, err)
+		}
+	}
+	return ticker, nil
+}
+
+// bookLevels parses the price levels of a depth response
+func bookLevels(levels []common.PriceLevel) ([]models.BookLevel, error) {
+	parsed := make([]models.BookLevel, 0, len(levels))
+	for _, level := range levels {
+		price, err := decimal.NewFromString(level.Price)
+		if err != nil {
+			return nil, err
+		}
+		quantity, err := decimal.NewFromString(level.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, models.BookLevel{Price: price, Quantity: quantity})
+	}
+	return parsed, nil
+}
+
+// streamedPrice returns the close of the forming candle while the stream is connected, 0 otherwise
+func (b *BinanceClient) streamedPrice(symbol string) decimal.Decimal {
+	b.streamMu.RLock()
+	stream := b.stream
+	b.streamMu.RUnlock()
+	if stream == nil || !stream.isLive() {
+		return decimal.Zero
+	}
+	if candles, ok := b.cachedCandles(symbol, 1); ok {
+		return decimal.NewFromFloat(candles[0].Close)
+	}
+	return decimal.Zero
+}
+
+// streamedTicker returns the streamed best bid and ask while the stream is connected
+func (b *BinanceClient) streamedTicker(symbol string) (*models.BookTicker, bool) {
+	b.streamMu.RLock()
+	stream := b.stream
+	b.streamMu.RUnlock()
+	if stream == nil || !stream.isLive() {
+		return nil, false
+	}
+
+	b.cacheMutex.RLock()
+	defer b.cacheMutex.RUnlock()
+	ticker, ok := b.tickerCache[symbol]
+	if !ok {
+		return nil, false
+	}
+	return &ticker.BookTicker, true
+}
+
+// FetchCandles implements the Exchange interface, serving streamed candles when available
 func (b *BinanceClient) FetchCandles(symbol, interval string, limit int) ([]models.CandleStick, error) {
+	if b.streamFor(interval) != nil {
+		if candles, ok := b.cachedCandles(symbol, limit); ok {
+			return candles, nil
+		}
+	}
+	return b.fetchKlines(symbol, interval, limit)
+}
+
+// fetchKlines fetches candles over REST
+func (b *BinanceClient) fetchKlines(symbol, interval string, limit int) ([]models.CandleStick, error) {
 	var klines []*binance.Kline
 	err := retry(func() error {
 		var err error
@@ -142,28 +251,43 @@ func (b *BinanceClient) FetchCandles(symbol, interval string, limit int) ([]mode
 		}
 	}
 
-	b.cacheMutex.Lock()
-	b.candleCache[symbol] = candles
-	b.cacheMutex.Unlock()
-
 	return candles, nil
 }
 
 // GetBalance implements the Exchange interface
-func (b *BinanceClient) GetBalance(asset string) (float64, error) {
+func (b *BinanceClient) GetBalance(asset string) (decimal.Decimal, error) {
 	account, err := b.client.NewGetAccountService().Do(context.Background())
 	if err != nil {
-		return 0, fmt.Errorf("failed to get account info: %v", err)
+		return decimal.Zero, fmt.Errorf("failed to get account info: %v", err)
 	}
 
 	for _, balance := range account.Balances {
 		if balance.Asset == asset {
-			free, _ := strconv.ParseFloat(balance.Free, 64)
+			free, _ := decimal.NewFromString(balance.Free)
 			return free, nil
 		}
 	}
 
-	return 0, fmt.Errorf("asset %s not found", asset)
+	return decimal.Zero, fmt.Errorf("asset %s not found", asset)
+}
+
+// GetBalances returns the free and locked balances of every asset in the account
+func (b *BinanceClient) GetBalances() (map[string]models.Balance, error) {
+	account, err := b.client.NewGetAccountService().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account info: %v", err)
+	}
+
+	balances := make(map[string]models.Balance, len(account.Balances))
+	for _, balance := range account.Balances {
+		free, _ := decimal.NewFromString(balance.Free)
+		locked, _ := decimal.NewFromString(balance.Locked)
+		if free.IsZero() && locked.IsZero() {
+			continue
+		}
+		balances[balance.Asset] = models.Balance{Asset: balance.Asset, Free: free, Locked: locked}
+	}
+	return balances, nil
 }
 
 // CreateOrder implements the Exchange interface
@@ -211,288 +335,335 @@ func (b *BinanceClient) CreateOrder(symbol, orderType, side string, amount strin
 	return executedPrice, nil
 }
 
-func (b *BinanceClient) CreateMarketOrder(symbol, side, quantity string) (float64, error) {
-	// Check if the trading pair is configured
-	b.pairsMutex.RLock()
-	_, exists := b.pairs[symbol]
-	b.pairsMutex.RUnlock()
-
-	if !exists {
-		return 0, fmt.Errorf("trading pair %s not configured", symbol)
+func (b *BinanceClient) CreateMarketOrder(symbol, side, quantity string) (*models.Order, error) {
+	rules, err := b.pairRules(symbol)
+	if err != nil {
+		return nil, err
+	}
+	qty, err := decimal.NewFromString(quantity)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quantity format: %v", err)
+	}
+	refPrice := b.streamedPrice(symbol)
+	if quantity, _, err = rules.PrepareOrder(string(binance.OrderTypeMarket), side, qty, decimal.Zero, refPrice); err != nil {
+		return nil, fmt.Errorf("MARKET %s order for %s rejected: %v", side, symbol, err)
 	}
 
 	// Place the market order
-	order, err := b.client.NewCreateOrderService().
+	res, err := b.client.NewCreateOrderService().
 		Symbol(symbol).
 		Side(binance.SideType(side)).
 		Type(binance.OrderTypeMarket). // Market order
 		Quantity(quantity).            // Base asset quantity
+		NewOrderRespType(binance.NewOrderRespTypeFULL).
 		Do(context.Background())
 
 	if err != nil {
-		return 0, fmt.Errorf("failed to place MARKET %s order for %s: %v", side, symbol, err)
+		return nil, fmt.Errorf("failed to place MARKET %s order for %s: %v", side, symbol, err)
 	}
 
-	// Calculate the executed price based on fills
-	var totalQuoteQty float64
-	var totalBaseQty float64
+	order := &models.Order{
+		OrderID:   res.OrderID,
+		Symbol:    symbol,
+		Side:      side,
+		Type:      string(binance.OrderTypeMarket),
+		Status:    string(res.Status),
+		UpdatedAt: time.Now(),
+	}
+	order.Quantity, _ = decimal.NewFromString(res.OrigQuantity)
 
-	for _, fill := range order.Fills {
-		price, err := strconv.ParseFloat(fill.Price, 64)
+	// Calculate the executed quantity, price and commission based on fills
+	totalQuoteQty := decimal.Zero
+	for _, fill := range res.Fills {
+		price, err := decimal.NewFromString(fill.Price)
 		if err != nil {
-			return 0, fmt.Errorf("failed to parse fill price: %v", err)
+			return nil, fmt.Errorf("failed to parse fill price: %v", err)
 		}
-		quantity, err := strconv.ParseFloat(fill.Quantity, 64)
+		qty, err := decimal.NewFromString(fill.Quantity)
 		if err != nil {
-			return 0, fmt.Errorf("failed to parse fill quantity: %v", err)
+			return nil, fmt.Errorf("failed to parse fill quantity: %v", err)
 		}
-		totalQuoteQty += price * quantity
-		totalBaseQty += quantity
+		commission, _ := decimal.NewFromString(fill.Commission)
+		totalQuoteQty = totalQuoteQty.Add(price.Mul(qty))
+		order.FilledQty = order.FilledQty.Add(qty)
+		order.Commission = order.Commission.Add(commission)
+		order.CommissionAsset = fill.CommissionAsset
 	}
 
 	// Calculate the average executed price
-	if totalBaseQty == 0 {
-		return 0, fmt.Errorf("no fills returned for the market order")
+	if order.FilledQty.IsZero() {
+		return nil, fmt.Errorf("no fills returned for the market order")
 	}
-	averagePrice := totalQuoteQty / totalBaseQty
+	order.AvgPrice = totalQuoteQty.Div(order.FilledQty)
+	order.Price = order.AvgPrice
 
-	return averagePrice, nil
+	return order, nil
 }
 
 func (b *BinanceClient) CreateLimitOrder(symbol, side, quantity, price string) (int64, error) {
-	// Fetch symbol filters to comply with LOT_SIZE and PRICE_FILTER
-	info, err := b.client.NewExchangeInfoService().Symbol(symbol).Do(context.Background())
-	if err != nil {
-		return 0, fmt.Errorf("failed to fetch exchange info for %s: %v", symbol, err)
-	}
-
-	var minQty, maxQty, stepSize, tickSize float64
-	for _, filter := range info.Symbols[0].Filters {
-		if filter["filterType"] == "LOT_SIZE" {
-			minQty, err = strconv.ParseFloat(filter["minQty"].(string), 64)
-			if err != nil {
-				return 0, fmt.Errorf("failed to parse minQty for %s: %v", symbol, err)
-			}
-			maxQty, err = strconv.ParseFloat(filter["maxQty"].(string), 64)
-			if err != nil {
-				return 0, fmt.Errorf("failed to parse maxQty for %s: %v", symbol, err)
-			}
-			stepSize, err = strconv.ParseFloat(filter["stepSize"].(string), 64)
-			if err != nil {
-				return 0, fmt.Errorf("failed to parse stepSize for %s: %v", symbol, err)
-			}
-			fmt.Println(minQty, maxQty, stepSize)
-		}
-		if filter["filterType"] == "PRICE_FILTER" {
-			tickSize, err = strconv.ParseFloat(filter["tickSize"].(string), 64)
-			if err != nil {
-				return 0, fmt.Errorf("failed to parse tickSize for %s: %v", symbol, err)
-			}
-		}
-	}
-
-	// Adjust quantity to comply with LOT_SIZE
-	quantityFloat, err := strconv.ParseFloat(quantity, 64)
+	formattedQty, formattedPrice, err := b.prepareLimitOrder(symbol, side, string(binance.OrderTypeLimit), quantity, price)
 	if err != nil {
-		return 0, fmt.Errorf("invalid quantity format: %v", err)
+		return 0, err
 	}
 
-	if quantityFloat < minQty {
-		return 0, fmt.Errorf("quantity %.8f is below the minimum allowed %.8f for %s", quantityFloat, minQty, symbol)
-	}
+	// Place the limit order
+	order, err := b.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(binance.SideType(side)).
+		Type(binance.OrderTypeLimit).
+		TimeInForce(binance.TimeInForceTypeGTC).
+		Quantity(formattedQty).
+		Price(formattedPrice).
+		Do(context.Background())
 
-	if quantityFloat > maxQty {
-		quantityFloat = maxQty // Cap at maxQty
+	if err != nil {
+		return 0, fmt.Errorf("failed to place LIMIT %s order for %s: %v", side, symbol, err)
 	}
 
-	// Ensure stepSize is valid
-	if stepSize <= 0 {
-		logger.Infof("Invalid stepSize for %s: %.8f", symbol, stepSize)
-		return 0, fmt.Errorf("invalid stepSize for %s: %.8f", symbol, stepSize)
-	}
+	logger.Infof("Successfully placed LIMIT %s order for %s: OrderID=%d Quantity=%s Price=%s", side, symbol, order.OrderID, formattedQty, formattedPrice)
+	return order.OrderID, nil
+}
 
-	// Align with StepSize
-	adjustedQty := math.Floor(quantityFloat/stepSize) * stepSize
-	if math.IsNaN(adjustedQty) || adjustedQty <= 0 {
-		logger.Infof("Adjusted Quantity for %s is invalid: Original=%s, Adjusted=NaN or <= 0", symbol, quantity)
-		return 0, fmt.Errorf("adjusted quantity is invalid for %s: Original=%s", symbol, quantity)
+func (b *BinanceClient) CreateStopLossLimitOrder(symbol, side, quantity, price, stopLoss string) (int64, error) {
+	orderType := string(binance.OrderTypeStopLossLimit)
+	formattedQty, formattedPrice, err := b.prepareLimitOrder(symbol, side, orderType, quantity, price)
+	if err != nil {
+		return 0, err
 	}
 
-	formattedQty := strconv.FormatFloat(adjustedQty, 'f', -1, 64)
-	logger.Infof("Adjusted Quantity for %s: Original=%s, Adjusted=%s", symbol, quantity, formattedQty)
-
-	// Adjust price using PRICE_FILTER (already implemented in previous steps)
-	priceFloat, err := strconv.ParseFloat(price, 64)
+	rules, err := b.pairRules(symbol)
 	if err != nil {
-		return 0, fmt.Errorf("invalid price format: %v", err)
+		return 0, err
 	}
-	pricePrecision := 0
-	if side == "BUY" {
-		pricePrecision = info.Symbols[0].QuotePrecision
-	} else {
-		pricePrecision = info.Symbols[0].BaseAssetPrecision
-
+	stopPrice, err := decimal.NewFromString(stopLoss)
+	if err != nil {
+		return 0, fmt.Errorf("invalid stop price format: %v", err)
 	}
-	adjustedPrice := math.Floor(priceFloat/tickSize) * tickSize
-	formattedPrice := strconv.FormatFloat(adjustedPrice, 'f', pricePrecision, 64)
-	formattedQty = strconv.FormatFloat(adjustedQty, 'f', pricePrecision, 64)
-
-	fmt.Println("Price Precision: ", pricePrecision)
-	fmt.Println("Adjusted Price: ", adjustedPrice)
-	fmt.Println("Formatted Price: ", formattedPrice)
-	fmt.Println("Formatted Qty: ", formattedQty)
-	fmt.Println("Amount in USDT: ", adjustedPrice*adjustedQty)
+	formattedStopPrice := rules.FormatPrice(rules.QuantizePrice(stopPrice))
 
-	// Place the limit order
+	// Place the stop loss limit order
 	order, err := b.client.NewCreateOrderService().
 		Symbol(symbol).
 		Side(binance.SideType(side)).
-		Type(binance.OrderTypeLimit).
+		Type(binance.OrderTypeStopLossLimit).
 		TimeInForce(binance.TimeInForceTypeGTC).
 		Quantity(formattedQty).
 		Price(formattedPrice).
+		StopPrice(formattedStopPrice).
 		Do(context.Background())
 
 	if err != nil {
-		return 0, fmt.Errorf("failed to place LIMIT %s order for %s: %v", side, symbol, err)
+		return 0, fmt.Errorf("failed to place STOP_LOSS_LIMIT %s order for %s: %v", side, symbol, err)
 	}
 
-	logger.Infof("Successfully placed LIMIT %s order for %s: OrderID=%d", side, symbol, order.OrderID)
+	logger.Infof("Successfully placed STOP_LOSS_LIMIT %s order for %s: OrderID=%d Quantity=%s Price=%s Stop=%s", side, symbol, order.OrderID, formattedQty, formattedPrice, formattedStopPrice)
 	return order.OrderID, nil
 }
 
-func (b *BinanceClient) CreateStopLossLimitOrder(symbol, side, quantity, price, stopLoss string) (int64, error) {
-	// Fetch symbol filters to comply with LOT_SIZE and PRICE_FILTER
-	info, err := b.client.NewExchangeInfoService().Symbol(symbol).Do(context.Background())
-	if err != nil {
-		return 0, fmt.Errorf("failed to fetch exchange info for %s: %v", symbol, err)
-	}
-
-	var minQty, maxQty, stepSize, tickSize float64
-	for _, filter := range info.Symbols[0].Filters {
-		if filter["filterType"] == "LOT_SIZE" {
-			minQty, err = strconv.ParseFloat(filter["minQty"].(string), 64)
-			if err != nil {
-				return 0, fmt.Errorf("failed to parse minQty for %s: %v", symbol, err)
-			}
-			maxQty, err = strconv.ParseFloat(filter["maxQty"].(string), 64)
-			if err != nil {
-				return 0, fmt.Errorf("failed to parse maxQty for %s: %v", symbol, err)
-			}
-			stepSize, err = strconv.ParseFloat(filter["stepSize"].(string), 64)
-			if err != nil {
-				return 0, fmt.Errorf("failed to parse stepSize for %s: %v", symbol, err)
-			}
-			fmt.Println(minQty, maxQty, stepSize)
-		}
-		if filter["filterType"] == "PRICE_FILTER" {
-			tickSize, err = strconv.ParseFloat(filter["tickSize"].(string), 64)
-			if err != nil {
-				return 0, fmt.Errorf("failed to parse tickSize for %s: %v", symbol, err)
-			}
-		}
+// CreateLimitMakerOrder places a post-only limit order, the exchange rejects it when it would match immediately
+func (b *BinanceClient) CreateLimitMakerOrder(symbol, side, quantity, price string) (int64, error) {
+	formattedQty, formattedPrice, err := b.prepareLimitOrder(symbol, side, string(binance.OrderTypeLimitMaker), quantity, price)
+	if err != nil {
+		return 0, err
 	}
 
-	// Adjust quantity to comply with LOT_SIZE
-	quantityFloat, err := strconv.ParseFloat(quantity, 64)
+	order, err := b.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(binance.SideType(side)).
+		Type(binance.OrderTypeLimitMaker).
+		Quantity(formattedQty).
+		Price(formattedPrice).
+		Do(context.Background())
+
 	if err != nil {
-		return 0, fmt.Errorf("invalid quantity format: %v", err)
+		return 0, fmt.Errorf("failed to place LIMIT_MAKER %s order for %s: %v", side, symbol, err)
 	}
 
-	if quantityFloat < minQty {
-		return 0, fmt.Errorf("quantity %.8f is below the minimum allowed %.8f for %s", quantityFloat, minQty, symbol)
+	logger.Infof("Successfully placed LIMIT_MAKER %s order for %s: OrderID=%d Quantity=%s Price=%s", side, symbol, order.OrderID, formattedQty, formattedPrice)
+	return order.OrderID, nil
+}
+
+// CreateOCOOrder places a limit order and a stop-limit order of which the first to fill or trigger
+// cancels the other. For a SELL the limit price is above and the stop price below the market.
+func (b *BinanceClient) CreateOCOOrder(symbol, side, quantity, price, stopPrice, stopLimitPrice string) ([]*models.Order, error) {
+	formattedQty, formattedPrice, err := b.prepareLimitOrder(symbol, side, string(binance.OrderTypeLimitMaker), quantity, price)
+	if err != nil {
+		return nil, err
 	}
-	if quantityFloat > maxQty {
-		quantityFloat = maxQty // Cap at maxQty
+	_, formattedStopLimit, err := b.prepareLimitOrder(symbol, side, string(binance.OrderTypeStopLossLimit), formattedQty, stopLimitPrice)
+	if err != nil {
+		return nil, err
 	}
 
-	// Ensure stepSize is valid
-	if stepSize <= 0 {
-		logger.Infof("Invalid stepSize for %s: %.8f", symbol, stepSize)
-		return 0, fmt.Errorf("invalid stepSize for %s: %.8f", symbol, stepSize)
+	rules, err := b.pairRules(symbol)
+	if err != nil {
+		return nil, err
 	}
-
-	// Align with StepSize
-	adjustedQty := math.Floor(quantityFloat/stepSize) * stepSize
-	if math.IsNaN(adjustedQty) || adjustedQty <= 0 {
-		logger.Infof("Adjusted Quantity for %s is invalid: Original=%s, Adjusted=NaN or <= 0", symbol, quantity)
-		return 0, fmt.Errorf("adjusted quantity is invalid for %s: Original=%s", symbol, quantity)
+	stop, err := decimal.NewFromString(stopPrice)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stop price format: %v", err)
 	}
+	formattedStopPrice := rules.FormatPrice(rules.QuantizePrice(stop))
 
-	formattedQty := strconv.FormatFloat(adjustedQty, 'f', -1, 64)
-	logger.Infof("Adjusted Quantity for %s: Original=%s, Adjusted=%s", symbol, quantity, formattedQty)
-
-	// Adjust price using PRICE_FILTER (already implemented in previous steps)
-	priceFloat, err := strconv.ParseFloat(price, 64)
+	res, err := b.client.NewCreateOCOService().
+		Symbol(symbol).
+		Side(binance.SideType(side)).
+		Quantity(formattedQty).
+		Price(formattedPrice).
+		StopPrice(formattedStopPrice).
+		StopLimitPrice(formattedStopLimit).
+		StopLimitTimeInForce(binance.TimeInForceTypeGTC).
+		Do(context.Background())
 	if err != nil {
-		return 0, fmt.Errorf("invalid price format: %v", err)
+		return nil, fmt.Errorf("failed to place OCO %s order for %s: %v", side, symbol, err)
+	}
+
+	orders := make([]*models.Order, 0, len(res.OrderReports))
+	for _, report := range res.OrderReports {
+		order := &models.Order{
+			OrderID:   report.OrderID,
+			Symbol:    report.Symbol,
+			Side:      string(report.Side),
+			Type:      string(report.Type),
+			Status:    string(report.Status),
+			UpdatedAt: time.UnixMilli(report.TransactionTime),
+		}
+		order.Quantity, _ = decimal.NewFromString(report.OrigQuantity)
+		order.Price, _ = decimal.NewFromString(report.Price)
+		orders = append(orders, order)
 	}
-	pricePrecision := info.Symbols[0].QuotePrecision
-	adjustedPrice := math.Floor(priceFloat/tickSize) * tickSize
-	formattedPrice := strconv.FormatFloat(adjustedPrice, 'f', pricePrecision, 64)
 
-	fmt.Println("Price Precision: ", pricePrecision)
-	fmt.Println("Adjusted Price: ", adjustedPrice)
-	fmt.Println("Formatted Price: ", formattedPrice)
+	logger.Infof("Successfully placed OCO %s order for %s: OrderListID=%d Quantity=%s Price=%s Stop=%s StopLimit=%s", side, symbol, res.OrderListID, formattedQty, formattedPrice, formattedStopPrice, formattedStopLimit)
+	return orders, nil
+}
 
-	// Adjust price using PRICE_FILTER (already implemented in previous steps)
-	priceStopLossFloat, err := strconv.ParseFloat(stopLoss, 64)
+// prepareLimitOrder quantizes and validates a limit order against the cached trading rules
+func (b *BinanceClient) prepareLimitOrder(symbol, side, orderType, quantity, price string) (string, string, error) {
+	rules, err := b.pairRules(symbol)
 	if err != nil {
-		return 0, fmt.Errorf("invalid price format: %v", err)
+		return "", "", err
+	}
+	qty, err := decimal.NewFromString(quantity)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid quantity format: %v", err)
+	}
+	limitPrice, err := decimal.NewFromString(price)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid price format: %v", err)
 	}
-	adjustedStopLossPrice := math.Floor(priceStopLossFloat/tickSize) * tickSize
-	formattedStopLossPrice := strconv.FormatFloat(adjustedStopLossPrice, 'f', pricePrecision, 64)
 
-	fmt.Println("Stop Loss Price Precision: ", pricePrecision)
-	fmt.Println("Stop Loss Adjusted Price: ", adjustedStopLossPrice)
-	fmt.Println("Stop Loss Formatted Price: ", formattedStopLossPrice)
+	refPrice := b.streamedPrice(symbol)
+	formattedQty, formattedPrice, err := rules.PrepareOrder(orderType, side, qty, limitPrice, refPrice)
+	if err != nil {
+		return "", "", fmt.Errorf("%s %s order for %s rejected: %v", orderType, side, symbol, err)
+	}
+	return formattedQty, formattedPrice, nil
+}
 
-	// Place the limit order
-	order, err := b.client.NewCreateOrderService().
+// GetOrder fetches the status and fills of an order
+func (b *BinanceClient) GetOrder(symbol string, orderID int64) (*models.Order, error) {
+	res, err := b.client.NewGetOrderService().
 		Symbol(symbol).
-		Side(binance.SideType(side)).
-		Type(binance.OrderTypeStopLossLimit).
-		TimeInForce(binance.TimeInForceTypeGTC).
-		Quantity(formattedQty).
-		Price(formattedPrice).
-		StopPrice(formattedStopLossPrice).
+		OrderID(orderID).
 		Do(context.Background())
-
 	if err != nil {
-		return 0, fmt.Errorf("failed to place LIMIT %s order for %s: %v", side, symbol, err)
+		return nil, fmt.Errorf("failed to fetch order status for %s: %v", symbol, err)
+	}
+
+	order := &models.Order{
+		OrderID:   res.OrderID,
+		Symbol:    res.Symbol,
+		Side:      string(res.Side),
+		Type:      string(res.Type),
+		Status:    string(res.Status),
+		UpdatedAt: time.UnixMilli(res.UpdateTime),
+	}
+	order.Quantity, _ = decimal.NewFromString(res.OrigQuantity)
+	order.Price, _ = decimal.NewFromString(res.Price)
+	order.FilledQty, _ = decimal.NewFromString(res.ExecutedQuantity)
+	cumQuoteQty, _ := decimal.NewFromString(res.CummulativeQuoteQuantity)
+	if order.FilledQty.IsPositive() {
+		order.AvgPrice = cumQuoteQty.Div(order.FilledQty)
+
+		// Commission is only reported on the trades of the order
+		trades, err := b.client.NewListTradesService().
+			Symbol(symbol).
+			OrderId(orderID).
+			Do(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch trades of order %d for %s: %v", orderID, symbol, err)
+		}
+		for _, trade := range trades {
+			commission, _ := decimal.NewFromString(trade.Commission)
+			order.Commission = order.Commission.Add(commission)
+			order.CommissionAsset = trade.CommissionAsset
+		}
 	}
 
-	logger.Infof("Successfully placed LIMIT %s order for %s: OrderID=%d", side, symbol, order.OrderID)
-	return order.OrderID, nil
+	return order, nil
 }
 
-func (b *BinanceClient) MonitorOrder(symbol string, orderID int64) (bool, error) {
+// GetOpenOrders fetches the open orders of a symbol
+func (b *BinanceClient) GetOpenOrders(symbol string) ([]*models.Order, error) {
+	res, err := b.client.NewListOpenOrdersService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch open orders for %s: %v", symbol, err)
+	}
+
+	orders := make([]*models.Order, 0, len(res))
+	for _, o := range res {
+		order := &models.Order{
+			OrderID:   o.OrderID,
+			Symbol:    o.Symbol,
+			Side:      string(o.Side),
+			Type:      string(o.Type),
+			Status:    string(o.Status),
+			UpdatedAt: time.UnixMilli(o.UpdateTime),
+		}
+		order.Quantity, _ = decimal.NewFromString(o.OrigQuantity)
+		order.Price, _ = decimal.NewFromString(o.Price)
+		order.FilledQty, _ = decimal.NewFromString(o.ExecutedQuantity)
+		cumQuoteQty, _ := decimal.NewFromString(o.CummulativeQuoteQuantity)
+		if order.FilledQty.IsPositive() {
+			order.AvgPrice = cumQuoteQty.Div(order.FilledQty)
+		}
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+// MonitorOrder waits until an order is filled, canceled or rejected, or until ctx is done
+func (b *BinanceClient) MonitorOrder(ctx context.Context, symbol string, orderID int64) (bool, error) {
 	logger.Infof("Monitoring order %d for %s", orderID, symbol)
 
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
 	for {
 		// Fetch order status
-		order, err := b.client.NewGetOrderService().
-			Symbol(symbol).
-			OrderID(orderID).
-			Do(context.Background())
+		order, err := b.GetOrder(symbol, orderID)
 		if err != nil {
-			return false, fmt.Errorf("failed to fetch order status for %s: %v", symbol, err)
+			return false, err
 		}
 
-		logger.Infof("Order %d status: %s (Filled Quantity: %s)", orderID, order.Status, order.ExecutedQuantity)
+		logger.Infof("Order %d status: %s (Filled Quantity: %s)", orderID, order.Status, order.FilledQty)
 
 		// Check if the order is fully filled
-		if order.Status == binance.OrderStatusTypeFilled {
+		if order.Status == models.OrderStatusFilled {
 			return true, nil
 		}
 
-		// Break the loop if the order is canceled or rejected
-		if order.Status == binance.OrderStatusTypeCanceled || order.Status == binance.OrderStatusTypeRejected {
+		// Break the loop if the order can no longer fill
+		if !order.IsOpen() {
 			return false, nil
 		}
 
 		// Wait before the next status check
-		time.Sleep(5 * time.Second)
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-ticker.C:
+		}
 	}
 }
 
@@ -526,6 +697,15 @@ func (b *BinanceClient) GetFeeRate() (float64, error) {
 	return feeRate, nil
 }
 
+// GetServerTime fetches the exchange clock, candle boundaries are aligned to it
+func (b *BinanceClient) GetServerTime() (time.Time, error) {
+	ms, err := b.client.NewServerTimeService().Do(context.Background())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to fetch server time: %v", err)
+	}
+	return time.UnixMilli(ms), nil
+}
+
 // Retry helper for API calls
 func retry(fn func() error, retries int, delay time.Duration) error {
 	for i := 0; i < retries; i++ {
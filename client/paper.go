@@ -0,0 +1,835 @@
+package client
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/interfaces"
+	"binance_bot/logger"
+	"binance_bot/models"
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PaperConfig holds the settings of a paper trading wallet
+type PaperConfig struct {
+	Balances map[string]float64 // Starting balances per asset
+	FeeRate  float64            // Fee charged on every fill, in the quote asset
+	Slippage float64            // Fraction the fill price moves against taker orders, e.g. 0.0005
+	Clock    func() time.Time   // Time source for fills, defaults to time.Now
+	Persist  bool               // Keep the wallet and its orders in the database, a stored wallet replaces Balances
+}
+
+// PaperFill is a simulated fill recorded by PaperClient
+type PaperFill struct {
+	OrderID    int64
+	Timestamp  time.Time
+	Symbol     string
+	Side       string
+	Quantity   decimal.Decimal
+	Price      decimal.Decimal
+	Fee        decimal.Decimal // Fee paid in quote asset
+	ProfitLoss decimal.Decimal // Realized profit/loss of a SELL, net of fees
+}
+
+// paperOrder is an order placed on the simulated exchange
+type paperOrder struct {
+	id        int64
+	symbol    string
+	side      string
+	orderType string
+	quantity  decimal.Decimal
+	price     decimal.Decimal
+	stopPrice decimal.Decimal
+	triggered bool  // Stop price was reached, the order now behaves as a limit order
+	sibling   int64 // Other leg of an OCO, 0 for a single order
+	shared    bool  // The sibling of an OCO holds the funds of both legs
+	status    string
+	filledQty decimal.Decimal
+	avgPrice  decimal.Decimal
+	fee       decimal.Decimal
+	updatedAt time.Time
+}
+
+// PaperClient implements the ExchangeClient interface with virtual balances.
+// Market data comes from the wrapped client, orders are filled locally.
+type PaperClient struct {
+	market    interfaces.MarketDataClient
+	cfg       PaperConfig
+	balances  map[string]decimal.Decimal // Free balances
+	locked    map[string]decimal.Decimal // Balances held by resting orders
+	costBasis map[string]decimal.Decimal // Average entry price including buy fees per symbol
+	orders    map[int64]*paperOrder
+	fills     []PaperFill
+	nextID    int64
+	dirty     map[int64]*paperOrder // Orders changed since the wallet was last stored
+	mu        sync.Mutex
+}
+
+// NewPaperClient creates a paper trading client on top of a market data source. With Persist the
+// wallet stored by an earlier run is restored, including its resting orders.
+func NewPaperClient(market interfaces.MarketDataClient, cfg PaperConfig) *PaperClient {
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	p := &PaperClient{
+		market:    market,
+		cfg:       cfg,
+		balances:  make(map[string]decimal.Decimal),
+		locked:    make(map[string]decimal.Decimal),
+		costBasis: make(map[string]decimal.Decimal),
+		orders:    make(map[int64]*paperOrder),
+		dirty:     make(map[int64]*paperOrder),
+	}
+	if cfg.Persist && p.restore() {
+		return p
+	}
+
+	for asset, amount := range cfg.Balances {
+		p.balances[asset] = decimal.NewFromFloat(amount)
+	}
+	logger.Infof("Started paper trading with balances %v", p.balances)
+	if cfg.Persist {
+		p.persistLocked()
+	}
+	return p
+}
+
+// restore loads the wallet stored by an earlier run and reports whether there was one
+func (p *PaperClient) restore() bool {
+	wallet, err := db2.SQLiteDB.GetPaperWallet()
+	if err != nil {
+		logger.Errorf("Error restoring paper wallet, starting a new one: %v", err)
+		return false
+	}
+	if len(wallet.Balances) == 0 {
+		return false
+	}
+
+	for asset, balance := range wallet.Balances {
+		p.balances[asset], p.locked[asset] = balance.Free, balance.Locked
+	}
+	p.costBasis = wallet.CostBasis
+	open := 0
+	for _, o := range wallet.Orders {
+		p.orders[o.OrderID] = &paperOrder{
+			id:        o.OrderID,
+			symbol:    o.Symbol,
+			side:      o.Side,
+			orderType: o.Type,
+			quantity:  o.Quantity,
+			price:     o.Price,
+			stopPrice: o.StopPrice,
+			triggered: o.Triggered,
+			sibling:   o.Sibling,
+			shared:    o.Shared,
+			status:    o.Status,
+			filledQty: o.FilledQty,
+			avgPrice:  o.AvgPrice,
+			fee:       o.Commission,
+			updatedAt: o.UpdatedAt,
+		}
+		p.nextID = max(p.nextID, o.OrderID)
+		if o.Status == models.OrderStatusNew {
+			open++
+		}
+	}
+	logger.Infof("Restored paper wallet with balances %v, locked %v and %d open orders", p.balances, p.locked, open)
+	return true
+}
+
+// markLocked records that an order changed, so the next save stores it
+func (p *PaperClient) markLocked(orders ...*paperOrder) {
+	for _, order := range orders {
+		p.dirty[order.id] = order
+	}
+}
+
+// saveLocked stores the wallet with the orders that changed, balances only change with orders
+func (p *PaperClient) saveLocked() {
+	if p.cfg.Persist && len(p.dirty) > 0 {
+		p.persistLocked()
+	}
+}
+
+// persistLocked stores the balances, the cost basis and the changed orders of the wallet
+func (p *PaperClient) persistLocked() {
+	wallet := &models.PaperWallet{Balances: make(map[string]models.Balance), CostBasis: p.costBasis}
+	for asset, free := range p.balances {
+		wallet.Balances[asset] = models.Balance{Asset: asset, Free: free, Locked: p.locked[asset]}
+	}
+	for asset, locked := range p.locked {
+		wallet.Balances[asset] = models.Balance{Asset: asset, Free: p.balances[asset], Locked: locked}
+	}
+	for _, order := range p.dirty {
+		wallet.Orders = append(wallet.Orders, &models.PaperOrder{
+			Order:     *p.toOrder(order),
+			StopPrice: order.stopPrice,
+			Triggered: order.triggered,
+			Sibling:   order.sibling,
+			Shared:    order.shared,
+		})
+	}
+	if err := db2.SQLiteDB.SavePaperWallet(wallet); err != nil {
+		logger.Errorf("Error storing paper wallet: %v", err)
+		return
+	}
+	clear(p.dirty)
+}
+
+func (p *PaperClient) GetTradingPairs() map[string]*models.TradingPair {
+	return p.market.GetTradingPairs()
+}
+
+func (p *PaperClient) AddTradingPair(pair models.TradingPair) error {
+	return p.market.AddTradingPair(pair)
+}
+
+// GetCurrentPrice fetches the price from the market and fills resting orders it crosses
+func (p *PaperClient) GetCurrentPrice(symbol string) (decimal.Decimal, error) {
+	price, err := p.market.GetCurrentPrice(symbol)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	p.MatchOrders(symbol, price, price)
+	return price, nil
+}
+
+// WatchPrice forwards the price updates of the market, a market without a stream sends none
+func (p *PaperClient) WatchPrice(symbol string) (<-chan decimal.Decimal, func()) {
+	if watcher, ok := p.market.(interfaces.PriceWatcher); ok {
+		return watcher.WatchPrice(symbol)
+	}
+	return nil, func() {}
+}
+
+// GetOrderBook fetches the order book from the market. Markets without one, like backtests, get a
+// single level per side at the current price moved by the slippage, deep enough for any order.
+func (p *PaperClient) GetOrderBook(symbol string, limit int) (*models.OrderBook, error) {
+	if books, ok := p.market.(interfaces.OrderBookClient); ok {
+		return books.GetOrderBook(symbol, limit)
+	}
+
+	ticker, err := p.syntheticTicker(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &models.OrderBook{
+		Symbol:    symbol,
+		Bids:      []models.BookLevel{{Price: ticker.BidPrice, Quantity: ticker.BidQty}},
+		Asks:      []models.BookLevel{{Price: ticker.AskPrice, Quantity: ticker.AskQty}},
+		UpdatedAt: p.cfg.Clock(),
+	}, nil
+}
+
+// GetBookTicker fetches the best bid and ask from the market, see GetOrderBook for markets without a book
+func (p *PaperClient) GetBookTicker(symbol string) (*models.BookTicker, error) {
+	if books, ok := p.market.(interfaces.OrderBookClient); ok {
+		return books.GetBookTicker(symbol)
+	}
+	return p.syntheticTicker(symbol)
+}
+
+// syntheticTicker quotes the current price moved by the slippage on either side
+func (p *PaperClient) syntheticTicker(symbol string) (*models.BookTicker, error) {
+	price, err := p.market.GetCurrentPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+	slippage := decimal.NewFromFloat(p.cfg.Slippage)
+	depth := decimal.New(1, 18)
+	return &models.BookTicker{
+		Symbol:   symbol,
+		BidPrice: price.Mul(decimal.NewFromInt(1).Sub(slippage)),
+		BidQty:   depth,
+		AskPrice: price.Mul(decimal.NewFromInt(1).Add(slippage)),
+		AskQty:   depth,
+	}, nil
+}
+
+// FetchCandles fetches candles from the market and fills resting orders crossed by the last close
+func (p *PaperClient) FetchCandles(symbol, interval string, limit int) ([]models.CandleStick, error) {
+	candles, err := p.market.FetchCandles(symbol, interval, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(candles) > 0 {
+		last := decimal.NewFromFloat(candles[len(candles)-1].Close)
+		p.MatchOrders(symbol, last, last)
+	}
+	return candles, nil
+}
+
+// GetServerTime reports the market clock when available and the paper clock otherwise
+func (p *PaperClient) GetServerTime() (time.Time, error) {
+	if clock, ok := p.market.(interfaces.ServerClock); ok {
+		return clock.GetServerTime()
+	}
+	return p.cfg.Clock(), nil
+}
+
+// RefreshRules refreshes the trading rules of the market when it supports it
+func (p *PaperClient) RefreshRules() error {
+	if refresher, ok := p.market.(interfaces.RulesRefresher); ok {
+		return refresher.RefreshRules()
+	}
+	return nil
+}
+
+// GetBalance returns the free virtual balance of an asset
+func (p *PaperClient) GetBalance(asset string) (decimal.Decimal, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.balances[asset], nil
+}
+
+// GetBalances returns the free and locked virtual balances
+func (p *PaperClient) GetBalances() (map[string]models.Balance, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	balances := make(map[string]models.Balance)
+	for asset, free := range p.balances {
+		balances[asset] = models.Balance{Asset: asset, Free: free, Locked: p.locked[asset]}
+	}
+	for asset, locked := range p.locked {
+		if _, ok := balances[asset]; !ok {
+			balances[asset] = models.Balance{Asset: asset, Locked: locked}
+		}
+	}
+	return balances, nil
+}
+
+// CreateOrder fills a market order sized in the quote asset
+func (p *PaperClient) CreateOrder(symbol, _, side string, amount string) (float64, error) {
+	quoteAmount, err := decimal.NewFromString(amount)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount format: %v", err)
+	}
+	price, err := p.market.GetCurrentPrice(symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.saveLocked()
+	fillPrice := p.slipped(side, price)
+	if _, err := p.marketFillLocked(symbol, side, quoteAmount.Div(fillPrice), fillPrice); err != nil {
+		return 0, err
+	}
+	return fillPrice.InexactFloat64(), nil
+}
+
+// CreateMarketOrder fills immediately at the current price plus slippage
+func (p *PaperClient) CreateMarketOrder(symbol, side, quantity string) (*models.Order, error) {
+	qty, err := decimal.NewFromString(quantity)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quantity format: %v", err)
+	}
+	price, err := p.market.GetCurrentPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if pair, ok := p.market.GetTradingPairs()[symbol]; ok && pair.Rules != nil {
+		if qty, _, err = pair.Rules.Quantize("MARKET", side, qty, decimal.Zero, price); err != nil {
+			return nil, fmt.Errorf("MARKET %s order for %s rejected: %v", side, symbol, err)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.saveLocked()
+	fillPrice := p.slipped(side, price)
+	order, err := p.marketFillLocked(symbol, side, qty, fillPrice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place MARKET %s order for %s: %v", side, symbol, err)
+	}
+	logger.Infof("Paper MARKET %s %s filled: Quantity=%s Price=%s", side, symbol, qty, fillPrice)
+	return p.toOrder(order), nil
+}
+
+// marketFillLocked records a market order and fills it at once
+func (p *PaperClient) marketFillLocked(symbol, side string, qty, price decimal.Decimal) (*paperOrder, error) {
+	p.nextID++
+	order := &paperOrder{id: p.nextID, symbol: symbol, side: side, orderType: "MARKET", quantity: qty, price: price, triggered: true}
+	if err := p.fillOrderLocked(order, price); err != nil {
+		return nil, err
+	}
+	p.orders[order.id] = order
+	return order, nil
+}
+
+// CreateLimitOrder fills marketable orders immediately, others rest until the price crosses them
+func (p *PaperClient) CreateLimitOrder(symbol, side, quantity, price string) (int64, error) {
+	return p.placeOrder(symbol, side, "LIMIT", quantity, price, "")
+}
+
+// CreateStopLossLimitOrder rests until the stop price is reached and then behaves as a limit order
+func (p *PaperClient) CreateStopLossLimitOrder(symbol, side, quantity, price, stopLoss string) (int64, error) {
+	return p.placeOrder(symbol, side, "STOP_LOSS_LIMIT", quantity, price, stopLoss)
+}
+
+// CreateLimitMakerOrder rests like a limit order and is rejected when it would match immediately
+func (p *PaperClient) CreateLimitMakerOrder(symbol, side, quantity, price string) (int64, error) {
+	return p.placeOrder(symbol, side, "LIMIT_MAKER", quantity, price, "")
+}
+
+func (p *PaperClient) placeOrder(symbol, side, orderType, quantity, price, stopPrice string) (int64, error) {
+	order, pair, currentPrice, err := p.newOrder(symbol, side, orderType, quantity, price, stopPrice)
+	if err != nil {
+		return 0, err
+	}
+	if orderType == "LIMIT_MAKER" && crosses(order, currentPrice) {
+		return 0, fmt.Errorf("LIMIT_MAKER %s order for %s rejected: price %s would immediately match at %s", side, symbol, order.price, currentPrice)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.saveLocked()
+
+	// Hold the funds the order needs, like the exchange does
+	if err := p.holdFundsLocked(pair, order); err != nil {
+		return 0, fmt.Errorf("failed to place %s %s order for %s: %v", orderType, side, symbol, err)
+	}
+	p.addOrderLocked(order)
+	logger.Infof("Paper %s %s order for %s placed: OrderID=%d Quantity=%s Price=%s", orderType, side, symbol, order.id, order.quantity, order.price)
+
+	p.matchLocked(symbol, currentPrice, currentPrice)
+	return order.id, nil
+}
+
+// CreateOCOOrder places a limit maker leg and a stop-limit leg that hold the funds once.
+// The first leg to trigger or fill expires the other one.
+func (p *PaperClient) CreateOCOOrder(symbol, side, quantity, price, stopPrice, stopLimitPrice string) ([]*models.Order, error) {
+	limit, pair, currentPrice, err := p.newOrder(symbol, side, "LIMIT_MAKER", quantity, price, "")
+	if err != nil {
+		return nil, err
+	}
+	stop, _, _, err := p.newOrder(symbol, side, "STOP_LOSS_LIMIT", quantity, stopLimitPrice, stopPrice)
+	if err != nil {
+		return nil, err
+	}
+	if crosses(limit, currentPrice) {
+		return nil, fmt.Errorf("OCO %s order for %s rejected: limit price %s would immediately match at %s", side, symbol, limit.price, currentPrice)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.saveLocked()
+
+	if err := p.holdFundsLocked(pair, limit); err != nil {
+		return nil, fmt.Errorf("failed to place OCO %s order for %s: %v", side, symbol, err)
+	}
+	p.addOrderLocked(limit)
+	p.addOrderLocked(stop)
+	limit.sibling, stop.sibling, stop.shared = stop.id, limit.id, true
+	p.markLocked(limit, stop)
+	logger.Infof("Paper OCO %s order for %s placed: OrderIDs=%d/%d Quantity=%s Price=%s Stop=%s", side, symbol, limit.id, stop.id, limit.quantity, limit.price, stop.stopPrice)
+
+	p.matchLocked(symbol, currentPrice, currentPrice)
+	return []*models.Order{p.toOrder(limit), p.toOrder(stop)}, nil
+}
+
+// newOrder parses an order and rounds it to the exchange rules of the pair
+func (p *PaperClient) newOrder(symbol, side, orderType, quantity, price, stopPrice string) (*paperOrder, *models.TradingPair, decimal.Decimal, error) {
+	order := &paperOrder{symbol: symbol, side: side, orderType: orderType, status: models.OrderStatusNew}
+
+	var err error
+	if order.quantity, err = decimal.NewFromString(quantity); err != nil {
+		return nil, nil, decimal.Zero, fmt.Errorf("invalid quantity format: %v", err)
+	}
+	if order.price, err = decimal.NewFromString(price); err != nil {
+		return nil, nil, decimal.Zero, fmt.Errorf("invalid price format: %v", err)
+	}
+	if stopPrice != "" {
+		if order.stopPrice, err = decimal.NewFromString(stopPrice); err != nil {
+			return nil, nil, decimal.Zero, fmt.Errorf("invalid stop price format: %v", err)
+		}
+	} else {
+		order.triggered = true
+	}
+	if !order.quantity.IsPositive() || !order.price.IsPositive() {
+		return nil, nil, decimal.Zero, fmt.Errorf("invalid %s %s order for %s: Quantity=%s Price=%s", orderType, side, symbol, quantity, price)
+	}
+
+	pair, ok := p.market.GetTradingPairs()[symbol]
+	if !ok {
+		return nil, nil, decimal.Zero, fmt.Errorf("trading pair %s not configured", symbol)
+	}
+
+	current, err := p.market.GetCurrentPrice(symbol)
+	if err != nil {
+		return nil, nil, decimal.Zero, err
+	}
+
+	// Apply the exchange rules so paper orders are rejected where real ones would be
+	if pair.Rules != nil {
+		if order.quantity, order.price, err = pair.Rules.Quantize(orderType, side, order.quantity, order.price, current); err != nil {
+			return nil, nil, decimal.Zero, fmt.Errorf("%s %s order for %s rejected: %v", orderType, side, symbol, err)
+		}
+		order.stopPrice = pair.Rules.QuantizePrice(order.stopPrice)
+	} else if notional := order.quantity.Mul(order.price); notional.LessThan(pair.MinNotional) {
+		return nil, nil, decimal.Zero, fmt.Errorf("order notional %s is below minimum %s for %s", notional, pair.MinNotional, symbol)
+	}
+	return order, pair, current, nil
+}
+
+// crosses reports whether a limit order would match immediately at the current price
+func crosses(order *paperOrder, current decimal.Decimal) bool {
+	if order.side == "BUY" {
+		return order.price.GreaterThanOrEqual(current)
+	}
+	return order.price.LessThanOrEqual(current)
+}
+
+// addOrderLocked assigns an ID to an order and puts it on the book
+func (p *PaperClient) addOrderLocked(order *paperOrder) {
+	p.nextID++
+	order.id = p.nextID
+	order.updatedAt = p.cfg.Clock()
+	p.orders[order.id] = order
+	p.markLocked(order)
+}
+
+// MatchOrders fills resting orders of a symbol crossed by a price range.
+// Backtests call it with the high and low of every replayed candle.
+func (p *PaperClient) MatchOrders(symbol string, low, high decimal.Decimal) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.saveLocked()
+	p.matchLocked(symbol, low, high)
+}
+
+func (p *PaperClient) matchLocked(symbol string, low, high decimal.Decimal) {
+	// Orders are matched in the order they were placed. Stops are triggered first, so the stop leg
+	// of an OCO wins when the price range crosses both legs.
+	ids := make([]int64, 0, len(p.orders))
+	for id, order := range p.orders {
+		if order.symbol == symbol && order.status == models.OrderStatusNew {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		order := p.orders[id]
+		if order.triggered || order.status != models.OrderStatusNew {
+			continue
+		}
+		if (order.side == "SELL" && low.LessThanOrEqual(order.stopPrice)) || (order.side == "BUY" && high.GreaterThanOrEqual(order.stopPrice)) {
+			order.triggered = true
+			p.markLocked(order)
+			p.expireSiblingLocked(order)
+			logger.Infof("Paper stop price %s reached for order %d on %s", order.stopPrice, id, symbol)
+		}
+	}
+
+	for _, id := range ids {
+		order := p.orders[id]
+		if !order.triggered || order.status != models.OrderStatusNew {
+			continue
+		}
+
+		var fillPrice decimal.Decimal
+		switch {
+		case order.side == "BUY" && low.LessThanOrEqual(order.price):
+			fillPrice = decimal.Min(order.price, p.slipped(order.side, high))
+		case order.side == "SELL" && high.GreaterThanOrEqual(order.price):
+			fillPrice = decimal.Max(order.price, p.slipped(order.side, low))
+		default:
+			continue
+		}
+
+		p.expireSiblingLocked(order)
+		p.releaseLocked(order)
+		if err := p.fillOrderLocked(order, fillPrice); err != nil {
+			logger.Warnf("Paper order %d for %s rejected: %v", id, symbol, err)
+			order.status = models.OrderStatusRejected
+			order.updatedAt = p.cfg.Clock()
+			p.markLocked(order)
+			continue
+		}
+		logger.Infof("Paper %s %s order %d for %s filled at %s", order.orderType, order.side, id, symbol, fillPrice)
+	}
+}
+
+// expireSiblingLocked expires the other leg of an OCO once a leg triggers or fills.
+// The funds held for the OCO move to the remaining leg.
+func (p *PaperClient) expireSiblingLocked(order *paperOrder) {
+	sibling, ok := p.orders[order.sibling]
+	if !ok || sibling.status != models.OrderStatusNew {
+		return
+	}
+	if order.shared {
+		p.releaseLocked(sibling)
+		order.shared = false
+		if pair, ok := p.market.GetTradingPairs()[order.symbol]; ok {
+			if err := p.holdFundsLocked(pair, order); err != nil {
+				logger.Warnf("Paper order %d for %s cannot hold the funds of its OCO: %v", order.id, order.symbol, err)
+			}
+		}
+	}
+	sibling.shared = true
+	sibling.status = models.OrderStatusExpired
+	sibling.updatedAt = p.cfg.Clock()
+	p.markLocked(order, sibling)
+	logger.Infof("Paper OCO leg %d for %s expired by order %d", sibling.id, sibling.symbol, order.id)
+}
+
+// GetOrder returns the status and fills of a paper order
+func (p *PaperClient) GetOrder(symbol string, orderID int64) (*models.Order, error) {
+	// Fill the order first if the price has crossed it
+	if _, err := p.GetCurrentPrice(symbol); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	order, ok := p.orders[orderID]
+	if !ok || order.symbol != symbol {
+		return nil, fmt.Errorf("paper order %d for %s not found", orderID, symbol)
+	}
+	return p.toOrder(order), nil
+}
+
+// GetOpenOrders returns the resting paper orders of a symbol
+func (p *PaperClient) GetOpenOrders(symbol string) ([]*models.Order, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var orders []*models.Order
+	for _, order := range p.orders {
+		if order.symbol == symbol && order.status == models.OrderStatusNew {
+			orders = append(orders, p.toOrder(order))
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].OrderID < orders[j].OrderID })
+	return orders, nil
+}
+
+// MonitorOrder waits until a paper order is filled, canceled or rejected, or until ctx is done
+func (p *PaperClient) MonitorOrder(ctx context.Context, symbol string, orderID int64) (bool, error) {
+	logger.Infof("Monitoring paper order %d for %s", orderID, symbol)
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		order, err := p.GetOrder(symbol, orderID)
+		if err != nil {
+			return false, err
+		}
+		if order.Status == models.OrderStatusFilled {
+			return true, nil
+		}
+		if !order.IsOpen() {
+			return false, nil
+		}
+
+		// Wait before the next status check
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// CancelOrder cancels a resting paper order and releases its funds
+func (p *PaperClient) CancelOrder(symbol string, orderID int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.saveLocked()
+
+	order, ok := p.orders[orderID]
+	if !ok || order.symbol != symbol {
+		return fmt.Errorf("failed to cancel order %d for %s: unknown order", orderID, symbol)
+	}
+	if order.status != models.OrderStatusNew {
+		return fmt.Errorf("failed to cancel order %d for %s: order is %s", orderID, symbol, order.status)
+	}
+
+	// Canceling a leg of an OCO cancels the whole list
+	for _, leg := range []*paperOrder{order, p.orders[order.sibling]} {
+		if leg == nil || leg.status != models.OrderStatusNew {
+			continue
+		}
+		p.releaseLocked(leg)
+		leg.status = models.OrderStatusCanceled
+		leg.updatedAt = p.cfg.Clock()
+		p.markLocked(leg)
+		logger.Infof("Successfully canceled paper order %d for %s", leg.id, symbol)
+	}
+	return nil
+}
+
+func (p *PaperClient) GetFeeRate() (float64, error) {
+	return p.cfg.FeeRate, nil
+}
+
+// Fills returns the simulated fills recorded so far
+func (p *PaperClient) Fills() []PaperFill {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PaperFill(nil), p.fills...)
+}
+
+// Equity values the wallet, including funds held by resting orders, in the given quote asset
+func (p *PaperClient) Equity(quoteAsset string) float64 {
+	p.mu.Lock()
+	holdings := make(map[string]decimal.Decimal)
+	for asset, amount := range p.balances {
+		holdings[asset] = holdings[asset].Add(amount)
+	}
+	for asset, amount := range p.locked {
+		holdings[asset] = holdings[asset].Add(amount)
+	}
+	p.mu.Unlock()
+
+	equity := holdings[quoteAsset]
+	for symbol, pair := range p.market.GetTradingPairs() {
+		if pair.QuoteAsset != quoteAsset || !holdings[pair.BaseAsset].IsPositive() {
+			continue
+		}
+		price, err := p.market.GetCurrentPrice(symbol)
+		if err != nil {
+			continue
+		}
+		equity = equity.Add(holdings[pair.BaseAsset].Mul(price))
+	}
+	return equity.InexactFloat64()
+}
+
+// slipped moves a price against the taker by the configured slippage
+func (p *PaperClient) slipped(side string, price decimal.Decimal) decimal.Decimal {
+	slippage := decimal.NewFromFloat(p.cfg.Slippage)
+	if side == "BUY" {
+		return price.Mul(decimal.NewFromInt(1).Add(slippage))
+	}
+	return price.Mul(decimal.NewFromInt(1).Sub(slippage))
+}
+
+// releaseLocked returns the funds held by a resting order to the free balance
+func (p *PaperClient) releaseLocked(order *paperOrder) {
+	pair, ok := p.market.GetTradingPairs()[order.symbol]
+	if !ok {
+		return
+	}
+	asset, amount := p.holdLocked(pair, order)
+	p.locked[asset] = p.locked[asset].Sub(amount)
+	p.balances[asset] = p.balances[asset].Add(amount)
+}
+
+// holdFundsLocked moves the funds a resting order needs from the free to the locked balance
+func (p *PaperClient) holdFundsLocked(pair *models.TradingPair, order *paperOrder) error {
+	asset, amount := p.holdLocked(pair, order)
+	if p.balances[asset].LessThan(amount) {
+		return fmt.Errorf("insufficient %s balance, need %s have %s", asset, amount, p.balances[asset])
+	}
+	p.balances[asset] = p.balances[asset].Sub(amount)
+	p.locked[asset] = p.locked[asset].Add(amount)
+	return nil
+}
+
+// holdLocked returns the asset and amount a resting order holds, BUY orders include the fee.
+// The stop leg of an OCO holds nothing while the other leg holds the funds.
+func (p *PaperClient) holdLocked(pair *models.TradingPair, order *paperOrder) (string, decimal.Decimal) {
+	if order.shared {
+		if order.side == "BUY" {
+			return pair.QuoteAsset, decimal.Zero
+		}
+		return pair.BaseAsset, decimal.Zero
+	}
+	if order.side == "BUY" {
+		return pair.QuoteAsset, order.quantity.Mul(order.price).Mul(decimal.NewFromInt(1).Add(p.feeRate()))
+	}
+	return pair.BaseAsset, order.quantity
+}
+
+// feeRate returns the configured fee rate as a decimal
+func (p *PaperClient) feeRate() decimal.Decimal {
+	return decimal.NewFromFloat(p.cfg.FeeRate)
+}
+
+// toOrder converts a paper order to the shared order model
+func (p *PaperClient) toOrder(order *paperOrder) *models.Order {
+	result := &models.Order{
+		OrderID:   order.id,
+		Symbol:    order.symbol,
+		Side:      order.side,
+		Type:      order.orderType,
+		Quantity:  order.quantity,
+		Price:     order.price,
+		Status:    order.status,
+		FilledQty: order.filledQty,
+		AvgPrice:  order.avgPrice,
+		UpdatedAt: order.updatedAt,
+	}
+	if pair, ok := p.market.GetTradingPairs()[order.symbol]; ok {
+		result.Commission = order.fee
+		result.CommissionAsset = pair.QuoteAsset
+	}
+	return result
+}
+
+// fillOrderLocked fills the whole order at price and marks it as filled
+func (p *PaperClient) fillOrderLocked(order *paperOrder, price decimal.Decimal) error {
+	fee, err := p.fillLocked(order.id, order.symbol, order.side, order.quantity, price)
+	if err != nil {
+		return err
+	}
+	order.status = models.OrderStatusFilled
+	order.filledQty = order.quantity
+	order.avgPrice = price
+	order.fee = fee
+	order.updatedAt = p.cfg.Clock()
+	p.markLocked(order)
+	return nil
+}
+
+// fillLocked moves balances for a fill, charging the fee in the quote asset
+func (p *PaperClient) fillLocked(orderID int64, symbol, side string, qty, price decimal.Decimal) (decimal.Decimal, error) {
+	pair, ok := p.market.GetTradingPairs()[symbol]
+	if !ok {
+		return decimal.Zero, fmt.Errorf("trading pair %s not configured", symbol)
+	}
+	if !qty.IsPositive() {
+		return decimal.Zero, fmt.Errorf("invalid quantity %s for %s", qty, symbol)
+	}
+
+	notional := qty.Mul(price)
+	if notional.LessThan(pair.MinNotional) {
+		return decimal.Zero, fmt.Errorf("order notional %s is below minimum %s for %s", notional, pair.MinNotional, symbol)
+	}
+	fee := notional.Mul(p.feeRate())
+	fill := PaperFill{OrderID: orderID, Timestamp: p.cfg.Clock(), Symbol: symbol, Side: side, Quantity: qty, Price: price, Fee: fee}
+
+	switch side {
+	case "BUY":
+		if p.balances[pair.QuoteAsset].LessThan(notional.Add(fee)) {
+			return decimal.Zero, fmt.Errorf("insufficient %s balance: need %s have %s", pair.QuoteAsset, notional.Add(fee), p.balances[pair.QuoteAsset])
+		}
+		held := p.balances[pair.BaseAsset].Add(p.locked[pair.BaseAsset])
+		p.costBasis[symbol] = p.costBasis[symbol].Mul(held).Add(notional).Add(fee).Div(held.Add(qty))
+		p.balances[pair.QuoteAsset] = p.balances[pair.QuoteAsset].Sub(notional.Add(fee))
+		p.balances[pair.BaseAsset] = p.balances[pair.BaseAsset].Add(qty)
+	case "SELL":
+		if p.balances[pair.BaseAsset].LessThan(qty) {
+			return decimal.Zero, fmt.Errorf("insufficient %s balance: need %s have %s", pair.BaseAsset, qty, p.balances[pair.BaseAsset])
+		}
+		fill.ProfitLoss = price.Sub(p.costBasis[symbol]).Mul(qty).Sub(fee)
+		p.balances[pair.BaseAsset] = p.balances[pair.BaseAsset].Sub(qty)
+		p.balances[pair.QuoteAsset] = p.balances[pair.QuoteAsset].Add(notional.Sub(fee))
+		if p.balances[pair.BaseAsset].Add(p.locked[pair.BaseAsset]).IsZero() {
+			delete(p.costBasis, symbol)
+		}
+	default:
+		return decimal.Zero, fmt.Errorf("unknown order side %s", side)
+	}
+
+	p.fills = append(p.fills, fill)
+	return fee, nil
+}
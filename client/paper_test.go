@@ -0,0 +1,302 @@
+package client
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/models"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+// paperMarket is a market data source with a fixed price per symbol
+type paperMarket struct {
+	pairs  map[string]*models.TradingPair
+	prices map[string]decimal.Decimal
+}
+
+func newPaperMarket() *paperMarket {
+	return &paperMarket{
+		pairs:  map[string]*models.TradingPair{"ETHUSDT": {Symbol: "ETHUSDT", BaseAsset: "ETH", QuoteAsset: "USDT", MinNotional: decimal.NewFromInt(5)}},
+		prices: map[string]decimal.Decimal{"ETHUSDT": decimal.NewFromInt(100)},
+	}
+}
+
+func (m *paperMarket) AddTradingPair(pair models.TradingPair) error {
+	m.pairs[pair.Symbol] = &pair
+	return nil
+}
+
+func (m *paperMarket) GetCurrentPrice(symbol string) (decimal.Decimal, error) {
+	return m.prices[symbol], nil
+}
+
+func (m *paperMarket) FetchCandles(symbol, interval string, limit int) ([]models.CandleStick, error) {
+	return nil, nil
+}
+
+func (m *paperMarket) GetTradingPairs() map[string]*models.TradingPair {
+	return m.pairs
+}
+
+// match runs the matching engine over a candle range given as strings
+func match(p *PaperClient, low, high string) {
+	p.MatchOrders("ETHUSDT", decimal.RequireFromString(low), decimal.RequireFromString(high))
+}
+
+func TestPaperMatching(t *testing.T) {
+	tests := []struct {
+		name     string
+		balances map[string]float64
+		run      func(p *PaperClient, m *paperMarket) ([]int64, error) // Places orders and moves the price, returns the order IDs
+		wantErr  bool
+		status   []string // Per order
+		price    []string // Average fill price per order, empty when not filled
+		free     map[string]string
+		locked   map[string]string
+	}{
+		{
+			name:     "resting BUY fills at its limit once the low reaches it",
+			balances: map[string]float64{"USDT": 1000},
+			run: func(p *PaperClient, m *paperMarket) ([]int64, error) {
+				id, err := p.CreateLimitOrder("ETHUSDT", "BUY", "1", "95")
+				match(p, "96", "101")
+				match(p, "94", "99")
+				return []int64{id}, err
+			},
+			status: []string{models.OrderStatusFilled},
+			price:  []string{"95"},
+			free:   map[string]string{"USDT": "904.905", "ETH": "1"},
+			locked: map[string]string{"USDT": "0"},
+		},
+		{
+			name:     "marketable BUY fills at once with slippage",
+			balances: map[string]float64{"USDT": 1000},
+			run: func(p *PaperClient, m *paperMarket) ([]int64, error) {
+				id, err := p.CreateLimitOrder("ETHUSDT", "BUY", "1", "105")
+				return []int64{id}, err
+			},
+			status: []string{models.OrderStatusFilled},
+			price:  []string{"100.1"},
+			free:   map[string]string{"USDT": "899.7999", "ETH": "1"},
+		},
+		{
+			name:     "resting SELL holds the base asset until the high reaches it",
+			balances: map[string]float64{"ETH": 2},
+			run: func(p *PaperClient, m *paperMarket) ([]int64, error) {
+				id, err := p.CreateLimitOrder("ETHUSDT", "SELL", "1", "110")
+				match(p, "95", "109")
+				return []int64{id}, err
+			},
+			status: []string{models.OrderStatusNew},
+			price:  []string{""},
+			free:   map[string]string{"ETH": "1"},
+			locked: map[string]string{"ETH": "1"},
+		},
+		{
+			name:     "SELL fills at its limit when the range opens below it",
+			balances: map[string]float64{"ETH": 2},
+			run: func(p *PaperClient, m *paperMarket) ([]int64, error) {
+				id, err := p.CreateLimitOrder("ETHUSDT", "SELL", "1", "110")
+				match(p, "105", "111")
+				return []int64{id}, err
+			},
+			status: []string{models.OrderStatusFilled},
+			price:  []string{"110"},
+			free:   map[string]string{"USDT": "109.89", "ETH": "1"},
+			locked: map[string]string{"ETH": "0"},
+		},
+		{
+			// The gap triggers the stop below its limit, the order waits for the price to come back
+			name:     "triggered stop rests as a limit order",
+			balances: map[string]float64{"ETH": 1},
+			run: func(p *PaperClient, m *paperMarket) ([]int64, error) {
+				id, err := p.CreateStopLossLimitOrder("ETHUSDT", "SELL", "1", "89", "90")
+				match(p, "91", "95")
+				match(p, "80", "85")
+				m.prices["ETHUSDT"] = decimal.NewFromInt(85)
+				return []int64{id}, err
+			},
+			status: []string{models.OrderStatusNew},
+			price:  []string{""},
+			locked: map[string]string{"ETH": "1"},
+		},
+		{
+			name:     "triggered stop fills once the high reaches its limit",
+			balances: map[string]float64{"ETH": 1},
+			run: func(p *PaperClient, m *paperMarket) ([]int64, error) {
+				id, err := p.CreateStopLossLimitOrder("ETHUSDT", "SELL", "1", "89", "90")
+				match(p, "80", "85")
+				match(p, "88", "90")
+				return []int64{id}, err
+			},
+			status: []string{models.OrderStatusFilled},
+			price:  []string{"89"},
+			free:   map[string]string{"USDT": "88.911", "ETH": "0"},
+			locked: map[string]string{"ETH": "0"},
+		},
+		{
+			name:     "OCO stop leg wins when the range crosses both legs",
+			balances: map[string]float64{"ETH": 1},
+			run: func(p *PaperClient, m *paperMarket) ([]int64, error) {
+				legs, err := p.CreateOCOOrder("ETHUSDT", "SELL", "1", "110", "90", "89")
+				if err != nil {
+					return nil, err
+				}
+				match(p, "85", "115")
+				return []int64{legs[0].OrderID, legs[1].OrderID}, nil
+			},
+			status: []string{models.OrderStatusExpired, models.OrderStatusFilled},
+			price:  []string{"", "89"},
+			free:   map[string]string{"USDT": "88.911", "ETH": "0"},
+			locked: map[string]string{"ETH": "0"},
+		},
+		{
+			name:     "OCO limit leg fill expires the stop leg",
+			balances: map[string]float64{"ETH": 1},
+			run: func(p *PaperClient, m *paperMarket) ([]int64, error) {
+				legs, err := p.CreateOCOOrder("ETHUSDT", "SELL", "1", "110", "90", "89")
+				if err != nil {
+					return nil, err
+				}
+				match(p, "100", "111")
+				return []int64{legs[0].OrderID, legs[1].OrderID}, nil
+			},
+			status: []string{models.OrderStatusFilled, models.OrderStatusExpired},
+			price:  []string{"110", ""},
+			free:   map[string]string{"USDT": "109.89", "ETH": "0"},
+			locked: map[string]string{"ETH": "0"},
+		},
+		{
+			name:     "cancel releases the held funds",
+			balances: map[string]float64{"USDT": 1000},
+			run: func(p *PaperClient, m *paperMarket) ([]int64, error) {
+				id, err := p.CreateLimitOrder("ETHUSDT", "BUY", "1", "95")
+				if err != nil {
+					return nil, err
+				}
+				return []int64{id}, p.CancelOrder("ETHUSDT", id)
+			},
+			status: []string{models.OrderStatusCanceled},
+			price:  []string{""},
+			free:   map[string]string{"USDT": "1000"},
+			locked: map[string]string{"USDT": "0"},
+		},
+		{
+			name:     "LIMIT_MAKER that would take liquidity is rejected",
+			balances: map[string]float64{"USDT": 1000},
+			run: func(p *PaperClient, m *paperMarket) ([]int64, error) {
+				_, err := p.CreateLimitMakerOrder("ETHUSDT", "BUY", "1", "101")
+				return nil, err
+			},
+			wantErr: true,
+			free:    map[string]string{"USDT": "1000"},
+		},
+		{
+			name:     "order beyond the free balance is rejected",
+			balances: map[string]float64{"USDT": 1000},
+			run: func(p *PaperClient, m *paperMarket) ([]int64, error) {
+				_, err := p.CreateLimitOrder("ETHUSDT", "BUY", "20", "99")
+				return nil, err
+			},
+			wantErr: true,
+			free:    map[string]string{"USDT": "1000"},
+			locked:  map[string]string{"USDT": "0"},
+		},
+		{
+			name:     "order below the minimum notional is rejected",
+			balances: map[string]float64{"USDT": 1000},
+			run: func(p *PaperClient, m *paperMarket) ([]int64, error) {
+				_, err := p.CreateLimitOrder("ETHUSDT", "BUY", "0.04", "99")
+				return nil, err
+			},
+			wantErr: true,
+			free:    map[string]string{"USDT": "1000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newPaperMarket()
+			p := NewPaperClient(m, PaperConfig{Balances: tt.balances, FeeRate: 0.001, Slippage: 0.001})
+
+			ids, err := tt.run(p, m)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			for i, id := range ids {
+				order, err := p.GetOrder("ETHUSDT", id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if order.Status != tt.status[i] {
+					t.Errorf("order %d: got status %s, want %s", id, order.Status, tt.status[i])
+				}
+				if want := tt.price[i]; want != "" && !order.AvgPrice.Equal(decimal.RequireFromString(want)) {
+					t.Errorf("order %d: got fill price %s, want %s", id, order.AvgPrice, want)
+				}
+			}
+			expectBalances(t, p, tt.free, tt.locked)
+		})
+	}
+}
+
+func TestPaperWalletRestore(t *testing.T) {
+	if err := db2.InitDBAt(t.TempDir() + "/paper.db"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db2.SQLiteDB.DB.Close() })
+
+	cfg := PaperConfig{Balances: map[string]float64{"USDT": 1000}, FeeRate: 0.001, Persist: true}
+	p := NewPaperClient(newPaperMarket(), cfg)
+	filled, err := p.CreateLimitOrder("ETHUSDT", "BUY", "1", "100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resting, err := p.CreateLimitOrder("ETHUSDT", "BUY", "2", "90")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new client picks up the stored wallet instead of the configured balances
+	cfg.Balances = map[string]float64{"USDT": 5}
+	restored := NewPaperClient(newPaperMarket(), cfg)
+	expectBalances(t, restored, map[string]string{"USDT": "719.72", "ETH": "1"}, map[string]string{"USDT": "180.18"})
+
+	order, err := restored.GetOrder("ETHUSDT", filled)
+	if err != nil || order.Status != models.OrderStatusFilled {
+		t.Fatalf("filled order after restore: %v %v", order, err)
+	}
+	match(restored, "89", "95")
+	order, err = restored.GetOrder("ETHUSDT", resting)
+	if err != nil || order.Status != models.OrderStatusFilled {
+		t.Fatalf("resting order after restore: %v %v", order, err)
+	}
+	expectBalances(t, restored, map[string]string{"USDT": "719.72", "ETH": "3"}, map[string]string{"USDT": "0"})
+
+	// Order IDs continue after the restored orders
+	next, err := restored.CreateLimitOrder("ETHUSDT", "BUY", "1", "90")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next <= resting {
+		t.Errorf("new order got ID %d, the restored orders go up to %d", next, resting)
+	}
+}
+
+// expectBalances compares the free and locked balances of a paper wallet
+func expectBalances(t *testing.T, p *PaperClient, free, locked map[string]string) {
+	t.Helper()
+	balances, err := p.GetBalances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for asset, want := range free {
+		if got := balances[asset].Free; !got.Equal(decimal.RequireFromString(want)) {
+			t.Errorf("free %s: got %s, want %s", asset, got, want)
+		}
+	}
+	for asset, want := range locked {
+		if got := balances[asset].Locked; !got.Equal(decimal.RequireFromString(want)) {
+			t.Errorf("locked %s: got %s, want %s", asset, got, want)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package client
+
+import (
+	"binance_bot/logger"
+	"binance_bot/models"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	streamReadTimeout     = 5 * time.Minute // Binance pings every 3 minutes
+	streamMinReconnectGap = time.Second
+	streamMaxReconnectGap = time.Minute
+)
+
+// bookTicker holds the streamed best bid and ask of a symbol
+type bookTicker struct {
+	models.BookTicker
+	Updated time.Time
+}
+
+// marketStream is a combined kline and bookTicker stream for all trading pairs
+type marketStream struct {
+	endpoint string // Websocket endpoint, a local stand-in server in tests
+	minGap   time.Duration
+	maxGap   time.Duration // Reconnect delays double from minGap up to maxGap
+	interval string
+	window   int
+	symbols  []string
+	conn     *websocket.Conn
+	connMu   sync.Mutex
+	live     bool
+	liveMu   sync.RWMutex
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// streamMessage is the envelope of combined stream payloads
+type streamMessage struct {
+	Stream string          `json:"stream"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type klineEvent struct {
+	Symbol string `json:"s"`
+	Kline  struct {
+		OpenTime int64  `json:"t"`
+		Interval string `json:"i"`
+		Open     string `json:"o"`
+		High     string `json:"h"`
+		Low      string `json:"l"`
+		Close    string `json:"c"`
+		Volume   string `json:"v"`
+		IsFinal  bool   `json:"x"`
+	} `json:"k"`
+}
+
+type bookTickerEvent struct {
+	Symbol string `json:"s"`
+	Bid    string `json:"b"`
+	BidQty string `json:"B"`
+	Ask    string `json:"a"`
+	AskQty string `json:"A"`
+}
+
+// StartStreaming subscribes to kline and bookTicker streams of every trading pair at endpoint and
+// keeps a rolling window of candles per symbol in memory. FetchCandles and GetCurrentPrice are served
+// from it while the stream is connected and fall back to REST otherwise.
+func (b *BinanceClient) StartStreaming(endpoint, interval string, window int) error {
+	b.pairsMutex.RLock()
+	symbols := make([]string, 0, len(b.pairs))
+	for symbol := range b.pairs {
+		symbols = append(symbols, symbol)
+	}
+	b.pairsMutex.RUnlock()
+	if len(symbols) == 0 {
+		return fmt.Errorf("no trading pairs to stream")
+	}
+
+	stream := &marketStream{
+		endpoint: endpoint,
+		minGap:   streamMinReconnectGap,
+		maxGap:   streamMaxReconnectGap,
+		interval: interval,
+		window:   window,
+		symbols:  symbols,
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+	if err := b.startStream(stream); err != nil {
+		return err
+	}
+	logger.Infof("Started market data stream for %d pairs on %s candles", len(symbols), interval)
+	return nil
+}
+
+// startStream runs a stream unless one is running already
+func (b *BinanceClient) startStream(stream *marketStream) error {
+	b.streamMu.Lock()
+	defer b.streamMu.Unlock()
+
+	if b.stream != nil {
+		return fmt.Errorf("market data stream already started")
+	}
+	b.stream = stream
+	go b.runStream(stream)
+	return nil
+}
+
+// StopStreaming closes the market data stream
+func (b *BinanceClient) StopStreaming() {
+	b.streamMu.Lock()
+	stream := b.stream
+	b.stream = nil
+	b.streamMu.Unlock()
+
+	if stream == nil {
+		return
+	}
+	close(stream.stopCh)
+	stream.connMu.Lock()
+	if stream.conn != nil {
+		stream.conn.Close()
+	}
+	stream.connMu.Unlock()
+	<-stream.doneCh
+}
+
+// streamFor returns the running stream if it is connected and serves the interval
+func (b *BinanceClient) streamFor(interval string) *marketStream {
+	b.streamMu.RLock()
+	stream := b.stream
+	b.streamMu.RUnlock()
+
+	if stream == nil || stream.interval != interval || !stream.isLive() {
+		return nil
+	}
+	return stream
+}
+
+func (s *marketStream) isLive() bool {
+	s.liveMu.RLock()
+	defer s.liveMu.RUnlock()
+	return s.live
+}
+
+func (s *marketStream) setLive(live bool) {
+	s.liveMu.Lock()
+	s.live = live
+	s.liveMu.Unlock()
+}
+
+func (s *marketStream) url() string {
+	streams := make([]string, 0, len(s.symbols)*2)
+	for _, symbol := range s.symbols {
+		lower := strings.ToLower(symbol)
+		streams = append(streams, lower+"@kline_"+s.interval, lower+"@bookTicker")
+	}
+	return strings.TrimSuffix(s.endpoint, "/") + "/stream?streams=" + strings.Join(streams, "/")
+}
+
+// runStream keeps the stream connected, backfilling the candle windows over REST after every (re)connect
+func (b *BinanceClient) runStream(stream *marketStream) {
+	defer close(stream.doneCh)
+
+	delay := stream.minGap
+	for {
+		conn, _, err := websocket.DefaultDialer.Dial(stream.url(), nil)
+		if err == nil {
+			stream.connMu.Lock()
+			stream.conn = conn
+			stream.connMu.Unlock()
+
+			// Fill the window and any gap left while disconnected
+			b.backfill(stream, stream.symbols)
+			stream.setLive(true)
+			delay = stream.minGap
+
+			err = b.readStream(stream, conn)
+			stream.setLive(false)
+			conn.Close()
+		}
+
+		select {
+		case <-stream.stopCh:
+			logger.Info("Market data stream stopped")
+			return
+		default:
+		}
+
+		logger.Warnf("Market data stream disconnected: %v. Reconnecting in %s", err, delay)
+		select {
+		case <-stream.stopCh:
+			return
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, stream.maxGap)
+	}
+}
+
+func (b *BinanceClient) readStream(stream *marketStream, conn *websocket.Conn) error {
+	conn.SetReadDeadline(time.Now().Add(streamReadTimeout))
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(streamReadTimeout))
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(10*time.Second))
+	})
+
+	for {
+		_, payload, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		conn.SetReadDeadline(time.Now().Add(streamReadTimeout))
+
+		var msg streamMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			logger.Warnf("Invalid market data message: %v", err)
+			continue
+		}
+
+		switch {
+		case strings.Contains(msg.Stream, "@kline_"):
+			b.handleKline(stream, msg.Data)
+		case strings.HasSuffix(msg.Stream, "@bookTicker"):
+			b.handleBookTicker(msg.Data)
+		}
+	}
+}
+
+func (b *BinanceClient) handleKline(stream *marketStream, data json.RawMessage) {
+	var event klineEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		logger.Warnf("Invalid kline event: %v", err)
+		return
+	}
+
+	k := event.Kline
+	candle := models.CandleStick{Timestamp: time.UnixMilli(k.OpenTime)}
+	candle.Open, _ = strconv.ParseFloat(k.Open, 64)
+	candle.High, _ = strconv.ParseFloat(k.High, 64)
+	candle.Low, _ = strconv.ParseFloat(k.Low, 64)
+	candle.Close, _ = strconv.ParseFloat(k.Close, 64)
+	candle.Volume, _ = strconv.ParseFloat(k.Volume, 64)
+
+	if gap := b.mergeCandle(event.Symbol, candle, stream.window); gap {
+		logger.Warnf("Gap detected in %s candles, backfilling", event.Symbol)
+		go b.backfill(stream, []string{event.Symbol})
+	}
+}
+
+func (b *BinanceClient) handleBookTicker(data json.RawMessage) {
+	var event bookTickerEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		logger.Warnf("Invalid bookTicker event: %v", err)
+		return
+	}
+
+	ticker := bookTicker{BookTicker: models.BookTicker{Symbol: event.Symbol}, Updated: time.Now()}
+	ticker.BidPrice, _ = decimal.NewFromString(event.Bid)
+	ticker.BidQty, _ = decimal.NewFromString(event.BidQty)
+	ticker.AskPrice, _ = decimal.NewFromString(event.Ask)
+	ticker.AskQty, _ = decimal.NewFromString(event.AskQty)
+
+	b.cacheMutex.Lock()
+	b.tickerCache[event.Symbol] = ticker
+	b.cacheMutex.Unlock()
+
+	if ticker.BidPrice.IsPositive() {
+		b.notifyWatchers(event.Symbol, ticker.BidPrice)
+	}
+}
+
+// WatchPrice delivers the best bid of a symbol, the price a position sells at, on every bookTicker
+// update of the stream. A slow reader gets the latest price only.
+func (b *BinanceClient) WatchPrice(symbol string) (<-chan decimal.Decimal, func()) {
+	updates := make(chan decimal.Decimal, 1)
+	b.watchMu.Lock()
+	if b.watchers == nil {
+		b.watchers = make(map[string][]chan decimal.Decimal)
+	}
+	b.watchers[symbol] = append(b.watchers[symbol], updates)
+	b.watchMu.Unlock()
+
+	stop := func() {
+		b.watchMu.Lock()
+		defer b.watchMu.Unlock()
+		watchers := b.watchers[symbol]
+		for i, ch := range watchers {
+			if ch == updates {
+				b.watchers[symbol] = append(watchers[:i:i], watchers[i+1:]...)
+				break
+			}
+		}
+	}
+	return updates, stop
+}
+
+// notifyWatchers hands a price to the watchers of a symbol, replacing a price not read yet
+func (b *BinanceClient) notifyWatchers(symbol string, price decimal.Decimal) {
+	b.watchMu.RLock()
+	defer b.watchMu.RUnlock()
+
+	for _, updates := range b.watchers[symbol] {
+		select {
+		case <-updates:
+		default:
+		}
+		select {
+		case updates <- price:
+		default:
+		}
+	}
+}
+
+// mergeCandle updates the forming candle or appends a new one, reporting whether candles are missing
+func (b *BinanceClient) mergeCandle(symbol string, candle models.CandleStick, window int) bool {
+	b.cacheMutex.Lock()
+	defer b.cacheMutex.Unlock()
+
+	candles := b.candleCache[symbol]
+	gap := false
+	if n := len(candles); n > 0 {
+		last := candles[n-1]
+		switch {
+		case candle.Timestamp.Equal(last.Timestamp):
+			candles[n-1] = candle
+			return false
+		case candle.Timestamp.Before(last.Timestamp):
+			return false // Stale update
+		case n > 1:
+			step := last.Timestamp.Sub(candles[n-2].Timestamp)
+			gap = candle.Timestamp.Sub(last.Timestamp) > step
+		}
+	}
+
+	candles = append(candles, candle)
+	if len(candles) > window {
+		candles = candles[len(candles)-window:]
+	}
+	b.candleCache[symbol] = candles
+	return gap
+}
+
+// backfill loads the candle window of symbols over REST and merges it with streamed candles
+func (b *BinanceClient) backfill(stream *marketStream, symbols []string) {
+	for _, symbol := range symbols {
+		candles, err := b.fetchKlines(symbol, stream.interval, stream.window)
+		if err != nil {
+			logger.Warnf("Failed to backfill candles for %s: %v", symbol, err)
+			continue
+		}
+
+		b.cacheMutex.Lock()
+		cached := b.candleCache[symbol]
+		// Keep streamed candles newer than the REST snapshot
+		for _, c := range cached {
+			if len(candles) == 0 || c.Timestamp.After(candles[len(candles)-1].Timestamp) {
+				candles = append(candles, c)
+			} else if c.Timestamp.Equal(candles[len(candles)-1].Timestamp) {
+				candles[len(candles)-1] = c
+			}
+		}
+		if len(candles) > stream.window {
+			candles = candles[len(candles)-stream.window:]
+		}
+		b.candleCache[symbol] = candles
+		b.cacheMutex.Unlock()
+	}
+}
+
+// cachedCandles returns the last limit streamed candles of a symbol
+func (b *BinanceClient) cachedCandles(symbol string, limit int) ([]models.CandleStick, bool) {
+	b.cacheMutex.RLock()
+	defer b.cacheMutex.RUnlock()
+
+	candles := b.candleCache[symbol]
+	if len(candles) < limit {
+		return nil, false
+	}
+	return append([]models.CandleStick(nil), candles[len(candles)-limit:]...), true
+}
@@ -0,0 +1,307 @@
+package client
+
+import (
+	"binance_bot/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+)
+
+// t0 is the open time of the first candle served by the stand-in
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// standIn serves the Binance kline REST endpoint and the combined market data websocket
+type standIn struct {
+	server   *httptest.Server
+	conns    chan *websocket.Conn
+	mu       sync.Mutex
+	closes   []float64 // Closes of the REST candles, one per minute from t0
+	refuse   int       // Websocket dials to refuse before accepting
+	dials    []time.Time
+	accepted []*websocket.Conn
+}
+
+func newStandIn(t *testing.T, closes ...float64) *standIn {
+	s := &standIn{closes: closes, conns: make(chan *websocket.Conn, 4)}
+	upgrader := websocket.Upgrader{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/klines", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		rows := make([][]interface{}, 0, len(s.closes))
+		for i, c := range s.closes {
+			open := t0.Add(time.Duration(i) * time.Minute).UnixMilli()
+			price := strconv.FormatFloat(c, 'f', -1, 64)
+			rows = append(rows, []interface{}{open, price, price, price, price, "1", open + 59999, "1", 1, "1", "1", "0"})
+		}
+		if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit < len(rows) {
+			rows = rows[len(rows)-limit:]
+		}
+		json.NewEncoder(w).Encode(rows)
+	})
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.dials = append(s.dials, time.Now())
+		refuse := s.refuse > 0
+		if refuse {
+			s.refuse--
+		}
+		s.mu.Unlock()
+		if refuse {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		s.conns <- conn
+	})
+	s.server = httptest.NewServer(mux)
+	t.Cleanup(func() {
+		s.server.Close()
+		for _, conn := range s.accepted {
+			conn.Close()
+		}
+	})
+	return s
+}
+
+// setCloses replaces the candles served over REST
+func (s *standIn) setCloses(closes ...float64) {
+	s.mu.Lock()
+	s.closes = closes
+	s.mu.Unlock()
+}
+
+// accept waits for the next websocket connection of the client
+func (s *standIn) accept(t *testing.T) *websocket.Conn {
+	t.Helper()
+	select {
+	case conn := <-s.conns:
+		s.accepted = append(s.accepted, conn)
+		return conn
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not connect")
+		return nil
+	}
+}
+
+// start streams BTCUSDT 1m candles with a window of window candles from the stand-in
+func (s *standIn) start(t *testing.T, window int, minGap, maxGap time.Duration) *BinanceClient {
+	t.Helper()
+	rest := binance.NewClient("", "")
+	rest.BaseURL = s.server.URL
+	b := &BinanceClient{
+		client:      rest,
+		pairs:       map[string]*models.TradingPair{"BTCUSDT": {Symbol: "BTCUSDT"}},
+		candleCache: make(map[string][]models.CandleStick),
+		tickerCache: make(map[string]bookTicker),
+		watchers:    make(map[string][]chan decimal.Decimal),
+	}
+	stream := &marketStream{
+		endpoint: "ws" + strings.TrimPrefix(s.server.URL, "http"),
+		minGap:   minGap,
+		maxGap:   maxGap,
+		interval: "1m",
+		window:   window,
+		symbols:  []string{"BTCUSDT"},
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+	if err := b.startStream(stream); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(b.StopStreaming)
+	return b
+}
+
+func sendKline(t *testing.T, conn *websocket.Conn, minute int, closePrice float64) {
+	t.Helper()
+	open := t0.Add(time.Duration(minute) * time.Minute).UnixMilli()
+	price := strconv.FormatFloat(closePrice, 'f', -1, 64)
+	frame := fmt.Sprintf(`{"stream":"btcusdt@kline_1m","data":{"s":"BTCUSDT","k":{"t":%d,"i":"1m","o":"%s","h":"%s","l":"%s","c":"%s","v":"1","x":false}}}`,
+		open, price, price, price, price)
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(frame)); err != nil {
+		t.Fatalf("error sending kline: %v", err)
+	}
+}
+
+// eventually fails the test when cond does not hold within a few seconds
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// streamedCloses returns the closes of the cached window with the minute of the first candle
+func streamedCloses(b *BinanceClient, limit int) (int, []float64) {
+	candles, ok := b.cachedCandles("BTCUSDT", limit)
+	if !ok || b.streamFor("1m") == nil {
+		return -1, nil
+	}
+	closes := make([]float64, len(candles))
+	for i, c := range candles {
+		closes[i] = c.Close
+	}
+	return int(candles[0].Timestamp.Sub(t0) / time.Minute), closes
+}
+
+func equalCloses(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStreamMergesWindow(t *testing.T) {
+	s := newStandIn(t, 1, 2, 3)
+	b := s.start(t, 3, time.Millisecond, time.Millisecond)
+	conn := s.accept(t)
+	eventually(t, "the backfilled window", func() bool {
+		_, closes := streamedCloses(b, 3)
+		return equalCloses(closes, []float64{1, 2, 3})
+	})
+
+	tests := []struct {
+		name      string
+		minute    int
+		close     float64
+		wantFirst int
+		want      []float64
+	}{
+		{"forming candle is updated", 2, 3.5, 0, []float64{1, 2, 3.5}},
+		{"next candle is appended and the window rolls", 3, 4, 1, []float64{2, 3.5, 4}},
+		{"stale candle is ignored", 1, 9, 1, []float64{2, 3.5, 4}},
+		{"next candle closes the window again", 4, 5, 2, []float64{3.5, 4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sendKline(t, conn, tt.minute, tt.close)
+			eventually(t, fmt.Sprintf("closes %v", tt.want), func() bool {
+				first, closes := streamedCloses(b, 3)
+				return first == tt.wantFirst && equalCloses(closes, tt.want)
+			})
+		})
+	}
+
+	candles, err := b.FetchCandles("BTCUSDT", "1m", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := candles[len(candles)-1].Close; got != 5 {
+		t.Errorf("FetchCandles served close %v, want the streamed 5", got)
+	}
+}
+
+func TestStreamBackfillsGaps(t *testing.T) {
+	s := newStandIn(t, 1, 2, 3)
+	b := s.start(t, 10, time.Millisecond, time.Millisecond)
+	conn := s.accept(t)
+	eventually(t, "the backfilled window", func() bool {
+		_, closes := streamedCloses(b, 3)
+		return equalCloses(closes, []float64{1, 2, 3})
+	})
+
+	t.Run("after a dropped connection", func(t *testing.T) {
+		// Candles 3 and 4 close while the client is disconnected
+		s.setCloses(1, 2, 3, 4, 5)
+		conn.Close()
+		conn = s.accept(t)
+		eventually(t, "the candles missed while disconnected", func() bool {
+			_, closes := streamedCloses(b, 5)
+			return equalCloses(closes, []float64{1, 2, 3, 4, 5})
+		})
+	})
+
+	t.Run("after a skipped candle", func(t *testing.T) {
+		// Candle 5 never arrives over the stream, candle 6 reveals the gap
+		s.setCloses(1, 2, 3, 4, 5, 6, 7)
+		sendKline(t, conn, 6, 7)
+		eventually(t, "the skipped candle", func() bool {
+			_, closes := streamedCloses(b, 7)
+			return equalCloses(closes, []float64{1, 2, 3, 4, 5, 6, 7})
+		})
+	})
+}
+
+func TestStreamReconnectBackoff(t *testing.T) {
+	const minGap, maxGap = 40 * time.Millisecond, 100 * time.Millisecond
+
+	s := newStandIn(t, 1, 2, 3)
+	s.refuse = 4
+	b := s.start(t, 3, minGap, maxGap)
+	s.accept(t)
+	eventually(t, "the stream to go live", func() bool { return b.streamFor("1m") != nil })
+
+	s.mu.Lock()
+	dials := append([]time.Time(nil), s.dials...)
+	s.mu.Unlock()
+	if len(dials) != 5 {
+		t.Fatalf("got %d dials, want 4 refused and 1 accepted", len(dials))
+	}
+
+	// Delays double from minGap and stop growing at maxGap
+	for i, want := range []time.Duration{minGap, 2 * minGap, maxGap, maxGap} {
+		gap := dials[i+1].Sub(dials[i])
+		if gap < want {
+			t.Errorf("reconnect %d after %s, want at least %s", i+1, gap, want)
+		}
+		if gap > want+time.Second {
+			t.Errorf("reconnect %d after %s, want about %s", i+1, gap, want)
+		}
+	}
+}
+
+func TestStreamBookTicker(t *testing.T) {
+	s := newStandIn(t, 1, 2, 3)
+	b := s.start(t, 3, time.Millisecond, time.Millisecond)
+	updates, stop := b.WatchPrice("BTCUSDT")
+	defer stop()
+	conn := s.accept(t)
+	eventually(t, "the stream to go live", func() bool { return b.streamFor("1m") != nil })
+
+	frame := `{"stream":"btcusdt@bookTicker","data":{"s":"BTCUSDT","b":"99.5","B":"2","a":"100.5","A":"3"}}`
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(frame)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case price := <-updates:
+		if price.String() != "99.5" {
+			t.Errorf("watched price %s, want the bid 99.5", price)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no price update")
+	}
+
+	ticker, err := b.GetBookTicker("BTCUSDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticker.BidPrice.String() != "99.5" || ticker.AskPrice.String() != "100.5" || ticker.BidQty.String() != "2" || ticker.AskQty.String() != "3" {
+		t.Errorf("book ticker %+v, want the streamed 99.5 x 2 / 100.5 x 3", ticker)
+	}
+}
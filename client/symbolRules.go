@@ -0,0 +1,128 @@
+package client
+
+import (
+	"binance_bot/logger"
+	"binance_bot/models"
+	"context"
+	"fmt"
+	"github.com/adshao/go-binance/v2"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// parseSymbolRules reads the trading rules from the filters of a symbol
+func parseSymbolRules(symbol binance.Symbol) (*models.SymbolRules, error) {
+	rules := &models.SymbolRules{
+		Status:     symbol.Status,
+		OrderTypes: symbol.OrderTypes,
+		UpdatedAt:  time.Now(),
+	}
+
+	for _, filter := range symbol.Filters {
+		f := filterValues(filter)
+		var err error
+		switch filter["filterType"] {
+		case "PRICE_FILTER":
+			err = f.parse(&rules.TickSize, "tickSize", &rules.MinPrice, "minPrice", &rules.MaxPrice, "maxPrice")
+		case "LOT_SIZE":
+			err = f.parse(&rules.StepSize, "stepSize", &rules.MinQty, "minQty", &rules.MaxQty, "maxQty")
+		case "MARKET_LOT_SIZE":
+			err = f.parse(&rules.MarketStepSize, "stepSize", &rules.MarketMinQty, "minQty", &rules.MarketMaxQty, "maxQty")
+		case "MIN_NOTIONAL":
+			err = f.parse(&rules.MinNotional, "minNotional")
+			rules.ApplyMinNotionalToMarket, _ = filter["applyToMarket"].(bool)
+		case "NOTIONAL":
+			err = f.parse(&rules.MinNotional, "minNotional", &rules.MaxNotional, "maxNotional")
+			rules.ApplyMinNotionalToMarket, _ = filter["applyMinToMarket"].(bool)
+			rules.ApplyMaxNotionalToMarket, _ = filter["applyMaxToMarket"].(bool)
+		case "PERCENT_PRICE":
+			err = f.parse(&rules.BidMultiplierUp, "multiplierUp", &rules.BidMultiplierDown, "multiplierDown")
+			rules.AskMultiplierUp, rules.AskMultiplierDown = rules.BidMultiplierUp, rules.BidMultiplierDown
+		case "PERCENT_PRICE_BY_SIDE":
+			err = f.parse(&rules.BidMultiplierUp, "bidMultiplierUp", &rules.BidMultiplierDown, "bidMultiplierDown",
+				&rules.AskMultiplierUp, "askMultiplierUp", &rules.AskMultiplierDown, "askMultiplierDown")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %v filter for %s: %v", filter["filterType"], symbol.Symbol, err)
+		}
+	}
+
+	if !rules.StepSize.IsPositive() || !rules.TickSize.IsPositive() {
+		return nil, fmt.Errorf("missing LOT_SIZE or PRICE_FILTER for %s", symbol.Symbol)
+	}
+	return rules, nil
+}
+
+type filterValues map[string]interface{}
+
+// parse reads pairs of destination and key, the exchange sends filter values as strings
+func (f filterValues) parse(targets ...interface{}) error {
+	for i := 0; i+1 < len(targets); i += 2 {
+		dst, key := targets[i].(*decimal.Decimal), targets[i+1].(string)
+		raw, ok := f[key].(string)
+		if !ok {
+			continue
+		}
+		value, err := decimal.NewFromString(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %v", key, err)
+		}
+		*dst = value
+	}
+	return nil
+}
+
+// RefreshRules fetches the trading rules of all pairs again, the exchange may change them at any time
+func (b *BinanceClient) RefreshRules() error {
+	b.pairsMutex.RLock()
+	symbols := make([]string, 0, len(b.pairs))
+	for symbol := range b.pairs {
+		symbols = append(symbols, symbol)
+	}
+	b.pairsMutex.RUnlock()
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	info, err := b.client.NewExchangeInfoService().Symbols(symbols...).Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to refresh exchange info: %v", err)
+	}
+
+	for _, symbol := range info.Symbols {
+		rules, err := parseSymbolRules(symbol)
+		if err != nil {
+			logger.Errorf("%v", err)
+			continue
+		}
+
+		b.pairsMutex.Lock()
+		if pair, ok := b.pairs[symbol.Symbol]; ok {
+			if pair.Rules != nil && pair.Rules.Status != rules.Status {
+				logger.Warnf("%s status changed from %s to %s", symbol.Symbol, pair.Rules.Status, rules.Status)
+			}
+			// Pairs handed out before stay as they are, readers pick up the new rules on their next lookup
+			updated := *pair
+			updated.ApplyRules(rules)
+			b.pairs[symbol.Symbol] = &updated
+		}
+		b.pairsMutex.Unlock()
+	}
+	logger.Debugf("Refreshed trading rules for %d pairs", len(info.Symbols))
+	return nil
+}
+
+// pairRules returns the cached trading rules of a configured pair
+func (b *BinanceClient) pairRules(symbol string) (*models.SymbolRules, error) {
+	b.pairsMutex.RLock()
+	defer b.pairsMutex.RUnlock()
+
+	pair, ok := b.pairs[symbol]
+	if !ok {
+		return nil, fmt.Errorf("trading pair %s not configured", symbol)
+	}
+	if pair.Rules == nil {
+		return nil, fmt.Errorf("no trading rules for %s", symbol)
+	}
+	return pair.Rules, nil
+}
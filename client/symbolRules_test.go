@@ -0,0 +1,110 @@
+package client
+
+import (
+	"binance_bot/models"
+	"encoding/json"
+	"github.com/adshao/go-binance/v2"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// rulesServer serves the exchange info of ETHUSDT, the step size changes on every request
+func rulesServer(t *testing.T) *httptest.Server {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		step := "0.0001"
+		if requests.Add(1)%2 == 0 {
+			step = "0.01"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"symbols": []map[string]interface{}{{
+				"symbol":     "ETHUSDT",
+				"status":     "TRADING",
+				"baseAsset":  "ETH",
+				"quoteAsset": "USDT",
+				"orderTypes": []string{"LIMIT", "MARKET"},
+				"filters": []map[string]interface{}{
+					{"filterType": "PRICE_FILTER", "minPrice": "0.01", "maxPrice": "1000000", "tickSize": "0.01"},
+					{"filterType": "LOT_SIZE", "minQty": step, "maxQty": "9000", "stepSize": step},
+					{"filterType": "NOTIONAL", "minNotional": "5", "applyMinToMarket": true, "maxNotional": "9000000"},
+				},
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRefreshRulesReplacesPair(t *testing.T) {
+	rest := binance.NewClient("", "")
+	rest.BaseURL = rulesServer(t).URL
+	b := &BinanceClient{client: rest, pairs: make(map[string]*models.TradingPair)}
+	if err := b.AddTradingPair(models.NewTradingPair("ETHUSDT")); err != nil {
+		t.Fatal(err)
+	}
+
+	before := b.GetTradingPairs()["ETHUSDT"]
+	if err := b.RefreshRules(); err != nil {
+		t.Fatal(err)
+	}
+	after := b.GetTradingPairs()["ETHUSDT"]
+
+	qty := decimal.RequireFromString("1.23456")
+	if got := before.FormatQty(qty); got != "1.2345" {
+		t.Errorf("pair handed out before the refresh formats %s, want 1.2345", got)
+	}
+	if got := after.FormatQty(qty); got != "1.23" {
+		t.Errorf("pair after the refresh formats %s, want 1.23", got)
+	}
+}
+
+// Run with -race: formatting orders must not race with the hourly refresh of the rules
+func TestRefreshRulesWhileFormatting(t *testing.T) {
+	rest := binance.NewClient("", "")
+	rest.BaseURL = rulesServer(t).URL
+	b := &BinanceClient{client: rest, pairs: make(map[string]*models.TradingPair)}
+	if err := b.AddTradingPair(models.NewTradingPair("ETHUSDT")); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			if err := b.RefreshRules(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	qty, price := decimal.RequireFromString("1.23456"), decimal.RequireFromString("2345.678")
+	for formatted := 0; ; formatted++ {
+		select {
+		case <-done:
+			wg.Wait()
+			if formatted == 0 {
+				t.Error("no order was formatted during the refreshes")
+			}
+			return
+		default:
+		}
+		pair := b.GetTradingPairs()["ETHUSDT"]
+		if q := pair.FormatQty(qty); q != "1.2345" && q != "1.23" {
+			t.Fatalf("formatted quantity %s with step %s", q, pair.Rules.StepSize)
+		}
+		if p := pair.FormatPrice(price); p != "2345.67" {
+			t.Fatalf("formatted price %s", p)
+		}
+		if !pair.MinNotional.Equal(decimal.NewFromInt(5)) || pair.PricePrecision != 2 {
+			t.Fatalf("minimum notional %s, price precision %d", pair.MinNotional, pair.PricePrecision)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package config
+
+import (
+	sqlite "binance_bot/db"
+	"binance_bot/execution"
+	"binance_bot/exits"
+	"binance_bot/grid"
+	"binance_bot/interfaces"
+	"binance_bot/liquidity"
+	"binance_bot/models"
+	"binance_bot/protection"
+	"binance_bot/reconcile"
+	"binance_bot/risk"
+	"binance_bot/sizing"
+	"binance_bot/strategies"
+	"binance_bot/utils"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Config holds everything needed to build and run the bot
+type Config struct {
+	Interval   string            `json:"interval"`  // Candle interval, e.g. 15m
+	DBPath     string            `json:"db_path"`   // SQLite database location
+	Pairs      []string          `json:"pairs"`     // Symbols to trade
+	FeeRate    float64           `json:"fee_rate"`  // Used by backtests and strategies with a fee_rate parameter
+	Reconcile  string            `json:"reconcile"` // Startup reconciliation mode: off, flag, adopt or close
+	Strategy   StrategyConfig    `json:"strategy"`
+	Paper      PaperConfig       `json:"paper"`
+	MarketData MarketDataConfig  `json:"market_data"`
+	Risk       risk.Config       `json:"risk"` // Portfolio limits, 0 disables a limit
+	Sizing     SizingConfig      `json:"sizing"`
+	Exits      *exits.Config     `json:"exits"`      // Exit rules of every position, the rules of the strategy when left out
+	Protection protection.Config `json:"protection"` // Protective orders on the exchange for every position
+	Execution  execution.Config  `json:"execution"`  // Repricing and timeouts of entry and exit limit orders
+	Liquidity  liquidity.Config  `json:"liquidity"`  // Spread and slippage bounds a pair must meet to act on a signal
+	Grid       grid.Config       `json:"grid"`       // Pairs traded by a grid of limit orders instead of the strategy
+}
+
+// StrategyConfig selects a registered strategy and holds its parameters.
+// Parameters that are left out use the defaults of the strategy.
+type StrategyConfig struct {
+	Type   string                 `json:"type"`
+	Params map[string]interface{} `json:"params"`
+}
+
+// PaperConfig holds the paper trading wallet settings
+type PaperConfig struct {
+	Enabled  bool    `json:"enabled"`
+	Balance  float64 `json:"balance"`  // Starting USDT balance
+	Slippage float64 `json:"slippage"` // Fraction, e.g. 0.0005 for 0.05%
+	DBPath   string  `json:"db_path"`  // Paper trades are kept apart from live trades
+}
+
+// MarketDataConfig controls the websocket market data stream
+type MarketDataConfig struct {
+	Streaming bool   `json:"streaming"` // Serve candles and prices from kline and bookTicker streams
+	WSURL     string `json:"ws_url"`    // Stream endpoint, override to use a local stand-in server
+	Window    int    `json:"window"`    // Candles kept in memory per symbol
+}
+
+// SizingConfig selects the position sizer of every BUY. The sizer of a pair takes precedence over
+// the sizer of the running strategy, which takes precedence over the default.
+type SizingConfig struct {
+	sizing.Config                          // Default sizer
+	Strategies    map[string]sizing.Config `json:"strategies"` // Sizer per strategy type
+	Pairs         map[string]sizing.Config `json:"pairs"`      // Sizer per symbol
+}
+
+// validIntervals lists the kline intervals supported by Binance
+var validIntervals = map[string]bool{
+	"1s": true, "1m": true, "3m": true, "5m": true, "15m": true, "30m": true,
+	"1h": true, "2h": true, "4h": true, "6h": true, "8h": true, "12h": true,
+	"1d": true, "3d": true, "1w": true, "1M": true,
+}
+
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9]{5,20}$`)
+
+// Default returns the built-in configuration
+func Default() *Config {
+	return &Config{
+		Interval: "15m",
+		DBPath:   sqlite.DefaultDBPath,
+		Pairs: []string{
+			"BTCUSDT", "ETHUSDT", "DOGEUSDT", "XRPUSDT", "SOLUSDT", "FTMUSDT", "ADAUSDT",
+			"HBARUSDT", "POWRUSDT", "OGUSDT", "BNBUSDT", "CTXCUSDT", "SCRTUSDT", "XLMUSDT",
+			"AVAXUSDT", "ALGOUSDT", "DEGOUSDT", "IOTAUSDT", "EOSUSDT", "DGBUSDT", "THETAUSDT",
+			"HOTUSDT", "FIDAUSDT", "WLDUSDT", "LUMIAUSDT", "TRXUSDT", "SHIBUSDT", "DOTUSDT",
+			"LTCUSDT", "ICPUSDT", "POLUSDT", "ETCUSDT", "TAOUSDT", "APTUSDT", "CRVUSDT",
+			"ACTUSDT", "CETUSUST", "FILUSDT", "SUIUSDT", "ORDIUSDT", "WIFUSDT", "FLOWUSDT",
+		},
+		FeeRate:   0.001,
+		Reconcile: string(reconcile.ModeFlag),
+		Strategy: StrategyConfig{
+			Type: strategies.RSIMACDStrategyType.String(),
+		},
+		Paper: PaperConfig{
+			Balance:  1000,
+			Slippage: 0.0005,
+			DBPath:   sqlite.PaperDBPath,
+		},
+		MarketData: MarketDataConfig{
+			Streaming: true,
+			WSURL:     "wss://stream.binance.com:9443",
+			Window:    500,
+		},
+		Sizing: SizingConfig{
+			Config: sizing.Config{Type: sizing.FixedFractionType},
+		},
+	}
+}
+
+// Load reads a JSON config file on top of the defaults and validates it.
+// Unknown fields are rejected so typos do not silently fall back to defaults.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %v", path, err)
+	}
+
+	cfg := Default()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks every setting and reports all problems at once
+func (c *Config) Validate() error {
+	var v utils.Validation
+
+	v.Check(validIntervals[c.Interval], "interval: unknown interval %q", c.Interval)
+	v.Check(c.DBPath != "", "db_path: must not be empty")
+
+	v.Check(len(c.Pairs) > 0, "pairs: at least one trading pair is required")
+	seen := make(map[string]bool)
+	for _, symbol := range c.Pairs {
+		v.Check(symbolPattern.MatchString(symbol), "pairs: invalid symbol %q", symbol)
+		v.Check(!seen[symbol], "pairs: duplicate symbol %q", symbol)
+		seen[symbol] = true
+	}
+
+	v.Check(c.FeeRate >= 0 && c.FeeRate < 0.1, "fee_rate: must be between 0 and 0.1, got %v", c.FeeRate)
+	if _, err := reconcile.ParseMode(c.Reconcile); err != nil {
+		v.Add("reconcile", err)
+	}
+	if _, err := strategies.ParseStrategyType(c.Strategy.Type); err != nil {
+		v.Add("strategy.type", err)
+	} else if _, err := c.BuildStrategy(); err != nil {
+		v.Add("strategy.params", err)
+	}
+
+	if c.Paper.Enabled {
+		v.Check(c.Paper.Balance > 0, "paper.balance: must be positive")
+		v.Check(c.Paper.Slippage >= 0 && c.Paper.Slippage < 0.1, "paper.slippage: must be between 0 and 0.1, got %v", c.Paper.Slippage)
+		v.Check(c.Paper.DBPath != "", "paper.db_path: must not be empty")
+	}
+
+	if c.MarketData.Streaming {
+		v.Check(strings.HasPrefix(c.MarketData.WSURL, "ws://") || strings.HasPrefix(c.MarketData.WSURL, "wss://"), "market_data.ws_url: must start with ws:// or wss://, got %q", c.MarketData.WSURL)
+		v.Check(c.MarketData.Window > 100 && c.MarketData.Window <= 1000, "market_data.window: must be between 101 and 1000, got %d", c.MarketData.Window)
+	}
+
+	v.Check(c.Risk.MaxOpenPositions >= 0, "risk.max_open_positions: must not be negative, got %d", c.Risk.MaxOpenPositions)
+	v.Check(c.Risk.MaxAssetExposure >= 0 && c.Risk.MaxAssetExposure <= 1, "risk.max_asset_exposure: must be between 0 and 1, got %v", c.Risk.MaxAssetExposure)
+	v.Check(c.Risk.MaxTotalExposure >= 0 && c.Risk.MaxTotalExposure <= 1, "risk.max_total_exposure: must be between 0 and 1, got %v", c.Risk.MaxTotalExposure)
+	v.Check(c.Risk.MaxTotalExposure == 0 || c.Risk.MaxAssetExposure <= c.Risk.MaxTotalExposure, "risk.max_asset_exposure: must not exceed risk.max_total_exposure")
+	v.Check(c.Risk.MaxOrderNotional >= 0, "risk.max_order_notional: must not be negative, got %v", c.Risk.MaxOrderNotional)
+	v.Check(c.Risk.MaxDailyLoss >= 0, "risk.max_daily_loss: must not be negative, got %v", c.Risk.MaxDailyLoss)
+	v.Check(c.Risk.MaxDrawdown >= 0 && c.Risk.MaxDrawdown < 1, "risk.max_drawdown: must be between 0 and 1, got %v", c.Risk.MaxDrawdown)
+
+	if c.Exits != nil {
+		v.Add("exits", c.Exits.Validate())
+	}
+
+	v.Add("protection", c.Protection.Validate())
+	v.Add("execution", c.Execution.Validate())
+	v.Add("liquidity", c.Liquidity.Validate())
+	v.Add("grid", c.Grid.Validate())
+
+	for name := range c.Sizing.Strategies {
+		_, ok := strategies.Lookup(name)
+		v.Check(ok, "sizing.strategies: unknown strategy type %q", name)
+	}
+	for symbol := range c.Sizing.Pairs {
+		v.Check(seen[symbol], "sizing.pairs: %q is not a configured pair", symbol)
+	}
+	for symbol := range c.Execution.Pairs {
+		v.Check(seen[symbol], "execution.pairs: %q is not a configured pair", symbol)
+	}
+	for symbol := range c.Liquidity.Pairs {
+		v.Check(seen[symbol], "liquidity.pairs: %q is not a configured pair", symbol)
+	}
+	for symbol := range c.Grid.Pairs {
+		v.Check(seen[symbol], "grid.pairs: %q is not a configured pair", symbol)
+	}
+	if _, err := c.BuildSizers(); err != nil {
+		v.Add("sizing", err)
+	}
+
+	return v.Err()
+}
+
+// BuildStrategy creates the configured strategy from the registry
+func (c *Config) BuildStrategy() (interfaces.Strategy, error) {
+	def, ok := strategies.Lookup(c.Strategy.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy type %q", c.Strategy.Type)
+	}
+
+	// Strategies that account for fees use the configured fee rate unless set explicitly
+	params := make(map[string]interface{}, len(c.Strategy.Params)+1)
+	for _, spec := range def.Params {
+		if spec.Name == "fee_rate" {
+			params["fee_rate"] = c.FeeRate
+		}
+	}
+	for name, value := range c.Strategy.Params {
+		params[name] = value
+	}
+
+	return def.Build(params)
+}
+
+// BuildSizers creates the position sizers of the configured strategy and pairs
+func (c *Config) BuildSizers() (*sizing.Table, error) {
+	cfg, ok := c.Sizing.Strategies[c.Strategy.Type]
+	if !ok {
+		cfg = c.Sizing.Config
+	}
+	def, err := sizing.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make(map[string]sizing.PositionSizer, len(c.Sizing.Pairs))
+	for symbol, cfg := range c.Sizing.Pairs {
+		if pairs[symbol], err = sizing.New(cfg); err != nil {
+			return nil, fmt.Errorf("%s: %v", symbol, err)
+		}
+	}
+	return sizing.NewTable(def, pairs), nil
+}
+
+// ExitRules returns the configured exit rules, false when the rules of the strategy apply
+func (c *Config) ExitRules() (exits.Config, bool) {
+	if c.Exits == nil {
+		return exits.Config{}, false
+	}
+	rules := *c.Exits
+	rules.FeeRate = c.FeeRate
+	return rules, true
+}
+
+// TradingPairs returns the configured pairs, values will be fetched from the exchange
+func (c *Config) TradingPairs() []models.TradingPair {
+	pairs := make([]models.TradingPair, 0, len(c.Pairs))
+	for _, symbol := range c.Pairs {
+		pairs = append(pairs, models.NewTradingPair(symbol))
+	}
+	return pairs
+}
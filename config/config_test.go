@@ -0,0 +1,171 @@
+package config
+
+import (
+	"binance_bot/exits"
+	"binance_bot/sizing"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *Config)
+		wantErr []string // Parts of the error, empty for a valid config
+	}{
+		{name: "defaults", change: func(c *Config) {}},
+		{
+			name: "strategy parameters",
+			change: func(c *Config) {
+				c.Strategy = StrategyConfig{Type: "stochastic", Params: map[string]interface{}{"period": 21.0, "cross": false}}
+			},
+		},
+		{
+			name: "strategy parameter out of range",
+			change: func(c *Config) {
+				c.Strategy = StrategyConfig{Type: "stochastic", Params: map[string]interface{}{"period": 0.0}}
+			},
+			wantErr: []string{"strategy.params: stochastic.period: must be between 1 and 500, got 0"},
+		},
+		{
+			name:    "unknown interval",
+			change:  func(c *Config) { c.Interval = "7m" },
+			wantErr: []string{`interval: unknown interval "7m"`},
+		},
+		{
+			name:    "no pairs",
+			change:  func(c *Config) { c.Pairs = nil },
+			wantErr: []string{"pairs: at least one trading pair is required"},
+		},
+		{
+			name:    "invalid and duplicate symbols",
+			change:  func(c *Config) { c.Pairs = []string{"ETHUSDT", "eth-usdt", "ETHUSDT"} },
+			wantErr: []string{`pairs: invalid symbol "eth-usdt"`, `pairs: duplicate symbol "ETHUSDT"`},
+		},
+		{
+			name:    "fee rate",
+			change:  func(c *Config) { c.FeeRate = 0.2 },
+			wantErr: []string{"fee_rate: must be between 0 and 0.1, got 0.2"},
+		},
+		{
+			name:    "reconcile mode",
+			change:  func(c *Config) { c.Reconcile = "ignore" },
+			wantErr: []string{`reconcile: unknown reconcile mode "ignore"`},
+		},
+		{
+			name:    "unknown strategy",
+			change:  func(c *Config) { c.Strategy.Type = "momentum" },
+			wantErr: []string{"strategy.type"},
+		},
+		{
+			name:    "unknown strategy parameter",
+			change:  func(c *Config) { c.Strategy.Params = map[string]interface{}{"rsi_periods": 14.0} },
+			wantErr: []string{`strategy.params: rsi-macd: unknown parameter "rsi_periods"`},
+		},
+		{
+			name: "paper wallet",
+			change: func(c *Config) {
+				c.Paper = PaperConfig{Enabled: true, Slippage: 0.5}
+			},
+			wantErr: []string{"paper.balance", "paper.slippage", "paper.db_path"},
+		},
+		{
+			name:   "paper settings are ignored when disabled",
+			change: func(c *Config) { c.Paper = PaperConfig{} },
+		},
+		{
+			name: "market data stream",
+			change: func(c *Config) {
+				c.MarketData = MarketDataConfig{Streaming: true, WSURL: "https://stream.binance.com", Window: 50}
+			},
+			wantErr: []string{"market_data.ws_url", "market_data.window: must be between 101 and 1000, got 50"},
+		},
+		{
+			name: "risk limits",
+			change: func(c *Config) {
+				c.Risk.MaxOpenPositions = -1
+				c.Risk.MaxAssetExposure = 0.6
+				c.Risk.MaxTotalExposure = 0.5
+				c.Risk.MaxDrawdown = 1
+			},
+			wantErr: []string{"risk.max_open_positions", "risk.max_asset_exposure: must not exceed risk.max_total_exposure", "risk.max_drawdown"},
+		},
+		{
+			name:    "exit rules",
+			change:  func(c *Config) { c.Exits = &exits.Config{StopLossPercent: 120} },
+			wantErr: []string{"exits: stop_loss_percent"},
+		},
+		{
+			name: "settings of pairs that are not traded",
+			change: func(c *Config) {
+				c.Pairs = []string{"ETHUSDT"}
+				c.Sizing.Pairs = map[string]sizing.Config{"BTCUSDT": {Type: sizing.FixedFractionType}}
+			},
+			wantErr: []string{`sizing.pairs: "BTCUSDT" is not a configured pair`},
+		},
+		{
+			name: "sizer of an unknown strategy",
+			change: func(c *Config) {
+				c.Sizing.Strategies = map[string]sizing.Config{"momentum": {Type: sizing.FixedFractionType}}
+			},
+			wantErr: []string{`sizing.strategies: unknown strategy type "momentum"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.change(cfg)
+
+			err := cfg.Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("no error, want %v", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	if _, err := Load("../config.sample.json"); err != nil {
+		t.Fatalf("sample config: %v", err)
+	}
+
+	path := t.TempDir() + "/config.json"
+	write := func(data string) {
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Settings that are left out keep their defaults
+	write(`{"interval": "1h", "pairs": ["ETHUSDT"]}`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Interval != "1h" || len(cfg.Pairs) != 1 || cfg.FeeRate != 0.001 || cfg.MarketData.Window != 500 {
+		t.Errorf("got interval %s, pairs %v, fee rate %v, window %d", cfg.Interval, cfg.Pairs, cfg.FeeRate, cfg.MarketData.Window)
+	}
+
+	write(`{"intervall": "1h"}`)
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), `unknown field "intervall"`) {
+		t.Errorf("got error %v for a misspelled field", err)
+	}
+
+	write(`{"interval": "7m", "fee_rate": -1}`)
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "interval") || !strings.Contains(err.Error(), "fee_rate") {
+		t.Errorf("got error %v, want both problems", err)
+	}
+}
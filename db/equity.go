@@ -0,0 +1,55 @@
+package db
+
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// LogEquitySnapshot stores the value of the account in the quote asset
+func (s *SQLite) LogEquitySnapshot(equity decimal.Decimal) error {
+	if _, err := s.DB.Exec(`INSERT INTO equity_snapshots (equity) VALUES (?)`, equity.String()); err != nil {
+		return fmt.Errorf("error inserting equity snapshot: %v", err)
+	}
+	return nil
+}
+
+// PeakEquity returns the highest stored equity, zero without snapshots
+func (s *SQLite) PeakEquity() (decimal.Decimal, error) {
+	rows, err := s.DB.Query(`SELECT equity FROM equity_snapshots`)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("error fetching equity snapshots: %v", err)
+	}
+	defer rows.Close()
+
+	// Equity is stored as text, so compare the values in Go instead of with MAX
+	peak := decimal.Zero
+	for rows.Next() {
+		var equity decimal.Decimal
+		if err := rows.Scan(&equity); err != nil {
+			return decimal.Zero, fmt.Errorf("error scanning equity snapshot: %v", err)
+		}
+		peak = decimal.Max(peak, equity)
+	}
+	return peak, rows.Err()
+}
+
+// RealizedPnLSince sums the profit and loss of the trades completed since a point in time
+func (s *SQLite) RealizedPnLSince(since time.Time) (decimal.Decimal, error) {
+	rows, err := s.DB.Query(`SELECT profit_loss FROM completed_trades WHERE timestamp >= ?`,
+		since.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("error fetching completed trades: %v", err)
+	}
+	defer rows.Close()
+
+	total := decimal.Zero
+	for rows.Next() {
+		var pnl decimal.Decimal
+		if err := rows.Scan(&pnl); err != nil {
+			return decimal.Zero, fmt.Errorf("error scanning completed trade: %v", err)
+		}
+		total = total.Add(pnl)
+	}
+	return total, rows.Err()
+}
@@ -0,0 +1,21 @@
+package db
+
+import (
+	"binance_bot/models"
+	"fmt"
+)
+
+// LogExecutionReport stores the outcome of a finished parent order and sets its ID
+func (s *SQLite) LogExecutionReport(r *models.ExecutionReport) error {
+	query := `INSERT INTO execution_reports (symbol, side, algorithm, quantity, filled, arrival_price, average_price, slippage, children, status, started_at, finished_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := s.DB.Exec(query, r.Symbol, r.Side, r.Algorithm, r.Quantity, r.Filled, r.ArrivalPrice, r.AveragePrice,
+		r.Slippage, r.Children, r.Status, r.StartedAt, r.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("error inserting %s execution report for %s: %v", r.Algorithm, r.Symbol, err)
+	}
+	if r.ID, err = res.LastInsertId(); err != nil {
+		return fmt.Errorf("error reading ID of %s execution report for %s: %v", r.Algorithm, r.Symbol, err)
+	}
+	return nil
+}
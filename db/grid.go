@@ -0,0 +1,80 @@
+package db
+
+import (
+	"binance_bot/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const gridColumns = `id, symbol, lower, upper, levels, spacing, quote_per_level, status, updated_at`
+
+const gridLevelColumns = `grid_id, level, buy_price, sell_price, side, quantity, order_id, updated_at`
+
+// LogGrid stores a newly started grid and sets its ID
+func (s *SQLite) LogGrid(g *models.Grid) error {
+	query := `INSERT INTO grids (symbol, lower, upper, levels, spacing, quote_per_level, status, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := s.DB.Exec(query, g.Symbol, g.Lower, g.Upper, g.Levels, g.Spacing, g.QuotePerLevel, g.Status, time.Now())
+	if err != nil {
+		return fmt.Errorf("error inserting grid for %s: %v", g.Symbol, err)
+	}
+	if g.ID, err = res.LastInsertId(); err != nil {
+		return fmt.Errorf("error reading ID of grid for %s: %v", g.Symbol, err)
+	}
+	return nil
+}
+
+// UpdateGridStatus stores the status of a grid
+func (s *SQLite) UpdateGridStatus(id int64, status string) error {
+	if _, err := s.DB.Exec(`UPDATE grids SET status = ?, updated_at = ? WHERE id = ?`, status, time.Now(), id); err != nil {
+		return fmt.Errorf("error updating grid %d: %v", id, err)
+	}
+	return nil
+}
+
+// GetLatestGrid fetches the last grid started for a symbol, nil when there is none
+func (s *SQLite) GetLatestGrid(symbol string) (*models.Grid, error) {
+	var g models.Grid
+	query := `SELECT ` + gridColumns + ` FROM grids WHERE symbol = ? ORDER BY id DESC LIMIT 1`
+	err := s.DB.QueryRow(query, symbol).Scan(&g.ID, &g.Symbol, &g.Lower, &g.Upper, &g.Levels, &g.Spacing, &g.QuotePerLevel, &g.Status, &g.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching grid for %s: %v", symbol, err)
+	}
+	return &g, nil
+}
+
+// SaveGridLevel stores the state of a grid level, replacing the previous one
+func (s *SQLite) SaveGridLevel(l *models.GridLevel) error {
+	query := `INSERT INTO grid_levels (` + gridLevelColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (grid_id, level) DO UPDATE SET side = excluded.side, quantity = excluded.quantity,
+		order_id = excluded.order_id, updated_at = excluded.updated_at`
+	l.UpdatedAt = time.Now()
+	if _, err := s.DB.Exec(query, l.GridID, l.Level, l.BuyPrice, l.SellPrice, l.Side, l.Quantity, l.OrderID, l.UpdatedAt); err != nil {
+		return fmt.Errorf("error saving level %d of grid %d: %v", l.Level, l.GridID, err)
+	}
+	return nil
+}
+
+// GetGridLevels fetches the levels of a grid ordered from the lowest price
+func (s *SQLite) GetGridLevels(gridID int64) ([]*models.GridLevel, error) {
+	rows, err := s.DB.Query(`SELECT `+gridLevelColumns+` FROM grid_levels WHERE grid_id = ? ORDER BY level`, gridID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching levels of grid %d: %v", gridID, err)
+	}
+	defer rows.Close()
+
+	var levels []*models.GridLevel
+	for rows.Next() {
+		var l models.GridLevel
+		if err := rows.Scan(&l.GridID, &l.Level, &l.BuyPrice, &l.SellPrice, &l.Side, &l.Quantity, &l.OrderID, &l.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning level of grid %d: %v", gridID, err)
+		}
+		levels = append(levels, &l)
+	}
+	return levels, rows.Err()
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"binance_bot/models"
+	"database/sql"
+	"fmt"
+	"github.com/shopspring/decimal"
+)
+
+// The position ledger keeps the lots bought by the bot in active_trades. Sells are sized
+// from the ledger and close lots first in, first out, so holdings the bot did not buy are never sold.
+// A fill changes the lots and stores the latest snapshot of its order in one transaction, so the
+// next poll of the order never applies the same fill again.
+
+// OpenLot records a lot opened by a BUY fill of an order and stores the order with the fill.
+// fee is the entry fee in the quote asset.
+func (s *SQLite) OpenLot(order *models.Order, price, quantity, fee decimal.Decimal) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error opening lot for %s: %v", order.Symbol, err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO active_trades (symbol, buy_price, quantity, order_id, entry_fee) VALUES (?, ?, ?, ?, ?)`
+	if _, err := tx.Exec(query, order.Symbol, price, quantity, order.OrderID, fee); err != nil {
+		return fmt.Errorf("error opening lot for %s: %v", order.Symbol, err)
+	}
+	if err := updateOrder(tx, order); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error opening lot for %s: %v", order.Symbol, err)
+	}
+	return nil
+}
+
+// OpenQuantity returns the quantity held in open lots of a symbol
+func (s *SQLite) OpenQuantity(symbol string) (decimal.Decimal, error) {
+	lots, err := queryLots(s.DB, symbol)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	qty := decimal.Zero
+	for _, lot := range lots {
+		qty = qty.Add(lot.Quantity)
+	}
+	return qty, nil
+}
+
+// SellableQuantity returns the quantity in open lots not already reserved by open SELL orders.
+// Both legs of an OCO sell the same quantity, so its take-profit leg is not counted while the stop leg is open.
+func (s *SQLite) SellableQuantity(symbol string) (decimal.Decimal, error) {
+	open, err := s.OpenQuantity(symbol)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	// Values are decimal strings, so they are summed here rather than with SQL SUM
+	query := `SELECT quantity, filled_qty FROM orders WHERE symbol = ? AND side = 'SELL' AND status IN ('NEW', 'PARTIALLY_FILLED')
+		AND order_id NOT IN (
+			SELECT p.limit_order_id FROM protective_orders p
+			JOIN orders stop ON stop.symbol = p.symbol AND stop.order_id = p.stop_order_id
+			WHERE p.symbol = ? AND p.limit_order_id != 0 AND stop.status IN ('NEW', 'PARTIALLY_FILLED'))`
+	rows, err := s.DB.Query(query, symbol, symbol)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("error summing open SELL orders for %s: %v", symbol, err)
+	}
+	defer rows.Close()
+
+	pending := decimal.Zero
+	for rows.Next() {
+		var qty, filled decimal.Decimal
+		if err := rows.Scan(&qty, &filled); err != nil {
+			return decimal.Zero, fmt.Errorf("error summing open SELL orders for %s: %v", symbol, err)
+		}
+		pending = pending.Add(qty.Sub(filled))
+	}
+	if err := rows.Err(); err != nil {
+		return decimal.Zero, fmt.Errorf("error summing open SELL orders for %s: %v", symbol, err)
+	}
+	return decimal.Max(open.Sub(pending), decimal.Zero), nil
+}
+
+// CloseLots closes lots first in, first out for a SELL fill of an order, records the completed
+// trades and stores the order with the fill. fee is the exit fee in the quote asset. It returns
+// the quantity that was not covered by open lots.
+func (s *SQLite) CloseLots(order *models.Order, sellPrice, quantity, fee decimal.Decimal) (decimal.Decimal, error) {
+	symbol := order.Symbol
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("error closing lots for %s: %v", symbol, err)
+	}
+	defer tx.Rollback()
+
+	lots, err := queryLots(tx, symbol)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	remaining := quantity
+	for _, lot := range lots {
+		if !remaining.IsPositive() {
+			break
+		}
+		closed := decimal.Min(remaining, lot.Quantity)
+		entryFee := lot.EntryFee.Mul(closed).Div(lot.Quantity)
+		exitFee := fee.Mul(closed).Div(quantity)
+		profitLoss := sellPrice.Sub(lot.BuyPrice).Mul(closed).Sub(entryFee).Sub(exitFee)
+
+		_, err := tx.Exec(`INSERT INTO completed_trades (symbol, buy_price, sell_price, quantity, profit_loss) VALUES (?, ?, ?, ?, ?)`,
+			symbol, lot.BuyPrice, sellPrice, closed, profitLoss)
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("error inserting completed trade for %s: %v", symbol, err)
+		}
+
+		if left := lot.Quantity.Sub(closed); !left.IsPositive() {
+			_, err = tx.Exec(`DELETE FROM active_trades WHERE id = ?`, lot.ID)
+		} else {
+			_, err = tx.Exec(`UPDATE active_trades SET quantity = ?, entry_fee = ? WHERE id = ?`, left, lot.EntryFee.Sub(entryFee), lot.ID)
+		}
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("error updating lot %d for %s: %v", lot.ID, symbol, err)
+		}
+		remaining = remaining.Sub(closed)
+	}
+
+	if err := updateOrder(tx, order); err != nil {
+		return decimal.Zero, err
+	}
+	if err := tx.Commit(); err != nil {
+		return decimal.Zero, fmt.Errorf("error closing lots for %s: %v", symbol, err)
+	}
+	return decimal.Max(remaining, decimal.Zero), nil
+}
+
+// queryLots fetches the open lots of a symbol, oldest first
+func queryLots(q interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}, symbol string) ([]*models.ActiveTrade, error) {
+	rows, err := q.Query(`SELECT id, symbol, buy_price, quantity, order_id, entry_fee FROM active_trades WHERE symbol = ? ORDER BY id`, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching lots for %s: %v", symbol, err)
+	}
+	defer rows.Close()
+
+	var lots []*models.ActiveTrade
+	for rows.Next() {
+		var lot models.ActiveTrade
+		if err := rows.Scan(&lot.ID, &lot.Symbol, &lot.BuyPrice, &lot.Quantity, &lot.OrderID, &lot.EntryFee); err != nil {
+			return nil, fmt.Errorf("error scanning lot for %s: %v", symbol, err)
+		}
+		lots = append(lots, &lot)
+	}
+	return lots, rows.Err()
+}
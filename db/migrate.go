@@ -0,0 +1,189 @@
+package db
+
+import (
+	"binance_bot/logger"
+	"database/sql"
+	"fmt"
+)
+
+// schemas holds the current column definitions of the tables that store money and quantity values
+var schemas = map[string]string{
+	"active_trades": `(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    buy_price TEXT NOT NULL,
+    quantity TEXT NOT NULL,
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+    order_id INTEGER NOT NULL DEFAULT 0,
+    entry_fee TEXT NOT NULL DEFAULT '0'
+)`,
+	"completed_trades": `(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    buy_price TEXT NOT NULL,
+    sell_price TEXT NOT NULL,
+    quantity TEXT NOT NULL,
+    profit_loss TEXT NOT NULL,
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+)`,
+	"orders": `(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id INTEGER NOT NULL,
+    symbol TEXT NOT NULL,
+    side TEXT NOT NULL,
+    type TEXT NOT NULL,
+    quantity TEXT NOT NULL,
+    price TEXT NOT NULL,
+    status TEXT NOT NULL,
+    filled_qty TEXT NOT NULL DEFAULT '0',
+    avg_price TEXT NOT NULL DEFAULT '0',
+    commission TEXT NOT NULL DEFAULT '0',
+    commission_asset TEXT NOT NULL DEFAULT '',
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (symbol, order_id)
+)`,
+	"equity_snapshots": `(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    equity TEXT NOT NULL,
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+)`,
+	"protective_orders": `(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    type TEXT NOT NULL,
+    stop_order_id INTEGER NOT NULL,
+    limit_order_id INTEGER NOT NULL DEFAULT 0,
+    position TEXT NOT NULL,
+    quantity TEXT NOT NULL,
+    stop_price TEXT NOT NULL,
+    limit_price TEXT NOT NULL,
+    take_profit TEXT NOT NULL DEFAULT '0',
+    status TEXT NOT NULL,
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+)`,
+	"execution_reports": `(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    side TEXT NOT NULL,
+    algorithm TEXT NOT NULL,
+    quantity TEXT NOT NULL,
+    filled TEXT NOT NULL,
+    arrival_price TEXT NOT NULL,
+    average_price TEXT NOT NULL,
+    slippage TEXT NOT NULL,
+    children INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME NOT NULL
+)`,
+	"grids": `(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    lower TEXT NOT NULL,
+    upper TEXT NOT NULL,
+    levels INTEGER NOT NULL,
+    spacing TEXT NOT NULL,
+    quote_per_level TEXT NOT NULL,
+    status TEXT NOT NULL,
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+)`,
+	"grid_levels": `(
+    grid_id INTEGER NOT NULL,
+    level INTEGER NOT NULL,
+    buy_price TEXT NOT NULL,
+    sell_price TEXT NOT NULL,
+    side TEXT NOT NULL,
+    quantity TEXT NOT NULL,
+    order_id INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (grid_id, level)
+)`,
+	"paper_balances": `(
+    asset TEXT PRIMARY KEY,
+    free TEXT NOT NULL,
+    locked TEXT NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+)`,
+	"paper_cost_basis": `(
+    symbol TEXT PRIMARY KEY,
+    cost_basis TEXT NOT NULL
+)`,
+	"paper_orders": `(
+    order_id INTEGER PRIMARY KEY,
+    symbol TEXT NOT NULL,
+    side TEXT NOT NULL,
+    type TEXT NOT NULL,
+    quantity TEXT NOT NULL,
+    price TEXT NOT NULL,
+    stop_price TEXT NOT NULL DEFAULT '0',
+    triggered INTEGER NOT NULL DEFAULT 0,
+    sibling INTEGER NOT NULL DEFAULT 0,
+    shared INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL,
+    filled_qty TEXT NOT NULL DEFAULT '0',
+    avg_price TEXT NOT NULL DEFAULT '0',
+    commission TEXT NOT NULL DEFAULT '0',
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+)`,
+}
+
+// migrations upgrade older databases, migration i brings the schema to user_version i+1
+var migrations = []func(tx *sql.Tx) error{
+	decimalColumns,
+}
+
+// migrate applies the migrations the database has not seen yet, each in its own transaction
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("error reading schema version: %v", err)
+	}
+
+	for ; version < len(migrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("error migrating to schema version %d: %v", version+1, err)
+		}
+		if err := migrations[version](tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error migrating to schema version %d: %v", version+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error migrating to schema version %d: %v", version+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("error migrating to schema version %d: %v", version+1, err)
+		}
+		logger.Infof("Migrated database to schema version %d", version+1)
+	}
+	return nil
+}
+
+// decimalColumns rebuilds the tables that stored prices and quantities as REAL with TEXT columns,
+// so values are kept as exact decimal strings. Stored REAL values are converted with 15 significant digits.
+func decimalColumns(tx *sql.Tx) error {
+	columns := map[string]string{
+		"active_trades":    `id, symbol, buy_price, quantity, timestamp, order_id, entry_fee`,
+		"completed_trades": `id, symbol, buy_price, sell_price, quantity, profit_loss, timestamp`,
+		"orders":           `id, order_id, symbol, side, type, quantity, price, status, filled_qty, avg_price, commission, commission_asset, timestamp, updated_at`,
+	}
+
+	for _, table := range []string{"active_trades", "completed_trades", "orders"} {
+		statements := []string{
+			fmt.Sprintf(`CREATE TABLE %s_new %s`, table, schemas[table]),
+			fmt.Sprintf(`INSERT INTO %s_new (%s) SELECT %s FROM %s`, table, columns[table], columns[table], table),
+			fmt.Sprintf(`DROP TABLE %s`, table),
+			fmt.Sprintf(`ALTER TABLE %s_new RENAME TO %s`, table, table),
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return fmt.Errorf("error rebuilding %s: %v", table, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"binance_bot/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const orderColumns = `order_id, symbol, side, type, quantity, price, status, filled_qty, avg_price, commission, commission_asset, updated_at`
+
+// LogOrder stores a newly placed order
+func (s *SQLite) LogOrder(order *models.Order) error {
+	query := `INSERT INTO orders (` + orderColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.DB.Exec(query, order.OrderID, order.Symbol, order.Side, order.Type, order.Quantity, order.Price,
+		order.Status, order.FilledQty, order.AvgPrice, order.Commission, order.CommissionAsset, time.Now())
+	if err != nil {
+		return fmt.Errorf("error inserting order %d for %s: %v", order.OrderID, order.Symbol, err)
+	}
+	return nil
+}
+
+// UpdateOrder stores the latest status and fills of an order
+func (s *SQLite) UpdateOrder(order *models.Order) error {
+	return updateOrder(s.DB, order)
+}
+
+func updateOrder(db execer, order *models.Order) error {
+	query := `UPDATE orders SET status = ?, filled_qty = ?, avg_price = ?, commission = ?, commission_asset = ?, updated_at = ?
+		WHERE symbol = ? AND order_id = ?`
+	_, err := db.Exec(query, order.Status, order.FilledQty, order.AvgPrice, order.Commission, order.CommissionAsset,
+		time.Now(), order.Symbol, order.OrderID)
+	if err != nil {
+		return fmt.Errorf("error updating order %d for %s: %v", order.OrderID, order.Symbol, err)
+	}
+	return nil
+}
+
+// GetOrder fetches a stored order
+func (s *SQLite) GetOrder(symbol string, orderID int64) (*models.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE symbol = ? AND order_id = ?`
+	order, err := scanOrder(s.DB.QueryRow(query, symbol, orderID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("no order %d found for symbol: %s", orderID, symbol)
+		}
+		return nil, fmt.Errorf("error fetching order %d for symbol %s: %v", orderID, symbol, err)
+	}
+	return order, nil
+}
+
+// GetOpenOrders fetches all stored orders that can still receive fills
+func (s *SQLite) GetOpenOrders() ([]*models.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE status IN (?, ?) ORDER BY id`
+	rows, err := s.DB.Query(query, models.OrderStatusNew, models.OrderStatusPartiallyFilled)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*models.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+// execer is implemented by sql.DB and sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// scanner is implemented by sql.Row and sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOrder(row scanner) (*models.Order, error) {
+	var order models.Order
+	err := row.Scan(&order.OrderID, &order.Symbol, &order.Side, &order.Type, &order.Quantity, &order.Price,
+		&order.Status, &order.FilledQty, &order.AvgPrice, &order.Commission, &order.CommissionAsset, &order.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
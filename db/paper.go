@@ -0,0 +1,98 @@
+package db
+
+import (
+	"binance_bot/models"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+const paperOrderColumns = `order_id, symbol, side, type, quantity, price, stop_price, triggered, sibling, shared, status, filled_qty, avg_price, commission, updated_at`
+
+// SavePaperWallet stores the balances and cost basis of the paper wallet and the given orders in one transaction
+func (s *SQLite) SavePaperWallet(w *models.PaperWallet) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error saving paper wallet: %v", err)
+	}
+	defer tx.Rollback()
+
+	for asset, b := range w.Balances {
+		query := `INSERT INTO paper_balances (asset, free, locked, updated_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT (asset) DO UPDATE SET free = excluded.free, locked = excluded.locked, updated_at = excluded.updated_at`
+		if _, err := tx.Exec(query, asset, b.Free, b.Locked, time.Now()); err != nil {
+			return fmt.Errorf("error saving paper %s balance: %v", asset, err)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM paper_cost_basis`); err != nil {
+		return fmt.Errorf("error saving paper cost basis: %v", err)
+	}
+	for symbol, cost := range w.CostBasis {
+		if _, err := tx.Exec(`INSERT INTO paper_cost_basis (symbol, cost_basis) VALUES (?, ?)`, symbol, cost); err != nil {
+			return fmt.Errorf("error saving paper cost basis of %s: %v", symbol, err)
+		}
+	}
+	for _, o := range w.Orders {
+		query := `INSERT INTO paper_orders (` + paperOrderColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (order_id) DO UPDATE SET triggered = excluded.triggered, shared = excluded.shared, status = excluded.status,
+			filled_qty = excluded.filled_qty, avg_price = excluded.avg_price, commission = excluded.commission, updated_at = excluded.updated_at`
+		if _, err := tx.Exec(query, o.OrderID, o.Symbol, o.Side, o.Type, o.Quantity, o.Price, o.StopPrice, o.Triggered, o.Sibling, o.Shared,
+			o.Status, o.FilledQty, o.AvgPrice, o.Commission, o.UpdatedAt); err != nil {
+			return fmt.Errorf("error saving paper order %d for %s: %v", o.OrderID, o.Symbol, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error saving paper wallet: %v", err)
+	}
+	return nil
+}
+
+// GetPaperWallet fetches the stored paper wallet, with no balances when none was stored
+func (s *SQLite) GetPaperWallet() (*models.PaperWallet, error) {
+	w := &models.PaperWallet{Balances: make(map[string]models.Balance), CostBasis: make(map[string]decimal.Decimal)}
+
+	rows, err := s.DB.Query(`SELECT asset, free, locked FROM paper_balances`)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching paper balances: %v", err)
+	}
+	for rows.Next() {
+		var b models.Balance
+		if err := rows.Scan(&b.Asset, &b.Free, &b.Locked); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning paper balance: %v", err)
+		}
+		w.Balances[b.Asset] = b
+	}
+	rows.Close()
+
+	rows, err = s.DB.Query(`SELECT symbol, cost_basis FROM paper_cost_basis`)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching paper cost basis: %v", err)
+	}
+	for rows.Next() {
+		var symbol string
+		var cost decimal.Decimal
+		if err := rows.Scan(&symbol, &cost); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning paper cost basis: %v", err)
+		}
+		w.CostBasis[symbol] = cost
+	}
+	rows.Close()
+
+	rows, err = s.DB.Query(`SELECT ` + paperOrderColumns + ` FROM paper_orders ORDER BY order_id`)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching paper orders: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var o models.PaperOrder
+		if err := rows.Scan(&o.OrderID, &o.Symbol, &o.Side, &o.Type, &o.Quantity, &o.Price, &o.StopPrice, &o.Triggered, &o.Sibling, &o.Shared,
+			&o.Status, &o.FilledQty, &o.AvgPrice, &o.Commission, &o.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning paper order: %v", err)
+		}
+		w.Orders = append(w.Orders, &o)
+	}
+	return w, rows.Err()
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"binance_bot/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const protectiveColumns = `id, symbol, type, stop_order_id, limit_order_id, position, quantity, stop_price, limit_price, take_profit, status, updated_at`
+
+// LogProtectiveOrder stores a newly placed protective order and sets its ID
+func (s *SQLite) LogProtectiveOrder(p *models.ProtectiveOrder) error {
+	query := `INSERT INTO protective_orders (symbol, type, stop_order_id, limit_order_id, position, quantity, stop_price, limit_price, take_profit, status, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := s.DB.Exec(query, p.Symbol, p.Type, p.StopOrderID, p.LimitOrderID, p.Position, p.Quantity,
+		p.StopPrice, p.LimitPrice, p.TakeProfit, p.Status, time.Now())
+	if err != nil {
+		return fmt.Errorf("error inserting protective order %d for %s: %v", p.StopOrderID, p.Symbol, err)
+	}
+	if p.ID, err = res.LastInsertId(); err != nil {
+		return fmt.Errorf("error reading ID of protective order %d for %s: %v", p.StopOrderID, p.Symbol, err)
+	}
+	return nil
+}
+
+// UpdateProtectiveOrderStatus stores the status of a protective order
+func (s *SQLite) UpdateProtectiveOrderStatus(id int64, status string) error {
+	if _, err := s.DB.Exec(`UPDATE protective_orders SET status = ?, updated_at = ? WHERE id = ?`, status, time.Now(), id); err != nil {
+		return fmt.Errorf("error updating protective order %d: %v", id, err)
+	}
+	return nil
+}
+
+// GetActiveProtectiveOrder fetches the protective order resting for a symbol, nil when there is none
+func (s *SQLite) GetActiveProtectiveOrder(symbol string) (*models.ProtectiveOrder, error) {
+	query := `SELECT ` + protectiveColumns + ` FROM protective_orders WHERE symbol = ? AND status = ? ORDER BY id DESC LIMIT 1`
+	p, err := scanProtectiveOrder(s.DB.QueryRow(query, symbol, models.ProtectionActive))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching protective order for %s: %v", symbol, err)
+	}
+	return p, nil
+}
+
+// GetActiveProtectiveOrders fetches the protective orders resting for all symbols
+func (s *SQLite) GetActiveProtectiveOrders() ([]*models.ProtectiveOrder, error) {
+	query := `SELECT ` + protectiveColumns + ` FROM protective_orders WHERE status = ? ORDER BY id`
+	rows, err := s.DB.Query(query, models.ProtectionActive)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching protective orders: %v", err)
+	}
+	defer rows.Close()
+
+	var orders []*models.ProtectiveOrder
+	for rows.Next() {
+		p, err := scanProtectiveOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning protective order: %v", err)
+		}
+		orders = append(orders, p)
+	}
+	return orders, rows.Err()
+}
+
+func scanProtectiveOrder(row scanner) (*models.ProtectiveOrder, error) {
+	var p models.ProtectiveOrder
+	err := row.Scan(&p.ID, &p.Symbol, &p.Type, &p.StopOrderID, &p.LimitOrderID, &p.Position, &p.Quantity,
+		&p.StopPrice, &p.LimitPrice, &p.TakeProfit, &p.Status, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
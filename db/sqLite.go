@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/shopspring/decimal"
 	"log"
 )
 
@@ -21,11 +22,20 @@ type SQLite struct {
 
 var SQLiteDB SQLite
 
+// DefaultDBPath is the location of the database inside the Docker mount
+const DefaultDBPath = "/app/data/trades.db"
+
+// PaperDBPath keeps paper trades apart from live trades
+const PaperDBPath = "/app/data/paper_trades.db"
+
 // InitDB initializes the SQLite database
 func InitDB() error {
 	// Ensure the database file is created in the mounted volume
-	dbPath := "/app/data/trades.db" // Adjusted to match the Docker mount
+	return InitDBAt(DefaultDBPath)
+}
 
+// InitDBAt initializes the SQLite database at the given path or DSN
+func InitDBAt(dbPath string) error {
 	logger.Infof("Initializing database at %s", dbPath)
 
 	db, err := sql.Open("sqlite3", dbPath)
@@ -49,32 +59,37 @@ func InitDB() error {
 		return err
 	}
 
-	query = `
-    CREATE TABLE IF NOT EXISTS active_trades (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    symbol TEXT NOT NULL,
-    buy_price REAL NOT NULL,
-    quantity REAL NOT NULL,
-    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
-	_, err = db.Exec(query)
-	if err != nil {
-		logger.Infof("Error creating active_trades table: %v", err)
+	// Prices, quantities and amounts are stored as exact decimal strings
+	for _, table := range []string{"active_trades", "completed_trades", "orders", "equity_snapshots", "protective_orders", "execution_reports", "grids", "grid_levels", "paper_balances", "paper_cost_basis", "paper_orders"} {
+		if _, err = db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s %s`, table, schemas[table])); err != nil {
+			logger.Infof("Error creating %s table: %v", table, err)
+			return err
+		}
+	}
+
+	// Ledger columns added after the first release
+	if err = addColumn(db, "active_trades", "order_id", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err = addColumn(db, "active_trades", "entry_fee", "TEXT NOT NULL DEFAULT '0'"); err != nil {
 		return err
 	}
 
-	query = `CREATE TABLE IF NOT EXISTS completed_trades (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    symbol TEXT NOT NULL,
-    buy_price REAL NOT NULL,
-    sell_price REAL NOT NULL,
-    quantity REAL NOT NULL,
-    profit_loss REAL NOT NULL,
-    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+	query = `CREATE TABLE IF NOT EXISTS strategy_state (
+    strategy TEXT NOT NULL,
+    pair TEXT NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (strategy, pair, key)
 );`
 	_, err = db.Exec(query)
 	if err != nil {
-		logger.Infof("Error creating completed_trades table: %v", err)
+		logger.Infof("Error creating strategy_state table: %v", err)
+		return err
+	}
+
+	if err = migrate(db); err != nil {
 		return err
 	}
 
@@ -83,6 +98,30 @@ func InitDB() error {
 	return nil
 }
 
+// addColumn adds a column to an existing table unless it is already there
+func addColumn(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return fmt.Errorf("error reading columns of %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("error reading columns of %s: %v", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+
+	if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition)); err != nil {
+		return fmt.Errorf("error adding column %s to %s: %v", column, table, err)
+	}
+	return nil
+}
+
 // Deprecated: LogTrade logs a trade to the SQLite database
 func (s *SQLite) LogTrade(symbol, side string, amount, price float64) error {
 	query := `INSERT INTO trades (symbol, side, amount, price) VALUES (?, ?, ?, ?)`
@@ -91,7 +130,7 @@ func (s *SQLite) LogTrade(symbol, side string, amount, price float64) error {
 }
 
 // LogActiveTrade logs an active trade to the SQLite database
-func (s *SQLite) LogActiveTrade(symbol string, buyPrice, quantity float64) error {
+func (s *SQLite) LogActiveTrade(symbol string, buyPrice, quantity decimal.Decimal) error {
 	query := `INSERT INTO active_trades (symbol, buy_price, quantity) VALUES (?, ?, ?)`
 	result, err := s.DB.Exec(query, symbol, buyPrice, quantity)
 	if err != nil {
@@ -105,7 +144,7 @@ func (s *SQLite) LogActiveTrade(symbol string, buyPrice, quantity float64) error
 }
 
 // LogCompletedTrade logs a completed trade to the SQLite database
-func (s *SQLite) LogCompletedTrade(symbol string, buyPrice, sellPrice, quantity, profitLoss float64) error {
+func (s *SQLite) LogCompletedTrade(symbol string, buyPrice, sellPrice, quantity, profitLoss decimal.Decimal) error {
 	query := `INSERT INTO completed_trades (symbol, buy_price, sell_price, quantity, profit_loss) VALUES (?, ?, ?, ?, ?)`
 	result, err := s.DB.Exec(query, symbol, buyPrice, sellPrice, quantity, profitLoss)
 	if err != nil {
@@ -118,6 +157,27 @@ func (s *SQLite) LogCompletedTrade(symbol string, buyPrice, sellPrice, quantity,
 	return nil
 }
 
+// RecentProfits returns the profit and loss of the latest completed trades of a symbol, newest first.
+// An empty symbol returns the trades of all symbols.
+func (s *SQLite) RecentProfits(symbol string, limit int) ([]decimal.Decimal, error) {
+	query := `SELECT profit_loss FROM completed_trades WHERE ? = '' OR symbol = ? ORDER BY id DESC LIMIT ?`
+	rows, err := s.DB.Query(query, symbol, symbol, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching completed trades for %s: %v", symbol, err)
+	}
+	defer rows.Close()
+
+	var profits []decimal.Decimal
+	for rows.Next() {
+		var pnl decimal.Decimal
+		if err := rows.Scan(&pnl); err != nil {
+			return nil, fmt.Errorf("error scanning completed trade: %v", err)
+		}
+		profits = append(profits, pnl)
+	}
+	return profits, rows.Err()
+}
+
 // GetActiveTrade fetches the active trade for a given symbol
 func (s *SQLite) GetActiveTrade(symbol string) (*models.ActiveTrade, error) {
 	query := `SELECT id, symbol, buy_price, quantity FROM active_trades WHERE symbol = ? LIMIT 1`
@@ -135,25 +195,9 @@ func (s *SQLite) GetActiveTrade(symbol string) (*models.ActiveTrade, error) {
 	return &trade, nil
 }
 
-// GetActiveTrades fetches all active trades for a given symbol
+// GetActiveTrades fetches all active trades for a given symbol, oldest first
 func (s *SQLite) GetActiveTrades(symbol string) ([]*models.ActiveTrade, error) {
-	query := `SELECT id, symbol, buy_price, quantity FROM active_trades WHERE symbol = ?`
-	rows, err := s.DB.Query(query, symbol)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var trades []*models.ActiveTrade
-	for rows.Next() {
-		var trade models.ActiveTrade
-		err := rows.Scan(&trade.ID, &trade.Symbol, &trade.BuyPrice, &trade.Quantity)
-		if err != nil {
-			return nil, err
-		}
-		trades = append(trades, &trade)
-	}
-	return trades, nil
+	return queryLots(s.DB, symbol)
 }
 
 // RemoveActiveTrade removes an active trade from the SQLite database
@@ -161,3 +205,9 @@ func (s *SQLite) RemoveActiveTrade(id int) error {
 	_, err := s.DB.Exec(`DELETE FROM active_trades WHERE id = ?`, id)
 	return err
 }
+
+// UpdateActiveTradeQuantity changes the remaining quantity of an active trade
+func (s *SQLite) UpdateActiveTradeQuantity(id int, quantity decimal.Decimal) error {
+	_, err := s.DB.Exec(`UPDATE active_trades SET quantity = ? WHERE id = ?`, quantity, id)
+	return err
+}
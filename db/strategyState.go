@@ -0,0 +1,175 @@
+package db
+
+import (
+	"binance_bot/logger"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SaveStrategyState stores a runtime value of a strategy for a pair, replacing the previous one
+func (s *SQLite) SaveStrategyState(strategy, pair, key, value string) error {
+	query := `INSERT INTO strategy_state (strategy, pair, key, value, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (strategy, pair, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`
+	if _, err := s.DB.Exec(query, strategy, pair, key, value, time.Now()); err != nil {
+		return fmt.Errorf("error saving %s state %s for %s: %v", strategy, key, pair, err)
+	}
+	return nil
+}
+
+// DeleteStrategyState removes a runtime value of a strategy for a pair
+func (s *SQLite) DeleteStrategyState(strategy, pair, key string) error {
+	query := `DELETE FROM strategy_state WHERE strategy = ? AND pair = ? AND key = ?`
+	if _, err := s.DB.Exec(query, strategy, pair, key); err != nil {
+		return fmt.Errorf("error deleting %s state %s for %s: %v", strategy, key, pair, err)
+	}
+	return nil
+}
+
+// LoadStrategyState fetches all runtime values of a strategy by pair and key
+func (s *SQLite) LoadStrategyState(strategy string) (map[string]map[string]string, error) {
+	rows, err := s.DB.Query(`SELECT pair, key, value FROM strategy_state WHERE strategy = ?`, strategy)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s state: %v", strategy, err)
+	}
+	defer rows.Close()
+
+	state := make(map[string]map[string]string)
+	for rows.Next() {
+		var pair, key, value string
+		if err := rows.Scan(&pair, &key, &value); err != nil {
+			return nil, fmt.Errorf("error scanning %s state: %v", strategy, err)
+		}
+		if state[pair] == nil {
+			state[pair] = make(map[string]string)
+		}
+		state[pair][key] = value
+	}
+	return state, rows.Err()
+}
+
+// StateStore keeps the runtime state of a strategy or the bot in memory and checkpoints
+// every change to the strategy_state table, so it survives restarts.
+// The stored state is loaded on first use, once the database is initialized.
+type StateStore struct {
+	strategy string
+	values   map[string]map[string]string
+	loaded   bool
+	mu       sync.Mutex
+}
+
+// NewStateStore creates a state store for the given strategy name
+func NewStateStore(strategy string) *StateStore {
+	return &StateStore{
+		strategy: strategy,
+		values:   make(map[string]map[string]string),
+	}
+}
+
+// Get returns a stored value
+func (s *StateStore) Get(pair, key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+
+	value, ok := s.values[pair][key]
+	return value, ok
+}
+
+// Set stores a value and checkpoints it
+func (s *StateStore) Set(pair, key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+
+	if current, ok := s.values[pair][key]; ok && current == value {
+		return
+	}
+	if s.values[pair] == nil {
+		s.values[pair] = make(map[string]string)
+	}
+	s.values[pair][key] = value
+
+	if SQLiteDB.DB != nil {
+		if err := SQLiteDB.SaveStrategyState(s.strategy, pair, key, value); err != nil {
+			logger.Errorf("%v", err)
+		}
+	}
+}
+
+// Delete removes a value
+func (s *StateStore) Delete(pair, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+
+	if _, ok := s.values[pair][key]; !ok {
+		return
+	}
+	delete(s.values[pair], key)
+
+	if SQLiteDB.DB != nil {
+		if err := SQLiteDB.DeleteStrategyState(s.strategy, pair, key); err != nil {
+			logger.Errorf("%v", err)
+		}
+	}
+}
+
+// GetDecimal returns a stored price or quantity
+func (s *StateStore) GetDecimal(pair, key string) (decimal.Decimal, bool) {
+	value, ok := s.Get(pair, key)
+	if !ok {
+		return decimal.Zero, false
+	}
+	d, err := decimal.NewFromString(value)
+	return d, err == nil
+}
+
+// SetDecimal stores a price or quantity as its exact decimal string
+func (s *StateStore) SetDecimal(pair, key string, value decimal.Decimal) {
+	s.Set(pair, key, value.String())
+}
+
+// GetInt returns a stored integer
+func (s *StateStore) GetInt(pair, key string) (int, bool) {
+	value, ok := s.Get(pair, key)
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(value)
+	return i, err == nil
+}
+
+// SetInt stores an integer
+func (s *StateStore) SetInt(pair, key string, value int) {
+	s.Set(pair, key, strconv.Itoa(value))
+}
+
+// loadLocked reads the stored state once the database is available, values set before take precedence
+func (s *StateStore) loadLocked() {
+	if s.loaded || SQLiteDB.DB == nil {
+		return
+	}
+	s.loaded = true
+
+	stored, err := SQLiteDB.LoadStrategyState(s.strategy)
+	if err != nil {
+		logger.Errorf("%v", err)
+		return
+	}
+	for pair, values := range stored {
+		if s.values[pair] == nil {
+			s.values[pair] = make(map[string]string)
+		}
+		for key, value := range values {
+			if _, ok := s.values[pair][key]; !ok {
+				s.values[pair][key] = value
+			}
+		}
+	}
+	if len(stored) > 0 {
+		logger.Infof("Restored %s state for %d pairs", s.strategy, len(stored))
+	}
+}
@@ -0,0 +1,203 @@
+package execution
+
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Execution algorithms selectable from the config
+const (
+	TWAPType     = "twap"
+	IcebergType  = "iceberg"
+	PostOnlyType = "post_only"
+)
+
+// AlgorithmConfig selects the algorithm that splits an order into child orders and holds its
+// settings. Settings left at 0 use the defaults of the algorithm, an empty type places a single
+// limit order.
+type AlgorithmConfig struct {
+	Type            string  `json:"type"`             // twap, iceberg, post_only or empty
+	DurationSeconds float64 `json:"duration_seconds"` // twap: seconds the order is spread over
+	Slices          int     `json:"slices"`           // twap: number of slices, 5 when 0
+	VisibleQuantity float64 `json:"visible_quantity"` // iceberg: largest child order in the base asset
+	VisiblePercent  float64 `json:"visible_percent"`  // iceberg: largest child order in percent of the order, used when visible_quantity is 0
+	RequeueSeconds  float64 `json:"requeue_seconds"`  // post_only: seconds before an order the market moved away from is requeued, 10 when 0
+	MaxRequeues     int     `json:"max_requeues"`     // post_only: requeues before the rest of the order expires, 10 when 0
+}
+
+// Algorithm decides the child orders a parent order is split into. The executor places, follows
+// and cancels the children and adds up their fills.
+type Algorithm interface {
+	Name() string
+	// Next returns the child order to place while the parent has none on the book, false waits
+	Next(p *Parent, market decimal.Decimal, now time.Time) (*Child, bool)
+	// Stale reports whether the child on the book is canceled to make room for the next one
+	Stale(p *Parent, market decimal.Decimal, now time.Time) bool
+	// Expired reports whether the parent gives up on its unfilled quantity
+	Expired(p *Parent, now time.Time) bool
+}
+
+// NewAlgorithm creates the algorithm selected by a config, nil for an empty type. The price offset
+// and the timeout are those of the execution policy.
+func NewAlgorithm(cfg AlgorithmConfig, offset decimal.Decimal, timeout time.Duration) (Algorithm, error) {
+	switch cfg.Type {
+	case "":
+		return nil, nil
+	case TWAPType:
+		if cfg.DurationSeconds <= 0 {
+			return nil, fmt.Errorf("%s: duration_seconds must be positive, got %v", cfg.Type, cfg.DurationSeconds)
+		}
+		twap := &TWAP{
+			Duration: time.Duration(cfg.DurationSeconds * float64(time.Second)),
+			Slices:   int(orDefault(float64(cfg.Slices), 5)),
+			Offset:   offset,
+		}
+		if twap.Slices < 1 || twap.Duration/time.Duration(twap.Slices) < time.Second {
+			return nil, fmt.Errorf("%s: slices must be at least 1 and at most one per second, got %d", cfg.Type, cfg.Slices)
+		}
+		return twap, nil
+	case IcebergType:
+		if cfg.VisibleQuantity < 0 || cfg.VisiblePercent < 0 || cfg.VisiblePercent > 100 {
+			return nil, fmt.Errorf("%s: visible_quantity must not be negative and visible_percent must be between 0 and 100", cfg.Type)
+		}
+		if cfg.VisibleQuantity == 0 && cfg.VisiblePercent == 0 {
+			return nil, fmt.Errorf("%s: visible_quantity or visible_percent must be set", cfg.Type)
+		}
+		return &Iceberg{
+			VisibleQuantity: decimal.NewFromFloat(cfg.VisibleQuantity),
+			VisiblePercent:  cfg.VisiblePercent,
+			Timeout:         timeout,
+		}, nil
+	case PostOnlyType:
+		if cfg.RequeueSeconds < 0 || cfg.MaxRequeues < 0 {
+			return nil, fmt.Errorf("%s: requeue_seconds and max_requeues must not be negative", cfg.Type)
+		}
+		return &PostOnly{
+			Offset:          offset,
+			RequeueInterval: time.Duration(orDefault(cfg.RequeueSeconds, 10) * float64(time.Second)),
+			MaxRequeues:     int(orDefault(float64(cfg.MaxRequeues), 10)),
+			Timeout:         timeout,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown algorithm type %q, available: %s, %s, %s", cfg.Type, TWAPType, IcebergType, PostOnlyType)
+}
+
+// TWAP spreads an order evenly over a duration. Every slice tops the fills up to the share of the
+// order due by then, the unfilled rest of a slice is canceled when the next one is due. Whatever is
+// left after the last slice expires one slice interval after the duration.
+type TWAP struct {
+	Duration time.Duration
+	Slices   int
+	Offset   decimal.Decimal // Limit price of a slice this fraction through the market price
+}
+
+func (t *TWAP) Name() string {
+	return TWAPType
+}
+
+func (t *TWAP) interval() time.Duration {
+	return t.Duration / time.Duration(t.Slices)
+}
+
+// due returns the number of slices due at a time
+func (t *TWAP) due(p *Parent, at time.Time) int64 {
+	due := int64(at.Sub(p.Started)/t.interval()) + 1
+	if due > int64(t.Slices) {
+		return int64(t.Slices)
+	}
+	return due
+}
+
+func (t *TWAP) Next(p *Parent, market decimal.Decimal, now time.Time) (*Child, bool) {
+	target := p.Quantity.Mul(decimal.NewFromInt(t.due(p, now))).Div(decimal.NewFromInt(int64(t.Slices)))
+	quantity := target.Sub(p.Filled)
+	if !quantity.IsPositive() {
+		return nil, false
+	}
+	return &Child{Quantity: quantity, Price: through(p.Side, market, t.Offset)}, true
+}
+
+func (t *TWAP) Stale(p *Parent, _ decimal.Decimal, now time.Time) bool {
+	return t.due(p, now) > t.due(p, p.Live.Placed)
+}
+
+func (t *TWAP) Expired(p *Parent, now time.Time) bool {
+	return !now.Before(p.Started.Add(t.Duration + t.interval()))
+}
+
+// Iceberg rests an order at its limit price showing at most a visible quantity on the book, the
+// next part is placed once the visible one filled
+type Iceberg struct {
+	VisibleQuantity decimal.Decimal // Largest child order, VisiblePercent of the order when 0
+	VisiblePercent  float64
+	Timeout         time.Duration // Unfilled rest expires after it, 0 keeps the order until it fills
+}
+
+func (i *Iceberg) Name() string {
+	return IcebergType
+}
+
+func (i *Iceberg) Next(p *Parent, _ decimal.Decimal, _ time.Time) (*Child, bool) {
+	visible := i.VisibleQuantity
+	if !visible.IsPositive() {
+		visible = p.Quantity.Mul(decimal.NewFromFloat(i.VisiblePercent / 100))
+	}
+	return &Child{Quantity: decimal.Min(visible, p.Remaining()), Price: p.Limit}, true
+}
+
+func (i *Iceberg) Stale(*Parent, decimal.Decimal, time.Time) bool {
+	return false
+}
+
+func (i *Iceberg) Expired(p *Parent, now time.Time) bool {
+	return i.Timeout > 0 && now.Sub(p.Started) >= i.Timeout
+}
+
+// PostOnly rests an order as LIMIT_MAKER an offset away from the market price, so it never takes
+// liquidity. An order the market moved away from, or one rejected for crossing, is requeued at the
+// new market price.
+type PostOnly struct {
+	Offset          decimal.Decimal // Limit price this fraction away from the market price, below it for a BUY
+	RequeueInterval time.Duration
+	MaxRequeues     int
+	Timeout         time.Duration // Unfilled rest expires after it, 0 only expires after MaxRequeues
+}
+
+func (o *PostOnly) Name() string {
+	return PostOnlyType
+}
+
+func (o *PostOnly) Next(p *Parent, market decimal.Decimal, _ time.Time) (*Child, bool) {
+	return &Child{Quantity: p.Remaining(), Price: through(p.Side, market, o.Offset.Neg()), Maker: true}, true
+}
+
+func (o *PostOnly) Stale(p *Parent, market decimal.Decimal, now time.Time) bool {
+	if now.Sub(p.Live.Placed) < o.RequeueInterval {
+		return false
+	}
+	price := through(p.Side, market, o.Offset.Neg())
+	if p.Side == "BUY" {
+		return price.GreaterThan(p.Live.Price)
+	}
+	return price.LessThan(p.Live.Price)
+}
+
+func (o *PostOnly) Expired(p *Parent, now time.Time) bool {
+	return p.Requeues > o.MaxRequeues || (o.Timeout > 0 && now.Sub(p.Started) >= o.Timeout)
+}
+
+// through returns a price an offset through the market price, above it for a BUY and below it for a SELL
+func through(side string, market, offset decimal.Decimal) decimal.Decimal {
+	if side == "BUY" {
+		return market.Mul(decimal.NewFromInt(1).Add(offset))
+	}
+	return market.Mul(decimal.NewFromInt(1).Sub(offset))
+}
+
+func orDefault(value, def float64) float64 {
+	if value == 0 {
+		return def
+	}
+	return value
+}
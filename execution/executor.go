@@ -0,0 +1,527 @@
+package execution
+
+import (
+	"binance_bot/interfaces"
+	"binance_bot/logger"
+	"binance_bot/models"
+	"binance_bot/utils"
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Config is the execution policy of the limit orders of entries and exits, a zero value places
+// the order once and leaves it on the book until it fills. Pairs with an algorithm split their
+// orders into child orders instead.
+type Config struct {
+	OffsetPercent   float64                    `json:"offset_percent"`   // Limit price this percent through the market price, 0.1 when 0
+	RepriceAttempts int                        `json:"reprice_attempts"` // Times an unfilled order is moved to the market price
+	RepriceSeconds  float64                    `json:"reprice_seconds"`  // Seconds between reprices
+	TimeoutSeconds  float64                    `json:"timeout_seconds"`  // Seconds after which an unfilled order is canceled, 0 keeps it on the book
+	MarketFallback  bool                       `json:"market_fallback"`  // Fill what is left with a market order after the timeout
+	Algorithm       AlgorithmConfig            `json:"algorithm"`        // Algorithm of every pair, a single limit order when the type is empty
+	Pairs           map[string]AlgorithmConfig `json:"pairs"`            // Algorithm per symbol, taking precedence over algorithm
+}
+
+// Validate checks the execution policy and reports all problems at once
+func (c Config) Validate() error {
+	var v utils.Validation
+
+	v.Check(c.OffsetPercent >= 0 && c.OffsetPercent < 10, "offset_percent: must be between 0 and 10, got %v", c.OffsetPercent)
+	v.Check(c.RepriceAttempts >= 0, "reprice_attempts: must not be negative, got %d", c.RepriceAttempts)
+	v.Check(c.RepriceSeconds >= 0, "reprice_seconds: must not be negative, got %v", c.RepriceSeconds)
+	v.Check(c.RepriceAttempts == 0 || c.RepriceSeconds > 0, "reprice_seconds: must be positive to reprice")
+	v.Check(c.TimeoutSeconds >= 0, "timeout_seconds: must not be negative, got %v", c.TimeoutSeconds)
+	v.Check(!c.MarketFallback || c.TimeoutSeconds > 0, "market_fallback: needs a timeout_seconds")
+
+	if _, err := NewAlgorithm(c.Algorithm, c.offset(), c.timeout()); err != nil {
+		v.Add("algorithm", err)
+	}
+	for symbol, cfg := range c.Pairs {
+		if _, err := NewAlgorithm(cfg, c.offset(), c.timeout()); err != nil {
+			v.Add("pairs."+symbol, err)
+		}
+	}
+
+	return v.Err()
+}
+
+func (c Config) offset() decimal.Decimal {
+	if c.OffsetPercent == 0 {
+		return decimal.RequireFromString("0.001")
+	}
+	return decimal.NewFromFloat(c.OffsetPercent / 100)
+}
+
+func (c Config) repriceInterval() time.Duration {
+	return time.Duration(c.RepriceSeconds * float64(time.Second))
+}
+
+func (c Config) timeout() time.Duration {
+	return time.Duration(c.TimeoutSeconds * float64(time.Second))
+}
+
+// follows reports whether placed orders need to be followed at all
+func (c Config) follows() bool {
+	return c.RepriceAttempts > 0 || c.TimeoutSeconds > 0
+}
+
+// Executor places the limit orders of the bot and follows them with the execution policy: an
+// unfilled order is moved to the market price a number of times, canceled after a timeout and
+// optionally replaced by a market order. Orders of pairs with an algorithm are split into child
+// orders. Fills are applied by the order tracker.
+type Executor struct {
+	cfg        Config
+	exchange   interfaces.ExchangeClient
+	orders     interfaces.OrderTracker
+	allow      func(pair *models.TradingPair, side, quantity, price string) bool // Portfolio limits of every order
+	clock      func() time.Time
+	algorithm  Algorithm            // Algorithm of pairs without one of their own, nil for single orders
+	algorithms map[string]Algorithm // Algorithm per symbol
+	running    []*execution
+	parents    []*Parent
+	mu         sync.Mutex // Guards the executions and the state Available reads, never held over exchange calls
+	stepMu     sync.Mutex // Keeps steps from running at once
+	version    int        // Counts the updates of the executions
+}
+
+// execution is a limit order followed by the executor
+type execution struct {
+	ctx      context.Context
+	pair     *models.TradingPair
+	side     string
+	origin   int64           // Order Submit returned
+	orderID  int64           // Current order
+	price    decimal.Decimal // Limit price of the current order
+	started  time.Time
+	placed   time.Time // Time the current order was placed or last checked for a reprice
+	reprices int
+	held     decimal.Decimal // Quantity between a cancel and its replacement, off the book but not free
+	failures int             // Failed placements of the replacement
+	done     bool
+}
+
+// NewExecutor creates an executor, clock is the time source of the policy and defaults to time.Now.
+// Every order the executor places, reprices, child orders and market fallbacks included, is only
+// placed when allow accepts it, a nil allow accepts all orders.
+func NewExecutor(cfg Config, exchange interfaces.ExchangeClient, orders interfaces.OrderTracker, allow func(pair *models.TradingPair, side, quantity, price string) bool, clock func() time.Time) *Executor {
+	if clock == nil {
+		clock = time.Now
+	}
+	e := &Executor{cfg: cfg, exchange: exchange, orders: orders, allow: allow, clock: clock, algorithms: make(map[string]Algorithm)}
+
+	// The config is validated before, an invalid algorithm falls back to single orders
+	var err error
+	if e.algorithm, err = NewAlgorithm(cfg.Algorithm, cfg.offset(), cfg.timeout()); err != nil {
+		logger.Errorf("Error creating execution algorithm: %v", err)
+	}
+	for symbol, algoCfg := range cfg.Pairs {
+		if e.algorithms[symbol], err = NewAlgorithm(algoCfg, cfg.offset(), cfg.timeout()); err != nil {
+			logger.Errorf("Error creating execution algorithm of %s: %v", symbol, err)
+		}
+	}
+	return e
+}
+
+// LimitPrice returns the limit price of an order at a market price, above it for a BUY and below it for a SELL
+func (e *Executor) LimitPrice(pair *models.TradingPair, side string, price decimal.Decimal) string {
+	return pair.FormatPrice(through(side, price, e.cfg.offset()))
+}
+
+// algorithmFor returns the algorithm of a pair, nil for single orders
+func (e *Executor) algorithmFor(symbol string) Algorithm {
+	if algo, ok := e.algorithms[symbol]; ok {
+		return algo
+	}
+	return e.algorithm
+}
+
+// Submit places a limit order and follows it until it is filled or canceled, or hands the order to
+// the algorithm of the pair. When ctx is done the order is canceled. The ID of the first order
+// placed is returned, 0 when a post-only algorithm requeues its first child.
+func (e *Executor) Submit(ctx context.Context, pair *models.TradingPair, side, quantity, price string) (int64, error) {
+	if algo := e.algorithmFor(pair.Symbol); algo != nil {
+		// The whole order is checked as well, so splitting it does not get around the limits
+		if e.allow != nil && !e.allow(pair, side, quantity, price) {
+			return 0, errLimits
+		}
+		return e.start(ctx, algo, pair, side, quantity, price)
+	}
+
+	order, err := e.place(pair, side, "LIMIT", quantity, price)
+	if err != nil {
+		return 0, err
+	}
+	orderID := order.OrderID
+	if e.cfg.follows() {
+		now := e.clock()
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.running = append(e.running, &execution{
+			ctx:     ctx,
+			pair:    pair,
+			side:    side,
+			origin:  orderID,
+			orderID: orderID,
+			price:   decimal.RequireFromString(price),
+			started: now,
+			placed:  now,
+		})
+	}
+	return orderID, nil
+}
+
+// Available returns the free balance of an asset. It leaves out orders between a cancel and their
+// replacement and what algorithms have yet to place, so the funds of an order under execution are
+// never counted as free. The balance is fetched again when the executions change meanwhile.
+func (e *Executor) Available(asset string) (decimal.Decimal, error) {
+	for attempt := 1; ; attempt++ {
+		held, version := e.held(asset)
+		free, err := e.exchange.GetBalance(asset)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		e.mu.Lock()
+		changed := e.version != version
+		e.mu.Unlock()
+		if !changed || attempt == maxBalanceAttempts {
+			return decimal.Max(free.Sub(held), decimal.Zero), nil
+		}
+	}
+}
+
+// maxBalanceAttempts caps the balance fetches of Available while executions keep changing
+const maxBalanceAttempts = 3
+
+// held returns the funds of an asset that orders under execution hold off the book, and the
+// version of the executions they were read at
+func (e *Executor) held(asset string) (decimal.Decimal, int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	held := decimal.Zero
+	hold := func(side string, pair *models.TradingPair, quantity, price decimal.Decimal) {
+		switch {
+		case side == "BUY" && pair.QuoteAsset == asset:
+			held = held.Add(quantity.Mul(price))
+		case side == "SELL" && pair.BaseAsset == asset:
+			held = held.Add(quantity)
+		}
+	}
+	for _, ex := range e.running {
+		hold(ex.side, ex.pair, ex.held, ex.price)
+	}
+	for _, p := range e.parents {
+		hold(p.Side, p.Pair, p.unplaced(), p.Limit)
+	}
+	return held, e.version
+}
+
+// update changes the state of executions that Available and Pending read
+func (e *Executor) update(change func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	change()
+	e.version++
+}
+
+// Working reports whether the order Submit returned with orderID is still executed, by a reprice
+// of it or by the children of its algorithm
+func (e *Executor) Working(symbol string, orderID int64) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, ex := range e.running {
+		if ex.pair.Symbol == symbol && ex.origin == orderID {
+			return true
+		}
+	}
+	for _, p := range e.parents {
+		if p.Pair.Symbol == symbol && p.origin == orderID {
+			return true
+		}
+	}
+	return false
+}
+
+// Pending returns the quantity of the orders of a pair and side that algorithms have yet to place
+func (e *Executor) Pending(symbol, side string) decimal.Decimal {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	pending := decimal.Zero
+	for _, p := range e.parents {
+		if p.Pair.Symbol == symbol && p.Side == side {
+			pending = pending.Add(p.unplaced())
+		}
+	}
+	return pending
+}
+
+// Run steps the executions every interval until ctx is done
+func (e *Executor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Cancel the orders of executions that stopped with ctx
+			e.Step()
+			return
+		case <-ticker.C:
+			e.Step()
+		}
+	}
+}
+
+// Step moves every order under execution along the policy, backtests call it after every replay step.
+// The executions are advanced outside the lock, orders submitted meanwhile wait for the next step.
+func (e *Executor) Step() {
+	e.stepMu.Lock()
+	defer e.stepMu.Unlock()
+
+	e.mu.Lock()
+	running := append([]*execution(nil), e.running...)
+	parents := append([]*Parent(nil), e.parents...)
+	e.mu.Unlock()
+
+	for _, ex := range running {
+		if !e.advance(ex) {
+			e.update(func() { ex.done = true })
+		}
+	}
+	for _, p := range parents {
+		if !e.advanceParent(p) {
+			e.update(func() { p.done = true })
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.running = slices.DeleteFunc(e.running, func(ex *execution) bool { return ex.done })
+	e.parents = slices.DeleteFunc(e.parents, func(p *Parent) bool { return p.done })
+}
+
+// advance applies the policy to an execution and reports whether it is still running
+func (e *Executor) advance(ex *execution) bool {
+	symbol := ex.pair.Symbol
+	if ex.held.IsPositive() {
+		// The order was canceled by a reprice whose replacement is not on the book yet
+		return e.retryReplace(ex, e.clock())
+	}
+	order, err := e.orders.Refresh(symbol, ex.orderID)
+	if err != nil {
+		logger.Warnf("Error refreshing %s order %d for %s: %v", ex.side, ex.orderID, symbol, err)
+		return true
+	}
+	if !order.IsOpen() {
+		if order.Status != models.OrderStatusFilled {
+			logger.Infof("%s order %d for %s is %s, stopped following it", ex.side, ex.orderID, symbol, order.Status)
+		}
+		return false
+	}
+
+	now := e.clock()
+	switch {
+	case ex.ctx.Err() != nil:
+		if _, ok := e.cancel(symbol, ex.side, ex.orderID); !ok {
+			return true
+		}
+		logger.Infof("Canceled %s order %d for %s: %v", ex.side, ex.orderID, symbol, ex.ctx.Err())
+		return false
+	case e.cfg.TimeoutSeconds > 0 && now.Sub(ex.started) >= e.cfg.timeout():
+		canceled, ok := e.cancel(symbol, ex.side, ex.orderID)
+		if !ok {
+			return true
+		}
+		remaining := canceled.Remaining()
+		logger.Infof("%s order %d for %s timed out after %s with %s unfilled", ex.side, ex.orderID, symbol, now.Sub(ex.started).Round(time.Second), remaining)
+		if e.cfg.MarketFallback && remaining.IsPositive() {
+			e.fallback(ex.pair, ex.side, remaining)
+		}
+		return false
+	case ex.reprices < e.cfg.RepriceAttempts && now.Sub(ex.placed) >= e.cfg.repriceInterval():
+		return e.reprice(ex, order, now)
+	}
+	return true
+}
+
+// reprice moves the order of an execution to the market price and reports whether it is still running
+func (e *Executor) reprice(ex *execution, order *models.Order, now time.Time) bool {
+	symbol := ex.pair.Symbol
+	current, err := e.exchange.GetCurrentPrice(symbol)
+	if err != nil {
+		logger.Warnf("Error fetching current price to reprice %s: %v", symbol, err)
+		return true
+	}
+
+	// Only chase a market that moved away, the market moving to the order fills it at its price
+	price := e.LimitPrice(ex.pair, ex.side, current)
+	limit := decimal.RequireFromString(price)
+	if (ex.side == "BUY" && !limit.GreaterThan(ex.price)) || (ex.side == "SELL" && !limit.LessThan(ex.price)) {
+		ex.placed = now
+		return true
+	}
+
+	// Hold the funds of the order until its replacement is on the book
+	e.update(func() { ex.held = order.Remaining() })
+
+	canceled, ok := e.cancel(symbol, ex.side, ex.orderID)
+	if !ok {
+		e.update(func() { ex.held = decimal.Zero })
+		return true
+	}
+	remaining := canceled.Remaining()
+	if !remaining.IsPositive() {
+		e.update(func() { ex.held = decimal.Zero })
+		return false
+	}
+	e.update(func() { ex.held = remaining })
+	return e.replace(ex, price, now)
+}
+
+// replace places the order of an execution whose previous order a reprice canceled at price. When
+// that fails the funds stay held and the placement is retried on the next step, or what is left is
+// filled at the market with MarketFallback. It reports whether the execution is still running.
+func (e *Executor) replace(ex *execution, price string, now time.Time) bool {
+	symbol := ex.pair.Symbol
+	order, err := e.place(ex.pair, ex.side, "LIMIT", ex.pair.FormatQty(ex.held), price)
+	if err != nil {
+		ex.failures++
+		if e.cfg.MarketFallback {
+			logger.Errorf("Error repricing %s order %d for %s to %s, filling %s at the market: %v", ex.side, ex.orderID, symbol, price, ex.held, err)
+			if e.fallback(ex.pair, ex.side, ex.held) != nil {
+				e.update(func() { ex.held = decimal.Zero })
+				return false
+			}
+			return true
+		}
+		logger.Errorf("Error repricing %s order %d for %s to %s (%d/%d), retrying on the next step: %v", ex.side, ex.orderID, symbol, price, ex.failures, maxReplaceAttempts, err)
+		return true
+	}
+	orderID := order.OrderID
+
+	ex.reprices++
+	logger.Infof("Repriced %s order %d for %s from %s to %s as order %d (%d/%d)", ex.side, ex.orderID, symbol, ex.price, price, orderID, ex.reprices, e.cfg.RepriceAttempts)
+	limit := decimal.RequireFromString(price)
+	e.update(func() { ex.orderID, ex.price, ex.placed, ex.held, ex.failures = orderID, limit, now, decimal.Zero, 0 })
+	return true
+}
+
+// maxReplaceAttempts caps the placements of the replacement of a repriced order before its funds are given up
+const maxReplaceAttempts = 5
+
+// retryReplace places the replacement of an order a reprice canceled again at the current price.
+// The held funds are given up when ctx is done, the execution timed out or the placements keep failing.
+func (e *Executor) retryReplace(ex *execution, now time.Time) bool {
+	symbol := ex.pair.Symbol
+	switch {
+	case ex.ctx.Err() != nil:
+		logger.Infof("Stopped replacing %s order %d for %s: %v", ex.side, ex.orderID, symbol, ex.ctx.Err())
+	case e.cfg.TimeoutSeconds > 0 && now.Sub(ex.started) >= e.cfg.timeout():
+		logger.Errorf("%s order %d for %s timed out after %s with %s not placed again", ex.side, ex.orderID, symbol, now.Sub(ex.started).Round(time.Second), ex.held)
+	case ex.failures >= maxReplaceAttempts:
+		logger.Errorf("Gave up repricing %s order %d for %s with %s not placed again", ex.side, ex.orderID, symbol, ex.held)
+	default:
+		current, err := e.exchange.GetCurrentPrice(symbol)
+		if err != nil {
+			logger.Warnf("Error fetching current price to reprice %s: %v", symbol, err)
+			return true
+		}
+		return e.replace(ex, e.LimitPrice(ex.pair, ex.side, current), now)
+	}
+	e.update(func() { ex.held = decimal.Zero })
+	return false
+}
+
+// fallback fills the rest of an order with a market order and returns it
+func (e *Executor) fallback(pair *models.TradingPair, side string, remaining decimal.Decimal) *models.Order {
+	symbol := pair.Symbol
+	order, err := e.place(pair, side, "MARKET", pair.FormatQty(remaining), "")
+	if err != nil {
+		logger.Errorf("Error placing MARKET %s fallback for %s: %v", side, symbol, err)
+		return nil
+	}
+	logger.Infof("Placed MARKET %s fallback for %s: Order ID %d Quantity=%s", side, symbol, order.OrderID, order.Quantity)
+	return order
+}
+
+// cancel cancels an order and returns it with the fills it got before. It reports false when the
+// order is still open.
+func (e *Executor) cancel(symbol, side string, orderID int64) (*models.Order, bool) {
+	cancelErr := e.exchange.CancelOrder(symbol, orderID)
+
+	// Apply the fills the order got before it was canceled
+	order, err := e.orders.Refresh(symbol, orderID)
+	if err != nil {
+		logger.Warnf("Error refreshing %s order %d for %s: %v", side, orderID, symbol, err)
+		return nil, false
+	}
+	if order.IsOpen() {
+		logger.Warnf("Failed to cancel %s order %d for %s: %v", side, orderID, symbol, cancelErr)
+		return nil, false
+	}
+	return order, true
+}
+
+// errLimits is returned for orders the portfolio limits reject, the rejection itself is logged by allow
+var errLimits = fmt.Errorf("order breaks the portfolio limits")
+
+// place checks an order against the portfolio limits, places it as a LIMIT, LIMIT_MAKER or MARKET
+// order and hands it to the order tracker. Every order of the executor goes through it, a MARKET
+// order is checked at the current price.
+func (e *Executor) place(pair *models.TradingPair, side, orderType, quantity, price string) (*models.Order, error) {
+	if orderType == "MARKET" {
+		current, err := e.exchange.GetCurrentPrice(pair.Symbol)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching current price of %s: %v", pair.Symbol, err)
+		}
+		price = pair.FormatPrice(current)
+	}
+	if e.allow != nil && !e.allow(pair, side, quantity, price) {
+		return nil, errLimits
+	}
+
+	var order *models.Order
+	switch orderType {
+	case "MARKET":
+		var err error
+		if order, err = e.exchange.CreateMarketOrder(pair.Symbol, side, quantity); err != nil {
+			return nil, err
+		}
+	default:
+		var orderID int64
+		var err error
+		if orderType == "LIMIT_MAKER" {
+			maker, ok := e.exchange.(interfaces.MakerClient)
+			if !ok {
+				return nil, fmt.Errorf("exchange client cannot place LIMIT_MAKER orders")
+			}
+			orderID, err = maker.CreateLimitMakerOrder(pair.Symbol, side, quantity, price)
+		} else {
+			orderID, err = e.exchange.CreateLimitOrder(pair.Symbol, side, quantity, price)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		order = &models.Order{
+			OrderID: orderID,
+			Symbol:  pair.Symbol,
+			Side:    side,
+			Type:    orderType,
+			Status:  models.OrderStatusNew,
+		}
+		order.Quantity, _ = decimal.NewFromString(quantity)
+		order.Price, _ = decimal.NewFromString(price)
+	}
+
+	if err := e.orders.Track(order); err != nil {
+		logger.Errorf("Error tracking %s %s order %d for %s: %v", orderType, side, order.OrderID, pair.Symbol, err)
+	}
+	return order, nil
+}
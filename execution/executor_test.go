@@ -0,0 +1,353 @@
+package execution
+
+import (
+	"binance_bot/interfaces"
+	"binance_bot/models"
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeExchange keeps the orders placed on it at a price set by the test. The quote balance is
+// 1000 USDT less what open BUY orders lock.
+type fakeExchange struct {
+	interfaces.ExchangeClient
+	price     decimal.Decimal
+	orders    []*models.Order // Indexed by order ID - 1
+	failLimit int             // LIMIT orders to reject before accepting them again
+	crossing  int             // LIMIT_MAKER orders to reject for crossing the book
+	onPlace   func()          // Called before a LIMIT or LIMIT_MAKER order is placed
+}
+
+func (x *fakeExchange) GetCurrentPrice(symbol string) (decimal.Decimal, error) {
+	return x.price, nil
+}
+
+func (x *fakeExchange) GetBalance(asset string) (decimal.Decimal, error) {
+	free := d("1000")
+	for _, order := range x.orders {
+		if order.Side == "BUY" && order.IsOpen() {
+			free = free.Sub(order.Remaining().Mul(order.Price))
+		}
+	}
+	return free, nil
+}
+
+func (x *fakeExchange) add(side, orderType, quantity, price, status string) *models.Order {
+	order := &models.Order{
+		OrderID:  int64(len(x.orders) + 1),
+		Symbol:   "ETHUSDT",
+		Side:     side,
+		Type:     orderType,
+		Quantity: d(quantity),
+		Price:    d(price),
+		Status:   status,
+	}
+	x.orders = append(x.orders, order)
+	return order
+}
+
+func (x *fakeExchange) CreateLimitOrder(symbol, side, quantity, price string) (int64, error) {
+	if x.onPlace != nil {
+		x.onPlace()
+	}
+	if x.failLimit > 0 {
+		x.failLimit--
+		return 0, fmt.Errorf("rejected")
+	}
+	return x.add(side, "LIMIT", quantity, price, models.OrderStatusNew).OrderID, nil
+}
+
+func (x *fakeExchange) CreateLimitMakerOrder(symbol, side, quantity, price string) (int64, error) {
+	if x.onPlace != nil {
+		x.onPlace()
+	}
+	if x.crossing > 0 {
+		x.crossing--
+		return 0, fmt.Errorf("order would immediately match and take")
+	}
+	return x.add(side, "LIMIT_MAKER", quantity, price, models.OrderStatusNew).OrderID, nil
+}
+
+// CreateMarketOrder fills at the current price
+func (x *fakeExchange) CreateMarketOrder(symbol, side, quantity string) (*models.Order, error) {
+	order := x.add(side, "MARKET", quantity, x.price.String(), models.OrderStatusFilled)
+	order.FilledQty, order.AvgPrice = order.Quantity, x.price
+	placed := *order
+	return &placed, nil
+}
+
+func (x *fakeExchange) CancelOrder(symbol string, orderID int64) error {
+	x.orders[orderID-1].Status = models.OrderStatusCanceled
+	return nil
+}
+
+func (x *fakeExchange) GetOrder(symbol string, orderID int64) (*models.Order, error) {
+	order := *x.orders[orderID-1]
+	return &order, nil
+}
+
+// fill fills an order up to a quantity at its limit price
+func (x *fakeExchange) fill(orderID int64, quantity string) {
+	order := x.orders[orderID-1]
+	order.FilledQty, order.AvgPrice = d(quantity), order.Price
+	order.Status = models.OrderStatusPartiallyFilled
+	if order.FilledQty.Equal(order.Quantity) {
+		order.Status = models.OrderStatusFilled
+	}
+}
+
+// book lists the orders as "ID TYPE quantity@price status"
+func (x *fakeExchange) book() string {
+	lines := make([]string, len(x.orders))
+	for i, o := range x.orders {
+		lines[i] = fmt.Sprintf("%d %s %s@%s %s", o.OrderID, o.Type, o.Quantity, o.Price, o.Status)
+	}
+	return strings.Join(lines, ", ")
+}
+
+// fakeTracker reads the orders from the fake exchange
+type fakeTracker struct {
+	exchange *fakeExchange
+}
+
+func (t fakeTracker) Track(order *models.Order) error {
+	return nil
+}
+
+func (t fakeTracker) Refresh(symbol string, orderID int64) (*models.Order, error) {
+	return t.exchange.GetOrder(symbol, orderID)
+}
+
+var ethusdt = &models.TradingPair{Symbol: "ETHUSDT", BaseAsset: "ETH", QuoteAsset: "USDT", QtyPrecision: 3, PricePrecision: 2, MinNotional: decimal.NewFromInt(5)}
+
+// t0 is the time of the first order of a test
+var t0 = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newTestExecutor returns an executor on a fake exchange at a price of 100 and a clock set by the test
+func newTestExecutor(cfg Config) (*Executor, *fakeExchange, *time.Time) {
+	x := &fakeExchange{price: d("100")}
+	now := t0
+	return NewExecutor(cfg, x, fakeTracker{x}, nil, func() time.Time { return now }), x, &now
+}
+
+func d(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
+// tick is one step of the executor at some seconds after t0
+type tick struct {
+	seconds float64
+	price   string
+	fill    int64  // Order filled before the step, 0 for none
+	filled  string // Filled quantity of the order
+	book    string // Orders after the step
+}
+
+// run moves the clock and the price through the ticks and checks the book after each step
+func run(t *testing.T, e *Executor, x *fakeExchange, now *time.Time, ticks []tick) {
+	t.Helper()
+	for _, tk := range ticks {
+		*now = t0.Add(time.Duration(tk.seconds * float64(time.Second)))
+		if tk.price != "" {
+			x.price = d(tk.price)
+		}
+		if tk.fill != 0 {
+			x.fill(tk.fill, tk.filled)
+		}
+		e.Step()
+		if got := x.book(); got != tk.book {
+			t.Fatalf("after %vs: got book\n%s\nwant\n%s", tk.seconds, got, tk.book)
+		}
+	}
+}
+
+func TestReprice(t *testing.T) {
+	e, x, now := newTestExecutor(Config{RepriceAttempts: 2, RepriceSeconds: 10})
+	if _, err := e.Submit(context.Background(), ethusdt, "BUY", "1", e.LimitPrice(ethusdt, "BUY", x.price)); err != nil {
+		t.Fatal(err)
+	}
+
+	run(t, e, x, now, []tick{
+		{seconds: 5, price: "101", book: "1 LIMIT 1@100.1 NEW"},
+		// The unfilled rest moves 0.1% above the market
+		{seconds: 10, fill: 1, filled: "0.4", book: "1 LIMIT 1@100.1 CANCELED, 2 LIMIT 0.6@101.1 NEW"},
+		// A market moving to the order is not chased
+		{seconds: 20, price: "100", book: "1 LIMIT 1@100.1 CANCELED, 2 LIMIT 0.6@101.1 NEW"},
+		{seconds: 30, price: "102", book: "1 LIMIT 1@100.1 CANCELED, 2 LIMIT 0.6@101.1 CANCELED, 3 LIMIT 0.6@102.1 NEW"},
+		// No reprice attempts left
+		{seconds: 40, price: "103", book: "1 LIMIT 1@100.1 CANCELED, 2 LIMIT 0.6@101.1 CANCELED, 3 LIMIT 0.6@102.1 NEW"},
+	})
+	if !e.Working("ETHUSDT", 1) {
+		t.Error("execution stopped while its order is on the book")
+	}
+	run(t, e, x, now, []tick{{seconds: 50, fill: 3, filled: "0.6", book: "1 LIMIT 1@100.1 CANCELED, 2 LIMIT 0.6@101.1 CANCELED, 3 LIMIT 0.6@102.1 FILLED"}})
+	if e.Working("ETHUSDT", 1) {
+		t.Error("execution still running after the fill")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback bool
+		book     string
+	}{
+		{"unfilled rest is canceled", false, "1 LIMIT 1@100.1 CANCELED"},
+		{"unfilled rest fills at the market", true, "1 LIMIT 1@100.1 CANCELED, 2 MARKET 0.6@101 FILLED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, x, now := newTestExecutor(Config{TimeoutSeconds: 60, MarketFallback: tt.fallback})
+			if _, err := e.Submit(context.Background(), ethusdt, "BUY", "1", "100.1"); err != nil {
+				t.Fatal(err)
+			}
+			run(t, e, x, now, []tick{
+				{seconds: 30, price: "101", fill: 1, filled: "0.4", book: "1 LIMIT 1@100.1 PARTIALLY_FILLED"},
+				{seconds: 60, book: tt.book},
+			})
+			if e.Working("ETHUSDT", 1) {
+				t.Error("execution still running after the timeout")
+			}
+		})
+	}
+}
+
+func TestRepriceFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		failLimit int
+		ticks     []tick
+		working   bool   // Execution still running after the ticks
+		held      string // USDT Available leaves out after the ticks
+	}{
+		{
+			name:      "placement is retried on the next step",
+			cfg:       Config{RepriceAttempts: 1, RepriceSeconds: 10},
+			failLimit: 1,
+			ticks: []tick{
+				{seconds: 10, price: "101", book: "1 LIMIT 1@100.1 CANCELED"},
+				{seconds: 11, price: "102", book: "1 LIMIT 1@100.1 CANCELED, 2 LIMIT 1@102.1 NEW"},
+			},
+			working: true,
+			held:    "0",
+		},
+		{
+			name:      "funds stay held while the placement fails",
+			cfg:       Config{RepriceAttempts: 1, RepriceSeconds: 10},
+			failLimit: 2,
+			ticks: []tick{
+				{seconds: 10, price: "101", book: "1 LIMIT 1@100.1 CANCELED"},
+				{seconds: 11, book: "1 LIMIT 1@100.1 CANCELED"},
+			},
+			working: true,
+			held:    "100.1",
+		},
+		{
+			name:      "market fallback fills the rest",
+			cfg:       Config{RepriceAttempts: 1, RepriceSeconds: 10, TimeoutSeconds: 60, MarketFallback: true},
+			failLimit: 1,
+			ticks: []tick{
+				{seconds: 10, price: "101", book: "1 LIMIT 1@100.1 CANCELED, 2 MARKET 1@101 FILLED"},
+			},
+			held: "0",
+		},
+		{
+			name:      "held funds are given up after the timeout",
+			cfg:       Config{RepriceAttempts: 1, RepriceSeconds: 10, TimeoutSeconds: 20},
+			failLimit: 10,
+			ticks: []tick{
+				{seconds: 10, price: "101", book: "1 LIMIT 1@100.1 CANCELED"},
+				{seconds: 15, book: "1 LIMIT 1@100.1 CANCELED"},
+				{seconds: 20, book: "1 LIMIT 1@100.1 CANCELED"},
+			},
+			held: "0",
+		},
+		{
+			name:      "held funds are given up after repeated failures",
+			cfg:       Config{RepriceAttempts: 1, RepriceSeconds: 10},
+			failLimit: 10,
+			ticks: []tick{
+				{seconds: 10, price: "101", book: "1 LIMIT 1@100.1 CANCELED"},
+				{seconds: 11, book: "1 LIMIT 1@100.1 CANCELED"},
+				{seconds: 12, book: "1 LIMIT 1@100.1 CANCELED"},
+				{seconds: 13, book: "1 LIMIT 1@100.1 CANCELED"},
+				{seconds: 14, book: "1 LIMIT 1@100.1 CANCELED"},
+				{seconds: 15, book: "1 LIMIT 1@100.1 CANCELED"},
+			},
+			held: "0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, x, now := newTestExecutor(tt.cfg)
+			if _, err := e.Submit(context.Background(), ethusdt, "BUY", "1", "100.1"); err != nil {
+				t.Fatal(err)
+			}
+
+			// The canceled order is off the book, its funds are not free until the replacement is placed
+			var placedAt []decimal.Decimal
+			x.failLimit = tt.failLimit
+			x.onPlace = func() {
+				available, _ := e.Available("USDT")
+				placedAt = append(placedAt, available)
+			}
+			run(t, e, x, now, tt.ticks)
+			for i, available := range placedAt {
+				if !available.Equal(d("899.9")) {
+					t.Errorf("placement %d saw %s USDT available, want 899.9 without the 100.1 held", i+1, available)
+				}
+			}
+
+			if working := e.Working("ETHUSDT", 1); working != tt.working {
+				t.Errorf("working %v, want %v", working, tt.working)
+			}
+			free, _ := x.GetBalance("USDT")
+			available, err := e.Available("USDT")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if held := free.Sub(available); !held.Equal(d(tt.held)) {
+				t.Errorf("Available leaves out %s USDT, want %s", held, tt.held)
+			}
+		})
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	tests := []struct {
+		name      string
+		failLimit int // Reprice placements to fail before the cancel
+		book      string
+	}{
+		{"order on the book is canceled", 0, "1 LIMIT 1@100.1 CANCELED"},
+		{"held replacement is not placed", 1, "1 LIMIT 1@100.1 CANCELED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, x, now := newTestExecutor(Config{RepriceAttempts: 1, RepriceSeconds: 10})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if _, err := e.Submit(ctx, ethusdt, "BUY", "1", "100.1"); err != nil {
+				t.Fatal(err)
+			}
+
+			x.failLimit = tt.failLimit
+			if tt.failLimit > 0 {
+				run(t, e, x, now, []tick{{seconds: 10, price: "101", book: "1 LIMIT 1@100.1 CANCELED"}})
+			}
+			cancel()
+			run(t, e, x, now, []tick{{seconds: 11, book: tt.book}})
+			if e.Working("ETHUSDT", 1) {
+				t.Error("execution still running after its context was canceled")
+			}
+			if available, _ := e.Available("USDT"); !available.Equal(d("1000")) {
+				t.Errorf("got %s USDT available, want all 1000", available)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package execution
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/logger"
+	"binance_bot/models"
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Parent is an order split into child orders by an algorithm
+type Parent struct {
+	Pair     *models.TradingPair
+	Side     string
+	Quantity decimal.Decimal
+	Limit    decimal.Decimal // Limit price the order was submitted with
+	Arrival  decimal.Decimal // Market price when the order arrived
+	Started  time.Time
+	Filled   decimal.Decimal // Quantity filled by the children
+	Children int             // Child orders placed
+	Requeues int             // Maker children canceled or rejected to be placed again
+	Live     *Child          // Child on the book, nil between children
+	cost     decimal.Decimal // Quote value of the fills
+	origin   int64           // Order Submit returned, the first child or 0 when it was requeued
+	done     bool
+	ctx      context.Context
+	algo     Algorithm
+}
+
+// Child is a child order of a parent order
+type Child struct {
+	Quantity  decimal.Decimal
+	Price     decimal.Decimal
+	Maker     bool // Placed as LIMIT_MAKER, rejected instead of taking liquidity
+	OrderID   int64
+	Placed    time.Time
+	filled    decimal.Decimal // Fills already added to the parent
+	cost      decimal.Decimal
+	canceling bool // A cancel was sent, the quantity may be off the book already
+}
+
+// Remaining returns the quantity of the parent that is not filled yet
+func (p *Parent) Remaining() decimal.Decimal {
+	return p.Quantity.Sub(p.Filled)
+}
+
+// unplaced returns the remaining quantity that is not on the book in a child
+func (p *Parent) unplaced() decimal.Decimal {
+	unplaced := p.Remaining()
+	if p.Live != nil && !p.Live.canceling {
+		unplaced = unplaced.Sub(p.Live.Quantity.Sub(p.Live.filled))
+	}
+	return decimal.Max(unplaced, decimal.Zero)
+}
+
+// AveragePrice returns the average fill price of the children, 0 without fills
+func (p *Parent) AveragePrice() decimal.Decimal {
+	if !p.Filled.IsPositive() {
+		return decimal.Zero
+	}
+	return p.cost.Div(p.Filled)
+}
+
+// Slippage returns the average fill price against the arrival price as a fraction, positive when
+// the fills were worse than the arrival price
+func (p *Parent) Slippage() decimal.Decimal {
+	if !p.Filled.IsPositive() || !p.Arrival.IsPositive() {
+		return decimal.Zero
+	}
+	slippage := p.AveragePrice().Sub(p.Arrival).Div(p.Arrival)
+	if p.Side == "SELL" {
+		return slippage.Neg()
+	}
+	return slippage
+}
+
+// apply adds the fills a child order got since the last time to the parent
+func (p *Parent) apply(c *Child, order *models.Order) {
+	cost := order.FilledQty.Mul(order.AvgPrice)
+	p.Filled = p.Filled.Add(order.FilledQty.Sub(c.filled))
+	p.cost = p.cost.Add(cost.Sub(c.cost))
+	c.filled, c.cost = order.FilledQty, cost
+}
+
+// start creates a parent order and places its first child order. The order ID is 0 while a first
+// post-only child rejected for crossing waits for its requeue.
+func (e *Executor) start(ctx context.Context, algo Algorithm, pair *models.TradingPair, side, quantity, price string) (int64, error) {
+	market, err := e.exchange.GetCurrentPrice(pair.Symbol)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching arrival price of %s: %v", pair.Symbol, err)
+	}
+
+	p := &Parent{
+		Pair:     pair,
+		Side:     side,
+		Quantity: decimal.RequireFromString(quantity),
+		Limit:    decimal.RequireFromString(price),
+		Arrival:  market,
+		Started:  e.clock(),
+		ctx:      ctx,
+		algo:     algo,
+	}
+	logger.Infof("Starting %s %s of %s %s at arrival price %s", algo.Name(), side, quantity, pair.Symbol, pair.FormatPrice(p.Arrival))
+	if c, ok := algo.Next(p, p.Arrival, p.Started); ok {
+		if err := e.placeChild(p, c); err != nil {
+			if !c.Maker || err == errLimits {
+				return 0, err
+			}
+			// A post-only order crossing the market is requeued at the next price like later children
+			p.Requeues++
+			logger.Warnf("Requeuing %s %s child order for %s: %v", algo.Name(), side, pair.Symbol, err)
+		}
+	}
+	if p.Live != nil {
+		p.origin = p.Live.OrderID
+	}
+	e.update(func() { e.parents = append(e.parents, p) })
+	return p.origin, nil
+}
+
+// advanceParent applies the fills of the child on the book, replaces it when the algorithm asks for
+// the next one and reports whether the parent is still running
+func (e *Executor) advanceParent(p *Parent) bool {
+	symbol := p.Pair.Symbol
+	market, err := e.exchange.GetCurrentPrice(symbol)
+	if err != nil {
+		logger.Warnf("Error fetching current price of %s for %s: %v", symbol, p.algo.Name(), err)
+		return true
+	}
+	now := e.clock()
+
+	if c := p.Live; c != nil {
+		order, err := e.orders.Refresh(symbol, c.OrderID)
+		if err != nil {
+			logger.Warnf("Error refreshing %s child order %d for %s: %v", p.algo.Name(), c.OrderID, symbol, err)
+			return true
+		}
+		e.update(func() { p.apply(c, order) })
+		if order.IsOpen() {
+			stale := p.algo.Stale(p, market, now)
+			if p.ctx.Err() == nil && !stale && !p.algo.Expired(p, now) {
+				return true
+			}
+			e.update(func() { c.canceling = true })
+			canceled, ok := e.cancel(symbol, p.Side, c.OrderID)
+			if !ok {
+				e.update(func() { c.canceling = false })
+				return true
+			}
+			e.update(func() { p.apply(c, canceled) })
+			if c.Maker && stale {
+				p.Requeues++
+			}
+		}
+		e.update(func() { p.Live = nil })
+	}
+
+	switch {
+	case p.ctx.Err() != nil:
+		e.finish(p, models.OrderStatusCanceled, now)
+	case !p.Remaining().IsPositive() || p.Remaining().Mul(market).LessThan(p.Pair.MinNotional):
+		e.finish(p, models.OrderStatusFilled, now)
+	case p.algo.Expired(p, now):
+		if e.cfg.MarketFallback {
+			if order := e.fallback(p.Pair, p.Side, p.Remaining()); order != nil {
+				e.update(func() {
+					p.Children++
+					p.apply(&Child{}, order)
+				})
+			}
+		}
+		e.finish(p, models.OrderStatusExpired, now)
+	default:
+		c, ok := p.algo.Next(p, market, now)
+		if !ok {
+			return true
+		}
+		if err := e.placeChild(p, c); err != nil {
+			if c.Maker && err != errLimits {
+				// A post-only order crossing the market is requeued at the next price
+				p.Requeues++
+				logger.Warnf("Requeuing %s %s child order for %s: %v", p.algo.Name(), p.Side, symbol, err)
+				return true
+			}
+			logger.Warnf("Error placing %s %s child order for %s: %v", p.algo.Name(), p.Side, symbol, err)
+			e.finish(p, models.OrderStatusRejected, now)
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// placeChild sizes a child order to the minimum notional of the pair and places it. A child below
+// the minimum notional is grown to it, one leaving a rest below it takes the rest.
+func (e *Executor) placeChild(p *Parent, c *Child) error {
+	remaining := p.Remaining()
+	minimum := p.Pair.MinNotional.Div(c.Price).Mul(decimal.RequireFromString("1.01"))
+	quantity := decimal.Max(c.Quantity, minimum)
+	if remaining.Sub(quantity).LessThan(minimum) {
+		quantity = remaining
+	}
+
+	orderType := "LIMIT"
+	if c.Maker {
+		orderType = "LIMIT_MAKER"
+	}
+	qty, price := p.Pair.FormatQty(quantity), p.Pair.FormatPrice(c.Price)
+	order, err := e.place(p.Pair, p.Side, orderType, qty, price)
+	if err != nil {
+		return err
+	}
+	orderID := order.OrderID
+
+	c.Quantity, c.Price = decimal.RequireFromString(qty), decimal.RequireFromString(price)
+	c.OrderID, c.Placed = orderID, e.clock()
+	e.update(func() {
+		p.Live = c
+		p.Children++
+	})
+	logger.Debugf("Placed %s %s child order %d for %s: Quantity=%s Price=%s", p.algo.Name(), p.Side, orderID, p.Pair.Symbol, qty, price)
+	return nil
+}
+
+// finish reports the aggregated fills of a parent order and stores the report
+func (e *Executor) finish(p *Parent, status string, now time.Time) {
+	report := &models.ExecutionReport{
+		Symbol:       p.Pair.Symbol,
+		Side:         p.Side,
+		Algorithm:    p.algo.Name(),
+		Quantity:     p.Quantity,
+		Filled:       p.Filled,
+		ArrivalPrice: p.Arrival,
+		AveragePrice: p.AveragePrice(),
+		Slippage:     p.Slippage(),
+		Children:     p.Children,
+		Status:       status,
+		StartedAt:    p.Started,
+		FinishedAt:   now,
+	}
+	logger.Infof("%s %s of %s %s is %s: Filled=%s AvgPrice=%s Arrival=%s Slippage=%s%% Children=%d",
+		report.Algorithm, report.Side, report.Quantity, report.Symbol, status, report.Filled,
+		p.Pair.FormatPrice(report.AveragePrice), p.Pair.FormatPrice(report.ArrivalPrice), report.Slippage.Mul(decimal.NewFromInt(100)).StringFixed(3), report.Children)
+	if err := db2.SQLiteDB.LogExecutionReport(report); err != nil {
+		logger.Errorf("Error storing execution report: %v", err)
+	}
+}
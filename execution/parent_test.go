@@ -0,0 +1,158 @@
+package execution
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/models"
+	"context"
+	"testing"
+)
+
+// newParentExecutor returns an executor splitting orders with an algorithm, finished parents are
+// reported to a test database
+func newParentExecutor(t *testing.T, algo AlgorithmConfig) (*Executor, *fakeExchange, func(ticks []tick)) {
+	if err := db2.InitDBAt(t.TempDir() + "/execution.db"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db2.SQLiteDB.DB.Close() })
+	e, x, now := newTestExecutor(Config{Algorithm: algo})
+	return e, x, func(ticks []tick) {
+		t.Helper()
+		run(t, e, x, now, ticks)
+	}
+}
+
+func TestTWAP(t *testing.T) {
+	e, _, run := newParentExecutor(t, AlgorithmConfig{Type: TWAPType, DurationSeconds: 50, Slices: 5})
+	if _, err := e.Submit(context.Background(), ethusdt, "BUY", "1", "100.1"); err != nil {
+		t.Fatal(err)
+	}
+	p := e.parents[0]
+
+	// Every 10 seconds the fills are topped up to a fifth more of the order
+	run([]tick{
+		{seconds: 5, book: "1 LIMIT 0.2@100.1 NEW"},
+		{seconds: 10, fill: 1, filled: "0.2", book: "1 LIMIT 0.2@100.1 FILLED, 2 LIMIT 0.2@100.1 NEW"},
+		{seconds: 20, price: "101", book: "1 LIMIT 0.2@100.1 FILLED, 2 LIMIT 0.2@100.1 CANCELED, 3 LIMIT 0.4@101.1 NEW"},
+		{seconds: 30, fill: 3, filled: "0.1", book: "1 LIMIT 0.2@100.1 FILLED, 2 LIMIT 0.2@100.1 CANCELED, 3 LIMIT 0.4@101.1 CANCELED, 4 LIMIT 0.5@101.1 NEW"},
+		{seconds: 50, book: "1 LIMIT 0.2@100.1 FILLED, 2 LIMIT 0.2@100.1 CANCELED, 3 LIMIT 0.4@101.1 CANCELED, 4 LIMIT 0.5@101.1 CANCELED, 5 LIMIT 0.7@101.1 NEW"},
+		// The rest expires one slice after the duration
+		{seconds: 59, book: "1 LIMIT 0.2@100.1 FILLED, 2 LIMIT 0.2@100.1 CANCELED, 3 LIMIT 0.4@101.1 CANCELED, 4 LIMIT 0.5@101.1 CANCELED, 5 LIMIT 0.7@101.1 NEW"},
+		{seconds: 60, book: "1 LIMIT 0.2@100.1 FILLED, 2 LIMIT 0.2@100.1 CANCELED, 3 LIMIT 0.4@101.1 CANCELED, 4 LIMIT 0.5@101.1 CANCELED, 5 LIMIT 0.7@101.1 CANCELED"},
+	})
+
+	if e.Working("ETHUSDT", 1) {
+		t.Error("parent still running after it expired")
+	}
+	if !p.Filled.Equal(d("0.3")) || p.Children != 5 {
+		t.Errorf("got %s filled by %d children, want 0.3 by 5", p.Filled, p.Children)
+	}
+	// 0.2 at 100.1 and 0.1 at 101.1 against the arrival price of 100
+	if avg := p.AveragePrice().Round(4); !avg.Equal(d("100.4333")) {
+		t.Errorf("got average price %s, want 100.4333", avg)
+	}
+	if slippage := p.Slippage().Round(6); !slippage.Equal(d("0.004333")) {
+		t.Errorf("got slippage %s, want 0.004333", slippage)
+	}
+}
+
+func TestIceberg(t *testing.T) {
+	e, _, run := newParentExecutor(t, AlgorithmConfig{Type: IcebergType, VisibleQuantity: 0.3})
+	if _, err := e.Submit(context.Background(), ethusdt, "BUY", "0.95", "99"); err != nil {
+		t.Fatal(err)
+	}
+	p := e.parents[0]
+
+	run([]tick{
+		{seconds: 1, book: "1 LIMIT 0.3@99 NEW"},
+		{seconds: 2, fill: 1, filled: "0.3", book: "1 LIMIT 0.3@99 FILLED, 2 LIMIT 0.3@99 NEW"},
+		// A rest of 0.05 is below the minimum notional, the last child takes it along
+		{seconds: 3, fill: 2, filled: "0.3", book: "1 LIMIT 0.3@99 FILLED, 2 LIMIT 0.3@99 FILLED, 3 LIMIT 0.35@99 NEW"},
+		{seconds: 4, fill: 3, filled: "0.35", book: "1 LIMIT 0.3@99 FILLED, 2 LIMIT 0.3@99 FILLED, 3 LIMIT 0.35@99 FILLED"},
+	})
+
+	if e.Working("ETHUSDT", 1) {
+		t.Error("parent still running after it filled")
+	}
+	// Resting below the arrival price of 100 filled better than the market
+	if !p.AveragePrice().Equal(d("99")) || !p.Slippage().Equal(d("-0.01")) {
+		t.Errorf("got average price %s slippage %s, want 99 and -0.01", p.AveragePrice(), p.Slippage())
+	}
+}
+
+func TestPostOnly(t *testing.T) {
+	e, x, run := newParentExecutor(t, AlgorithmConfig{Type: PostOnlyType, RequeueSeconds: 10, MaxRequeues: 2})
+
+	// The first child crosses the book and is requeued instead of failing the order
+	x.crossing = 1
+	orderID, err := e.Submit(context.Background(), ethusdt, "BUY", "1", "100.1")
+	if err != nil || orderID != 0 {
+		t.Fatalf("got order %d %v, want the first child requeued", orderID, err)
+	}
+	p := e.parents[0]
+	if !e.Working("ETHUSDT", 0) || p.Requeues != 1 {
+		t.Fatalf("got working %v with %d requeues, want the parent running after 1", e.Working("ETHUSDT", 0), p.Requeues)
+	}
+
+	run([]tick{
+		{seconds: 1, book: "1 LIMIT_MAKER 1@99.9 NEW"},
+		// The market moving away is only followed after the requeue interval
+		{seconds: 5, price: "101", book: "1 LIMIT_MAKER 1@99.9 NEW"},
+		{seconds: 11, book: "1 LIMIT_MAKER 1@99.9 CANCELED, 2 LIMIT_MAKER 1@100.89 NEW"},
+		// The third requeue is one too many
+		{seconds: 21, price: "102", book: "1 LIMIT_MAKER 1@99.9 CANCELED, 2 LIMIT_MAKER 1@100.89 CANCELED"},
+	})
+	if p.Requeues != 3 || e.Working("ETHUSDT", 0) {
+		t.Errorf("got %d requeues, working %v, want the parent expired after 3", p.Requeues, e.Working("ETHUSDT", 0))
+	}
+}
+
+func TestStartRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		algo    AlgorithmConfig
+		wantErr bool
+	}{
+		{"rejected limit child fails the order", AlgorithmConfig{Type: TWAPType, DurationSeconds: 50}, true},
+		{"rejected post-only child is requeued", AlgorithmConfig{Type: PostOnlyType}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, x, _ := newParentExecutor(t, tt.algo)
+			x.failLimit, x.crossing = 1, 1
+			_, err := e.Submit(context.Background(), ethusdt, "BUY", "1", "100.1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if running := len(e.parents) == 1; running == tt.wantErr {
+				t.Errorf("parent running %v after the rejection", running)
+			}
+		})
+	}
+}
+
+func TestParentPrices(t *testing.T) {
+	tests := []struct {
+		name     string
+		side     string
+		fills    [][2]string // Quantity and price of the fills
+		average  string
+		slippage string
+	}{
+		{"no fills", "BUY", nil, "0", "0"},
+		{"BUY above the arrival price", "BUY", [][2]string{{"0.5", "101"}, {"0.5", "103"}}, "102", "0.02"},
+		{"BUY below the arrival price", "BUY", [][2]string{{"1", "99"}}, "99", "-0.01"},
+		{"SELL below the arrival price", "SELL", [][2]string{{"0.25", "97"}, {"0.75", "98.5"}}, "98.125", "0.01875"},
+		{"SELL above the arrival price", "SELL", [][2]string{{"1", "101"}}, "101", "-0.01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Parent{Pair: ethusdt, Side: tt.side, Quantity: d("1"), Arrival: d("100")}
+			for _, fill := range tt.fills {
+				p.apply(&Child{}, &models.Order{FilledQty: d(fill[0]), AvgPrice: d(fill[1])})
+			}
+			if !p.AveragePrice().Equal(d(tt.average)) || !p.Slippage().Equal(d(tt.slippage)) {
+				t.Errorf("got average price %s slippage %s, want %s and %s", p.AveragePrice(), p.Slippage(), tt.average, tt.slippage)
+			}
+		})
+	}
+}
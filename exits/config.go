@@ -0,0 +1,73 @@
+package exits
+
+import "binance_bot/utils"
+
+// Target is a step of the take-profit ladder
+type Target struct {
+	Percent  float64 `json:"percent"`  // Gain above the entry price
+	Fraction float64 `json:"fraction"` // Fraction of the position sold at the target
+}
+
+// Config holds the exit rules attached to every open position, a zero value disables a rule
+type Config struct {
+	StopLossPercent  float64  `json:"stop_loss_percent"`  // Stop this percent below the entry price
+	StopLossATR      float64  `json:"stop_loss_atr"`      // Stop this many ATRs below the entry price, the tighter stop wins
+	ATRPeriod        int      `json:"atr_period"`         // Candles in the average true range, 14 when 0
+	TakeProfit       []Target `json:"take_profit"`        // Ladder of partial exits by gain, in ascending order
+	TrailingPercent  float64  `json:"trailing_percent"`   // Stop this percent below the highest price since entry
+	BreakEvenPercent float64  `json:"break_even_percent"` // Move the stop to break-even after this gain
+	MaxHoldingHours  float64  `json:"max_holding_hours"`  // Sell the position after this many hours
+	FeeRate          float64  `json:"-"`                  // Fee of a fill for the break-even price, 0.1% when 0
+}
+
+// Validate checks the exit rules and reports all problems at once
+func (c Config) Validate() error {
+	var v utils.Validation
+
+	v.Check(c.StopLossPercent >= 0 && c.StopLossPercent < 100, "stop_loss_percent: must be between 0 and 100, got %v", c.StopLossPercent)
+	v.Check(c.StopLossATR >= 0, "stop_loss_atr: must not be negative, got %v", c.StopLossATR)
+	v.Check(c.ATRPeriod == 0 || c.ATRPeriod >= 2, "atr_period: must be at least 2, got %d", c.ATRPeriod)
+	v.Check(c.TrailingPercent >= 0 && c.TrailingPercent < 100, "trailing_percent: must be between 0 and 100, got %v", c.TrailingPercent)
+	v.Check(c.BreakEvenPercent >= 0, "break_even_percent: must not be negative, got %v", c.BreakEvenPercent)
+	v.Check(c.MaxHoldingHours >= 0, "max_holding_hours: must not be negative, got %v", c.MaxHoldingHours)
+
+	total, last := 0.0, 0.0
+	for i, target := range c.TakeProfit {
+		v.Check(target.Percent > last, "take_profit[%d].percent: must be positive and above the previous target, got %v", i, target.Percent)
+		v.Check(target.Fraction > 0 && target.Fraction <= 1, "take_profit[%d].fraction: must be above 0 and at most 1, got %v", i, target.Fraction)
+		total += target.Fraction
+		last = target.Percent
+	}
+	v.Check(total <= 1+1e-9, "take_profit: fractions add up to %v, must be at most 1", total)
+
+	return v.Err()
+}
+
+// Empty reports whether the config holds no exit rule
+func (c Config) Empty() bool {
+	return c.StopLossPercent == 0 && c.StopLossATR == 0 && len(c.TakeProfit) == 0 &&
+		c.TrailingPercent == 0 && c.BreakEvenPercent == 0 && c.MaxHoldingHours == 0
+}
+
+// sellsAll reports whether the take-profit ladder sells the whole position
+func (c Config) sellsAll() bool {
+	total := 0.0
+	for _, target := range c.TakeProfit {
+		total += target.Fraction
+	}
+	return total >= 1-1e-9
+}
+
+func (c Config) atrPeriod() int {
+	if c.ATRPeriod == 0 {
+		return 14
+	}
+	return c.ATRPeriod
+}
+
+func (c Config) feeRate() float64 {
+	if c.FeeRate == 0 {
+		return 0.001
+	}
+	return c.FeeRate
+}
@@ -0,0 +1,56 @@
+package exits
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr []string // Parts of the error, empty for a valid config
+	}{
+		{name: "no rules", cfg: Config{}},
+		{
+			name: "every rule",
+			cfg: Config{StopLossPercent: 5, StopLossATR: 2, ATRPeriod: 14, TrailingPercent: 3, BreakEvenPercent: 2, MaxHoldingHours: 48,
+				TakeProfit: []Target{{Percent: 5, Fraction: 0.5}, {Percent: 10, Fraction: 0.5}}},
+		},
+		{
+			name:    "out of range percentages",
+			cfg:     Config{StopLossPercent: 100, TrailingPercent: -1, ATRPeriod: 1},
+			wantErr: []string{"stop_loss_percent", "trailing_percent", "atr_period"},
+		},
+		{
+			name:    "targets out of order",
+			cfg:     Config{TakeProfit: []Target{{Percent: 10, Fraction: 0.5}, {Percent: 5, Fraction: 0.5}}},
+			wantErr: []string{"take_profit[1].percent"},
+		},
+		{
+			name:    "targets sell more than the position",
+			cfg:     Config{TakeProfit: []Target{{Percent: 5, Fraction: 0.6}, {Percent: 10, Fraction: 0.6}}},
+			wantErr: []string{"fractions add up to 1.2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("no error, want %v", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
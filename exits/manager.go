@@ -0,0 +1,372 @@
+package exits
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/indicators"
+	"binance_bot/logger"
+	"binance_bot/models"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CandleSource provides the candles for ATR stops
+type CandleSource interface {
+	FetchCandles(symbol, interval string, limit int) ([]models.CandleStick, error)
+}
+
+// Exit is a sell the exit rules of a position ask for
+type Exit struct {
+	Symbol   string
+	Quantity decimal.Decimal // Quantity to sell
+	Full     bool            // The whole position is sold
+	Reason   string
+	target   int // Next take-profit target once the exit is placed
+}
+
+// Manager attaches the exit rules to the open position of every pair and evaluates them on price
+// updates. Positions follow the lots in the ledger: rules are attached when the first lot opens,
+// the entry is averaged again when lots are added and the rules are dropped once all lots are closed.
+type Manager struct {
+	rules     Config
+	candles   CandleSource
+	interval  string
+	working   func(symbol string, orderID int64) bool // Reports whether an order can still fill
+	store     *db2.StateStore                         // Positions survive restarts
+	positions map[string]*position
+	signals   map[string]*signal
+	mu        sync.Mutex
+}
+
+// Levels are the prices a protective order on the exchange should rest at for an open position
+type Levels struct {
+	Position   decimal.Decimal // Open quantity of the position
+	Stop       decimal.Decimal // Stop-loss or trailing stop, whichever is higher, 0 for none
+	TakeProfit decimal.Decimal // Price the whole position is sold at, 0 for none
+}
+
+// position is the exit bookkeeping of the open position of a pair
+type position struct {
+	entry      decimal.Decimal // Average entry price of the open lots
+	quantity   decimal.Decimal // Open quantity at the last sync
+	base       decimal.Decimal // Quantity the take-profit ladder is sized from
+	high       decimal.Decimal // Highest price since entry
+	stop       decimal.Decimal // Stop price, 0 for none
+	takeProfit decimal.Decimal // Take-profit price suggested by the BUY signal, 0 for none
+	signalStop decimal.Decimal // Stop price suggested by the BUY signal, 0 for none
+	target     int             // Next take-profit target
+	breakEven  bool            // The stop was moved to break-even
+	opened     time.Time
+}
+
+// signal holds the levels of a BUY signal until the lots of its order open
+type signal struct {
+	orderID    int64
+	stopLoss   decimal.Decimal
+	takeProfit decimal.Decimal
+	applied    bool // Lots of the order opened with the levels
+}
+
+// NewManager creates an exit manager, candles are fetched for ATR stops. working reports whether
+// the BUY order of a signal, or the order that replaced it, can still fill.
+func NewManager(rules Config, candles CandleSource, interval string, working func(symbol string, orderID int64) bool) *Manager {
+	return &Manager{
+		rules:     rules,
+		candles:   candles,
+		interval:  interval,
+		working:   working,
+		store:     db2.NewStateStore("exits"),
+		positions: make(map[string]*position),
+		signals:   make(map[string]*signal),
+	}
+}
+
+// SetLevels keeps the stop-loss and take-profit prices suggested by a BUY signal for the lots its
+// order opens, they replace the stop of the rules. Zero leaves a level to the rules. The levels
+// replace those of an earlier signal and are dropped when the order ends without a fill.
+func (m *Manager) SetLevels(symbol string, orderID int64, stopLoss, takeProfit decimal.Decimal) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := &signal{orderID: orderID, stopLoss: stopLoss, takeProfit: takeProfit}
+	m.signals[symbol] = s
+	m.saveSignal(symbol, s)
+}
+
+// Evaluate returns the exit the rules ask for at the current price, nil to hold the position
+func (m *Manager) Evaluate(symbol string, price decimal.Decimal, now time.Time) (*Exit, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, err := m.sync(symbol, now)
+	if err != nil || p == nil || !price.IsPositive() || !p.entry.IsPositive() {
+		return nil, err
+	}
+	defer m.save(symbol, p)
+
+	p.high = decimal.Max(p.high, price)
+	gain := price.Sub(p.entry).Div(p.entry).Mul(hundred)
+	if m.rules.BreakEvenPercent > 0 && !p.breakEven && gain.GreaterThanOrEqual(decimal.NewFromFloat(m.rules.BreakEvenPercent)) {
+		p.breakEven = true
+		if breakEven := p.entry.Mul(decimal.NewFromFloat(1 + 2*m.rules.feeRate())); breakEven.GreaterThan(p.stop) {
+			p.stop = breakEven
+			logger.Infof("Moved the stop of %s to break-even at %s", symbol, breakEven)
+		}
+	}
+
+	full := func(reason string, args ...interface{}) *Exit {
+		return &Exit{Symbol: symbol, Quantity: p.quantity, Full: true, Reason: fmt.Sprintf(reason, args...), target: p.target}
+	}
+
+	if m.rules.MaxHoldingHours > 0 && now.Sub(p.opened).Hours() >= m.rules.MaxHoldingHours {
+		return full("held for %s, the maximum is %vh", now.Sub(p.opened).Round(time.Minute), m.rules.MaxHoldingHours), nil
+	}
+	if m.rules.TrailingPercent > 0 {
+		if trail := m.trailingStop(p); trail.GreaterThan(p.stop) && price.LessThanOrEqual(trail) {
+			return full("trailing stop %s reached, %.2f%% below the high of %s", trail, m.rules.TrailingPercent, p.high), nil
+		}
+	}
+	if p.stop.IsPositive() && price.LessThanOrEqual(p.stop) {
+		return full("stop-loss %s reached", p.stop), nil
+	}
+	if p.takeProfit.IsPositive() && price.GreaterThanOrEqual(p.takeProfit) {
+		return full("take-profit %s reached", p.takeProfit), nil
+	}
+
+	// Sell the share of every target the price passed, the last target sells what is left
+	quantity, target := decimal.Zero, p.target
+	for ; target < len(m.rules.TakeProfit) && gain.GreaterThanOrEqual(decimal.NewFromFloat(m.rules.TakeProfit[target].Percent)); target++ {
+		quantity = quantity.Add(p.base.Mul(decimal.NewFromFloat(m.rules.TakeProfit[target].Fraction)))
+	}
+	if target == p.target {
+		return nil, nil
+	}
+	exit := &Exit{
+		Symbol:   symbol,
+		Quantity: decimal.Min(quantity, p.quantity),
+		Full:     quantity.GreaterThanOrEqual(p.quantity) || target == len(m.rules.TakeProfit) && m.rules.sellsAll(),
+		Reason:   fmt.Sprintf("take-profit target %d at +%.2f%% reached", target, m.rules.TakeProfit[target-1].Percent),
+		target:   target,
+	}
+	if exit.Full {
+		exit.Quantity = p.quantity
+	}
+	return exit, nil
+}
+
+// Levels returns the stop and take-profit of the open position of a pair as of the last evaluation,
+// false when no position is open. Partial take-profit targets are left to the bot.
+func (m *Manager) Levels(symbol string) (Levels, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.positions[symbol]
+	if !ok || p.opened.IsZero() || !p.quantity.IsPositive() {
+		return Levels{}, false
+	}
+
+	levels := Levels{Position: p.quantity, Stop: p.stop, TakeProfit: p.takeProfit}
+	if m.rules.TrailingPercent > 0 {
+		levels.Stop = decimal.Max(levels.Stop, m.trailingStop(p))
+	}
+	if levels.TakeProfit.IsZero() && p.target < len(m.rules.TakeProfit) {
+		// Only a target that sells all that is left can rest on the exchange
+		if target := m.rules.TakeProfit[p.target]; (p.target == len(m.rules.TakeProfit)-1 && m.rules.sellsAll()) || target.Fraction >= 1 {
+			levels.TakeProfit = p.entry.Mul(decimal.NewFromFloat(1 + target.Percent/100))
+		}
+	}
+	return levels, true
+}
+
+// hundred turns fractions into percentages
+var hundred = decimal.NewFromInt(100)
+
+// trailingStop returns the trailing stop of a position below its highest price
+func (m *Manager) trailingStop(p *position) decimal.Decimal {
+	return p.high.Mul(decimal.NewFromFloat(1 - m.rules.TrailingPercent/100))
+}
+
+// Executed records that the order of an exit was placed, so a take-profit target is not sold twice
+func (m *Manager) Executed(exit *Exit) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if p, ok := m.positions[exit.Symbol]; ok && exit.target > p.target {
+		p.target = exit.target
+		m.save(exit.Symbol, p)
+	}
+}
+
+// sync aligns the position of a pair with its lots in the ledger, nil when no lots are open
+func (m *Manager) sync(symbol string, now time.Time) (*position, error) {
+	lots, err := db2.SQLiteDB.GetActiveTrades(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching lots of %s: %v", symbol, err)
+	}
+	quantity, cost := decimal.Zero, decimal.Zero
+	for _, lot := range lots {
+		quantity = quantity.Add(lot.Quantity)
+		cost = cost.Add(lot.Quantity.Mul(lot.BuyPrice))
+	}
+
+	p, s := m.load(symbol), m.loadSignal(symbol)
+	if !quantity.IsPositive() {
+		if !p.opened.IsZero() {
+			m.clear(symbol)
+		}
+		m.expireSignal(symbol, s)
+		return nil, nil
+	}
+
+	switch {
+	case p.opened.IsZero():
+		// New position
+		p.opened = now
+		p.high = decimal.Zero
+		fallthrough
+	case quantity.GreaterThan(p.quantity):
+		// Lots were added, the levels of the signal of the new entry replace those of the last one
+		p.signalStop, p.takeProfit = decimal.Zero, decimal.Zero
+		if s != nil {
+			p.signalStop, p.takeProfit = s.stopLoss, s.takeProfit
+			if !s.applied {
+				s.applied = true
+				m.saveSignal(symbol, s)
+			}
+		}
+
+		// Attach the rules to the new average entry
+		p.entry = cost.Div(quantity)
+		p.base, p.target, p.breakEven = quantity, 0, false
+		p.high = decimal.Max(p.high, p.entry)
+		p.stop = m.initialStop(symbol, p)
+		logger.Infof("Attached exit rules to %s %s at %s, stop %s", quantity, symbol, p.entry, p.stop)
+	default:
+		m.expireSignal(symbol, s)
+	}
+	p.quantity = quantity
+	return p, nil
+}
+
+// expireSignal drops the levels of a signal once its order can no longer fill
+func (m *Manager) expireSignal(symbol string, s *signal) {
+	if s == nil || m.working(symbol, s.orderID) {
+		return
+	}
+	if !s.applied {
+		logger.Infof("Dropped the levels of the BUY signal of %s, order %d ended without a fill", symbol, s.orderID)
+	}
+	delete(m.signals, symbol)
+	for _, key := range []string{"signal_order", "signal_stop_loss", "signal_take_profit", "signal_applied"} {
+		m.store.Delete(symbol, key)
+	}
+}
+
+// initialStop returns the stop of a position, the stop of the BUY signal replaces the rules
+func (m *Manager) initialStop(symbol string, p *position) decimal.Decimal {
+	if p.signalStop.IsPositive() {
+		return p.signalStop
+	}
+
+	stop := decimal.Zero
+	if m.rules.StopLossPercent > 0 {
+		stop = p.entry.Mul(decimal.NewFromFloat(1 - m.rules.StopLossPercent/100))
+	}
+	if m.rules.StopLossATR > 0 {
+		candles, err := m.candles.FetchCandles(symbol, m.interval, 100)
+		if err == nil {
+			var atr []float64
+			if atr, err = indicators.ATRSeries(candles, m.rules.atrPeriod()); err == nil {
+				stop = decimal.Max(stop, p.entry.Sub(decimal.NewFromFloat(m.rules.StopLossATR*atr[len(atr)-1])))
+			}
+		}
+		if err != nil {
+			logger.Warnf("No ATR stop for %s: %v", symbol, err)
+		}
+	}
+	return stop
+}
+
+// load returns the position of a pair, restoring it from the state store on first use
+func (m *Manager) load(symbol string) *position {
+	if p, ok := m.positions[symbol]; ok {
+		return p
+	}
+
+	p := &position{}
+	p.entry, _ = m.store.GetDecimal(symbol, "entry")
+	p.high, _ = m.store.GetDecimal(symbol, "high")
+	p.stop, _ = m.store.GetDecimal(symbol, "stop")
+	p.takeProfit, _ = m.store.GetDecimal(symbol, "take_profit")
+	p.signalStop, _ = m.store.GetDecimal(symbol, "signal_stop")
+	p.target, _ = m.store.GetInt(symbol, "target")
+	if value, ok := m.store.Get(symbol, "quantity"); ok {
+		p.quantity, _ = decimal.NewFromString(value)
+	}
+	if value, ok := m.store.Get(symbol, "base"); ok {
+		p.base, _ = decimal.NewFromString(value)
+	}
+	if value, ok := m.store.Get(symbol, "break_even"); ok {
+		p.breakEven, _ = strconv.ParseBool(value)
+	}
+	if opened, ok := m.store.GetInt(symbol, "opened"); ok && opened > 0 {
+		p.opened = time.Unix(int64(opened), 0)
+	}
+	m.positions[symbol] = p
+	return p
+}
+
+// loadSignal returns the pending signal of a pair, restoring it from the state store on first use.
+// It returns nil when there is none.
+func (m *Manager) loadSignal(symbol string) *signal {
+	if s, ok := m.signals[symbol]; ok {
+		return s
+	}
+
+	var s *signal
+	if orderID, ok := m.store.Get(symbol, "signal_order"); ok {
+		s = &signal{}
+		s.orderID, _ = strconv.ParseInt(orderID, 10, 64)
+		s.stopLoss, _ = m.store.GetDecimal(symbol, "signal_stop_loss")
+		s.takeProfit, _ = m.store.GetDecimal(symbol, "signal_take_profit")
+		if value, ok := m.store.Get(symbol, "signal_applied"); ok {
+			s.applied, _ = strconv.ParseBool(value)
+		}
+	}
+	m.signals[symbol] = s
+	return s
+}
+
+// saveSignal checkpoints the pending signal of a pair
+func (m *Manager) saveSignal(symbol string, s *signal) {
+	m.store.Set(symbol, "signal_order", strconv.FormatInt(s.orderID, 10))
+	m.store.SetDecimal(symbol, "signal_stop_loss", s.stopLoss)
+	m.store.SetDecimal(symbol, "signal_take_profit", s.takeProfit)
+	m.store.Set(symbol, "signal_applied", strconv.FormatBool(s.applied))
+}
+
+// save checkpoints the position of a pair, unchanged values are not written
+func (m *Manager) save(symbol string, p *position) {
+	m.store.SetDecimal(symbol, "entry", p.entry)
+	m.store.SetDecimal(symbol, "high", p.high)
+	m.store.SetDecimal(symbol, "stop", p.stop)
+	m.store.SetDecimal(symbol, "take_profit", p.takeProfit)
+	m.store.SetDecimal(symbol, "signal_stop", p.signalStop)
+	m.store.SetInt(symbol, "target", p.target)
+	m.store.Set(symbol, "quantity", p.quantity.String())
+	m.store.Set(symbol, "base", p.base.String())
+	m.store.Set(symbol, "break_even", strconv.FormatBool(p.breakEven))
+	if !p.opened.IsZero() {
+		m.store.SetInt(symbol, "opened", int(p.opened.Unix()))
+	}
+}
+
+// clear drops the position of a pair once all its lots are closed
+func (m *Manager) clear(symbol string) {
+	delete(m.positions, symbol)
+	for _, key := range []string{"entry", "high", "stop", "take_profit", "signal_stop", "target", "quantity", "base", "break_even", "opened"} {
+		m.store.Delete(symbol, key)
+	}
+}
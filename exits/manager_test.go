@@ -0,0 +1,206 @@
+package exits
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/models"
+	"github.com/shopspring/decimal"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flatCandles is a candle source whose candles all range from 99 to 101, an ATR of 2
+type flatCandles struct{}
+
+func (flatCandles) FetchCandles(symbol, interval string, limit int) ([]models.CandleStick, error) {
+	candles := make([]models.CandleStick, limit)
+	for i := range candles {
+		candles[i] = models.CandleStick{Open: 100, High: 101, Low: 99, Close: 100}
+	}
+	return candles, nil
+}
+
+// step buys a lot when buy is set, then evaluates the rules at a price some hours after the start
+type step struct {
+	buy   [2]string // Quantity and price of a lot opened before the evaluation
+	price string
+	hours float64 // The position opens at the first evaluation
+	exit  string  // Part of the reason of the expected exit, empty to hold
+	qty   string  // Quantity of the expected exit
+	full  bool
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  Config
+		signal [2]string // Stop-loss and take-profit of the BUY signal, set before the first lot opens
+		steps  []step
+	}{
+		{
+			name:  "stop-loss below the entry",
+			rules: Config{StopLossPercent: 5},
+			steps: []step{
+				{buy: [2]string{"1", "100"}, price: "96"},
+				{price: "95", exit: "stop-loss 95 reached", qty: "1", full: true},
+			},
+		},
+		{
+			// The stop 2 ATRs below the entry is tighter than the 5% stop
+			name:  "ATR stop",
+			rules: Config{StopLossPercent: 5, StopLossATR: 2, ATRPeriod: 7},
+			steps: []step{
+				{buy: [2]string{"1", "100"}, price: "96.5"},
+				{price: "96", exit: "stop-loss 96", qty: "1", full: true},
+			},
+		},
+		{
+			name:  "trailing stop follows the high",
+			rules: Config{TrailingPercent: 10},
+			steps: []step{
+				{buy: [2]string{"1", "100"}, price: "120"},
+				{price: "109"},
+				{price: "108", exit: "trailing stop 108 reached", qty: "1", full: true},
+			},
+		},
+		{
+			// The stop moves to the entry plus the fees of both fills
+			name:  "break-even",
+			rules: Config{StopLossPercent: 5, BreakEvenPercent: 3, FeeRate: 0.001},
+			steps: []step{
+				{buy: [2]string{"1", "100"}, price: "103"},
+				{price: "100.3"},
+				{price: "100.2", exit: "stop-loss 100.2 reached", qty: "1", full: true},
+			},
+		},
+		{
+			name:  "take-profit ladder sells each target once",
+			rules: Config{TakeProfit: []Target{{Percent: 5, Fraction: 0.5}, {Percent: 10, Fraction: 0.5}}},
+			steps: []step{
+				{buy: [2]string{"2", "100"}, price: "104"},
+				{price: "105", exit: "target 1 at +5.00%", qty: "1"},
+				{price: "108"},
+				{price: "110", exit: "target 2 at +10.00%", qty: "1", full: true},
+			},
+		},
+		{
+			name:  "price past several targets sells them together",
+			rules: Config{TakeProfit: []Target{{Percent: 5, Fraction: 0.25}, {Percent: 10, Fraction: 0.25}, {Percent: 20, Fraction: 0.5}}},
+			steps: []step{
+				{buy: [2]string{"4", "100"}, price: "112", exit: "target 2 at +10.00%", qty: "2"},
+				{price: "125", exit: "target 3 at +20.00%", qty: "2", full: true},
+			},
+		},
+		{
+			name:  "time exit",
+			rules: Config{MaxHoldingHours: 24},
+			steps: []step{
+				{buy: [2]string{"1", "100"}, price: "100"},
+				{price: "100", hours: 23.5},
+				{price: "100", hours: 24, exit: "held for 24h0m0s, the maximum is 24h", qty: "1", full: true},
+			},
+		},
+		{
+			name:  "added lot averages the entry",
+			rules: Config{StopLossPercent: 10},
+			steps: []step{
+				{buy: [2]string{"1", "100"}, price: "95"},
+				{buy: [2]string{"1", "80"}, price: "82"},
+				{price: "81", exit: "stop-loss 81 reached", qty: "2", full: true},
+			},
+		},
+		{
+			// The stop of the signal replaces the 5% stop of the rules
+			name:   "levels of the BUY signal",
+			rules:  Config{StopLossPercent: 5},
+			signal: [2]string{"90", "130"},
+			steps: []step{
+				{buy: [2]string{"1", "100"}, price: "91"},
+				{price: "130", exit: "take-profit 130 reached", qty: "1", full: true},
+			},
+		},
+	}
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := db2.InitDBAt(t.TempDir() + "/exits.db"); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db2.SQLiteDB.DB.Close() })
+
+			m := NewManager(tt.rules, flatCandles{}, "1h", func(string, int64) bool { return true })
+			if tt.signal[0] != "" {
+				m.SetLevels("ETHUSDT", 1, decimal.RequireFromString(tt.signal[0]), decimal.RequireFromString(tt.signal[1]))
+			}
+
+			for i, s := range tt.steps {
+				if s.buy[0] != "" {
+					if err := db2.SQLiteDB.LogActiveTrade("ETHUSDT", decimal.RequireFromString(s.buy[1]), decimal.RequireFromString(s.buy[0])); err != nil {
+						t.Fatal(err)
+					}
+				}
+				now := start.Add(time.Duration(s.hours * float64(time.Hour)))
+				exit, err := m.Evaluate("ETHUSDT", decimal.RequireFromString(s.price), now)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				switch {
+				case s.exit == "" && exit != nil:
+					t.Fatalf("step %d at %s: got exit %q, want to hold", i, s.price, exit.Reason)
+				case s.exit == "":
+					continue
+				case exit == nil:
+					t.Fatalf("step %d at %s: holding, want exit %q", i, s.price, s.exit)
+				}
+				if !strings.Contains(exit.Reason, s.exit) || !exit.Quantity.Equal(decimal.RequireFromString(s.qty)) || exit.Full != s.full {
+					t.Fatalf("step %d at %s: got %q of %s full %v, want %q of %s full %v", i, s.price, exit.Reason, exit.Quantity, exit.Full, s.exit, s.qty, s.full)
+				}
+
+				// Fill the exit so the next step sees the remaining lots
+				m.Executed(exit)
+				if _, err := db2.SQLiteDB.CloseLots(&models.Order{Symbol: "ETHUSDT"}, decimal.RequireFromString(s.price), exit.Quantity, decimal.Zero); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestLevels(t *testing.T) {
+	if err := db2.InitDBAt(t.TempDir() + "/exits.db"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db2.SQLiteDB.DB.Close() })
+
+	rules := Config{StopLossPercent: 5, TrailingPercent: 10, TakeProfit: []Target{{Percent: 25, Fraction: 1}}}
+	m := NewManager(rules, flatCandles{}, "1h", func(string, int64) bool { return false })
+	if _, ok := m.Levels("ETHUSDT"); ok {
+		t.Fatal("levels without an open position")
+	}
+
+	if err := db2.SQLiteDB.LogActiveTrade("ETHUSDT", decimal.NewFromInt(100), decimal.NewFromInt(1)); err != nil {
+		t.Fatal(err)
+	}
+	if exit, err := m.Evaluate("ETHUSDT", decimal.NewFromInt(120), time.Now()); exit != nil || err != nil {
+		t.Fatalf("got exit %v %v, want to hold", exit, err)
+	}
+
+	// The trailing stop is above the 5% stop
+	levels, ok := m.Levels("ETHUSDT")
+	if !ok || !levels.Position.Equal(decimal.NewFromInt(1)) || !levels.Stop.Equal(decimal.NewFromInt(108)) || !levels.TakeProfit.Equal(decimal.NewFromInt(125)) {
+		t.Fatalf("got levels %+v %v, want stop 108 and take-profit 125 for 1", levels, ok)
+	}
+
+	// The rules are dropped once the lots are closed
+	if _, err := db2.SQLiteDB.CloseLots(&models.Order{Symbol: "ETHUSDT"}, decimal.NewFromInt(110), decimal.NewFromInt(1), decimal.Zero); err != nil {
+		t.Fatal(err)
+	}
+	if exit, err := m.Evaluate("ETHUSDT", decimal.NewFromInt(110), time.Now()); exit != nil || err != nil {
+		t.Fatalf("got exit %v %v without an open position", exit, err)
+	}
+	if levels, ok := m.Levels("ETHUSDT"); ok {
+		t.Fatalf("got levels %+v after the lots closed", levels)
+	}
+}
@@ -4,13 +4,14 @@ go 1.23
 
 require (
 	github.com/adshao/go-binance/v2 v2.6.1
+	github.com/gorilla/websocket v1.5.3
 	github.com/joho/godotenv v1.5.1
 	github.com/mattn/go-sqlite3 v1.14.24
+	github.com/shopspring/decimal v1.4.0
 )
 
 require (
 	github.com/bitly/go-simplejson v0.5.1 // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
@@ -0,0 +1,420 @@
+package grid
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/interfaces"
+	"binance_bot/logger"
+	"binance_bot/models"
+	"binance_bot/utils"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"math"
+)
+
+// Spacings of the grid prices
+const (
+	ArithmeticSpacing = "arithmetic"
+	GeometricSpacing  = "geometric"
+)
+
+// maxLevels keeps the orders of a grid below the open order limit of a symbol on Binance
+const maxLevels = 200
+
+// Settings place a grid between two prices. A grid of n levels has n-1 slots, each holding a BUY
+// at its lower price or a SELL at its upper price.
+type Settings struct {
+	Lower         float64 `json:"lower"`           // Lowest price of the grid
+	Upper         float64 `json:"upper"`           // Highest price of the grid
+	Levels        int     `json:"levels"`          // Prices from lower to upper, both included
+	Spacing       string  `json:"spacing"`         // arithmetic for equal steps or geometric for equal ratios, arithmetic when empty
+	QuotePerLevel float64 `json:"quote_per_level"` // Quote amount of the order of every slot
+}
+
+// Validate checks the settings of a grid and reports all problems at once
+func (s Settings) Validate() error {
+	var v utils.Validation
+
+	v.Check(s.Lower > 0, "lower: must be positive, got %v", s.Lower)
+	v.Check(s.Upper > s.Lower, "upper: must be above lower (%v), got %v", s.Lower, s.Upper)
+	v.Check(s.Levels >= 2 && s.Levels <= maxLevels, "levels: must be between 2 and %d, got %d", maxLevels, s.Levels)
+	v.Check(s.Spacing == "" || s.Spacing == ArithmeticSpacing || s.Spacing == GeometricSpacing, "spacing: must be %q, %q or empty, got %q", ArithmeticSpacing, GeometricSpacing, s.Spacing)
+	v.Check(s.QuotePerLevel > 0, "quote_per_level: must be positive, got %v", s.QuotePerLevel)
+
+	return v.Err()
+}
+
+func (s Settings) spacing() string {
+	if s.Spacing == "" {
+		return ArithmeticSpacing
+	}
+	return s.Spacing
+}
+
+// Prices returns the prices of the grid from the lower to the upper bound
+func (s Settings) Prices() []float64 {
+	prices := make([]float64, s.Levels)
+	for i := range prices {
+		step := float64(i) / float64(s.Levels-1)
+		if s.spacing() == GeometricSpacing {
+			prices[i] = s.Lower * math.Pow(s.Upper/s.Lower, step)
+		} else {
+			prices[i] = s.Lower + (s.Upper-s.Lower)*step
+		}
+	}
+	prices[0], prices[len(prices)-1] = s.Lower, s.Upper
+	return prices
+}
+
+// Config holds the grids of the pairs traded by limit orders instead of the strategy
+type Config struct {
+	Pairs map[string]Settings `json:"pairs"` // Grid per symbol, pairs without one follow the strategy
+}
+
+// Validate checks the grids of the symbols
+func (c Config) Validate() error {
+	var v utils.Validation
+	for symbol, settings := range c.Pairs {
+		v.Add("pairs."+symbol, settings.Validate())
+	}
+	return v.Err()
+}
+
+// Grid keeps the ladder of limit orders of a pair on the exchange. When the BUY of a slot fills a
+// SELL of the bought quantity is placed one level up, and when that fills the BUY is placed again.
+// The grid stops and cancels its orders once the price leaves its range. Its state is kept in
+// SQLite, so a restarted bot resumes the orders it left on the exchange.
+type Grid struct {
+	settings  Settings
+	exchange  interfaces.ExchangeClient
+	orders    interfaces.OrderTracker
+	available func(asset string) (decimal.Decimal, error)                       // Free balance of an asset
+	allow     func(pair *models.TradingPair, side, quantity, price string) bool // Portfolio limits of a BUY
+	loaded    bool                                                              // Whether the stored grid was looked up
+	state     *models.Grid                                                      // nil until the grid started
+	levels    []*models.GridLevel
+}
+
+// New creates the grid of a pair. Its orders are handed to the tracker, sized from the free
+// balance reported by available and BUYs are only placed when allow accepts them.
+func New(settings Settings, exchange interfaces.ExchangeClient, orders interfaces.OrderTracker,
+	available func(asset string) (decimal.Decimal, error), allow func(pair *models.TradingPair, side, quantity, price string) bool) *Grid {
+	return &Grid{
+		settings:  settings,
+		exchange:  exchange,
+		orders:    orders,
+		available: available,
+		allow:     allow,
+	}
+}
+
+// Stopped reports whether the price left the range of the grid
+func (g *Grid) Stopped() bool {
+	return g.state != nil && g.state.Status == models.GridStopped
+}
+
+// Step moves the grid along with the price: it starts the grid once the price is in range,
+// refills the slots whose orders ended and stops the grid when the price left the range
+func (g *Grid) Step(pair *models.TradingPair, price decimal.Decimal) error {
+	if !g.loaded {
+		if err := g.load(pair); err != nil {
+			return err
+		}
+		g.loaded = true
+	}
+	if g.Stopped() {
+		return nil
+	}
+
+	if price.LessThan(decimal.NewFromFloat(g.settings.Lower)) || price.GreaterThan(decimal.NewFromFloat(g.settings.Upper)) {
+		if g.state == nil {
+			logger.Debugf("Price of %s (%s) is outside the grid range %v - %v, waiting to start", pair.Symbol, price, g.settings.Lower, g.settings.Upper)
+			return nil
+		}
+		return g.stop(pair, price)
+	}
+	if g.state == nil {
+		return g.start(pair, price)
+	}
+
+	var errs []error
+	for _, level := range g.levels {
+		if err := g.refill(pair, level); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// load resumes the grid stored for a pair with the same settings. A stored grid with other
+// settings is stopped, so a new one starts in its place.
+func (g *Grid) load(pair *models.TradingPair) error {
+	stored, err := db2.SQLiteDB.GetLatestGrid(pair.Symbol)
+	if err != nil || stored == nil {
+		return err
+	}
+
+	if !g.matches(stored) {
+		if stored.Status != models.GridActive {
+			return nil
+		}
+		levels, err := db2.SQLiteDB.GetGridLevels(stored.ID)
+		if err != nil {
+			return err
+		}
+		logger.Infof("Grid settings of %s changed, canceling the orders of grid %d", pair.Symbol, stored.ID)
+		if err := g.cancel(pair, levels); err != nil {
+			return err
+		}
+		return g.finish(stored)
+	}
+
+	g.state = stored
+	if stored.Status != models.GridActive {
+		logger.Infof("Grid of %s stopped on %s, change its settings to start a new one", pair.Symbol, stored.UpdatedAt.Format("2006-01-02 15:04"))
+		return nil
+	}
+	if g.levels, err = db2.SQLiteDB.GetGridLevels(stored.ID); err != nil {
+		return err
+	}
+	logger.Infof("Resumed grid %d of %s with %d slots", stored.ID, pair.Symbol, len(g.levels))
+	return nil
+}
+
+// matches reports whether a stored grid was started with the settings of the grid
+func (g *Grid) matches(stored *models.Grid) bool {
+	return stored.Lower.Equal(decimal.NewFromFloat(g.settings.Lower)) &&
+		stored.Upper.Equal(decimal.NewFromFloat(g.settings.Upper)) &&
+		stored.Levels == g.settings.Levels &&
+		stored.Spacing == g.settings.spacing() &&
+		stored.QuotePerLevel.Equal(decimal.NewFromFloat(g.settings.QuotePerLevel))
+}
+
+// start lays out the slots around the price, buys the base the SELLs above the price need when
+// the position of the pair does not cover it and places the orders of every slot
+func (g *Grid) start(pair *models.TradingPair, price decimal.Decimal) error {
+	prices := g.settings.Prices()
+	levels := make([]*models.GridLevel, 0, len(prices)-1)
+	needed := decimal.Zero
+	for i := 0; i < len(prices)-1; i++ {
+		level := &models.GridLevel{
+			Level:     i,
+			BuyPrice:  decimal.RequireFromString(pair.FormatPrice(decimal.NewFromFloat(prices[i]))),
+			SellPrice: decimal.RequireFromString(pair.FormatPrice(decimal.NewFromFloat(prices[i+1]))),
+			Side:      "BUY",
+		}
+		level.Quantity = g.quantity(pair, level)
+		if decimal.NewFromFloat(prices[i]).GreaterThanOrEqual(price) {
+			level.Side = "SELL"
+			needed = needed.Add(level.Quantity)
+		}
+		levels = append(levels, level)
+	}
+
+	if err := g.stock(pair, needed, price); err != nil {
+		return err
+	}
+
+	state := &models.Grid{
+		Symbol:        pair.Symbol,
+		Lower:         decimal.NewFromFloat(g.settings.Lower),
+		Upper:         decimal.NewFromFloat(g.settings.Upper),
+		Levels:        g.settings.Levels,
+		Spacing:       g.settings.spacing(),
+		QuotePerLevel: decimal.NewFromFloat(g.settings.QuotePerLevel),
+		Status:        models.GridActive,
+	}
+	if err := db2.SQLiteDB.LogGrid(state); err != nil {
+		return err
+	}
+	for _, level := range levels {
+		level.GridID = state.ID
+		if err := db2.SQLiteDB.SaveGridLevel(level); err != nil {
+			return err
+		}
+	}
+	g.state, g.levels = state, levels
+	logger.Infof("Started %s grid %d of %s: %d levels from %v to %v, %v per level at price %s",
+		state.Spacing, state.ID, pair.Symbol, state.Levels, g.settings.Lower, g.settings.Upper, g.settings.QuotePerLevel, price)
+
+	var errs []error
+	for _, level := range g.levels {
+		if err := g.place(pair, level); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// quantity returns the quantity the BUY of a slot buys for the quote amount per level
+func (g *Grid) quantity(pair *models.TradingPair, level *models.GridLevel) decimal.Decimal {
+	if !level.BuyPrice.IsPositive() {
+		return decimal.Zero
+	}
+	return decimal.RequireFromString(pair.FormatQty(decimal.NewFromFloat(g.settings.QuotePerLevel).Div(level.BuyPrice)))
+}
+
+// stock buys the part of the needed base the position of the pair does not hold at market
+func (g *Grid) stock(pair *models.TradingPair, needed decimal.Decimal, price decimal.Decimal) error {
+	held, err := db2.SQLiteDB.SellableQuantity(pair.Symbol)
+	if err != nil {
+		return fmt.Errorf("error fetching %s position: %v", pair.Symbol, err)
+	}
+	free, err := g.available(pair.BaseAsset)
+	if err != nil {
+		return fmt.Errorf("error fetching %s balance: %v", pair.BaseAsset, err)
+	}
+	shortfall := needed.Sub(decimal.Min(held, free))
+	if shortfall.Mul(price).LessThan(pair.MinNotional) {
+		return nil
+	}
+
+	quantity := pair.FormatQty(shortfall)
+	if !g.allow(pair, "BUY", quantity, pair.FormatPrice(price)) {
+		return fmt.Errorf("cannot buy the %s %s the SELLs of the grid need", quantity, pair.BaseAsset)
+	}
+	order, err := g.exchange.CreateMarketOrder(pair.Symbol, "BUY", quantity)
+	if err != nil {
+		return fmt.Errorf("error buying the %s %s the SELLs of the grid need: %v", quantity, pair.BaseAsset, err)
+	}
+	logger.Infof("Bought %s %s for the SELLs of the grid of %s. Order ID: %d Average price: %s", order.FilledQty, pair.BaseAsset, pair.Symbol, order.OrderID, order.AvgPrice)
+	if err := g.orders.Track(order); err != nil {
+		logger.Errorf("Error tracking grid BUY order for %s: %v", pair.Symbol, err)
+	}
+	return nil
+}
+
+// refill turns a slot whose order ended to its next side and places the order of a slot
+// without one
+func (g *Grid) refill(pair *models.TradingPair, level *models.GridLevel) error {
+	if level.OrderID != 0 {
+		order, err := db2.SQLiteDB.GetOrder(pair.Symbol, level.OrderID)
+		if err != nil {
+			return err
+		}
+		if order.IsOpen() {
+			return nil
+		}
+		g.filled(pair, level, order)
+		if err := db2.SQLiteDB.SaveGridLevel(level); err != nil {
+			return err
+		}
+	}
+	return g.place(pair, level)
+}
+
+// filled moves a slot on from its ended order. A filled BUY is sold one level up and a filled
+// SELL is bought back, a partially filled SELL places the rest again.
+func (g *Grid) filled(pair *models.TradingPair, level *models.GridLevel, order *models.Order) {
+	level.OrderID = 0
+	switch {
+	case order.Side == "BUY" && order.FilledQty.IsPositive():
+		level.Side, level.Quantity = "SELL", order.FilledQty
+		if order.CommissionAsset == pair.BaseAsset {
+			level.Quantity = level.Quantity.Sub(order.Commission)
+		}
+		logger.Infof("Grid BUY of %s %s filled at %s, selling at %s", order.FilledQty, pair.Symbol, order.AvgPrice, level.SellPrice)
+	case order.Side == "SELL" && !order.Remaining().IsPositive():
+		level.Side, level.Quantity = "BUY", g.quantity(pair, level)
+		logger.Infof("Grid SELL of %s %s filled at %s, buying back at %s", order.FilledQty, pair.Symbol, order.AvgPrice, level.BuyPrice)
+	case order.Side == "SELL" && order.FilledQty.IsPositive():
+		level.Quantity = order.Remaining()
+	}
+}
+
+// place puts the order of a slot on the exchange. A slot the balance or the minimum notional
+// does not allow an order for is tried again on the next step.
+func (g *Grid) place(pair *models.TradingPair, level *models.GridLevel) error {
+	if level.OrderID != 0 {
+		return nil
+	}
+
+	price, asset := level.BuyPrice, pair.QuoteAsset
+	if level.Side == "SELL" {
+		price, asset = level.SellPrice, pair.BaseAsset
+	}
+	free, err := g.available(asset)
+	if err != nil {
+		return fmt.Errorf("error fetching %s balance: %v", asset, err)
+	}
+	qty := level.Quantity
+	if level.Side == "SELL" {
+		qty = decimal.Min(qty, free)
+	} else if qty.Mul(price).GreaterThan(free) {
+		logger.Debugf("Insufficient %s balance for grid BUY of %s at %s", asset, pair.Symbol, price)
+		return nil
+	}
+	quantity, limit := pair.FormatQty(qty), pair.FormatPrice(price)
+	if notional := decimal.RequireFromString(quantity).Mul(price); !notional.IsPositive() || notional.LessThan(pair.MinNotional) {
+		logger.Debugf("Grid %s of %s %s at %s is below the minimum notional", level.Side, quantity, pair.Symbol, limit)
+		return nil
+	}
+	if level.Side == "BUY" && !g.allow(pair, level.Side, quantity, limit) {
+		return nil
+	}
+
+	orderID, err := g.exchange.CreateLimitOrder(pair.Symbol, level.Side, quantity, limit)
+	if err != nil {
+		return fmt.Errorf("error placing grid %s of %s %s at %s: %v", level.Side, quantity, pair.Symbol, limit, err)
+	}
+	order := &models.Order{
+		OrderID: orderID,
+		Symbol:  pair.Symbol,
+		Side:    level.Side,
+		Type:    "LIMIT",
+		Status:  models.OrderStatusNew,
+	}
+	order.Quantity, order.Price = decimal.RequireFromString(quantity), decimal.RequireFromString(limit)
+	if err := g.orders.Track(order); err != nil {
+		logger.Errorf("Error tracking grid %s order %d for %s: %v", level.Side, orderID, pair.Symbol, err)
+	}
+	logger.Debugf("Placed grid %s of %s %s at %s", level.Side, quantity, pair.Symbol, limit)
+
+	level.OrderID = orderID
+	return db2.SQLiteDB.SaveGridLevel(level)
+}
+
+// stop cancels the orders of the grid once the price left its range. The base the grid holds is
+// kept, an order that cannot be canceled is tried again on the next step.
+func (g *Grid) stop(pair *models.TradingPair, price decimal.Decimal) error {
+	logger.Warnf("Price of %s (%s) left the grid range %v - %v, stopping grid %d", pair.Symbol, price, g.settings.Lower, g.settings.Upper, g.state.ID)
+	if err := g.cancel(pair, g.levels); err != nil {
+		return err
+	}
+	return g.finish(g.state)
+}
+
+// cancel takes the orders of the slots off the exchange and applies the fills they got before
+func (g *Grid) cancel(pair *models.TradingPair, levels []*models.GridLevel) error {
+	var errs []error
+	for _, level := range levels {
+		if level.OrderID == 0 {
+			continue
+		}
+		cancelErr := g.exchange.CancelOrder(pair.Symbol, level.OrderID)
+		order, err := g.orders.Refresh(pair.Symbol, level.OrderID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error refreshing grid order %d for %s: %v", level.OrderID, pair.Symbol, err))
+			continue
+		}
+		if order.IsOpen() {
+			errs = append(errs, fmt.Errorf("failed to cancel grid order %d for %s: %v", level.OrderID, pair.Symbol, cancelErr))
+			continue
+		}
+		level.OrderID = 0
+		if err := db2.SQLiteDB.SaveGridLevel(level); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// finish records that a grid stopped
+func (g *Grid) finish(stored *models.Grid) error {
+	if err := db2.SQLiteDB.UpdateGridStatus(stored.ID, models.GridStopped); err != nil {
+		return err
+	}
+	stored.Status = models.GridStopped
+	logger.Infof("Grid %d of %s is %s", stored.ID, stored.Symbol, stored.Status)
+	return nil
+}
@@ -0,0 +1,343 @@
+package grid
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/interfaces"
+	"binance_bot/models"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"strings"
+	"testing"
+)
+
+// fakeExchange keeps the orders placed on it, IDs are their index plus one. It holds 1000 USDT
+// and the ETH bought, less what the open orders lock.
+type fakeExchange struct {
+	interfaces.ExchangeClient
+	orders     []*models.Order
+	base       decimal.Decimal // ETH held
+	failCancel bool
+}
+
+func (x *fakeExchange) GetBalance(asset string) (decimal.Decimal, error) {
+	free := d("1000")
+	if asset == "ETH" {
+		free = x.base
+	}
+	for _, order := range x.orders {
+		if !order.IsOpen() {
+			continue
+		}
+		if order.Side == "SELL" && asset == "ETH" {
+			free = free.Sub(order.Remaining())
+		} else if order.Side == "BUY" && asset == "USDT" {
+			free = free.Sub(order.Remaining().Mul(order.Price))
+		}
+	}
+	return free, nil
+}
+
+func (x *fakeExchange) CreateMarketOrder(symbol, side, quantity string) (*models.Order, error) {
+	order := &models.Order{
+		OrderID:   int64(len(x.orders) + 1),
+		Symbol:    symbol,
+		Side:      side,
+		Type:      "MARKET",
+		Quantity:  d(quantity),
+		Status:    models.OrderStatusFilled,
+		FilledQty: d(quantity),
+		AvgPrice:  d("100"),
+	}
+	x.orders = append(x.orders, order)
+	x.base = x.base.Add(order.FilledQty)
+	copied := *order
+	return &copied, nil
+}
+
+func (x *fakeExchange) CreateLimitOrder(symbol, side, quantity, price string) (int64, error) {
+	order := &models.Order{
+		OrderID:  int64(len(x.orders) + 1),
+		Symbol:   symbol,
+		Side:     side,
+		Type:     "LIMIT",
+		Quantity: d(quantity),
+		Price:    d(price),
+		Status:   models.OrderStatusNew,
+	}
+	x.orders = append(x.orders, order)
+	return order.OrderID, nil
+}
+
+func (x *fakeExchange) CancelOrder(symbol string, orderID int64) error {
+	if x.failCancel {
+		return fmt.Errorf("exchange unavailable")
+	}
+	if order := x.orders[orderID-1]; order.IsOpen() {
+		order.Status = models.OrderStatusCanceled
+	}
+	return nil
+}
+
+// fill fills an order up to a quantity and stores it as the order tracker would. A BUY pays its
+// commission in ETH.
+func (x *fakeExchange) fill(t *testing.T, orderID int64, filled, commission, status string) {
+	t.Helper()
+	order := x.orders[orderID-1]
+	if order.Side == "BUY" {
+		x.base = x.base.Add(d(filled).Sub(order.FilledQty)).Sub(d(commission))
+	} else {
+		x.base = x.base.Sub(d(filled).Sub(order.FilledQty))
+	}
+	order.FilledQty, order.AvgPrice, order.Status = d(filled), order.Price, status
+	order.Commission, order.CommissionAsset = d(commission), "ETH"
+	if _, err := (fakeTracker{x}).Refresh(order.Symbol, orderID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// book lists the orders as "<side> <quantity>@<price> <status>", market orders without a price
+func (x *fakeExchange) book() string {
+	var book []string
+	for _, order := range x.orders {
+		if order.Type == "MARKET" {
+			book = append(book, fmt.Sprintf("%s %s %s", order.Side, order.Quantity, order.Status))
+			continue
+		}
+		book = append(book, fmt.Sprintf("%s %s@%s %s", order.Side, order.Quantity, order.Price, order.Status))
+	}
+	return strings.Join(book, ", ")
+}
+
+// fakeTracker stores the orders of the exchange
+type fakeTracker struct {
+	x *fakeExchange
+}
+
+func (f fakeTracker) Track(order *models.Order) error {
+	return db2.SQLiteDB.LogOrder(order)
+}
+
+func (f fakeTracker) Refresh(symbol string, orderID int64) (*models.Order, error) {
+	order := *f.x.orders[orderID-1]
+	return &order, db2.SQLiteDB.UpdateOrder(&order)
+}
+
+var ethusdt = &models.TradingPair{
+	Symbol: "ETHUSDT", BaseAsset: "ETH", QuoteAsset: "USDT",
+	QtyPrecision: 3, PricePrecision: 2, MinNotional: d("5"),
+}
+
+// settings place 4 slots of 100 USDT from 90 to 110 in steps of 5
+var settings = Settings{Lower: 90, Upper: 110, Levels: 5, QuotePerLevel: 100}
+
+// newTestExchange returns an empty exchange, the grids are stored in a test database
+func newTestExchange(t *testing.T) *fakeExchange {
+	if err := db2.InitDBAt(t.TempDir() + "/grid.db"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db2.SQLiteDB.DB.Close() })
+	return &fakeExchange{}
+}
+
+// newGrid returns a grid trading on the exchange that is allowed every BUY
+func newGrid(x *fakeExchange, s Settings) *Grid {
+	allow := func(pair *models.TradingPair, side, quantity, price string) bool { return true }
+	return New(s, x, fakeTracker{x}, x.GetBalance, allow)
+}
+
+// step moves a grid to a price and checks the orders on the exchange afterwards
+func step(t *testing.T, g *Grid, x *fakeExchange, price, book string) {
+	t.Helper()
+	if err := g.Step(ethusdt, d(price)); err != nil {
+		t.Fatalf("step to %s: %v", price, err)
+	}
+	if got := x.book(); got != book {
+		t.Errorf("after step to %s got orders\n%s\nwant\n%s", price, got, book)
+	}
+}
+
+func d(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
+func TestStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		held     string // ETH held in an open position
+		price    string
+		book     string
+	}{
+		{
+			"slots below the price buy, the base of the SELLs above is bought",
+			settings, "0", "100",
+			"BUY 1.952 FILLED, BUY 1.111@90 NEW, BUY 1.052@95 NEW, SELL 1@105 NEW, SELL 0.952@110 NEW",
+		},
+		{
+			"held position covers the SELLs",
+			settings, "1.952", "100",
+			"BUY 1.111@90 NEW, BUY 1.052@95 NEW, SELL 1@105 NEW, SELL 0.952@110 NEW",
+		},
+		{
+			"price at the lower bound sells every slot",
+			settings, "0", "90",
+			"BUY 4.115 FILLED, SELL 1.111@95 NEW, SELL 1.052@100 NEW, SELL 1@105 NEW, SELL 0.952@110 NEW",
+		},
+		{
+			"geometric spacing",
+			Settings{Lower: 90, Upper: 110, Levels: 3, Spacing: GeometricSpacing, QuotePerLevel: 100}, "0", "100",
+			"BUY 1.111@90 NEW, BUY 1.005@99.49 NEW",
+		},
+		{"price outside the range waits", settings, "0", "120", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := newTestExchange(t)
+			if d(tt.held).IsPositive() {
+				if err := db2.SQLiteDB.LogActiveTrade("ETHUSDT", d("98"), d(tt.held)); err != nil {
+					t.Fatal(err)
+				}
+				x.base = d(tt.held)
+			}
+			g := newGrid(x, tt.settings)
+			step(t, g, x, tt.price, tt.book)
+			if g.Stopped() {
+				t.Error("grid stopped on start")
+			}
+		})
+	}
+}
+
+func TestRefill(t *testing.T) {
+	// ETH beyond the grid leaves the quantities of the SELLs to the slots
+	x := newTestExchange(t)
+	x.base = d("10")
+	g := newGrid(x, settings)
+	book := "BUY 1.952 FILLED, BUY 1.111@90 NEW, BUY 1.052@95 NEW, SELL 1@105 NEW, SELL 0.952@110 NEW"
+	step(t, g, x, "100", book)
+
+	// Open orders are left alone
+	step(t, g, x, "101", book)
+
+	// The BUY at 95 is sold at 100, less the commission paid in ETH
+	x.fill(t, 3, "1.052", "0.001", models.OrderStatusFilled)
+	book = "BUY 1.952 FILLED, BUY 1.111@90 NEW, BUY 1.052@95 FILLED, SELL 1@105 NEW, SELL 0.952@110 NEW, SELL 1.051@100 NEW"
+	step(t, g, x, "96", book)
+
+	// The rest of a SELL canceled after a partial fill is placed again
+	x.fill(t, 4, "0.4", "0", models.OrderStatusCanceled)
+	book = "BUY 1.952 FILLED, BUY 1.111@90 NEW, BUY 1.052@95 FILLED, SELL 1@105 CANCELED, SELL 0.952@110 NEW, SELL 1.051@100 NEW, SELL 0.6@105 NEW"
+	step(t, g, x, "104", book)
+
+	// A filled SELL is bought back one level down
+	x.fill(t, 5, "0.952", "0", models.OrderStatusFilled)
+	book = "BUY 1.952 FILLED, BUY 1.111@90 NEW, BUY 1.052@95 FILLED, SELL 1@105 CANCELED, SELL 0.952@110 FILLED, SELL 1.051@100 NEW, SELL 0.6@105 NEW, BUY 0.952@105 NEW"
+	step(t, g, x, "109", book)
+
+	// A partially filled SELL still open keeps resting
+	x.fill(t, 7, "0.2", "0", models.OrderStatusPartiallyFilled)
+	book = strings.Replace(book, "SELL 0.6@105 NEW", "SELL 0.6@105 PARTIALLY_FILLED", 1)
+	step(t, g, x, "104", book)
+}
+
+func TestStop(t *testing.T) {
+	x := newTestExchange(t)
+	g := newGrid(x, settings)
+	step(t, g, x, "100", "BUY 1.952 FILLED, BUY 1.111@90 NEW, BUY 1.052@95 NEW, SELL 1@105 NEW, SELL 0.952@110 NEW")
+
+	// Orders that cannot be canceled keep the grid running and are canceled on the next step
+	x.failCancel = true
+	if err := g.Step(ethusdt, d("111")); err == nil {
+		t.Error("grid stopped with its orders on the exchange")
+	}
+	if g.Stopped() {
+		t.Fatal("grid stopped with its orders on the exchange")
+	}
+
+	x.failCancel = false
+	book := "BUY 1.952 FILLED, BUY 1.111@90 CANCELED, BUY 1.052@95 CANCELED, SELL 1@105 CANCELED, SELL 0.952@110 CANCELED"
+	step(t, g, x, "111", book)
+	if !g.Stopped() {
+		t.Fatal("grid running after the price left its range")
+	}
+
+	// A stopped grid stays stopped when the price comes back
+	step(t, g, x, "100", book)
+	stored, err := db2.SQLiteDB.GetLatestGrid("ETHUSDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.GridStopped {
+		t.Errorf("stored grid is %s, want %s", stored.Status, models.GridStopped)
+	}
+}
+
+func TestResume(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		stopped  bool // Stop the stored grid before the restart
+		book     string
+		grids    int64 // ID of the latest stored grid
+		status   string
+	}{
+		{
+			"same settings resume the slots",
+			settings, false,
+			"BUY 1.952 FILLED, BUY 1.111@90 NEW, BUY 1.052@95 FILLED, SELL 1@105 NEW, SELL 0.952@110 NEW, SELL 1.051@100 NEW",
+			1, models.GridActive,
+		},
+		{
+			"changed settings cancel the stored grid and start a new one",
+			Settings{Lower: 90, Upper: 110, Levels: 3, QuotePerLevel: 100}, false,
+			"BUY 1.952 FILLED, BUY 1.111@90 CANCELED, BUY 1.052@95 FILLED, SELL 1@105 CANCELED, SELL 0.952@110 CANCELED, " +
+				"BUY 1 FILLED, BUY 1.111@90 NEW, SELL 1@110 NEW",
+			2, models.GridActive,
+		},
+		{
+			"stopped grid with the same settings does not start again",
+			settings, true,
+			"BUY 1.952 FILLED, BUY 1.111@90 CANCELED, BUY 1.052@95 FILLED, SELL 1@105 CANCELED, SELL 0.952@110 CANCELED",
+			1, models.GridStopped,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := newTestExchange(t)
+			stored := newGrid(x, settings)
+			step(t, stored, x, "100", "BUY 1.952 FILLED, BUY 1.111@90 NEW, BUY 1.052@95 NEW, SELL 1@105 NEW, SELL 0.952@110 NEW")
+			if tt.stopped {
+				x.fill(t, 3, "1.052", "0.001", models.OrderStatusFilled)
+				if err := stored.Step(ethusdt, d("80")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// The restarted bot finds the BUY at 95 filled while it was down
+			if !tt.stopped {
+				x.fill(t, 3, "1.052", "0.001", models.OrderStatusFilled)
+			}
+			g := newGrid(x, tt.settings)
+			step(t, g, x, "100", tt.book)
+
+			latest, err := db2.SQLiteDB.GetLatestGrid("ETHUSDT")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if latest.ID != tt.grids || latest.Status != tt.status {
+				t.Errorf("latest grid is %d %s, want %d %s", latest.ID, latest.Status, tt.grids, tt.status)
+			}
+			if tt.grids > 1 {
+				previous, err := db2.SQLiteDB.GetGridLevels(1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, level := range previous {
+					if level.OrderID != 0 {
+						t.Errorf("level %d of the replaced grid still has order %d", level.Level, level.OrderID)
+					}
+				}
+			}
+		})
+	}
+}
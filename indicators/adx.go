@@ -0,0 +1,96 @@
+package indicators
+
+import (
+	"binance_bot/models"
+	"math"
+)
+
+// ADXValue is a value of the average directional index, all between 0 and 100
+type ADXValue struct {
+	ADX     float64 // Strength of the trend whatever its direction
+	PlusDI  float64 // Strength of the upward moves
+	MinusDI float64 // Strength of the downward moves
+}
+
+// ADX is Wilder's average directional index over Period candles
+type ADX struct {
+	period  int
+	count   int // Candles seen
+	prev    models.CandleStick
+	tr      float64 // Wilder sums of the true range and the directional moves
+	plusDM  float64
+	minusDM float64
+	adx     wilder
+	value   ADXValue
+}
+
+// NewADX creates an average directional index, its directional indicators are ready after period+1
+// candles and the index period-1 candles later
+func NewADX(period int) *ADX {
+	mustPositive("ADX", period)
+	return &ADX{period: period, adx: wilder{period: period}}
+}
+
+// Update adds a candle and returns the index
+func (a *ADX) Update(c models.CandleStick) ADXValue {
+	a.count++
+	prev := a.prev
+	a.prev = c
+	if a.count == 1 {
+		return a.value
+	}
+
+	up, down := c.High-prev.High, prev.Low-c.Low
+	plusDM, minusDM := 0.0, 0.0
+	if up > down && up > 0 {
+		plusDM = up
+	}
+	if down > up && down > 0 {
+		minusDM = down
+	}
+
+	// The sums start as plain sums of the first period moves
+	n := float64(a.period)
+	if a.count <= a.period+1 {
+		a.tr += trueRange(c, prev.Close)
+		a.plusDM += plusDM
+		a.minusDM += minusDM
+		if a.count <= a.period {
+			return a.value
+		}
+	} else {
+		a.tr += trueRange(c, prev.Close) - a.tr/n
+		a.plusDM += plusDM - a.plusDM/n
+		a.minusDM += minusDM - a.minusDM/n
+	}
+
+	if a.tr > 0 {
+		a.value.PlusDI = 100 * a.plusDM / a.tr
+		a.value.MinusDI = 100 * a.minusDM / a.tr
+	}
+	dx := 0.0
+	if sum := a.value.PlusDI + a.value.MinusDI; sum > 0 {
+		dx = 100 * math.Abs(a.value.PlusDI-a.value.MinusDI) / sum
+	}
+	a.adx.update(dx)
+	if a.adx.ready() {
+		a.value.ADX = a.adx.value
+	}
+	return a.value
+}
+
+// Ready reports whether the index is complete
+func (a *ADX) Ready() bool {
+	return a.adx.ready()
+}
+
+// Value returns the latest index
+func (a *ADX) Value() ADXValue {
+	return a.value
+}
+
+// ADXSeries returns the indexes of the candles from the first complete index
+func ADXSeries(candles []models.CandleStick, period int) ([]ADXValue, error) {
+	a := NewADX(period)
+	return series("ADX", 2*period, candles, a.Update, a.Ready)
+}
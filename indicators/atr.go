@@ -0,0 +1,76 @@
+package indicators
+
+import (
+	"binance_bot/models"
+	"math"
+)
+
+// trueRange returns the range of a candle extended to the previous close
+func trueRange(c models.CandleStick, prevClose float64) float64 {
+	return math.Max(c.High-c.Low, math.Max(math.Abs(c.High-prevClose), math.Abs(c.Low-prevClose)))
+}
+
+// wilder is Wilder's smoothing, a running average seeded with the plain average of the first
+// period values
+type wilder struct {
+	period int
+	count  int
+	value  float64
+}
+
+func (w *wilder) update(value float64) float64 {
+	n := float64(w.period)
+	if w.count < w.period {
+		w.value += value / n
+	} else {
+		w.value = (w.value*(n-1) + value) / n
+	}
+	w.count++
+	return w.value
+}
+
+func (w *wilder) ready() bool {
+	return w.count >= w.period
+}
+
+// ATR is Wilder's average true range of the last Period candles
+type ATR struct {
+	average   wilder
+	prevClose float64
+	started   bool
+}
+
+// NewATR creates an average true range, it is ready after period+1 candles as the first candle
+// has no true range
+func NewATR(period int) *ATR {
+	mustPositive("ATR", period)
+	return &ATR{average: wilder{period: period}}
+}
+
+// Update adds a candle and returns the average true range
+func (a *ATR) Update(c models.CandleStick) float64 {
+	if a.started {
+		a.average.update(trueRange(c, a.prevClose))
+	}
+	a.prevClose, a.started = c.Close, true
+	return a.Value()
+}
+
+// Ready reports whether period true ranges were seen
+func (a *ATR) Ready() bool {
+	return a.average.ready()
+}
+
+// Value returns the latest average true range, 0 until ready
+func (a *ATR) Value() float64 {
+	if !a.Ready() {
+		return 0
+	}
+	return a.average.value
+}
+
+// ATRSeries returns the average true ranges of the candles from the first full period
+func ATRSeries(candles []models.CandleStick, period int) ([]float64, error) {
+	a := NewATR(period)
+	return series("ATR", period+1, candles, a.Update, a.Ready)
+}
@@ -0,0 +1,143 @@
+package indicators
+
+// SMA is the simple moving average of the last Period values
+type SMA struct {
+	period int
+	window *ring
+	sum    float64
+	value  float64
+}
+
+// NewSMA creates a simple moving average, it is ready after period values
+func NewSMA(period int) *SMA {
+	mustPositive("SMA", period)
+	return &SMA{period: period, window: newRing(period)}
+}
+
+// Update adds a value and returns the average
+func (s *SMA) Update(value float64) float64 {
+	old, full := s.window.push(value)
+	s.sum += value
+	if full {
+		s.sum -= old
+	}
+	if s.window.full {
+		s.value = s.sum / float64(s.period)
+	}
+	return s.value
+}
+
+// Ready reports whether the window is full
+func (s *SMA) Ready() bool {
+	return s.window.full
+}
+
+// Value returns the latest average, 0 until ready
+func (s *SMA) Value() float64 {
+	return s.value
+}
+
+// SMASeries returns the simple moving averages of the values from the first full window
+func SMASeries(values []float64, period int) ([]float64, error) {
+	s := NewSMA(period)
+	return series("SMA", period, values, s.Update, s.Ready)
+}
+
+// EMA is the exponential moving average with a smoothing of 2/(Period+1), seeded with the simple
+// average of the first Period values
+type EMA struct {
+	period int
+	alpha  float64
+	count  int
+	sum    float64
+	value  float64
+}
+
+// NewEMA creates an exponential moving average, it is ready after period values
+func NewEMA(period int) *EMA {
+	mustPositive("EMA", period)
+	return &EMA{period: period, alpha: 2 / (float64(period) + 1)}
+}
+
+// Update adds a value and returns the average
+func (e *EMA) Update(value float64) float64 {
+	switch {
+	case e.count >= e.period:
+		e.value += (value - e.value) * e.alpha
+	case e.count == e.period-1:
+		e.value = (e.sum + value) / float64(e.period)
+	default:
+		e.sum += value
+	}
+	e.count++
+	return e.value
+}
+
+// Ready reports whether the seed average is complete
+func (e *EMA) Ready() bool {
+	return e.count >= e.period
+}
+
+// Value returns the latest average, 0 until ready
+func (e *EMA) Value() float64 {
+	return e.value
+}
+
+// EMASeries returns the exponential moving averages of the values from the seed average
+func EMASeries(values []float64, period int) ([]float64, error) {
+	e := NewEMA(period)
+	return series("EMA", period, values, e.Update, e.Ready)
+}
+
+// WMA is the linearly weighted moving average of the last Period values, the latest value weighs
+// Period and the oldest 1
+type WMA struct {
+	period   int
+	window   *ring
+	count    int
+	sum      float64 // Plain sum of the window
+	weighted float64 // Weighted sum of the window
+	value    float64
+}
+
+// NewWMA creates a weighted moving average, it is ready after period values
+func NewWMA(period int) *WMA {
+	mustPositive("WMA", period)
+	return &WMA{period: period, window: newRing(period)}
+}
+
+// Update adds a value and returns the average
+func (w *WMA) Update(value float64) float64 {
+	old, full := w.window.push(value)
+	if w.count < w.period {
+		w.count++
+	}
+	if full {
+		// Every value loses one weight, the oldest drops out and the new one weighs period
+		w.weighted += float64(w.period)*value - w.sum
+		w.sum += value - old
+	} else {
+		w.weighted += float64(w.count) * value
+		w.sum += value
+	}
+	if w.window.full {
+		w.value = w.weighted / float64(w.period*(w.period+1)/2)
+	}
+	return w.value
+}
+
+// Ready reports whether the window is full
+func (w *WMA) Ready() bool {
+	return w.window.full
+}
+
+// Value returns the latest average, 0 until ready
+func (w *WMA) Value() float64 {
+	return w.value
+}
+
+// WMASeries returns the weighted moving averages of the values from the first full window
+func WMASeries(values []float64, period int) ([]float64, error) {
+	w := NewWMA(period)
+	return series("WMA", period, values, w.Update, w.Ready)
+}
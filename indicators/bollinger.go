@@ -0,0 +1,83 @@
+package indicators
+
+import "math"
+
+// BollingerValue is a value of the Bollinger Bands
+type BollingerValue struct {
+	Middle float64 // SMA of the values
+	Upper  float64 // Middle plus the multiple of the standard deviation
+	Lower  float64 // Middle minus the multiple of the standard deviation
+}
+
+// Width returns the distance of the bands as a fraction of the middle band
+func (b BollingerValue) Width() float64 {
+	if b.Middle == 0 {
+		return 0
+	}
+	return (b.Upper - b.Lower) / b.Middle
+}
+
+// PercentB returns where a price sits between the bands, 0 at the lower and 1 at the upper band
+func (b BollingerValue) PercentB(price float64) float64 {
+	if b.Upper == b.Lower {
+		return 0.5
+	}
+	return (price - b.Lower) / (b.Upper - b.Lower)
+}
+
+// Bollinger is the Bollinger Bands, an SMA of the last Period values with bands a multiple of their
+// population standard deviation away
+type Bollinger struct {
+	period     int
+	multiplier float64
+	window     *ring
+	sum        float64
+	sumSquares float64
+	value      BollingerValue
+}
+
+// NewBollinger creates Bollinger Bands, they are ready after period values
+func NewBollinger(period int, multiplier float64) *Bollinger {
+	mustPositive("Bollinger", period)
+	return &Bollinger{period: period, multiplier: multiplier, window: newRing(period)}
+}
+
+// Update adds a value and returns the bands
+func (b *Bollinger) Update(value float64) BollingerValue {
+	old, full := b.window.push(value)
+	b.sum += value
+	b.sumSquares += value * value
+	if full {
+		b.sum -= old
+		b.sumSquares -= old * old
+	}
+	if !b.window.full {
+		return b.value
+	}
+
+	n := float64(b.period)
+	mean := b.sum / n
+	deviation := math.Sqrt(math.Max(b.sumSquares/n-mean*mean, 0))
+	b.value = BollingerValue{
+		Middle: mean,
+		Upper:  mean + b.multiplier*deviation,
+		Lower:  mean - b.multiplier*deviation,
+	}
+	return b.value
+}
+
+// Ready reports whether the window is full
+func (b *Bollinger) Ready() bool {
+	return b.window.full
+}
+
+// Value returns the latest bands
+func (b *Bollinger) Value() BollingerValue {
+	return b.value
+}
+
+// BollingerSeries returns the bands of the values from the first full window
+func BollingerSeries(values []float64, period int, multiplier float64) ([]BollingerValue, error) {
+	b := NewBollinger(period, multiplier)
+	return series("Bollinger Bands", period, values, b.Update, b.Ready)
+}
@@ -0,0 +1,98 @@
+// Package indicators holds the technical indicators of the strategies. Every indicator has a
+// streaming form, updated in constant time with each new value or candle, and a Series function
+// computing it over a whole input at once. Warm-up follows the TA-Lib conventions, so Series
+// results start at the first complete value.
+package indicators
+
+import (
+	"binance_bot/models"
+	"fmt"
+)
+
+// Closes returns the close prices of the candles
+func Closes(candles []models.CandleStick) []float64 {
+	closes := make([]float64, len(candles))
+	for i, c := range candles {
+		closes[i] = c.Close
+	}
+	return closes
+}
+
+// mustPositive panics on a period below 1, periods come from validated strategy parameters
+func mustPositive(name string, period int) {
+	if period < 1 {
+		panic(fmt.Sprintf("indicators: %s period must be at least 1, got %d", name, period))
+	}
+}
+
+// notEnough reports an input too short for a single value of an indicator
+func notEnough(name string, need, got int) error {
+	return fmt.Errorf("not enough data to calculate %s: need %d values, got %d", name, need, got)
+}
+
+// series runs a streaming indicator over an input and collects its values once it is ready
+func series[In, Out any](name string, need int, inputs []In, update func(In) Out, ready func() bool) ([]Out, error) {
+	if len(inputs) < need {
+		return nil, notEnough(name, need, len(inputs))
+	}
+	values := make([]Out, 0, len(inputs)-need+1)
+	for _, input := range inputs {
+		value := update(input)
+		if ready() {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// ring is a fixed size window of the latest values
+type ring struct {
+	values []float64
+	next   int
+	full   bool
+}
+
+func newRing(size int) *ring {
+	return &ring{values: make([]float64, size)}
+}
+
+// push adds a value and returns the one it pushed out of a full window
+func (r *ring) push(value float64) (float64, bool) {
+	old, full := r.values[r.next], r.full
+	r.values[r.next] = value
+	r.next++
+	if r.next == len(r.values) {
+		r.next, r.full = 0, true
+	}
+	return old, full
+}
+
+// extreme tracks the highest or lowest value of a sliding window in amortized constant time
+type extreme struct {
+	period  int
+	highest bool
+	index   int
+	indexes []int // Candidates from the oldest, their values are monotonic
+	values  []float64
+}
+
+func newExtreme(period int, highest bool) *extreme {
+	return &extreme{period: period, highest: highest}
+}
+
+// push adds a value and returns the extreme of the window ending with it
+func (e *extreme) push(value float64) float64 {
+	for n := len(e.values); n > 0; n-- {
+		last := e.values[n-1]
+		if (e.highest && last > value) || (!e.highest && last < value) {
+			break
+		}
+		e.values, e.indexes = e.values[:n-1], e.indexes[:n-1]
+	}
+	e.values, e.indexes = append(e.values, value), append(e.indexes, e.index)
+	if e.indexes[0] <= e.index-e.period {
+		e.values, e.indexes = e.values[1:], e.indexes[1:]
+	}
+	e.index++
+	return e.values[0]
+}
@@ -0,0 +1,468 @@
+package indicators
+
+import (
+	"binance_bot/models"
+	"math"
+	"testing"
+	"time"
+)
+
+// The published series below come from the example spreadsheets of the StockCharts ChartSchool
+// articles "Moving Averages - Simple and Exponential", "Relative Strength Index (RSI)" and
+// "Stochastic Oscillator (Fast, Slow, and Full)". The articles print 2 decimals, the spreadsheets
+// hold the 4 decimal inputs used here.
+
+// smaCloses are the closes of the moving average sheet
+var smaCloses = []float64{
+	22.2734, 22.1940, 22.0847, 22.1741, 22.1840, 22.1344, 22.2337, 22.4323, 22.2436, 22.2933,
+	22.1542, 22.3926, 22.3816, 22.6109, 23.3558, 24.0519, 23.7530, 23.8324, 23.9516, 23.6338,
+	23.8225, 23.8722, 23.6537, 23.1870, 23.0976, 23.3260, 22.6805, 23.0976, 22.4025, 22.1725,
+}
+
+// rsiCloses are the closes of the RSI sheet
+var rsiCloses = []float64{
+	44.3389, 44.0902, 44.1497, 43.6124, 44.3278, 44.8264, 45.0955, 45.4245, 45.8433, 46.0826,
+	45.8931, 46.0328, 45.6140, 46.2820, 46.2820, 46.0028, 46.0328, 46.4116, 46.2222, 45.6439,
+	46.2122, 46.2521, 45.7137, 46.4515, 45.7835, 45.3548, 44.0288, 44.1783, 44.2181, 44.5672,
+	43.4205, 42.6628, 43.1314,
+}
+
+// stochasticCandles are the highs, lows and closes of the stochastic sheet. The sheet lists closes
+// from the 14th row on, the earlier ones never enter %K and are set to the middle of the range.
+var stochasticCandles = toCandles([][4]float64{
+	{127.0090, 125.3574, 126.1832}, {127.6159, 126.1633, 126.8896}, {126.5911, 124.9296, 125.7604},
+	{127.3472, 126.0937, 126.7205}, {128.1730, 126.8199, 127.4965}, {128.4317, 126.4817, 127.4567},
+	{127.3671, 126.0340, 126.7006}, {126.4220, 124.8301, 125.6261}, {126.8995, 126.3921, 126.6458},
+	{126.8498, 125.7156, 126.2827}, {125.6460, 124.5615, 125.1038}, {125.7156, 124.5715, 125.1436},
+	{127.1582, 125.0689, 126.1136}, {127.7154, 126.8597, 127.2876}, {127.6855, 126.6309, 127.1781},
+	{128.2228, 126.8001, 128.0138}, {128.2725, 126.7105, 127.1085}, {128.0934, 126.8001, 127.7253},
+	{128.2725, 126.1335, 127.0587}, {127.7353, 125.9245, 127.3273}, {128.7700, 126.9891, 128.7103},
+	{129.2873, 127.8148, 127.8745}, {130.0633, 128.4715, 128.5809}, {129.1182, 128.0641, 128.6008},
+	{129.2873, 127.6059, 127.9342}, {128.4715, 127.5960, 128.1133}, {128.0934, 126.9990, 127.5960},
+	{128.6506, 126.8995, 127.5960}, {129.1381, 127.4865, 128.6904}, {128.6406, 127.3970, 128.2725},
+})
+
+// closes are the closes of the candles below, they feed the indicators without a published series
+var closes = []float64{
+	22.27, 22.19, 22.08, 22.17, 22.18, 22.13, 22.23, 22.43, 22.24, 22.29,
+	22.15, 22.39, 22.38, 22.61, 23.36, 24.05, 23.75, 23.83, 23.95, 23.63,
+	23.82, 23.87, 23.65, 23.19, 23.10, 23.33, 22.68, 23.10, 22.40, 22.17,
+}
+
+// candles are 4 hour candles closing at closes: high, low, close and volume
+var candles = toCandles([][4]float64{
+	{22.34, 22.22, 22.27, 1200},
+	{22.31, 22.11, 22.19, 950},
+	{22.17, 22.02, 22.08, 1430},
+	{22.23, 22.13, 22.17, 870},
+	{22.29, 22.11, 22.18, 1010},
+	{22.28, 22.03, 22.13, 1320},
+	{22.31, 22.17, 22.23, 990},
+	{22.61, 22.31, 22.43, 1800},
+	{22.37, 22.15, 22.24, 1150},
+	{22.39, 22.23, 22.29, 1040},
+	{22.26, 22.07, 22.15, 1260},
+	{22.55, 22.28, 22.39, 1500},
+	{22.45, 22.33, 22.38, 980},
+	{22.82, 22.47, 22.61, 2100},
+	{23.72, 23.12, 23.36, 3400},
+	{24.38, 23.83, 24.05, 3900},
+	{23.99, 23.59, 23.75, 2600},
+	{24.00, 23.72, 23.83, 1700},
+	{24.15, 23.82, 23.95, 1850},
+	{23.90, 23.45, 23.63, 2300},
+	{23.95, 23.74, 23.82, 1400},
+	{24.03, 23.77, 23.87, 1350},
+	{23.88, 23.50, 23.65, 1900},
+	{23.49, 22.99, 23.19, 2750},
+	{23.29, 22.98, 23.10, 1600},
+	{23.50, 23.21, 23.33, 1500},
+	{23.05, 22.43, 22.68, 3100},
+	{23.36, 22.92, 23.10, 2200},
+	{22.74, 22.17, 22.40, 2900},
+	{22.39, 22.03, 22.17, 2000},
+})
+
+// toCandles turns rows of high, low, close and volume into 4 hour candles
+func toCandles(rows [][4]float64) []models.CandleStick {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	result := make([]models.CandleStick, len(rows))
+	for i, r := range rows {
+		result[i] = models.CandleStick{
+			Timestamp: start.Add(time.Duration(i) * 4 * time.Hour),
+			High:      r[0],
+			Low:       r[1],
+			Close:     r[2],
+			Volume:    r[3],
+		}
+	}
+	return result
+}
+
+// reference is an indicator run both ways over an input, with the expected lines from the first
+// ready value. Cases with a tolerance check the published StockCharts values. The others have no
+// published series: their values are regression values, worked out once from the definitions of
+// the indicators with the whole window at every step, so a change in the output shows up.
+type reference struct {
+	name      string
+	series    func() ([][]float64, error)    // Series path, one slice per line
+	stream    func(t *testing.T) [][]float64 // Update path, one slice per line
+	want      [][]float64
+	tolerance float64 // Allowed difference from want, 1e-6 when 0
+}
+
+func TestReferenceSeries(t *testing.T) {
+	tests := []reference{
+		{
+			name:   "SMA 10",
+			series: lines(func() ([]float64, error) { return SMASeries(smaCloses, 10) }, ident),
+			stream: func(t *testing.T) [][]float64 {
+				s := NewSMA(10)
+				return collect(t, smaCloses, s.Update, s.Ready, s.Value, ident)
+			},
+			want: [][]float64{{
+				22.22, 22.21, 22.23, 22.26, 22.31, 22.42, 22.61, 22.77, 22.91, 23.08,
+				23.21, 23.38, 23.53, 23.65, 23.71, 23.69, 23.61, 23.51, 23.43, 23.28,
+				23.13,
+			}},
+			tolerance: 0.005,
+		},
+		{
+			name:   "EMA 10",
+			series: lines(func() ([]float64, error) { return EMASeries(smaCloses, 10) }, ident),
+			stream: func(t *testing.T) [][]float64 {
+				e := NewEMA(10)
+				return collect(t, smaCloses, e.Update, e.Ready, e.Value, ident)
+			},
+			want: [][]float64{{
+				22.22, 22.21, 22.24, 22.27, 22.33, 22.52, 22.80, 22.97, 23.13, 23.28,
+				23.34, 23.43, 23.51, 23.54, 23.47, 23.40, 23.39, 23.26, 23.23, 23.08,
+				22.92,
+			}},
+			tolerance: 0.005,
+		},
+		{
+			name:   "WMA 5",
+			series: lines(func() ([]float64, error) { return WMASeries(closes, 5) }, ident),
+			stream: func(t *testing.T) [][]float64 {
+				w := NewWMA(5)
+				return collect(t, closes, w.Update, w.Ready, w.Value, ident)
+			},
+			want: [][]float64{{
+				22.164667, 22.148667, 22.175333, 22.266000, 22.270000, 22.286000, 22.248000, 22.288667, 22.315333, 22.422000,
+				22.754000, 23.244667, 23.508667, 23.708667, 23.852000, 23.799333, 23.792000, 23.816667, 23.760000, 23.562000,
+				23.384667, 23.319333, 23.070000, 23.040000, 22.813333, 22.562667,
+			}},
+		},
+		{
+			name:   "RSI 14",
+			series: lines(func() ([]float64, error) { return RSISeries(rsiCloses, 14) }, ident),
+			stream: func(t *testing.T) [][]float64 {
+				r := NewRSI(14)
+				return collect(t, rsiCloses, r.Update, r.Ready, r.Value, ident)
+			},
+			want: [][]float64{{
+				70.53, 66.32, 66.55, 69.41, 66.36, 57.97, 62.93, 63.26, 56.06, 62.38,
+				54.71, 50.42, 39.99, 41.46, 41.87, 45.46, 37.30, 33.08, 37.77,
+			}},
+			tolerance: 0.005,
+		},
+		{
+			name:   "MACD 5 10 4",
+			series: lines(func() ([]MACDValue, error) { return MACDSeries(closes, 5, 10, 4) }, macdLines...),
+			stream: func(t *testing.T) [][]float64 {
+				m := NewMACD(5, 10, 4)
+				return collect(t, closes, m.Update, m.Ready, m.Value, macdLines...)
+			},
+			want: [][]float64{
+				{
+					0.048679, 0.084512, 0.212572, 0.374085, 0.394057, 0.393189, 0.387068, 0.311786, 0.280615, 0.254181,
+					0.191024, 0.075301, -0.006021, -0.015165, -0.117718, -0.102886, -0.194620, -0.267711,
+				},
+				{
+					0.039605, 0.057568, 0.119570, 0.221376, 0.290448, 0.331545, 0.353754, 0.336967, 0.314426, 0.290328,
+					0.250607, 0.180484, 0.105882, 0.057464, -0.012609, -0.048720, -0.107080, -0.171332,
+				},
+				{
+					0.009074, 0.026944, 0.093002, 0.152709, 0.103609, 0.061645, 0.033314, -0.025181, -0.033811, -0.036147,
+					-0.059582, -0.105183, -0.111903, -0.072628, -0.105109, -0.054166, -0.087540, -0.096378,
+				},
+			},
+		},
+		{
+			name:   "Bollinger 10 2",
+			series: lines(func() ([]BollingerValue, error) { return BollingerSeries(closes, 10, 2) }, bollingerLines...),
+			stream: func(t *testing.T) [][]float64 {
+				b := NewBollinger(10, 2)
+				return collect(t, closes, b.Update, b.Ready, b.Value, bollingerLines...)
+			},
+			want: [][]float64{
+				{
+					22.221000, 22.209000, 22.229000, 22.259000, 22.303000, 22.421000, 22.613000, 22.765000, 22.905000, 23.076000,
+					23.210000, 23.377000, 23.525000, 23.652000, 23.710000, 23.684000, 23.612000, 23.505000, 23.432000, 23.277000,
+					23.131000,
+				},
+				{
+					22.405054, 22.394354, 22.442813, 22.464806, 22.587120, 23.103551, 23.773174, 24.073442, 24.334133, 24.554302,
+					24.620390, 24.632804, 24.619066, 24.435775, 24.211916, 24.274810, 24.181968, 24.291702, 24.220000, 24.195436,
+					24.225804,
+				},
+				{
+					22.036946, 22.023646, 22.015187, 22.053194, 22.018880, 21.738449, 21.452826, 21.456558, 21.475867, 21.597698,
+					21.799610, 22.121196, 22.430934, 22.868225, 23.208084, 23.093190, 23.042032, 22.718298, 22.644000, 22.358564,
+					22.036196,
+				},
+			},
+		},
+		{
+			// The sheet publishes the fast %K, %D is its 3 period average rounded to 2 decimals
+			name:   "Stochastic 14 1 3",
+			series: lines(func() ([]StochasticValue, error) { return StochasticSeries(stochasticCandles, 14, 1, 3) }, stochasticLines...),
+			stream: func(t *testing.T) [][]float64 {
+				s := NewStochastic(14, 1, 3)
+				return collect(t, stochasticCandles, s.Update, s.Ready, s.Value, stochasticLines...)
+			},
+			want: [][]float64{
+				{89.20, 65.81, 81.75, 64.52, 74.53, 98.58, 70.10, 73.06, 73.42, 61.23, 60.96, 40.39, 40.39, 66.83, 56.73},
+				{75.75, 74.21, 78.92, 70.69, 73.60, 79.21, 81.07, 80.58, 72.19, 69.24, 65.20, 54.19, 47.24, 49.20, 54.65},
+			},
+			tolerance: 0.005,
+		},
+		{
+			name:   "Stochastic 5 3 3",
+			series: lines(func() ([]StochasticValue, error) { return StochasticSeries(candles, 5, 3, 3) }, stochasticLines...),
+			stream: func(t *testing.T) [][]float64 {
+				s := NewStochastic(5, 3, 3)
+				return collect(t, candles, s.Update, s.Ready, s.Value, stochasticLines...)
+			},
+			want: [][]float64{
+				{
+					59.195402, 50.000000, 31.949766, 39.633887, 46.219136, 65.280864, 71.588384, 78.155844, 77.245275, 74.919398,
+					68.781832, 52.144014, 46.028332, 44.070661, 47.142857, 35.934066, 19.743590, 21.330891, 20.667761, 37.730512,
+					32.383812, 29.811288,
+				},
+				{
+					57.471264, 56.321839, 47.048389, 40.527884, 39.267596, 50.377962, 61.029461, 71.675031, 75.663168, 76.773506,
+					73.648835, 65.281748, 55.651393, 47.414336, 45.747283, 42.382528, 34.273504, 25.669516, 20.580747, 26.576388,
+					30.260695, 33.308537,
+				},
+			},
+		},
+		{
+			name:   "ATR 7",
+			series: lines(func() ([]float64, error) { return ATRSeries(candles, 7) }, ident),
+			stream: func(t *testing.T) [][]float64 {
+				a := NewATR(7)
+				return collect(t, candles, a.Update, a.Ready, a.Value, ident)
+			},
+			want: [][]float64{{
+				0.215714, 0.224898, 0.215627, 0.216252, 0.242501, 0.225001, 0.255715, 0.377756, 0.469505, 0.468147,
+				0.441269, 0.425373, 0.436034, 0.419458, 0.396678, 0.394296, 0.432253, 0.414789, 0.412676, 0.482294,
+				0.510537, 0.570461, 0.541823,
+			}},
+		},
+		{
+			name:   "ADX 7",
+			series: lines(func() ([]ADXValue, error) { return ADXSeries(candles, 7) }, adxLines...),
+			stream: func(t *testing.T) [][]float64 {
+				a := NewADX(7)
+				return collect(t, candles, a.Update, a.Ready, a.Value, adxLines...)
+			},
+			want: [][]float64{
+				{
+					18.054902, 25.887595, 33.758809, 37.596795, 40.917094, 44.249288, 42.411111, 41.135278, 40.554837, 36.623926,
+					34.029873, 31.880190, 27.753874, 29.316319, 27.767768, 29.835107, 32.099642,
+				},
+				{
+					43.145811, 59.069981, 60.819124, 52.281883, 47.866395, 47.599097, 39.801695, 37.166819, 36.567782, 31.533215,
+					24.655002, 22.022663, 26.242831, 19.246932, 24.259041, 18.609244, 16.793835,
+				},
+				{
+					15.967747, 9.264931, 6.389493, 12.816297, 11.654530, 10.362894, 20.787547, 18.522034, 16.787729, 24.258794,
+					35.822510, 32.342260, 27.863858, 43.539702, 35.255156, 45.826237, 45.046936,
+				},
+			},
+		},
+		{
+			name:   "OBV",
+			series: lines(func() ([]float64, error) { return OBVSeries(candles) }, ident),
+			stream: func(t *testing.T) [][]float64 {
+				o := NewOBV()
+				return collect(t, candles, o.Update, o.Ready, o.Value, ident)
+			},
+			want: [][]float64{{
+				0, -950, -2380, -1510, -500, -1820, -830, 970, -180, 860,
+				-400, 1100, 120, 2220, 5620, 9520, 6920, 8620, 10470, 8170,
+				9570, 10920, 9020, 6270, 4670, 6170, 3070, 5270, 2370, 370,
+			}},
+		},
+		{
+			// Six candles make a day, the average restarts every UTC midnight
+			name:   "VWAP daily",
+			series: lines(func() ([]float64, error) { return VWAPSeries(candles, 24*time.Hour) }, ident),
+			stream: func(t *testing.T) [][]float64 {
+				v := NewVWAP(24 * time.Hour)
+				return collect(t, candles, v.Update, v.Ready, v.Value, ident)
+			},
+			want: [][]float64{{
+				22.276667, 22.244264, 22.182644, 22.181476, 22.183669, 22.176465, 22.236667, 22.374301, 22.338993, 22.331546,
+				22.296907, 22.318178, 22.386667, 22.554848, 22.998292, 23.407219, 23.481222, 23.523928, 23.973333, 23.799679,
+				23.809009, 23.824855, 23.792860, 23.657258, 23.123333, 23.231398, 22.975699, 23.015238, 22.866755, 22.765990,
+			}},
+		},
+		{
+			// The close drops through the lower band on the 27th candle and the line flips above the price
+			name:   "Supertrend 7 3",
+			series: lines(func() ([]SupertrendValue, error) { return SupertrendSeries(candles, 7, 3) }, supertrendLines...),
+			stream: func(t *testing.T) [][]float64 {
+				s := NewSupertrend(7, 3)
+				return collect(t, candles, s.Update, s.Ready, s.Value, supertrendLines...)
+			},
+			want: [][]float64{
+				{
+					21.812857, 21.812857, 21.812857, 21.812857, 21.812857, 21.812857, 21.877854, 22.286732, 22.696485, 22.696485,
+					22.696485, 22.708880, 22.708880, 22.708880, 22.709965, 22.709965, 22.709965, 22.709965, 22.709965, 24.186881,
+					24.186881, 24.166382, 23.835470,
+				},
+				{
+					1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+					1, 1, 1, 1, 1, 1, 1, 1, 1, 0,
+					0, 0, 0,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch, err := tt.series()
+			if err != nil {
+				t.Fatalf("series: %v", err)
+			}
+			streamed := tt.stream(t)
+
+			for i, want := range tt.want {
+				expectNear(t, "series", i, batch[i], want, tt.tolerance)
+				expectNear(t, "stream", i, streamed[i], want, tt.tolerance)
+				// Both paths run the same arithmetic, so they agree to the bit
+				for j := range batch[i] {
+					if batch[i][j] != streamed[i][j] {
+						t.Errorf("line %d value %d: series %v, stream %v", i, j, batch[i][j], streamed[i][j])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSeriesNotEnoughData(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{"SMA", func() error { _, err := SMASeries(closes[:9], 10); return err }},
+		{"EMA", func() error { _, err := EMASeries(closes[:9], 10); return err }},
+		{"WMA", func() error { _, err := WMASeries(closes[:4], 5); return err }},
+		{"RSI", func() error { _, err := RSISeries(rsiCloses[:14], 14); return err }},
+		{"MACD", func() error { _, err := MACDSeries(closes[:12], 5, 10, 4); return err }},
+		{"Bollinger", func() error { _, err := BollingerSeries(closes[:9], 10, 2); return err }},
+		{"Stochastic", func() error { _, err := StochasticSeries(candles[:8], 5, 3, 3); return err }},
+		{"ATR", func() error { _, err := ATRSeries(candles[:7], 7); return err }},
+		{"ADX", func() error { _, err := ADXSeries(candles[:13], 7); return err }},
+		{"OBV", func() error { _, err := OBVSeries(nil); return err }},
+		{"VWAP", func() error { _, err := VWAPSeries(nil, 0); return err }},
+		{"Supertrend", func() error { _, err := SupertrendSeries(candles[:7], 7, 3); return err }},
+	}
+
+	for _, tt := range tests {
+		if err := tt.run(); err == nil {
+			t.Errorf("%s: expected an error one value short of the first result", tt.name)
+		}
+	}
+}
+
+// collect feeds the inputs one at a time and splits the values after the indicator is ready into
+// lines, checking that Value returns what Update did
+func collect[In, Out any](t *testing.T, inputs []In, update func(In) Out, ready func() bool, value func() Out, fields ...func(Out) float64) [][]float64 {
+	result := make([][]float64, len(fields))
+	for _, input := range inputs {
+		got := update(input)
+		if !ready() {
+			continue
+		}
+		for i, field := range fields {
+			if v := field(value()); v != field(got) {
+				t.Errorf("Value returned %v after Update returned %v", v, field(got))
+			}
+			result[i] = append(result[i], field(got))
+		}
+	}
+	return result
+}
+
+// lines splits the result of a Series function into lines
+func lines[Out any](series func() ([]Out, error), fields ...func(Out) float64) func() ([][]float64, error) {
+	return func() ([][]float64, error) {
+		values, err := series()
+		if err != nil {
+			return nil, err
+		}
+		result := make([][]float64, len(fields))
+		for _, v := range values {
+			for i, field := range fields {
+				result[i] = append(result[i], field(v))
+			}
+		}
+		return result, nil
+	}
+}
+
+func ident(v float64) float64 { return v }
+
+var (
+	macdLines = []func(MACDValue) float64{
+		func(v MACDValue) float64 { return v.MACD },
+		func(v MACDValue) float64 { return v.Signal },
+		func(v MACDValue) float64 { return v.Histogram },
+	}
+	bollingerLines = []func(BollingerValue) float64{
+		func(v BollingerValue) float64 { return v.Middle },
+		func(v BollingerValue) float64 { return v.Upper },
+		func(v BollingerValue) float64 { return v.Lower },
+	}
+	stochasticLines = []func(StochasticValue) float64{
+		func(v StochasticValue) float64 { return v.K },
+		func(v StochasticValue) float64 { return v.D },
+	}
+	adxLines = []func(ADXValue) float64{
+		func(v ADXValue) float64 { return v.ADX },
+		func(v ADXValue) float64 { return v.PlusDI },
+		func(v ADXValue) float64 { return v.MinusDI },
+	}
+	supertrendLines = []func(SupertrendValue) float64{
+		func(v SupertrendValue) float64 { return v.Value },
+		func(v SupertrendValue) float64 {
+			if v.Up {
+				return 1
+			}
+			return 0
+		},
+	}
+)
+
+// expectNear compares a line with the reference values, which are rounded to 6 decimals unless a
+// tolerance is given
+func expectNear(t *testing.T, path string, line int, got, want []float64, tolerance float64) {
+	t.Helper()
+	if tolerance == 0 {
+		tolerance = 1e-6
+	}
+	if len(got) != len(want) {
+		t.Fatalf("%s line %d: got %d values, want %d", path, line, len(got), len(want))
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > tolerance {
+			t.Errorf("%s line %d value %d: got %.6f, want %.6f", path, line, i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package indicators
+
+// MACDValue is a value of the moving average convergence divergence
+type MACDValue struct {
+	MACD      float64 // Fast EMA minus slow EMA
+	Signal    float64 // EMA of the MACD line
+	Histogram float64 // MACD line minus signal line
+}
+
+// MACD is the moving average convergence divergence of two EMAs and the EMA of their difference
+type MACD struct {
+	fast   *EMA
+	slow   *EMA
+	signal *EMA
+	value  MACDValue
+}
+
+// NewMACD creates a moving average convergence divergence, its MACD line is ready after slow values
+// and the signal line signal-1 values later
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{fast: NewEMA(fast), slow: NewEMA(slow), signal: NewEMA(signal)}
+}
+
+// Update adds a value and returns the lines
+func (m *MACD) Update(value float64) MACDValue {
+	m.fast.Update(value)
+	m.slow.Update(value)
+	if !m.fast.Ready() || !m.slow.Ready() {
+		return m.value
+	}
+
+	m.value.MACD = m.fast.Value() - m.slow.Value()
+	m.signal.Update(m.value.MACD)
+	if m.signal.Ready() {
+		m.value.Signal = m.signal.Value()
+		m.value.Histogram = m.value.MACD - m.value.Signal
+	}
+	return m.value
+}
+
+// Ready reports whether the signal line is complete
+func (m *MACD) Ready() bool {
+	return m.signal.Ready()
+}
+
+// Value returns the latest lines
+func (m *MACD) Value() MACDValue {
+	return m.value
+}
+
+// MACDSeries returns the lines of the values from the first complete signal line
+func MACDSeries(values []float64, fast, slow, signal int) ([]MACDValue, error) {
+	m := NewMACD(fast, slow, signal)
+	return series("MACD", max(fast, slow)+signal-1, values, m.Update, m.Ready)
+}
@@ -0,0 +1,70 @@
+package indicators
+
+// RSI is Wilder's relative strength index of the last Period changes, between 0 and 100
+type RSI struct {
+	period  int
+	count   int // Values seen
+	prev    float64
+	avgGain float64
+	avgLoss float64
+	value   float64
+}
+
+// NewRSI creates a relative strength index, it is ready after period+1 values
+func NewRSI(period int) *RSI {
+	mustPositive("RSI", period)
+	return &RSI{period: period}
+}
+
+// Update adds a value and returns the index
+func (r *RSI) Update(value float64) float64 {
+	r.count++
+	if r.count == 1 {
+		r.prev = value
+		return r.value
+	}
+
+	gain, loss := 0.0, 0.0
+	if change := value - r.prev; change > 0 {
+		gain = change
+	} else {
+		loss = -change
+	}
+	r.prev = value
+
+	n := float64(r.period)
+	if r.count <= r.period+1 {
+		// The first averages are plain averages of the first period changes
+		r.avgGain += gain / n
+		r.avgLoss += loss / n
+		if r.count <= r.period {
+			return r.value
+		}
+	} else {
+		r.avgGain = (r.avgGain*(n-1) + gain) / n
+		r.avgLoss = (r.avgLoss*(n-1) + loss) / n
+	}
+
+	if total := r.avgGain + r.avgLoss; total > 0 {
+		r.value = 100 * r.avgGain / total
+	} else {
+		r.value = 50 // A flat market is neither overbought nor oversold
+	}
+	return r.value
+}
+
+// Ready reports whether period changes were seen
+func (r *RSI) Ready() bool {
+	return r.count > r.period
+}
+
+// Value returns the latest index, 0 until ready
+func (r *RSI) Value() float64 {
+	return r.value
+}
+
+// RSISeries returns the relative strength indexes of the values from the first full period of changes
+func RSISeries(values []float64, period int) ([]float64, error) {
+	r := NewRSI(period)
+	return series("RSI", period+1, values, r.Update, r.Ready)
+}
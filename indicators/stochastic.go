@@ -0,0 +1,73 @@
+package indicators
+
+import "binance_bot/models"
+
+// StochasticValue is a value of the stochastic oscillator, both lines between 0 and 100
+type StochasticValue struct {
+	K float64 // Close within the range of the last KPeriod candles, smoothed over KSmoothing candles
+	D float64 // SMA of %K over DPeriod candles
+}
+
+// Stochastic is the stochastic oscillator. A smoothing of 1 gives the fast oscillator, 3 the
+// common slow one.
+type Stochastic struct {
+	high  *extreme
+	low   *extreme
+	k     *SMA
+	d     *SMA
+	count int
+	need  int // Candles of the first complete %K window
+	value StochasticValue
+}
+
+// NewStochastic creates a stochastic oscillator, it is ready after kPeriod+kSmoothing+dPeriod-2 candles
+func NewStochastic(kPeriod, kSmoothing, dPeriod int) *Stochastic {
+	mustPositive("stochastic", kPeriod)
+	return &Stochastic{
+		high: newExtreme(kPeriod, true),
+		low:  newExtreme(kPeriod, false),
+		k:    NewSMA(kSmoothing),
+		d:    NewSMA(dPeriod),
+		need: kPeriod,
+	}
+}
+
+// Update adds a candle and returns the lines
+func (s *Stochastic) Update(c models.CandleStick) StochasticValue {
+	high, low := s.high.push(c.High), s.low.push(c.Low)
+	s.count++
+	if s.count < s.need {
+		return s.value
+	}
+
+	raw := 50.0 // A flat range puts the close in the middle
+	if high > low {
+		raw = (c.Close - low) / (high - low) * 100
+	}
+	s.k.Update(raw)
+	if !s.k.Ready() {
+		return s.value
+	}
+	s.value.K = s.k.Value()
+	s.d.Update(s.value.K)
+	if s.d.Ready() {
+		s.value.D = s.d.Value()
+	}
+	return s.value
+}
+
+// Ready reports whether %D is complete
+func (s *Stochastic) Ready() bool {
+	return s.d.Ready()
+}
+
+// Value returns the latest lines
+func (s *Stochastic) Value() StochasticValue {
+	return s.value
+}
+
+// StochasticSeries returns the lines of the candles from the first complete %D
+func StochasticSeries(candles []models.CandleStick, kPeriod, kSmoothing, dPeriod int) ([]StochasticValue, error) {
+	s := NewStochastic(kPeriod, kSmoothing, dPeriod)
+	return series("stochastic", kPeriod+kSmoothing+dPeriod-2, candles, s.Update, s.Ready)
+}
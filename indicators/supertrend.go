@@ -0,0 +1,78 @@
+package indicators
+
+import "binance_bot/models"
+
+// SupertrendValue is a value of the Supertrend
+type SupertrendValue struct {
+	Value float64 // The lower band in an uptrend and the upper band in a downtrend
+	Up    bool    // Whether the close is above the line
+}
+
+// Supertrend is a trailing line a multiple of the ATR away from the middle of the candle range.
+// The line only moves with the trend and flips to the other side when the close crosses it.
+type Supertrend struct {
+	atr        *ATR
+	multiplier float64
+	upper      float64
+	lower      float64
+	prevClose  float64
+	started    bool // Whether the bands hold a value
+	value      SupertrendValue
+}
+
+// NewSupertrend creates a Supertrend on an ATR of period candles, it is ready after period+1 candles
+func NewSupertrend(period int, multiplier float64) *Supertrend {
+	return &Supertrend{atr: NewATR(period), multiplier: multiplier}
+}
+
+// Update adds a candle and returns the line
+func (s *Supertrend) Update(c models.CandleStick) SupertrendValue {
+	atr := s.atr.Update(c)
+	prevClose := s.prevClose
+	s.prevClose = c.Close
+	if !s.atr.Ready() {
+		return s.value
+	}
+
+	mid := (c.High + c.Low) / 2
+	upper, lower := mid+s.multiplier*atr, mid-s.multiplier*atr
+	if !s.started {
+		s.upper, s.lower, s.started = upper, lower, true
+		s.value.Up = c.Close >= lower
+	} else {
+		// A band only tightens, unless the previous close broke through it
+		if upper < s.upper || prevClose > s.upper {
+			s.upper = upper
+		}
+		if lower > s.lower || prevClose < s.lower {
+			s.lower = lower
+		}
+		if s.value.Up {
+			s.value.Up = c.Close >= s.lower
+		} else {
+			s.value.Up = c.Close > s.upper
+		}
+	}
+
+	s.value.Value = s.upper
+	if s.value.Up {
+		s.value.Value = s.lower
+	}
+	return s.value
+}
+
+// Ready reports whether the ATR is complete
+func (s *Supertrend) Ready() bool {
+	return s.atr.Ready()
+}
+
+// Value returns the latest line
+func (s *Supertrend) Value() SupertrendValue {
+	return s.value
+}
+
+// SupertrendSeries returns the lines of the candles from the first complete ATR
+func SupertrendSeries(candles []models.CandleStick, period int, multiplier float64) ([]SupertrendValue, error) {
+	s := NewSupertrend(period, multiplier)
+	return series("Supertrend", period+1, candles, s.Update, s.Ready)
+}
@@ -0,0 +1,98 @@
+package indicators
+
+import (
+	"binance_bot/models"
+	"time"
+)
+
+// OBV is the on-balance volume, the running sum of the volume of up candles minus that of down
+// candles. It starts at 0, only its changes carry meaning.
+type OBV struct {
+	prevClose float64
+	started   bool
+	value     float64
+}
+
+// NewOBV creates an on-balance volume, it is ready after the first candle
+func NewOBV() *OBV {
+	return &OBV{}
+}
+
+// Update adds a candle and returns the on-balance volume
+func (o *OBV) Update(c models.CandleStick) float64 {
+	if o.started {
+		if c.Close > o.prevClose {
+			o.value += c.Volume
+		} else if c.Close < o.prevClose {
+			o.value -= c.Volume
+		}
+	}
+	o.prevClose, o.started = c.Close, true
+	return o.value
+}
+
+// Ready reports whether a candle was seen
+func (o *OBV) Ready() bool {
+	return o.started
+}
+
+// Value returns the latest on-balance volume
+func (o *OBV) Value() float64 {
+	return o.value
+}
+
+// OBVSeries returns the on-balance volume after every candle
+func OBVSeries(candles []models.CandleStick) ([]float64, error) {
+	o := NewOBV()
+	return series("OBV", 1, candles, o.Update, o.Ready)
+}
+
+// VWAP is the volume weighted average of the typical price (high+low+close)/3 of the candles
+type VWAP struct {
+	session time.Duration // Length of the sessions the average restarts with, 0 never restarts
+	start   time.Time
+	volume  float64
+	value   float64 // Sum of the typical prices times the volume
+	last    float64 // Typical price of the latest candle
+	started bool
+}
+
+// NewVWAP creates a volume weighted average price restarting every session, candles are assigned
+// to sessions by their open time in UTC. A session of 24h gives the common daily VWAP, 0 averages
+// all candles.
+func NewVWAP(session time.Duration) *VWAP {
+	return &VWAP{session: session}
+}
+
+// Update adds a candle and returns the average price of the session
+func (v *VWAP) Update(c models.CandleStick) float64 {
+	if v.session > 0 {
+		if start := c.Timestamp.UTC().Truncate(v.session); !start.Equal(v.start) {
+			v.start, v.volume, v.value = start, 0, 0
+		}
+	}
+	v.last = (c.High + c.Low + c.Close) / 3
+	v.volume += c.Volume
+	v.value += v.last * c.Volume
+	v.started = true
+	return v.Value()
+}
+
+// Ready reports whether a candle was seen
+func (v *VWAP) Ready() bool {
+	return v.started
+}
+
+// Value returns the latest average price, the typical price while the session has no volume
+func (v *VWAP) Value() float64 {
+	if v.volume == 0 {
+		return v.last
+	}
+	return v.value / v.volume
+}
+
+// VWAPSeries returns the volume weighted average price after every candle
+func VWAPSeries(candles []models.CandleStick, session time.Duration) ([]float64, error) {
+	v := NewVWAP(session)
+	return series("VWAP", 1, candles, v.Update, v.Ready)
+}
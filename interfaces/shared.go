@@ -1,8 +1,12 @@
 package interfaces
 
 import (
+	"binance_bot/exits"
 	"binance_bot/models"
 	"binance_bot/strategies"
+	"context"
+	"github.com/shopspring/decimal"
+	"time"
 )
 
 // Exchange interface defines methods our bot needs from an exchange
@@ -11,29 +15,99 @@ type Exchange interface {
 	CreateOrder(symbol, orderType, side string, amount float64) error
 	CreateLimitOrder(symbol, side, quantity, price string) (int64, error)
 	CreateStopLossLimitOrder(symbol, side, quantity, price, stopLoss string) (int64, error)
-	MonitorOrder(symbol string, orderID int64) (bool, error)
+	MonitorOrder(ctx context.Context, symbol string, orderID int64) (bool, error)
 	CancelOrder(symbol string, orderID int64) error
-	GetBalance(asset string) (float64, error)
+	GetBalance(asset string) (decimal.Decimal, error)
 }
 
 // Strategy interface for implementing different trading strategies
 type Strategy interface {
 	GetStrategyType() strategies.StrategyType
-	Calculate(candles []models.CandleStick, pair string, trend bool) (signal int, err error)
+	Calculate(candles []models.CandleStick, pair string, trend bool) (signal models.Signal, err error)
 }
 
 // ExchangeClient interface defines methods our bot needs from an exchange client
 type ExchangeClient interface {
 	AddTradingPair(pair models.TradingPair) error
-	GetCurrentPrice(symbol string) (float64, error)
+	GetCurrentPrice(symbol string) (decimal.Decimal, error)
+	GetOrderBook(symbol string, limit int) (*models.OrderBook, error)
+	GetBookTicker(symbol string) (*models.BookTicker, error)
 	FetchCandles(symbol, interval string, limit int) ([]models.CandleStick, error)
-	GetBalance(asset string) (float64, error)
+	GetBalance(asset string) (decimal.Decimal, error)
+	GetBalances() (map[string]models.Balance, error)
 	CreateOrder(symbol, orderType, side string, amount string) (float64, error)
-	CreateMarketOrder(symbol, side, quantity string) (float64, error)
+	CreateMarketOrder(symbol, side, quantity string) (*models.Order, error)
 	CreateLimitOrder(symbol, side, quantity, price string) (int64, error)
 	CreateStopLossLimitOrder(symbol, side, quantity, price, stopLoss string) (int64, error)
-	MonitorOrder(symbol string, orderID int64) (bool, error)
+	GetOrder(symbol string, orderID int64) (*models.Order, error)
+	GetOpenOrders(symbol string) ([]*models.Order, error)
+	MonitorOrder(ctx context.Context, symbol string, orderID int64) (bool, error)
 	CancelOrder(symbol string, orderID int64) error
 	GetFeeRate() (float64, error)
 	GetTradingPairs() map[string]*models.TradingPair
 }
+
+// OrderTracker persists placed orders and applies their fills to the positions
+type OrderTracker interface {
+	Track(order *models.Order) error
+	Refresh(symbol string, orderID int64) (*models.Order, error)
+}
+
+// MarketDataClient interface defines the market data methods of an exchange client
+type MarketDataClient interface {
+	AddTradingPair(pair models.TradingPair) error
+	GetCurrentPrice(symbol string) (decimal.Decimal, error)
+	FetchCandles(symbol, interval string, limit int) ([]models.CandleStick, error)
+	GetTradingPairs() map[string]*models.TradingPair
+}
+
+// OrderBookClient is implemented by market data sources that can report the order book of a symbol
+type OrderBookClient interface {
+	GetOrderBook(symbol string, limit int) (*models.OrderBook, error)
+	GetBookTicker(symbol string) (*models.BookTicker, error)
+}
+
+// MarketStreamer is implemented by clients that can push market data over a websocket
+type MarketStreamer interface {
+	StartStreaming(endpoint, interval string, window int) error
+	StopStreaming()
+}
+
+// PriceWatcher is implemented by clients that push the price of a symbol as their market data
+// stream delivers it. The channel holds the latest price only, stop ends the updates.
+type PriceWatcher interface {
+	WatchPrice(symbol string) (updates <-chan decimal.Decimal, stop func())
+}
+
+// ServerClock is implemented by clients that can report the exchange time
+type ServerClock interface {
+	GetServerTime() (time.Time, error)
+}
+
+// RulesRefresher is implemented by clients that can fetch the trading rules of their pairs again
+type RulesRefresher interface {
+	RefreshRules() error
+}
+
+// OCOClient is implemented by clients that can place a one-cancels-the-other pair of a limit
+// order and a stop-limit order, the legs are returned as placed
+type OCOClient interface {
+	CreateOCOOrder(symbol, side, quantity, price, stopPrice, stopLimitPrice string) ([]*models.Order, error)
+}
+
+// MakerClient is implemented by clients that can place post-only limit orders, which are
+// rejected instead of taking liquidity
+type MakerClient interface {
+	CreateLimitMakerOrder(symbol, side, quantity, price string) (int64, error)
+}
+
+// ExitRuleProvider is implemented by strategies that bring their own exit rules
+type ExitRuleProvider interface {
+	ExitRules() exits.Config
+}
+
+// IntraCandleStrategy is implemented by strategies that opt in to being evaluated
+// on the forming candle instead of once per closed candle
+type IntraCandleStrategy interface {
+	IntraCandle() bool
+}
@@ -0,0 +1,133 @@
+package liquidity
+
+import (
+	"binance_bot/interfaces"
+	"binance_bot/logger"
+	"binance_bot/models"
+	"binance_bot/utils"
+	"fmt"
+	"github.com/shopspring/decimal"
+)
+
+// defaultDepth is the number of book levels fetched to estimate the slippage
+const defaultDepth = 100
+
+// Filter bounds the spread and the estimated slippage of the orders of a pair, 0 disables a bound
+type Filter struct {
+	MaxSpreadPercent   float64 `json:"max_spread_percent"`   // Largest spread between the best bid and ask in percent of their mid price
+	MaxSlippagePercent float64 `json:"max_slippage_percent"` // Largest estimated slippage of the order against the best price in percent
+	Depth              int     `json:"depth"`                // Book levels fetched to estimate the slippage, 100 when 0
+}
+
+// Validate checks the bounds of a filter and reports all problems at once
+func (f Filter) Validate() error {
+	var v utils.Validation
+
+	v.Check(f.MaxSpreadPercent >= 0 && f.MaxSpreadPercent < 100, "max_spread_percent: must be between 0 and 100, got %v", f.MaxSpreadPercent)
+	v.Check(f.MaxSlippagePercent >= 0 && f.MaxSlippagePercent < 100, "max_slippage_percent: must be between 0 and 100, got %v", f.MaxSlippagePercent)
+	v.Check(f.Depth >= 0 && f.Depth <= 5000, "depth: must be between 0 and 5000, got %d", f.Depth)
+
+	return v.Err()
+}
+
+func (f Filter) depth() int {
+	if f.Depth == 0 {
+		return defaultDepth
+	}
+	return f.Depth
+}
+
+// Config holds the filter of every pair, the filter of a symbol takes precedence over the default
+type Config struct {
+	Filter                   // Default filter
+	Pairs  map[string]Filter `json:"pairs"` // Filter per symbol
+}
+
+// Validate checks the default filter and the filters of the symbols
+func (c Config) Validate() error {
+	var v utils.Validation
+	v.Merge(c.Filter.Validate())
+	for symbol, filter := range c.Pairs {
+		v.Add("pairs."+symbol, filter.Validate())
+	}
+	return v.Err()
+}
+
+// For returns the filter of a pair
+func (c Config) For(symbol string) Filter {
+	if filter, ok := c.Pairs[symbol]; ok {
+		return filter
+	}
+	return c.Filter
+}
+
+// Guard checks the order book of a pair before the bot acts on a signal, so it does not trade
+// where the spread is wide or the order would eat through several levels of the book
+type Guard struct {
+	cfg      Config
+	exchange interfaces.ExchangeClient
+}
+
+// NewGuard creates a guard reading the order books of an exchange
+func NewGuard(cfg Config, exchange interfaces.ExchangeClient) *Guard {
+	return &Guard{cfg: cfg, exchange: exchange}
+}
+
+// Check returns an error with the reason when an order spending a notional in the quote asset
+// breaks the filter of its pair, the rejection is logged. Pairs whose book cannot be fetched are
+// rejected as well.
+func (g *Guard) Check(pair *models.TradingPair, side string, notional decimal.Decimal) error {
+	err := g.check(g.cfg.For(pair.Symbol), pair.Symbol, side, notional)
+	if err != nil {
+		logger.Warnf("Liquidity filter skipped %s of %s %s on %s: %v", side, notional.StringFixed(2), pair.QuoteAsset, pair.Symbol, err)
+	}
+	return err
+}
+
+func (g *Guard) check(filter Filter, symbol, side string, notional decimal.Decimal) error {
+	// The best bid and ask are enough without a slippage bound
+	if filter.MaxSlippagePercent == 0 {
+		if filter.MaxSpreadPercent == 0 {
+			return nil
+		}
+		ticker, err := g.exchange.GetBookTicker(symbol)
+		if err != nil {
+			return fmt.Errorf("error fetching best bid and ask: %v", err)
+		}
+		return checkSpread(ticker.Spread(), filter.MaxSpreadPercent)
+	}
+
+	book, err := g.exchange.GetOrderBook(symbol, filter.depth())
+	if err != nil {
+		return fmt.Errorf("error fetching order book: %v", err)
+	}
+	if filter.MaxSpreadPercent > 0 {
+		if err := checkSpread(book.Spread(), filter.MaxSpreadPercent); err != nil {
+			return err
+		}
+	}
+
+	_, slippage, ok := book.EstimateFill(side, notional)
+	if !ok {
+		return fmt.Errorf("the book cannot fill the order within %d levels", filter.depth())
+	}
+	if slippage.GreaterThan(decimal.NewFromFloat(filter.MaxSlippagePercent / 100)) {
+		return fmt.Errorf("estimated slippage %s%% exceeds the maximum of %v%%", percent(slippage), filter.MaxSlippagePercent)
+	}
+	return nil
+}
+
+// checkSpread rejects a spread above the maximum, or a book missing a side
+func checkSpread(spread decimal.Decimal, maxPercent float64) error {
+	if !spread.IsPositive() {
+		return fmt.Errorf("the book has no bid or no ask")
+	}
+	if spread.GreaterThan(decimal.NewFromFloat(maxPercent / 100)) {
+		return fmt.Errorf("spread %s%% exceeds the maximum of %v%%", percent(spread), maxPercent)
+	}
+	return nil
+}
+
+func percent(fraction decimal.Decimal) string {
+	return fraction.Mul(decimal.NewFromInt(100)).StringFixed(3)
+}
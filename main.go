@@ -1,12 +1,17 @@
 package main
 
 import (
+	"binance_bot/backtest"
 	"binance_bot/bot"
 	"binance_bot/client"
+	"binance_bot/config"
 	sqlite "binance_bot/db"
+	"binance_bot/interfaces"
 	"binance_bot/logger"
 	"binance_bot/metrics"
-	"binance_bot/models"
+	"binance_bot/reconcile"
+	"binance_bot/risk"
+	"binance_bot/sizing"
 	"binance_bot/strategies"
 	"flag"
 	"fmt"
@@ -21,20 +26,74 @@ func main() {
 	// Set up logging
 	// Define a flag for log level
 	logLevel := flag.String("log", "info", "Log level: debug, info, warn, error")
+	backtestDir := flag.String("backtest", "", "Run a backtest on the <SYMBOL>.csv candle files in this directory and exit")
+	downloadDir := flag.String("download", "", "Store the latest candles of every trading pair in this directory and exit")
+	paper := flag.Bool("paper", false, "Paper trade: use live prices but fill orders locally against a virtual wallet")
+	configPath := flag.String("config", "", "Path to a JSON config file, built-in defaults are used when empty")
+	reconcileMode := flag.String("reconcile", "", "Startup reconciliation mode: off, flag, adopt or close. Overrides the config file")
+	listStrategies := flag.Bool("strategies", false, "List the available strategies and their parameters and exit")
 	flag.Parse()
 	logger.InitLogger(logLevel)
 
-	err := godotenv.Load()
+	if *listStrategies {
+		for _, def := range strategies.Registered() {
+			fmt.Println(def.Usage())
+		}
+		return
+	}
+
+	// Load configuration
+	cfg := config.Default()
+	if *configPath != "" {
+		var err error
+		cfg, err = config.Load(*configPath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+	}
+	if *paper || *reconcileMode != "" {
+		if *paper {
+			cfg.Paper.Enabled = true
+		}
+		if *reconcileMode != "" {
+			cfg.Reconcile = *reconcileMode
+		}
+		if err := cfg.Validate(); err != nil {
+			log.Fatalf("Invalid config: %v", err)
+		}
+	}
+
+	// Create trading strategy
+	strategy, err := cfg.BuildStrategy()
+	if err != nil {
+		log.Fatalf("Failed to create strategy: %v", err)
+	}
+	sizers, err := cfg.BuildSizers()
+	if err != nil {
+		log.Fatalf("Failed to create position sizers: %v", err)
+	}
+
+	if *backtestDir != "" {
+		runBacktest(*backtestDir, cfg, strategy, sizers)
+		return
+	}
+
+	err = godotenv.Load()
 	if err != nil {
 		fmt.Println("Error loading .env file")
 	}
 
-	if os.Getenv("BINANCE_API_KEY") == "" || os.Getenv("BINANCE_API_SECRET") == "" {
+	// Paper trading only needs public market data
+	if !cfg.Paper.Enabled && (os.Getenv("BINANCE_API_KEY") == "" || os.Getenv("BINANCE_API_SECRET") == "") {
 		log.Fatal("BINANCE_API_KEY or BINANCE_API_SECRET not set")
 	}
 
-	// Initialize database
-	err = sqlite.InitDB()
+	// Initialize database, paper trades are kept apart from live trades
+	if cfg.Paper.Enabled {
+		err = sqlite.InitDBAt(cfg.Paper.DBPath)
+	} else {
+		err = sqlite.InitDBAt(cfg.DBPath)
+	}
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
@@ -49,81 +108,63 @@ func main() {
 		log.Fatalf("Failed to create Binance client: %v", err)
 	}
 
-	// Create trading strategy
-	strategy := &strategies.CompoundStrategy{
-		RSI: &strategies.RSIStrategy{
-			Overbought: 65,
-			Oversold:   40,
-			Period:     18,
-		},
-		MACD: &strategies.MACDStrategy{
-			FastPeriod:   15, // Short-term EMA
-			SlowPeriod:   30, // Long-term EMA
-			SignalPeriod: 10, // Signal line EMA
-		},
-		FeeRate:                   0.001,
-		DesiredProfit:             50.0,
-		HighestPriceFallOffMargin: 2.0,
-	}
-
-	//strategy := &strategies.SpikeStrategy{
-	//	VolumeThreshold: 5000,
-	//}
-
-	bt := bot.NewMultiPairTradingBot(cl, strategy, "15m")
-
-	// Trading pairs
-	pairs := []models.TradingPair{
-		models.NewTradingPair("BTCUSDT"),
-		models.NewTradingPair("ETHUSDT"),
-		models.NewTradingPair("DOGEUSDT"),
-		models.NewTradingPair("XRPUSDT"),
-		models.NewTradingPair("SOLUSDT"),
-		models.NewTradingPair("FTMUSDT"),
-		models.NewTradingPair("ADAUSDT"),
-		models.NewTradingPair("HBARUSDT"),
-		models.NewTradingPair("POWRUSDT"),
-		models.NewTradingPair("OGUSDT"),
-		models.NewTradingPair("BNBUSDT"),
-		models.NewTradingPair("CTXCUSDT"),
-		models.NewTradingPair("SCRTUSDT"),
-		models.NewTradingPair("XLMUSDT"),
-		models.NewTradingPair("AVAXUSDT"),
-		models.NewTradingPair("ALGOUSDT"),
-		models.NewTradingPair("DEGOUSDT"),
-		models.NewTradingPair("IOTAUSDT"),
-		models.NewTradingPair("EOSUSDT"),
-		models.NewTradingPair("DGBUSDT"),
-		models.NewTradingPair("THETAUSDT"),
-		models.NewTradingPair("HOTUSDT"),
-		models.NewTradingPair("FIDAUSDT"),
-		models.NewTradingPair("WLDUSDT"),
-		models.NewTradingPair("LUMIAUSDT"),
-		models.NewTradingPair("TRXUSDT"),
-		models.NewTradingPair("SHIBUSDT"),
-		models.NewTradingPair("DOTUSDT"),
-		models.NewTradingPair("LTCUSDT"),
-		models.NewTradingPair("ICPUSDT"),
-		models.NewTradingPair("POLUSDT"),
-		models.NewTradingPair("ETCUSDT"),
-		models.NewTradingPair("TAOUSDT"),
-		models.NewTradingPair("APTUSDT"),
-		models.NewTradingPair("CRVUSDT"),
-		models.NewTradingPair("ACTUSDT"),
-		models.NewTradingPair("CETUSUST"),
-		models.NewTradingPair("FILUSDT"),
-		models.NewTradingPair("SUIUSDT"),
-		models.NewTradingPair("ORDIUSDT"),
-		models.NewTradingPair("WIFUSDT"),
-		models.NewTradingPair("FLOWUSDT"),
-	}
-
-	for _, pair := range pairs {
+	// Market data always comes from Binance, also when orders are filled locally
+	market := cl
+
+	if cfg.Paper.Enabled {
+		feeRate, err := cl.GetFeeRate()
+		if err != nil {
+			logger.Warnf("Failed to fetch fee rate, using 0.1%% for paper trading: %v", err)
+			feeRate = 0.001
+		}
+		cl = client.NewPaperClient(cl, client.PaperConfig{
+			Balances: map[string]float64{"USDT": cfg.Paper.Balance},
+			FeeRate:  feeRate,
+			Slippage: cfg.Paper.Slippage,
+			Persist:  true,
+		})
+	}
+
+	bt := bot.NewMultiPairTradingBot(cl, strategy, cfg.Interval)
+	bt.SetRiskManager(risk.NewManager(cfg.Risk, cl))
+	bt.SetPositionSizers(sizers)
+	if rules, ok := cfg.ExitRules(); ok {
+		bt.SetExitRules(rules)
+	}
+	bt.SetProtection(cfg.Protection)
+	bt.SetExecutionPolicy(cfg.Execution, nil)
+	bt.SetLiquidityFilters(cfg.Liquidity)
+	bt.SetGrids(cfg.Grid)
+
+	for _, pair := range cfg.TradingPairs() {
 		if err := cl.AddTradingPair(pair); err != nil {
 			logger.Infof("Failed to add trading pair %s: %v", pair.Symbol, err)
 		}
 	}
 
+	if *downloadDir != "" {
+		if err := backtest.DownloadCandles(cl, cfg.Interval, 1000, *downloadDir); err != nil {
+			log.Fatalf("Failed to download candles: %v", err)
+		}
+		return
+	}
+
+	if streamer, ok := market.(interfaces.MarketStreamer); ok && cfg.MarketData.Streaming {
+		if err := streamer.StartStreaming(cfg.MarketData.WSURL, cfg.Interval, cfg.MarketData.Window); err != nil {
+			logger.Warnf("Failed to start market data stream, polling REST instead: %v", err)
+		} else {
+			defer streamer.StopStreaming()
+		}
+	}
+
+	// Compare the stored book with the account before trading on it
+	mode, _ := reconcile.ParseMode(cfg.Reconcile)
+	report, err := reconcile.Run(cl, mode)
+	if err != nil {
+		log.Fatalf("Reconciliation failed: %v", err)
+	}
+	report.Log(cl)
+
 	go metrics.MonitorPerformance(cl)
 
 	go bt.StartTrading()
@@ -136,3 +177,36 @@ func main() {
 	bt.Stop()
 	log.Println("Trading bot stopped")
 }
+
+// runBacktest replays stored candles through the bot and writes the report next to them
+func runBacktest(dir string, cfg *config.Config, strategy interfaces.Strategy, sizers *sizing.Table) {
+	data, err := backtest.LoadCandles(dir)
+	if err != nil {
+		log.Fatalf("Failed to load candles: %v", err)
+	}
+
+	btCfg := backtest.DefaultConfig()
+	btCfg.Interval = cfg.Interval
+	btCfg.FeeRate = cfg.FeeRate
+	btCfg.Slippage = cfg.Paper.Slippage
+	btCfg.InitialBalance = cfg.Paper.Balance
+	btCfg.Risk = cfg.Risk
+	btCfg.Sizers = sizers
+	btCfg.Protection = cfg.Protection
+	btCfg.Execution = cfg.Execution
+	btCfg.Liquidity = cfg.Liquidity
+	btCfg.Grid = cfg.Grid
+	if rules, ok := cfg.ExitRules(); ok {
+		btCfg.Exits = &rules
+	}
+
+	result, err := backtest.Run(btCfg, strategy, data)
+	if err != nil {
+		log.Fatalf("Backtest failed: %v", err)
+	}
+
+	result.LogSummary()
+	if err := result.WriteReport(dir); err != nil {
+		log.Fatalf("Failed to write backtest report: %v", err)
+	}
+}
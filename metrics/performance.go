@@ -55,7 +55,7 @@ func calculatePerformance(db *sql.DB, binanceClient interfaces.ExchangeClient) (
 		}
 
 		// Calculate profit/loss for this trade
-		diff := (currentPrice - buyPrice) * quantity
+		diff := (currentPrice.InexactFloat64() - buyPrice) * quantity
 		if diff > 0 {
 			unrealizedProfit += diff
 		} else {
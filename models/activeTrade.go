@@ -1,8 +1,13 @@
 package models
 
+import "github.com/shopspring/decimal"
+
+// ActiveTrade is an open position lot bought by the bot
 type ActiveTrade struct {
-	ID       int     `json:"id" db:"id"`
-	Symbol   string  `json:"symbol" db:"symbol"`
-	BuyPrice float64 `json:"buy_price" db:"buy_price"`
-	Quantity float64 `json:"quantity" db:"quantity"`
+	ID       int             `json:"id" db:"id"`
+	Symbol   string          `json:"symbol" db:"symbol"`
+	BuyPrice decimal.Decimal `json:"buy_price" db:"buy_price"`
+	Quantity decimal.Decimal `json:"quantity" db:"quantity"`   // Remaining quantity, net of fees paid in the base asset
+	OrderID  int64           `json:"order_id" db:"order_id"`   // BUY order that opened the lot, 0 if adopted
+	EntryFee decimal.Decimal `json:"entry_fee" db:"entry_fee"` // Entry fee in quote asset of the remaining quantity
 }
@@ -0,0 +1,15 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+// Balance holds the free and locked amount of an asset in the account
+type Balance struct {
+	Asset  string
+	Free   decimal.Decimal
+	Locked decimal.Decimal // Held by open orders
+}
+
+// Total returns the free and locked amount
+func (b Balance) Total() decimal.Decimal {
+	return b.Free.Add(b.Locked)
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// ExecutionReport is the outcome of an order split into child orders by an execution algorithm
+type ExecutionReport struct {
+	ID           int64
+	Symbol       string
+	Side         string
+	Algorithm    string          // twap, iceberg or post_only
+	Quantity     decimal.Decimal // Quantity of the parent order
+	Filled       decimal.Decimal // Quantity filled by all child orders
+	ArrivalPrice decimal.Decimal // Market price when the order arrived
+	AveragePrice decimal.Decimal // Average fill price of all child orders, 0 without fills
+	Slippage     decimal.Decimal // Average price against the arrival price as a fraction, positive when worse
+	Children     int             // Child orders placed
+	Status       string          // FILLED, EXPIRED, CANCELED or REJECTED
+	StartedAt    time.Time
+	FinishedAt   time.Time
+}
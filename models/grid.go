@@ -0,0 +1,38 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Grid statuses
+const (
+	GridActive  = "ACTIVE"  // Orders rest on the exchange and are refilled
+	GridStopped = "STOPPED" // The price left the range or the settings changed, the orders were canceled
+)
+
+// Grid is a ladder of limit orders between two prices traded for a symbol
+type Grid struct {
+	ID            int64
+	Symbol        string
+	Lower         decimal.Decimal
+	Upper         decimal.Decimal
+	Levels        int    // Price levels from Lower to Upper, both included
+	Spacing       string // arithmetic or geometric
+	QuotePerLevel decimal.Decimal
+	Status        string
+	UpdatedAt     time.Time
+}
+
+// GridLevel is the slot between two neighbouring prices of a grid. It waits with a BUY at its buy
+// price, and once that fills with a SELL of the bought quantity at its sell price.
+type GridLevel struct {
+	GridID    int64
+	Level     int // Index of the buy price, 0 is the lower bound
+	BuyPrice  decimal.Decimal
+	SellPrice decimal.Decimal
+	Side      string          // Side of the next order of the slot
+	Quantity  decimal.Decimal // Quantity of the next order
+	OrderID   int64           // Order resting for the slot, 0 while none is placed
+	UpdatedAt time.Time
+}
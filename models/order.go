@@ -0,0 +1,42 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Order statuses as reported by the exchange
+const (
+	OrderStatusNew             = "NEW"
+	OrderStatusPartiallyFilled = "PARTIALLY_FILLED"
+	OrderStatusFilled          = "FILLED"
+	OrderStatusCanceled        = "CANCELED"
+	OrderStatusRejected        = "REJECTED"
+	OrderStatusExpired         = "EXPIRED"
+)
+
+// Order represents an exchange order and its fill progress
+type Order struct {
+	OrderID         int64           `json:"order_id" db:"order_id"`
+	Symbol          string          `json:"symbol" db:"symbol"`
+	Side            string          `json:"side" db:"side"`
+	Type            string          `json:"type" db:"type"`
+	Quantity        decimal.Decimal `json:"quantity" db:"quantity"`
+	Price           decimal.Decimal `json:"price" db:"price"`
+	Status          string          `json:"status" db:"status"`
+	FilledQty       decimal.Decimal `json:"filled_qty" db:"filled_qty"`
+	AvgPrice        decimal.Decimal `json:"avg_price" db:"avg_price"`
+	Commission      decimal.Decimal `json:"commission" db:"commission"`
+	CommissionAsset string          `json:"commission_asset" db:"commission_asset"`
+	UpdatedAt       time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// IsOpen reports whether the order can still receive fills
+func (o *Order) IsOpen() bool {
+	return o.Status == OrderStatusNew || o.Status == OrderStatusPartiallyFilled
+}
+
+// Remaining returns the quantity that has not been filled yet
+func (o *Order) Remaining() decimal.Decimal {
+	return o.Quantity.Sub(o.FilledQty)
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// BookLevel is a price level of an order book
+type BookLevel struct {
+	Price    decimal.Decimal
+	Quantity decimal.Decimal
+}
+
+// BookTicker holds the best bid and ask of a symbol
+type BookTicker struct {
+	Symbol   string
+	BidPrice decimal.Decimal
+	BidQty   decimal.Decimal
+	AskPrice decimal.Decimal
+	AskQty   decimal.Decimal
+}
+
+// Spread returns the difference of the best ask and bid as a fraction of their mid price, 0
+// without both sides
+func (t *BookTicker) Spread() decimal.Decimal {
+	return spread(t.BidPrice, t.AskPrice)
+}
+
+// OrderBook is a depth snapshot of a symbol, bids from the highest and asks from the lowest price
+type OrderBook struct {
+	Symbol    string
+	Bids      []BookLevel
+	Asks      []BookLevel
+	UpdatedAt time.Time
+}
+
+// Ticker returns the best bid and ask of the book, zero for an empty side
+func (b *OrderBook) Ticker() *BookTicker {
+	ticker := &BookTicker{Symbol: b.Symbol}
+	if len(b.Bids) > 0 {
+		ticker.BidPrice, ticker.BidQty = b.Bids[0].Price, b.Bids[0].Quantity
+	}
+	if len(b.Asks) > 0 {
+		ticker.AskPrice, ticker.AskQty = b.Asks[0].Price, b.Asks[0].Quantity
+	}
+	return ticker
+}
+
+// Spread returns the difference of the best ask and bid as a fraction of their mid price
+func (b *OrderBook) Spread() decimal.Decimal {
+	return b.Ticker().Spread()
+}
+
+// EstimateFill walks the book for a market order spending a notional in the quote asset, the asks
+// for a BUY and the bids for a SELL. It returns the average fill price and the slippage against
+// the best price as a fraction, and reports false when the book is too thin to fill the notional.
+func (b *OrderBook) EstimateFill(side string, notional decimal.Decimal) (decimal.Decimal, decimal.Decimal, bool) {
+	levels := b.Asks
+	if side == "SELL" {
+		levels = b.Bids
+	}
+	if len(levels) == 0 || !notional.IsPositive() {
+		return decimal.Zero, decimal.Zero, false
+	}
+
+	left, quantity := notional, decimal.Zero
+	for _, level := range levels {
+		value := level.Price.Mul(level.Quantity)
+		if value.GreaterThanOrEqual(left) {
+			quantity = quantity.Add(left.Div(level.Price))
+			left = decimal.Zero
+			break
+		}
+		quantity = quantity.Add(level.Quantity)
+		left = left.Sub(value)
+	}
+
+	filled := notional.Sub(left)
+	if !quantity.IsPositive() {
+		return decimal.Zero, decimal.Zero, false
+	}
+	average := filled.Div(quantity)
+	best := levels[0].Price
+	slippage := average.Sub(best).Div(best).Abs()
+	return average, slippage, left.IsZero()
+}
+
+func spread(bid, ask decimal.Decimal) decimal.Decimal {
+	if !bid.IsPositive() || !ask.IsPositive() {
+		return decimal.Zero
+	}
+	mid := bid.Add(ask).Div(decimal.NewFromInt(2))
+	return ask.Sub(bid).Div(mid)
+}
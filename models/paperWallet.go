@@ -0,0 +1,19 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+// PaperOrder is an order of the paper trading wallet as it is stored
+type PaperOrder struct {
+	Order
+	StopPrice decimal.Decimal
+	Triggered bool  // The stop price was reached or the order has none
+	Sibling   int64 // Other leg of an OCO, 0 for a single order
+	Shared    bool  // The sibling of an OCO holds the funds of both legs
+}
+
+// PaperWallet is the stored state of the paper trading wallet
+type PaperWallet struct {
+	Balances  map[string]Balance
+	CostBasis map[string]decimal.Decimal // Average entry price including buy fees per symbol
+	Orders    []*PaperOrder
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Protective order statuses
+const (
+	ProtectionActive    = "ACTIVE"    // Resting on the exchange
+	ProtectionReplaced  = "REPLACED"  // Canceled to move the stop or cover a new quantity
+	ProtectionReleased  = "RELEASED"  // Canceled so the bot can sell the position itself
+	ProtectionTriggered = "TRIGGERED" // Filled on the exchange, at least partially
+	ProtectionClosed    = "CLOSED"    // Canceled, expired or rejected outside the bot
+)
+
+// ProtectiveOrder is a stop order, or an OCO of a take-profit and a stop order, resting on the
+// exchange to protect an open position while the bot is not watching it
+type ProtectiveOrder struct {
+	ID           int64
+	Symbol       string
+	Type         string          // STOP_LOSS_LIMIT or OCO
+	StopOrderID  int64           // Order ID of the stop leg
+	LimitOrderID int64           // Order ID of the take-profit leg of an OCO, 0 otherwise
+	Position     decimal.Decimal // Open quantity of the position the order was placed for
+	Quantity     decimal.Decimal // Quantity of the order
+	StopPrice    decimal.Decimal
+	LimitPrice   decimal.Decimal // Limit price of the stop leg
+	TakeProfit   decimal.Decimal // Limit price of the take-profit leg, 0 without one
+	Status       string
+	UpdatedAt    time.Time
+}
+
+// LegIDs returns the order IDs of the legs of the protective order
+func (p *ProtectiveOrder) LegIDs() []int64 {
+	if p.LimitOrderID != 0 {
+		return []int64{p.StopOrderID, p.LimitOrderID}
+	}
+	return []int64{p.StopOrderID}
+}
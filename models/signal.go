@@ -0,0 +1,87 @@
+package models
+
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+	"sort"
+	"strings"
+)
+
+// SignalAction is the trade direction suggested by a strategy
+type SignalAction int
+
+const (
+	ActionSell SignalAction = -1
+	ActionHold SignalAction = 0
+	ActionBuy  SignalAction = 1
+)
+
+// String returns the name of the action
+func (a SignalAction) String() string {
+	switch a {
+	case ActionBuy:
+		return "BUY"
+	case ActionSell:
+		return "SELL"
+	default:
+		return "HOLD"
+	}
+}
+
+// Signal is the outcome of a strategy evaluation. Zero values leave the decision to the bot.
+type Signal struct {
+	Action       SignalAction
+	Strength     float64            // Confidence between 0 and 1, 0 is treated as 1
+	SizeFraction float64            // Fraction of the available balance to trade, 0 uses the bot default
+	StopLoss     decimal.Decimal    // Price to exit a position at a loss, 0 for none
+	TakeProfit   decimal.Decimal    // Price to exit a position in profit, 0 for none
+	Reason       string             // Human-readable explanation
+	Indicators   map[string]float64 // Indicator values that produced the signal
+}
+
+// HoldSignal returns a signal that does not trade
+func HoldSignal(reason string) Signal {
+	return Signal{Action: ActionHold, Reason: reason}
+}
+
+// SignalFromInt converts a -1/0/1 signal to a Signal
+func SignalFromInt(signal int) Signal {
+	switch {
+	case signal > 0:
+		return Signal{Action: ActionBuy, Strength: 1}
+	case signal < 0:
+		return Signal{Action: ActionSell, Strength: 1}
+	}
+	return Signal{Action: ActionHold}
+}
+
+// String formats the signal for logging
+func (s Signal) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s", s.Action)
+	if s.Strength > 0 {
+		fmt.Fprintf(&b, " strength=%.2f", s.Strength)
+	}
+	if s.SizeFraction > 0 {
+		fmt.Fprintf(&b, " size=%.2f", s.SizeFraction)
+	}
+	if s.StopLoss.IsPositive() {
+		fmt.Fprintf(&b, " stop=%s", s.StopLoss)
+	}
+	if s.TakeProfit.IsPositive() {
+		fmt.Fprintf(&b, " target=%s", s.TakeProfit)
+	}
+	if s.Reason != "" {
+		fmt.Fprintf(&b, " (%s)", s.Reason)
+	}
+
+	names := make([]string, 0, len(s.Indicators))
+	for name := range s.Indicators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, " %s=%.6f", name, s.Indicators[name])
+	}
+	return b.String()
+}
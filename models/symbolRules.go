@@ -0,0 +1,199 @@
+package models
+
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+	"strings"
+	"time"
+)
+
+// SymbolRules holds the trading rules of a symbol as published in the exchange filters.
+// Values are kept as exact decimals, zero means the exchange does not enforce the rule.
+type SymbolRules struct {
+	Status string // TRADING when orders are accepted
+
+	// PRICE_FILTER
+	TickSize decimal.Decimal
+	MinPrice decimal.Decimal
+	MaxPrice decimal.Decimal
+
+	// LOT_SIZE
+	StepSize decimal.Decimal
+	MinQty   decimal.Decimal
+	MaxQty   decimal.Decimal
+
+	// MARKET_LOT_SIZE, falls back to LOT_SIZE when not set
+	MarketStepSize decimal.Decimal
+	MarketMinQty   decimal.Decimal
+	MarketMaxQty   decimal.Decimal
+
+	// NOTIONAL or MIN_NOTIONAL
+	MinNotional              decimal.Decimal
+	MaxNotional              decimal.Decimal
+	ApplyMinNotionalToMarket bool
+	ApplyMaxNotionalToMarket bool
+
+	// PERCENT_PRICE_BY_SIDE or PERCENT_PRICE, as multipliers of the reference price
+	BidMultiplierUp   decimal.Decimal
+	BidMultiplierDown decimal.Decimal
+	AskMultiplierUp   decimal.Decimal
+	AskMultiplierDown decimal.Decimal
+
+	OrderTypes []string // Permitted order types, e.g. LIMIT, MARKET, STOP_LOSS_LIMIT
+	UpdatedAt  time.Time
+}
+
+// AllowsOrderType reports whether the order type is permitted, an empty list permits all
+func (r *SymbolRules) AllowsOrderType(orderType string) bool {
+	if len(r.OrderTypes) == 0 {
+		return true
+	}
+	for _, t := range r.OrderTypes {
+		if t == orderType {
+			return true
+		}
+	}
+	return false
+}
+
+// PriceDecimals returns the number of decimals of the tick size
+func (r *SymbolRules) PriceDecimals() int32 {
+	return decimals(r.TickSize)
+}
+
+// QtyDecimals returns the number of decimals of the step size
+func (r *SymbolRules) QtyDecimals(market bool) int32 {
+	step, _, _ := r.lot(market)
+	return decimals(step)
+}
+
+// QuantizePrice rounds a price down to the tick size
+func (r *SymbolRules) QuantizePrice(price decimal.Decimal) decimal.Decimal {
+	return floorTo(price, r.TickSize)
+}
+
+// QuantizeQty rounds a quantity down to the step size
+func (r *SymbolRules) QuantizeQty(qty decimal.Decimal, market bool) decimal.Decimal {
+	step, _, _ := r.lot(market)
+	return floorTo(qty, step)
+}
+
+// FormatPrice formats a price with the decimals of the tick size
+func (r *SymbolRules) FormatPrice(price decimal.Decimal) string {
+	return price.StringFixed(r.PriceDecimals())
+}
+
+// FormatQty formats a quantity with the decimals of the step size
+func (r *SymbolRules) FormatQty(qty decimal.Decimal, market bool) string {
+	return qty.StringFixed(r.QtyDecimals(market))
+}
+
+// Validate checks an order against the rules. price is zero for market orders, refPrice is the
+// current price used for notional and percent price checks of market orders, zero skips them.
+func (r *SymbolRules) Validate(orderType, side string, qty, price, refPrice decimal.Decimal) error {
+	if r.Status != "" && r.Status != "TRADING" {
+		return fmt.Errorf("symbol is not trading, status %s", r.Status)
+	}
+	if !r.AllowsOrderType(orderType) {
+		return fmt.Errorf("order type %s is not permitted", orderType)
+	}
+
+	market := orderType == "MARKET"
+	_, minQty, maxQty := r.lot(market)
+	if !qty.IsPositive() || qty.LessThan(minQty) {
+		return fmt.Errorf("quantity %s is below the minimum %s", qty, minQty)
+	}
+	if maxQty.IsPositive() && qty.GreaterThan(maxQty) {
+		return fmt.Errorf("quantity %s is above the maximum %s", qty, maxQty)
+	}
+
+	if !market {
+		if !price.IsPositive() || price.LessThan(r.MinPrice) {
+			return fmt.Errorf("price %s is below the minimum %s", price, r.MinPrice)
+		}
+		if r.MaxPrice.IsPositive() && price.GreaterThan(r.MaxPrice) {
+			return fmt.Errorf("price %s is above the maximum %s", price, r.MaxPrice)
+		}
+		if refPrice.IsPositive() {
+			up, down := r.BidMultiplierUp, r.BidMultiplierDown
+			if side == "SELL" {
+				up, down = r.AskMultiplierUp, r.AskMultiplierDown
+			}
+			if up.IsPositive() && price.GreaterThan(refPrice.Mul(up)) {
+				return fmt.Errorf("price %s is more than %s times the reference price %s", price, up, refPrice)
+			}
+			if down.IsPositive() && price.LessThan(refPrice.Mul(down)) {
+				return fmt.Errorf("price %s is less than %s times the reference price %s", price, down, refPrice)
+			}
+		}
+	}
+
+	notionalPrice := price
+	if market {
+		notionalPrice = refPrice
+	}
+	if notionalPrice.IsPositive() {
+		notional := qty.Mul(notionalPrice)
+		if (!market || r.ApplyMinNotionalToMarket) && notional.LessThan(r.MinNotional) {
+			return fmt.Errorf("notional %s is below the minimum %s", notional, r.MinNotional)
+		}
+		if (!market || r.ApplyMaxNotionalToMarket) && r.MaxNotional.IsPositive() && notional.GreaterThan(r.MaxNotional) {
+			return fmt.Errorf("notional %s is above the maximum %s", notional, r.MaxNotional)
+		}
+	}
+	return nil
+}
+
+// Quantize rounds an order to the rules, caps the quantity at the maximum and validates it
+func (r *SymbolRules) Quantize(orderType, side string, qty, price, refPrice decimal.Decimal) (decimal.Decimal, decimal.Decimal, error) {
+	market := orderType == "MARKET"
+	if _, _, maxQty := r.lot(market); maxQty.IsPositive() && qty.GreaterThan(maxQty) {
+		qty = maxQty
+	}
+	qty = r.QuantizeQty(qty, market)
+	if !market {
+		price = r.QuantizePrice(price)
+	}
+
+	if err := r.Validate(orderType, side, qty, price, refPrice); err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	return qty, price, nil
+}
+
+// PrepareOrder quantizes and validates an order and formats quantity and price for the exchange
+func (r *SymbolRules) PrepareOrder(orderType, side string, qty, price, refPrice decimal.Decimal) (string, string, error) {
+	qty, price, err := r.Quantize(orderType, side, qty, price, refPrice)
+	if err != nil {
+		return "", "", err
+	}
+	return r.FormatQty(qty, orderType == "MARKET"), r.FormatPrice(price), nil
+}
+
+// lot returns the step size and quantity bounds for limit or market orders
+func (r *SymbolRules) lot(market bool) (step, minQty, maxQty decimal.Decimal) {
+	if market && r.MarketStepSize.IsPositive() {
+		return r.MarketStepSize, decimal.Max(r.MarketMinQty, r.MinQty), r.MarketMaxQty
+	}
+	return r.StepSize, r.MinQty, r.MaxQty
+}
+
+// floorTo rounds a value down to a multiple of step
+func floorTo(value, step decimal.Decimal) decimal.Decimal {
+	if !step.IsPositive() {
+		return value
+	}
+	return value.Sub(value.Mod(step)).Truncate(decimals(step))
+}
+
+// decimals returns the number of significant decimals of a step such as 0.00100000
+func decimals(step decimal.Decimal) int32 {
+	if !step.IsPositive() {
+		return 8
+	}
+	s := step.String()
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return int32(len(s) - i - 1)
+	}
+	return 0
+}
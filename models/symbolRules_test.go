@@ -0,0 +1,177 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"strings"
+	"testing"
+)
+
+// shibRules are the rules of SHIBUSDT: a price with eight decimals and whole coin quantities
+func shibRules() *SymbolRules {
+	d := decimal.RequireFromString
+	return &SymbolRules{
+		Status:                   "TRADING",
+		TickSize:                 d("0.00000001"),
+		MinPrice:                 d("0.00000001"),
+		MaxPrice:                 d("1.00000000"),
+		StepSize:                 d("1.00"),
+		MinQty:                   d("1.00"),
+		MaxQty:                   d("92141578.00"),
+		MarketStepSize:           d("1.00"),
+		MarketMinQty:             d("0.00"),
+		MarketMaxQty:             d("50000000.00"),
+		MinNotional:              d("5.00"),
+		MaxNotional:              d("9000000.00"),
+		ApplyMinNotionalToMarket: true,
+		BidMultiplierUp:          d("5"),
+		BidMultiplierDown:        d("0.2"),
+		AskMultiplierUp:          d("5"),
+		AskMultiplierDown:        d("0.2"),
+		OrderTypes:               []string{"LIMIT", "LIMIT_MAKER", "MARKET", "STOP_LOSS_LIMIT", "TAKE_PROFIT_LIMIT"},
+	}
+}
+
+func TestPrepareOrder(t *testing.T) {
+	refPrice := decimal.RequireFromString("0.00002345")
+
+	tests := []struct {
+		name      string
+		rules     func(r *SymbolRules)
+		orderType string
+		side      string
+		qty       decimal.Decimal
+		price     decimal.Decimal
+		wantQty   string
+		wantPrice string
+		wantErr   string
+	}{
+		{
+			name:      "limit rounded down to tick and step",
+			orderType: "LIMIT", side: "BUY",
+			qty:     decimal.RequireFromString("1234567.89"),
+			price:   decimal.RequireFromString("0.0000234567"),
+			wantQty: "1234567", wantPrice: "0.00002345",
+		},
+		{
+			// Float noise just below a boundary must not round up past it
+			name:      "values from float arithmetic",
+			orderType: "LIMIT", side: "SELL",
+			qty:     decimal.NewFromFloat(2132199.9999999995),
+			price:   decimal.NewFromFloat(0.000023449999999),
+			wantQty: "2132199", wantPrice: "0.00002344",
+		},
+		{
+			name:      "quantity above the maximum is capped",
+			orderType: "LIMIT", side: "BUY",
+			qty:     decimal.NewFromInt(100000000),
+			price:   refPrice,
+			wantQty: "92141578", wantPrice: "0.00002345",
+		},
+		{
+			name:      "market order on the market lot",
+			orderType: "MARKET", side: "SELL",
+			qty:     decimal.RequireFromString("1000000.7"),
+			wantQty: "1000000", wantPrice: "0.00000000",
+		},
+		{
+			name:      "market quantity capped at the market maximum",
+			orderType: "MARKET", side: "BUY",
+			qty:     decimal.NewFromInt(60000000),
+			wantQty: "50000000", wantPrice: "0.00000000",
+		},
+		{
+			name:      "fraction of a coin rounds to nothing",
+			orderType: "LIMIT", side: "BUY",
+			qty:     decimal.RequireFromString("0.5"),
+			price:   refPrice,
+			wantErr: "quantity 0 is below the minimum 1",
+		},
+		{
+			name:      "notional below the minimum",
+			orderType: "LIMIT", side: "BUY",
+			qty:     decimal.NewFromInt(200000),
+			price:   refPrice,
+			wantErr: "notional 4.69 is below the minimum 5",
+		},
+		{
+			name:      "market notional below the minimum at the reference price",
+			orderType: "MARKET", side: "SELL",
+			qty:     decimal.NewFromInt(100),
+			wantErr: "notional 0.002345 is below the minimum 5",
+		},
+		{
+			name:      "market notional ignored when the filter does not apply to market orders",
+			rules:     func(r *SymbolRules) { r.ApplyMinNotionalToMarket = false },
+			orderType: "MARKET", side: "SELL",
+			qty:     decimal.NewFromInt(100),
+			wantQty: "100", wantPrice: "0.00000000",
+		},
+		{
+			name:      "notional above the maximum",
+			rules:     func(r *SymbolRules) { r.MaxNotional = decimal.NewFromInt(1000) },
+			orderType: "LIMIT", side: "BUY",
+			qty:     decimal.NewFromInt(90000000),
+			price:   decimal.RequireFromString("0.0001"),
+			wantErr: "notional 9000 is above the maximum 1000",
+		},
+		{
+			name:      "price too far below the reference price",
+			orderType: "LIMIT", side: "BUY",
+			qty:     decimal.NewFromInt(2000000),
+			price:   decimal.RequireFromString("0.000004"),
+			wantErr: "less than 0.2 times the reference price",
+		},
+		{
+			name:      "price too far above the reference price",
+			orderType: "LIMIT", side: "SELL",
+			qty:     decimal.NewFromInt(2000000),
+			price:   decimal.RequireFromString("0.0002"),
+			wantErr: "more than 5 times the reference price",
+		},
+		{
+			name:      "price below the tick size",
+			orderType: "LIMIT", side: "BUY",
+			qty:     decimal.NewFromInt(2000000),
+			price:   decimal.RequireFromString("0.000000009"),
+			wantErr: "price 0 is below the minimum 0.00000001",
+		},
+		{
+			name:      "order type not permitted",
+			orderType: "STOP_LOSS", side: "SELL",
+			qty:     decimal.NewFromInt(2000000),
+			price:   refPrice,
+			wantErr: "order type STOP_LOSS is not permitted",
+		},
+		{
+			name:      "symbol halted",
+			rules:     func(r *SymbolRules) { r.Status = "BREAK" },
+			orderType: "LIMIT", side: "BUY",
+			qty:     decimal.NewFromInt(2000000),
+			price:   refPrice,
+			wantErr: "symbol is not trading, status BREAK",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := shibRules()
+			if tt.rules != nil {
+				tt.rules(rules)
+			}
+
+			qty, price, err := rules.PrepareOrder(tt.orderType, tt.side, tt.qty, tt.price, refPrice)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if qty != tt.wantQty || price != tt.wantPrice {
+				t.Errorf("got %s at %s, want %s at %s", qty, price, tt.wantQty, tt.wantPrice)
+			}
+		})
+	}
+}
@@ -1,17 +1,46 @@
 package models
 
-// TradingPair represents a single trading pair configuration
+import "github.com/shopspring/decimal"
+
+// TradingPair represents a single trading pair configuration. A pair handed out by an exchange
+// client is not modified, refreshed rules replace it with a new pair.
 type TradingPair struct {
 	Symbol         string
 	BaseAsset      string
 	QuoteAsset     string
-	TradeAmount    float64
-	MinNotional    float64
+	TradeAmount    decimal.Decimal
+	MinNotional    decimal.Decimal // Minimum order value, taken from Rules once they are fetched
 	PricePrecision int
 	QtyPrecision   int
+	Rules          *SymbolRules // Exchange filters, nil until fetched from the exchange
 }
 
 func NewTradingPair(symbol string) TradingPair {
 	// Initialize a new trading pair with the symbol, values will be fetched from the exchange
-	return TradingPair{symbol, "", "", 0, 0, 0, 0}
+	return TradingPair{Symbol: symbol}
+}
+
+// ApplyRules sets the rules and derives the minimum notional and precisions from them, it must
+// not be called on a pair that is already shared
+func (p *TradingPair) ApplyRules(rules *SymbolRules) {
+	p.Rules = rules
+	p.MinNotional = rules.MinNotional
+	p.PricePrecision = int(rules.PriceDecimals())
+	p.QtyPrecision = int(rules.QtyDecimals(false))
+}
+
+// FormatQty rounds a quantity down to the step size and formats it for the exchange
+func (p *TradingPair) FormatQty(qty decimal.Decimal) string {
+	if p.Rules != nil {
+		return p.Rules.FormatQty(p.Rules.QuantizeQty(qty, false), false)
+	}
+	return qty.Truncate(int32(p.QtyPrecision)).StringFixed(int32(p.QtyPrecision))
+}
+
+// FormatPrice rounds a price down to the tick size and formats it for the exchange
+func (p *TradingPair) FormatPrice(price decimal.Decimal) string {
+	if p.Rules != nil {
+		return p.Rules.FormatPrice(p.Rules.QuantizePrice(price))
+	}
+	return price.Truncate(int32(p.PricePrecision)).StringFixed(int32(p.PricePrecision))
 }
@@ -0,0 +1,318 @@
+package protection
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/exits"
+	"binance_bot/interfaces"
+	"binance_bot/logger"
+	"binance_bot/models"
+	"binance_bot/utils"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"sync"
+	"time"
+)
+
+// Order types of protective orders
+const (
+	StopLossLimitType = "stop_loss_limit"
+	OCOType           = "oco"
+)
+
+// retryDelay is how long placing the protective order of a pair waits after a failure
+const retryDelay = time.Minute
+
+// Config selects the protective order resting on the exchange for every open position
+type Config struct {
+	Type               string  `json:"type"`                 // stop_loss_limit, oco or empty to keep stops in the bot only
+	LimitOffsetPercent float64 `json:"limit_offset_percent"` // Limit price of the stop this percent below the stop price, 0.5 when 0
+	MinMovePercent     float64 `json:"min_move_percent"`     // Smallest stop move that replaces the order, 0.1 when 0
+}
+
+// Validate checks the protective order settings and reports all problems at once
+func (c Config) Validate() error {
+	var v utils.Validation
+
+	v.Check(c.Type == "" || c.Type == StopLossLimitType || c.Type == OCOType, "type: must be %q, %q or empty, got %q", StopLossLimitType, OCOType, c.Type)
+	v.Check(c.LimitOffsetPercent >= 0 && c.LimitOffsetPercent < 100, "limit_offset_percent: must be between 0 and 100, got %v", c.LimitOffsetPercent)
+	v.Check(c.MinMovePercent >= 0 && c.MinMovePercent < 100, "min_move_percent: must be between 0 and 100, got %v", c.MinMovePercent)
+
+	return v.Err()
+}
+
+func (c Config) limitOffset() float64 {
+	if c.LimitOffsetPercent == 0 {
+		return 0.5
+	}
+	return c.LimitOffsetPercent
+}
+
+func (c Config) minMove() float64 {
+	if c.MinMovePercent == 0 {
+		return 0.1
+	}
+	return c.MinMovePercent
+}
+
+// Manager keeps a protective SELL order on the exchange for the open position of every pair, so
+// the stop holds while the bot is down. The order is replaced when the position changes or the
+// stop moves, and released before the bot sells the position itself.
+type Manager struct {
+	cfg        Config
+	exchange   interfaces.ExchangeClient
+	orders     interfaces.OrderTracker
+	available  func(asset string) (decimal.Decimal, error) // Free balance of an asset
+	retryAfter map[string]time.Time                        // Pairs whose last placement failed
+	mu         sync.Mutex
+}
+
+// NewManager creates a protective order manager, the legs of its orders are handed to the tracker
+// and protective orders are sized from the free balance reported by available
+func NewManager(cfg Config, exchange interfaces.ExchangeClient, orders interfaces.OrderTracker, available func(asset string) (decimal.Decimal, error)) *Manager {
+	return &Manager{
+		cfg:        cfg,
+		exchange:   exchange,
+		orders:     orders,
+		available:  available,
+		retryAfter: make(map[string]time.Time),
+	}
+}
+
+// Protect places the protective order of a pair at the exit levels of its position, or replaces
+// the resting one when the position changed or the stop moved. A zero position releases it.
+func (m *Manager) Protect(pair *models.TradingPair, levels exits.Levels, price decimal.Decimal) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	active, err := m.active(pair.Symbol)
+	if err != nil {
+		return err
+	}
+	if !levels.Position.IsPositive() || !levels.Stop.IsPositive() {
+		if active != nil {
+			return m.cancel(active, models.ProtectionReleased)
+		}
+		return nil
+	}
+	if active != nil && !m.outdated(active, levels, price) {
+		return nil
+	}
+	if levels.Stop.GreaterThanOrEqual(price) || time.Now().Before(m.retryAfter[pair.Symbol]) {
+		// The bot sells a position below its stop itself, placing waits a while after a failure
+		return nil
+	}
+
+	if active != nil {
+		if err := m.cancel(active, models.ProtectionReplaced); err != nil {
+			return err
+		}
+	}
+	placed, err := m.place(pair, levels, price)
+	if err != nil || !placed {
+		m.retryAfter[pair.Symbol] = time.Now().Add(retryDelay)
+		return err
+	}
+	delete(m.retryAfter, pair.Symbol)
+	return nil
+}
+
+// Release cancels the protective order of a pair so the bot can sell the position itself
+func (m *Manager) Release(symbol string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	active, err := m.active(symbol)
+	if err != nil || active == nil {
+		return err
+	}
+	return m.cancel(active, models.ProtectionReleased)
+}
+
+// Protected returns the quantity the protective order of a pair holds on the exchange, the legs of
+// an OCO sell the same quantity and count once
+func (m *Manager) Protected(symbol string) (decimal.Decimal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	active, err := m.active(symbol)
+	if err != nil || active == nil {
+		return decimal.Zero, err
+	}
+	protected := decimal.Zero
+	for _, id := range active.LegIDs() {
+		order, err := db2.SQLiteDB.GetOrder(symbol, id)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		if order.IsOpen() {
+			protected = decimal.Max(protected, order.Remaining())
+		}
+	}
+	return protected, nil
+}
+
+// active returns the protective order resting for a pair. Orders whose legs all ended on the
+// exchange, by a fill or otherwise, are closed.
+func (m *Manager) active(symbol string) (*models.ProtectiveOrder, error) {
+	p, err := db2.SQLiteDB.GetActiveProtectiveOrder(symbol)
+	if err != nil || p == nil {
+		return nil, err
+	}
+
+	open, filled := false, false
+	for _, id := range p.LegIDs() {
+		order, err := db2.SQLiteDB.GetOrder(symbol, id)
+		if err != nil {
+			return nil, err
+		}
+		open = open || order.IsOpen()
+		filled = filled || order.FilledQty.IsPositive()
+	}
+	if open {
+		return p, nil
+	}
+
+	status := models.ProtectionClosed
+	if filled {
+		status = models.ProtectionTriggered
+	}
+	return nil, m.finish(p, status)
+}
+
+// outdated reports whether a protective order no longer matches the levels of the position
+func (m *Manager) outdated(p *models.ProtectiveOrder, levels exits.Levels, price decimal.Decimal) bool {
+	if !p.Position.Equal(levels.Position) {
+		return true
+	}
+	if m.moved(p.StopPrice, levels.Stop) {
+		return true
+	}
+	if m.cfg.Type != OCOType {
+		return false
+	}
+	if p.TakeProfit.IsZero() {
+		// A stop placed without a take-profit becomes an OCO once there is one above the price
+		return levels.TakeProfit.GreaterThan(price)
+	}
+	return m.moved(p.TakeProfit, levels.TakeProfit)
+}
+
+// moved reports whether a price moved by at least the minimum move
+func (m *Manager) moved(from, to decimal.Decimal) bool {
+	if !from.IsPositive() {
+		return to.IsPositive()
+	}
+	change := to.Sub(from).Div(from).Abs().Shift(2)
+	return change.GreaterThanOrEqual(decimal.NewFromFloat(m.cfg.minMove()))
+}
+
+// place puts a protective order for the part of the position no other SELL order covers on the
+// exchange. It reports false when that part is too small for an order.
+func (m *Manager) place(pair *models.TradingPair, levels exits.Levels, price decimal.Decimal) (bool, error) {
+	sellable, err := db2.SQLiteDB.SellableQuantity(pair.Symbol)
+	if err != nil {
+		return false, fmt.Errorf("error fetching %s position: %v", pair.Symbol, err)
+	}
+	free, err := m.available(pair.BaseAsset)
+	if err != nil {
+		return false, fmt.Errorf("error fetching %s balance: %v", pair.BaseAsset, err)
+	}
+	stop := levels.Stop
+	quantity := decimal.Min(sellable, free)
+	if quantity.Mul(stop).LessThan(pair.MinNotional) || !quantity.IsPositive() {
+		logger.Debugf("Nothing to protect for %s: %s sellable below the minimum notional", pair.Symbol, quantity)
+		return false, nil
+	}
+
+	p := &models.ProtectiveOrder{
+		Symbol:     pair.Symbol,
+		Position:   levels.Position,
+		Quantity:   decimal.RequireFromString(pair.FormatQty(quantity)),
+		StopPrice:  decimal.RequireFromString(pair.FormatPrice(stop)),
+		LimitPrice: decimal.RequireFromString(pair.FormatPrice(stop.Mul(decimal.NewFromFloat(1 - m.cfg.limitOffset()/100)))),
+		Status:     models.ProtectionActive,
+	}
+	qty, stopPrice, limitPrice := p.Quantity.String(), p.StopPrice.String(), p.LimitPrice.String()
+
+	var legs []*models.Order
+	oco, ok := m.exchange.(interfaces.OCOClient)
+	if m.cfg.Type == OCOType && !ok {
+		logger.Warnf("Exchange client cannot place OCO orders, protecting %s with a stop only", pair.Symbol)
+	}
+	if m.cfg.Type == OCOType && ok && levels.TakeProfit.GreaterThan(price) {
+		p.Type = "OCO"
+		p.TakeProfit = decimal.RequireFromString(pair.FormatPrice(levels.TakeProfit))
+		if legs, err = oco.CreateOCOOrder(pair.Symbol, "SELL", qty, p.TakeProfit.String(), stopPrice, limitPrice); err != nil {
+			return false, err
+		}
+		for _, leg := range legs {
+			if leg.Type == "STOP_LOSS_LIMIT" {
+				p.StopOrderID = leg.OrderID
+			} else {
+				p.LimitOrderID = leg.OrderID
+			}
+		}
+	} else {
+		p.Type = "STOP_LOSS_LIMIT"
+		orderID, err := m.exchange.CreateStopLossLimitOrder(pair.Symbol, "SELL", qty, limitPrice, stopPrice)
+		if err != nil {
+			return false, err
+		}
+		p.StopOrderID = orderID
+		legs = []*models.Order{{
+			OrderID:  orderID,
+			Symbol:   pair.Symbol,
+			Side:     "SELL",
+			Type:     p.Type,
+			Quantity: p.Quantity,
+			Price:    p.LimitPrice,
+			Status:   models.OrderStatusNew,
+		}}
+	}
+
+	// The legs close the position through the order manager when they fill
+	for _, leg := range legs {
+		if err := m.orders.Track(leg); err != nil {
+			logger.Errorf("Error tracking protective order %d for %s: %v", leg.OrderID, pair.Symbol, err)
+		}
+	}
+	if err := db2.SQLiteDB.LogProtectiveOrder(p); err != nil {
+		return true, err
+	}
+	logger.Infof("Placed protective %s order for %s %s: Stop=%s Limit=%s TakeProfit=%s", p.Type, p.Quantity, pair.Symbol, p.StopPrice, p.LimitPrice, p.TakeProfit)
+	return true, nil
+}
+
+// cancel takes a protective order off the exchange and applies the fills it got before.
+// An order that filled in the meantime is recorded as triggered instead of the given status.
+func (m *Manager) cancel(p *models.ProtectiveOrder, status string) error {
+	// Canceling one leg of an OCO cancels the other as well
+	cancelErr := m.exchange.CancelOrder(p.Symbol, p.StopOrderID)
+
+	filled := false
+	for _, id := range p.LegIDs() {
+		order, err := m.orders.Refresh(p.Symbol, id)
+		if err != nil {
+			return fmt.Errorf("error refreshing protective order %d for %s: %v", id, p.Symbol, err)
+		}
+		if order.IsOpen() {
+			return fmt.Errorf("failed to cancel protective order %d for %s: %v", id, p.Symbol, cancelErr)
+		}
+		filled = filled || order.FilledQty.IsPositive()
+	}
+
+	if filled {
+		status = models.ProtectionTriggered
+	}
+	return m.finish(p, status)
+}
+
+// finish records the final status of a protective order
+func (m *Manager) finish(p *models.ProtectiveOrder, status string) error {
+	if err := db2.SQLiteDB.UpdateProtectiveOrderStatus(p.ID, status); err != nil {
+		return err
+	}
+	p.Status = status
+	logger.Infof("Protective %s order %d for %s is %s", p.Type, p.StopOrderID, p.Symbol, status)
+	return nil
+}
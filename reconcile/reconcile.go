@@ -0,0 +1,309 @@
+package reconcile
+
+import (
+	"binance_bot/bot"
+	db2 "binance_bot/db"
+	"binance_bot/interfaces"
+	"binance_bot/logger"
+	"binance_bot/models"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"sort"
+	"strings"
+)
+
+// Mode decides what happens with discrepancies between the database and the exchange
+type Mode string
+
+const (
+	// ModeOff skips reconciliation
+	ModeOff Mode = "off"
+	// ModeFlag only reports discrepancies
+	ModeFlag Mode = "flag"
+	// ModeAdopt takes the exchange state as the truth and updates the database
+	ModeAdopt Mode = "adopt"
+	// ModeClose cancels unknown orders and sells untracked holdings
+	ModeClose Mode = "close"
+)
+
+// ParseMode converts a mode name to a Mode
+func ParseMode(name string) (Mode, error) {
+	switch mode := Mode(name); mode {
+	case ModeOff, ModeFlag, ModeAdopt, ModeClose:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown reconcile mode %q, use off, flag, adopt or close", name)
+}
+
+// PairReport is the comparison of one trading pair
+type PairReport struct {
+	Symbol        string
+	Price         decimal.Decimal
+	DBQty         decimal.Decimal // Sum of the active trades
+	ExchangeQty   decimal.Decimal // Free and locked base asset balance
+	UnknownOrders []*models.Order // Open on the exchange but not tracked in the database
+	StaleOrders   []*models.Order // Open in the database but not on the exchange
+	Shared        bool            // Base asset is shared with another pair and cannot be attributed
+	Actions       []string
+}
+
+// QtyDiff returns how much more the exchange holds than the database tracks
+func (p *PairReport) QtyDiff() decimal.Decimal {
+	return p.ExchangeQty.Sub(p.DBQty)
+}
+
+// HasDiscrepancy reports whether anything does not match
+func (p *PairReport) HasDiscrepancy(minNotional decimal.Decimal) bool {
+	return len(p.UnknownOrders) > 0 || len(p.StaleOrders) > 0 || !p.belowMinNotional(p.QtyDiff(), minNotional)
+}
+
+// belowMinNotional reports whether a quantity is worth less than the minimum order value
+func (p *PairReport) belowMinNotional(qty, minNotional decimal.Decimal) bool {
+	return qty.Abs().Mul(p.Price).LessThan(minNotional)
+}
+
+// Report is the result of a reconciliation run
+type Report struct {
+	Mode  Mode
+	Pairs []*PairReport
+}
+
+// Discrepancies returns the pairs that do not match
+func (r *Report) Discrepancies(exchange interfaces.ExchangeClient) []*PairReport {
+	pairs := exchange.GetTradingPairs()
+	var result []*PairReport
+	for _, p := range r.Pairs {
+		if p.HasDiscrepancy(pairs[p.Symbol].MinNotional) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// Log writes the diff report
+func (r *Report) Log(exchange interfaces.ExchangeClient) {
+	discrepancies := r.Discrepancies(exchange)
+	logger.Infof("Reconciliation (%s): %d pairs checked, %d with discrepancies", r.Mode, len(r.Pairs), len(discrepancies))
+
+	for _, p := range discrepancies {
+		logger.Warnf("%s | DB %s | Exchange %s | Diff %s (%s quote) | Unknown orders %s | Stale orders %s",
+			p.Symbol, p.DBQty, p.ExchangeQty, p.QtyDiff(), p.QtyDiff().Mul(p.Price).StringFixed(2), orderIDs(p.UnknownOrders), orderIDs(p.StaleOrders))
+		if p.Shared {
+			logger.Warnf("%s | Base asset is shared with another pair, balance discrepancy left untouched", p.Symbol)
+		}
+		for _, action := range p.Actions {
+			logger.Infof("%s | %s", p.Symbol, action)
+		}
+	}
+}
+
+// Run compares database positions and open orders with the exchange for every trading pair
+// and resolves discrepancies according to mode. It must run before the bot starts trading.
+func Run(exchange interfaces.ExchangeClient, mode Mode) (*Report, error) {
+	report := &Report{Mode: mode}
+	if mode == ModeOff {
+		return report, nil
+	}
+
+	// Apply fills that happened while the bot was down before comparing
+	bot.NewOrderManager(exchange).SyncOpenOrders()
+
+	balances, err := exchange.GetBalances()
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := db2.SQLiteDB.GetOpenOrders()
+	if err != nil {
+		return nil, err
+	}
+	storedBySymbol := make(map[string]map[int64]*models.Order)
+	for _, order := range stored {
+		if storedBySymbol[order.Symbol] == nil {
+			storedBySymbol[order.Symbol] = make(map[int64]*models.Order)
+		}
+		storedBySymbol[order.Symbol][order.OrderID] = order
+	}
+
+	pairs := exchange.GetTradingPairs()
+	symbols := make([]string, 0, len(pairs))
+	baseCount := make(map[string]int)
+	for symbol, pair := range pairs {
+		symbols = append(symbols, symbol)
+		baseCount[pair.BaseAsset]++
+	}
+	sort.Strings(symbols)
+
+	for _, symbol := range symbols {
+		pair := pairs[symbol]
+		p, err := comparePair(exchange, pair, balances, storedBySymbol[symbol])
+		if err != nil {
+			return nil, err
+		}
+		p.Shared = baseCount[pair.BaseAsset] > 1
+		report.Pairs = append(report.Pairs, p)
+
+		if mode != ModeFlag && p.HasDiscrepancy(pair.MinNotional) {
+			resolve(exchange, pair, p, mode)
+		}
+	}
+
+	return report, nil
+}
+
+// comparePair collects the database and exchange state of a pair
+func comparePair(exchange interfaces.ExchangeClient, pair *models.TradingPair, balances map[string]models.Balance, stored map[int64]*models.Order) (*PairReport, error) {
+	p := &PairReport{Symbol: pair.Symbol, ExchangeQty: balances[pair.BaseAsset].Total()}
+
+	price, err := exchange.GetCurrentPrice(pair.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	p.Price = price
+
+	trades, err := db2.SQLiteDB.GetActiveTrades(pair.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	for _, trade := range trades {
+		p.DBQty = p.DBQty.Add(trade.Quantity)
+	}
+
+	open, err := exchange.GetOpenOrders(pair.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	onExchange := make(map[int64]bool, len(open))
+	for _, order := range open {
+		onExchange[order.OrderID] = true
+		if _, ok := stored[order.OrderID]; !ok {
+			p.UnknownOrders = append(p.UnknownOrders, order)
+		}
+	}
+	for id, order := range stored {
+		if !onExchange[id] {
+			p.StaleOrders = append(p.StaleOrders, order)
+		}
+	}
+	sort.Slice(p.StaleOrders, func(i, j int) bool { return p.StaleOrders[i].OrderID < p.StaleOrders[j].OrderID })
+
+	return p, nil
+}
+
+// resolve adopts or closes the discrepancies of a pair
+func resolve(exchange interfaces.ExchangeClient, pair *models.TradingPair, p *PairReport, mode Mode) {
+	action := func(format string, args ...interface{}) {
+		p.Actions = append(p.Actions, fmt.Sprintf(format, args...))
+	}
+
+	// Orders the database believes are open but the exchange does not know about can no longer fill
+	for _, order := range p.StaleOrders {
+		order.Status = models.OrderStatusCanceled
+		if err := db2.SQLiteDB.UpdateOrder(order); err != nil {
+			action("Failed to mark stale order %d as canceled: %v", order.OrderID, err)
+			continue
+		}
+		action("Marked stale order %d as canceled", order.OrderID)
+	}
+
+	for _, order := range p.UnknownOrders {
+		if mode == ModeClose {
+			if err := exchange.CancelOrder(pair.Symbol, order.OrderID); err != nil {
+				action("Failed to cancel unknown order %d: %v", order.OrderID, err)
+				continue
+			}
+			action("Canceled unknown %s order %d", order.Side, order.OrderID)
+			continue
+		}
+
+		// Track the order from its current fills, earlier fills are already in the balances
+		var err error
+		if _, getErr := db2.SQLiteDB.GetOrder(order.Symbol, order.OrderID); getErr == nil {
+			err = db2.SQLiteDB.UpdateOrder(order)
+		} else {
+			err = db2.SQLiteDB.LogOrder(order)
+		}
+		if err != nil {
+			action("Failed to adopt unknown order %d: %v", order.OrderID, err)
+			continue
+		}
+		action("Adopted unknown %s order %d", order.Side, order.OrderID)
+	}
+
+	diff := p.QtyDiff()
+	if p.belowMinNotional(diff, pair.MinNotional) || p.Shared {
+		return
+	}
+
+	if diff.IsNegative() {
+		// The database tracks more than the account holds, drop the newest positions
+		if err := shrinkPositions(pair.Symbol, diff.Neg()); err != nil {
+			action("Failed to reduce tracked positions by %s: %v", diff.Neg(), err)
+			return
+		}
+		action("Reduced tracked positions by %s to match the balance", diff.Neg())
+		return
+	}
+
+	if mode == ModeAdopt {
+		// The entry price of untracked holdings is unknown, the current price is used
+		price := p.Price
+		if err := db2.SQLiteDB.LogActiveTrade(pair.Symbol, price, diff); err != nil {
+			action("Failed to adopt %s untracked %s: %v", diff, pair.BaseAsset, err)
+			return
+		}
+		action("Adopted %s untracked %s at %s", diff, pair.BaseAsset, price)
+		return
+	}
+
+	// Sell untracked holdings, rounded down so the order never exceeds the balance
+	qty := pair.FormatQty(diff)
+	if rounded, _ := decimal.NewFromString(qty); p.belowMinNotional(rounded, pair.MinNotional) {
+		return
+	}
+	order, err := exchange.CreateMarketOrder(pair.Symbol, "SELL", qty)
+	if err != nil {
+		action("Failed to sell %s untracked %s: %v", qty, pair.BaseAsset, err)
+		return
+	}
+	// Keep the order for the record, it does not close any tracked position
+	if err := db2.SQLiteDB.LogOrder(order); err != nil {
+		logger.Errorf("Error storing reconciliation order %d for %s: %v", order.OrderID, pair.Symbol, err)
+	}
+	action("Sold %s untracked %s at %s (order %d)", order.FilledQty, pair.BaseAsset, order.AvgPrice, order.OrderID)
+}
+
+// shrinkPositions removes qty from the active trades of a symbol, newest first
+func shrinkPositions(symbol string, qty decimal.Decimal) error {
+	trades, err := db2.SQLiteDB.GetActiveTrades(symbol)
+	if err != nil {
+		return err
+	}
+
+	for i := len(trades) - 1; i >= 0 && qty.IsPositive(); i-- {
+		trade := trades[i]
+		if trade.Quantity.LessThanOrEqual(qty) {
+			if err := db2.SQLiteDB.RemoveActiveTrade(trade.ID); err != nil {
+				return err
+			}
+			qty = qty.Sub(trade.Quantity)
+			continue
+		}
+		if err := db2.SQLiteDB.UpdateActiveTradeQuantity(trade.ID, trade.Quantity.Sub(qty)); err != nil {
+			return err
+		}
+		qty = decimal.Zero
+	}
+	return nil
+}
+
+func orderIDs(orders []*models.Order) string {
+	if len(orders) == 0 {
+		return "-"
+	}
+	ids := make([]string, len(orders))
+	for i, order := range orders {
+		ids[i] = fmt.Sprintf("%d(%s)", order.OrderID, order.Side)
+	}
+	return strings.Join(ids, ",")
+}
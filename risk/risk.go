@@ -0,0 +1,215 @@
+package risk
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/interfaces"
+	"binance_bot/logger"
+	"binance_bot/models"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"sync"
+	"time"
+)
+
+// Config holds the portfolio limits, a zero value disables the limit
+type Config struct {
+	MaxOpenPositions int     `json:"max_open_positions"` // Pairs holding a position or an open BUY order
+	MaxAssetExposure float64 `json:"max_asset_exposure"` // Fraction of equity held in a single base asset
+	MaxTotalExposure float64 `json:"max_total_exposure"` // Fraction of equity held in base assets
+	MaxOrderNotional float64 `json:"max_order_notional"` // Largest order value in the quote asset
+	MaxDailyLoss     float64 `json:"max_daily_loss"`     // Realized loss in the quote asset per UTC day that halts buying
+	MaxDrawdown      float64 `json:"max_drawdown"`       // Fraction below the equity peak that halts buying for good
+}
+
+// Manager checks every order against the portfolio limits. Orders that reduce risk, i.e. SELL
+// orders, are always allowed so positions can be closed while buying is halted.
+type Manager struct {
+	cfg      Config
+	exchange interfaces.ExchangeClient
+	peak     decimal.Decimal
+	loaded   bool
+	halted   string // Reason of the drawdown kill switch, empty while trading
+	mu       sync.Mutex
+}
+
+// NewManager creates a risk manager for the pairs of an exchange
+func NewManager(cfg Config, exchange interfaces.ExchangeClient) *Manager {
+	return &Manager{cfg: cfg, exchange: exchange}
+}
+
+// Check returns an error with the reason when an order would break a limit, the rejection is logged
+func (m *Manager) Check(pair *models.TradingPair, side string, qty, price decimal.Decimal) error {
+	if side != "BUY" {
+		return nil
+	}
+
+	err := m.checkBuy(pair, qty.Mul(price))
+	if err != nil {
+		logger.Warnf("Risk rejected BUY %s %s at %s: %v", qty, pair.Symbol, price, err)
+	}
+	return err
+}
+
+func (m *Manager) checkBuy(pair *models.TradingPair, notional decimal.Decimal) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cfg.MaxOrderNotional > 0 && notional.GreaterThan(decimal.NewFromFloat(m.cfg.MaxOrderNotional)) {
+		return fmt.Errorf("order value %s exceeds the maximum of %v", notional.StringFixed(2), m.cfg.MaxOrderNotional)
+	}
+
+	if m.cfg.MaxDailyLoss > 0 {
+		realized, err := db2.SQLiteDB.RealizedPnLSince(startOfDay(time.Now()))
+		if err != nil {
+			return fmt.Errorf("cannot check the daily loss: %v", err)
+		}
+		if realized.LessThanOrEqual(decimal.NewFromFloat(-m.cfg.MaxDailyLoss)) {
+			return fmt.Errorf("daily realized loss %s reached the limit of %v, buying is halted until 00:00 UTC", realized.Neg().StringFixed(2), m.cfg.MaxDailyLoss)
+		}
+	}
+
+	portfolio, err := Valuate(m.exchange)
+	if err != nil {
+		return fmt.Errorf("cannot value the portfolio: %v", err)
+	}
+	m.observeLocked(portfolio.Equity)
+	if m.halted != "" {
+		return fmt.Errorf("kill switch: %s", m.halted)
+	}
+
+	if m.cfg.MaxOpenPositions > 0 && !portfolio.Open[pair.Symbol] && len(portfolio.Open) >= m.cfg.MaxOpenPositions {
+		return fmt.Errorf("%d positions are open, the maximum is %d", len(portfolio.Open), m.cfg.MaxOpenPositions)
+	}
+
+	if !portfolio.Equity.IsPositive() {
+		return fmt.Errorf("equity is %s", portfolio.Equity)
+	}
+	if m.cfg.MaxAssetExposure > 0 {
+		exposure := portfolio.Exposure[pair.BaseAsset].Add(notional).Div(portfolio.Equity)
+		if exposure.GreaterThan(decimal.NewFromFloat(m.cfg.MaxAssetExposure)) {
+			return fmt.Errorf("%s exposure would be %s%% of equity, the maximum is %v%%", pair.BaseAsset, exposure.Shift(2).StringFixed(1), m.cfg.MaxAssetExposure*100)
+		}
+	}
+	if m.cfg.MaxTotalExposure > 0 {
+		exposure := portfolio.Total.Add(notional).Div(portfolio.Equity)
+		if exposure.GreaterThan(decimal.NewFromFloat(m.cfg.MaxTotalExposure)) {
+			return fmt.Errorf("total exposure would be %s%% of equity, the maximum is %v%%", exposure.Shift(2).StringFixed(1), m.cfg.MaxTotalExposure*100)
+		}
+	}
+	return nil
+}
+
+// Snapshot values the portfolio, stores the equity and trips the kill switch on a drawdown
+func (m *Manager) Snapshot() (decimal.Decimal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	portfolio, err := Valuate(m.exchange)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if err := db2.SQLiteDB.LogEquitySnapshot(portfolio.Equity); err != nil {
+		return decimal.Zero, err
+	}
+	m.observeLocked(portfolio.Equity)
+	return portfolio.Equity, nil
+}
+
+// Halted returns the reason buying was halted by the kill switch, empty while trading
+func (m *Manager) Halted() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.halted
+}
+
+// observeLocked tracks the equity peak, which survives restarts through the stored snapshots
+func (m *Manager) observeLocked(equity decimal.Decimal) {
+	if !m.loaded {
+		peak, err := db2.SQLiteDB.PeakEquity()
+		if err != nil {
+			logger.Errorf("Error loading the equity peak: %v", err)
+		}
+		m.peak, m.loaded = peak, true
+	}
+	if equity.GreaterThan(m.peak) {
+		m.peak = equity
+	}
+
+	if m.cfg.MaxDrawdown <= 0 || m.halted != "" || !m.peak.IsPositive() {
+		return
+	}
+	drawdown := m.peak.Sub(equity).Div(m.peak)
+	if drawdown.GreaterThanOrEqual(decimal.NewFromFloat(m.cfg.MaxDrawdown)) {
+		m.halted = fmt.Sprintf("equity %s is %s%% below the peak of %s, the maximum drawdown is %v%%",
+			equity.StringFixed(2), drawdown.Shift(2).StringFixed(1), m.peak.StringFixed(2), m.cfg.MaxDrawdown*100)
+		logger.Errorf("Kill switch tripped, buying is halted: %s", m.halted)
+	}
+}
+
+// Portfolio is the valuation of the account in the quote asset of the pairs
+type Portfolio struct {
+	Equity   decimal.Decimal
+	Total    decimal.Decimal            // Value of all base assets including open BUY orders
+	Exposure map[string]decimal.Decimal // Value per base asset including open BUY orders
+	Open     map[string]bool            // Pairs with a position or an open BUY order
+}
+
+// Valuate values the balances of the quote and base assets of the pairs of an exchange
+func Valuate(exchange interfaces.ExchangeClient) (*Portfolio, error) {
+	balances, err := exchange.GetBalances()
+	if err != nil {
+		return nil, err
+	}
+	orders, err := db2.SQLiteDB.GetOpenOrders()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Portfolio{Exposure: make(map[string]decimal.Decimal), Open: make(map[string]bool)}
+	pairs := exchange.GetTradingPairs()
+	quotes := make(map[string]bool)
+	for symbol, pair := range pairs {
+		if !quotes[pair.QuoteAsset] {
+			quotes[pair.QuoteAsset] = true
+			p.Equity = p.Equity.Add(balances[pair.QuoteAsset].Total())
+		}
+		if _, ok := p.Exposure[pair.BaseAsset]; ok {
+			continue
+		}
+
+		held := balances[pair.BaseAsset].Total()
+		if held.IsZero() {
+			p.Exposure[pair.BaseAsset] = decimal.Zero
+			continue
+		}
+		current, err := exchange.GetCurrentPrice(symbol)
+		if err != nil {
+			return nil, err
+		}
+		value := held.Mul(current)
+		p.Equity = p.Equity.Add(value)
+		p.Exposure[pair.BaseAsset] = value
+		if value.GreaterThanOrEqual(pair.MinNotional) {
+			p.Open[symbol] = true
+		}
+	}
+
+	// Quote held by open BUY orders is already in the equity, it becomes exposure once filled
+	for _, order := range orders {
+		pair, ok := pairs[order.Symbol]
+		if !ok || order.Side != "BUY" {
+			continue
+		}
+		p.Exposure[pair.BaseAsset] = p.Exposure[pair.BaseAsset].Add(order.Remaining().Mul(order.Price))
+		p.Open[order.Symbol] = true
+	}
+	for _, value := range p.Exposure {
+		p.Total = p.Total.Add(value)
+	}
+	return p, nil
+}
+
+// startOfDay returns midnight UTC of the day of t
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,249 @@
+package risk
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/interfaces"
+	"binance_bot/models"
+	"github.com/shopspring/decimal"
+	"strings"
+	"testing"
+	"time"
+)
+
+// riskExchange holds balances in USDT, ETH and BTC at fixed prices
+type riskExchange struct {
+	interfaces.ExchangeClient
+	balances map[string]models.Balance
+}
+
+var riskPairs = map[string]*models.TradingPair{
+	"ETHUSDT": {Symbol: "ETHUSDT", BaseAsset: "ETH", QuoteAsset: "USDT", MinNotional: decimal.NewFromInt(5)},
+	"BTCUSDT": {Symbol: "BTCUSDT", BaseAsset: "BTC", QuoteAsset: "USDT", MinNotional: decimal.NewFromInt(5)},
+}
+
+func (e *riskExchange) GetBalances() (map[string]models.Balance, error) {
+	return e.balances, nil
+}
+
+func (e *riskExchange) GetTradingPairs() map[string]*models.TradingPair {
+	return riskPairs
+}
+
+func (e *riskExchange) GetCurrentPrice(symbol string) (decimal.Decimal, error) {
+	if symbol == "BTCUSDT" {
+		return decimal.NewFromInt(1000), nil
+	}
+	return decimal.NewFromInt(100), nil
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		balances map[string]string // Free balance per asset
+		setup    func(t *testing.T)
+		symbol   string
+		side     string
+		qty      string
+		price    string
+		wantErr  string // Empty when the order is allowed
+	}{
+		{
+			name:     "within the limits",
+			cfg:      Config{MaxOpenPositions: 2, MaxAssetExposure: 0.5, MaxTotalExposure: 0.5, MaxOrderNotional: 200, MaxDailyLoss: 50, MaxDrawdown: 0.2},
+			balances: map[string]string{"USDT": "1000"},
+			symbol:   "ETHUSDT", side: "BUY", qty: "1", price: "100",
+		},
+		{
+			name:     "SELL passes every limit",
+			cfg:      Config{MaxOrderNotional: 10},
+			balances: map[string]string{"ETH": "5"},
+			symbol:   "ETHUSDT", side: "SELL", qty: "5", price: "100",
+		},
+		{
+			name:     "order value",
+			cfg:      Config{MaxOrderNotional: 50},
+			balances: map[string]string{"USDT": "1000"},
+			symbol:   "ETHUSDT", side: "BUY", qty: "1", price: "100",
+			wantErr: "order value 100.00 exceeds the maximum of 50",
+		},
+		{
+			name:     "daily loss",
+			cfg:      Config{MaxDailyLoss: 20},
+			balances: map[string]string{"USDT": "1000"},
+			setup: func(t *testing.T) {
+				completedTrade(t, "-15", time.Now())
+				completedTrade(t, "-10", time.Now())
+			},
+			symbol: "ETHUSDT", side: "BUY", qty: "1", price: "100",
+			wantErr: "daily realized loss 25.00 reached the limit of 20",
+		},
+		{
+			name:     "daily loss counts from midnight UTC",
+			cfg:      Config{MaxDailyLoss: 20},
+			balances: map[string]string{"USDT": "1000"},
+			setup: func(t *testing.T) {
+				completedTrade(t, "-25", startOfDay(time.Now()).Add(-time.Minute))
+				completedTrade(t, "-10", time.Now())
+			},
+			symbol: "ETHUSDT", side: "BUY", qty: "1", price: "100",
+		},
+		{
+			// The peak is restored from the snapshots of an earlier run
+			name:     "drawdown kill switch",
+			cfg:      Config{MaxDrawdown: 0.25},
+			balances: map[string]string{"USDT": "1000"},
+			setup: func(t *testing.T) {
+				if err := db2.SQLiteDB.LogEquitySnapshot(decimal.NewFromInt(1500)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			symbol: "ETHUSDT", side: "BUY", qty: "1", price: "100",
+			wantErr: "kill switch: equity 1000.00 is 33.3% below the peak of 1500.00",
+		},
+		{
+			name:     "open positions",
+			cfg:      Config{MaxOpenPositions: 1},
+			balances: map[string]string{"USDT": "1000", "ETH": "1"},
+			symbol:   "BTCUSDT", side: "BUY", qty: "0.01", price: "1000",
+			wantErr: "1 positions are open, the maximum is 1",
+		},
+		{
+			name:     "adding to an open position",
+			cfg:      Config{MaxOpenPositions: 1},
+			balances: map[string]string{"USDT": "1000", "ETH": "1"},
+			symbol:   "ETHUSDT", side: "BUY", qty: "1", price: "100",
+		},
+		{
+			name:     "dust is no position",
+			cfg:      Config{MaxOpenPositions: 1},
+			balances: map[string]string{"USDT": "1000", "ETH": "0.01"},
+			symbol:   "BTCUSDT", side: "BUY", qty: "0.01", price: "1000",
+		},
+		{
+			name:     "open BUY order counts as a position",
+			cfg:      Config{MaxOpenPositions: 1},
+			balances: map[string]string{"USDT": "1000"},
+			setup: func(t *testing.T) {
+				openOrder(t, "BTCUSDT", "0.01", "900")
+			},
+			symbol: "ETHUSDT", side: "BUY", qty: "1", price: "100",
+			wantErr: "1 positions are open, the maximum is 1",
+		},
+		{
+			name:     "asset exposure",
+			cfg:      Config{MaxAssetExposure: 0.1},
+			balances: map[string]string{"USDT": "1000"},
+			symbol:   "ETHUSDT", side: "BUY", qty: "2", price: "100",
+			wantErr: "ETH exposure would be 20.0% of equity, the maximum is 10%",
+		},
+		{
+			name:     "asset exposure includes open BUY orders",
+			cfg:      Config{MaxAssetExposure: 0.1},
+			balances: map[string]string{"USDT": "1000"},
+			setup: func(t *testing.T) {
+				openOrder(t, "ETHUSDT", "0.5", "100")
+			},
+			symbol: "ETHUSDT", side: "BUY", qty: "0.6", price: "100",
+			wantErr: "ETH exposure would be 11.0% of equity, the maximum is 10%",
+		},
+		{
+			name:     "total exposure",
+			cfg:      Config{MaxTotalExposure: 0.25},
+			balances: map[string]string{"USDT": "1000", "ETH": "1"},
+			symbol:   "BTCUSDT", side: "BUY", qty: "0.2", price: "1000",
+			wantErr: "total exposure would be 27.3% of equity, the maximum is 25%",
+		},
+		{
+			name:   "empty account",
+			cfg:    Config{MaxTotalExposure: 0.25},
+			symbol: "ETHUSDT", side: "BUY", qty: "1", price: "100",
+			wantErr: "equity is 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := db2.InitDBAt(t.TempDir() + "/risk.db"); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db2.SQLiteDB.DB.Close() })
+			if tt.setup != nil {
+				tt.setup(t)
+			}
+
+			exchange := &riskExchange{balances: make(map[string]models.Balance)}
+			for asset, free := range tt.balances {
+				exchange.balances[asset] = models.Balance{Asset: asset, Free: decimal.RequireFromString(free)}
+			}
+			m := NewManager(tt.cfg, exchange)
+
+			err := m.Check(riskPairs[tt.symbol], tt.side, decimal.RequireFromString(tt.qty), decimal.RequireFromString(tt.price))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("order rejected: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// completedTrade stores a completed ETHUSDT trade with a profit at a point in time
+func completedTrade(t *testing.T, profit string, at time.Time) {
+	t.Helper()
+	_, err := db2.SQLiteDB.DB.Exec(`INSERT INTO completed_trades (symbol, buy_price, sell_price, quantity, profit_loss, timestamp) VALUES ('ETHUSDT', '100', '90', '1', ?, ?)`,
+		profit, at.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// openOrder stores an open BUY order
+func openOrder(t *testing.T, symbol, qty, price string) {
+	t.Helper()
+	err := db2.SQLiteDB.LogOrder(&models.Order{
+		OrderID:  1,
+		Symbol:   symbol,
+		Side:     "BUY",
+		Type:     "LIMIT",
+		Quantity: decimal.RequireFromString(qty),
+		Price:    decimal.RequireFromString(price),
+		Status:   models.OrderStatusNew,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshotTripsKillSwitch(t *testing.T) {
+	if err := db2.InitDBAt(t.TempDir() + "/risk.db"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db2.SQLiteDB.DB.Close() })
+
+	exchange := &riskExchange{balances: map[string]models.Balance{"USDT": {Asset: "USDT", Free: decimal.NewFromInt(1000)}}}
+	m := NewManager(Config{MaxDrawdown: 0.2}, exchange)
+	if _, err := m.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	exchange.balances["USDT"] = models.Balance{Asset: "USDT", Free: decimal.NewFromInt(850)}
+	if _, err := m.Snapshot(); err != nil || m.Halted() != "" {
+		t.Fatalf("halted at a drawdown of 15%%: %q %v", m.Halted(), err)
+	}
+	exchange.balances["USDT"] = models.Balance{Asset: "USDT", Free: decimal.NewFromInt(800)}
+	if _, err := m.Snapshot(); err != nil || m.Halted() == "" {
+		t.Fatalf("not halted at a drawdown of 20%%: %v", err)
+	}
+
+	// Recovering does not resume buying, selling stays possible
+	exchange.balances["USDT"] = models.Balance{Asset: "USDT", Free: decimal.NewFromInt(1000)}
+	pair, qty, price := riskPairs["ETHUSDT"], decimal.NewFromInt(1), decimal.NewFromInt(100)
+	if err := m.Check(pair, "BUY", qty, price); err == nil {
+		t.Error("BUY allowed after the kill switch tripped")
+	}
+	if err := m.Check(pair, "SELL", qty, price); err != nil {
+		t.Errorf("SELL rejected after the kill switch tripped: %v", err)
+	}
+}
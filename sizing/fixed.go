@@ -0,0 +1,33 @@
+package sizing
+
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+)
+
+// FixedQuote spends the same quote amount on every BUY
+type FixedQuote struct {
+	Amount float64
+}
+
+func (s *FixedQuote) Name() string {
+	return fmt.Sprintf("%s(%v)", FixedQuoteType, s.Amount)
+}
+
+func (s *FixedQuote) Size(Context) (decimal.Decimal, error) {
+	return decimal.NewFromFloat(s.Amount), nil
+}
+
+// FixedFraction spends a fixed fraction of the equity on every BUY
+type FixedFraction struct {
+	Fraction   float64
+	SplitPairs bool
+}
+
+func (s *FixedFraction) Name() string {
+	return fmt.Sprintf("%s(%v)", FixedFractionType, s.Fraction)
+}
+
+func (s *FixedFraction) Size(ctx Context) (decimal.Decimal, error) {
+	return ctx.budget(s.SplitPairs).Mul(decimal.NewFromFloat(s.Fraction)), nil
+}
@@ -0,0 +1,63 @@
+package sizing
+
+import (
+	db2 "binance_bot/db"
+	"fmt"
+	"github.com/shopspring/decimal"
+)
+
+// Kelly sizes a BUY with a fraction of the Kelly bet computed from the win rate and the ratio of
+// the average win to the average loss of the latest completed trades of the pair. Fallback is
+// used until MinTrades trades are known, a negative edge skips the BUY.
+type Kelly struct {
+	KellyFraction float64
+	Lookback      int
+	MinTrades     int
+	Fallback      float64
+	MaxFraction   float64
+	SplitPairs    bool
+}
+
+func (s *Kelly) Name() string {
+	return fmt.Sprintf("%s(%v)", KellyType, s.KellyFraction)
+}
+
+func (s *Kelly) Size(ctx Context) (decimal.Decimal, error) {
+	profits, err := db2.SQLiteDB.RecentProfits(ctx.Symbol, s.Lookback)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	budget := ctx.budget(s.SplitPairs)
+	if len(profits) < s.MinTrades {
+		return budget.Mul(decimal.NewFromFloat(s.Fallback)), nil
+	}
+	fraction := decimal.NewFromFloat(s.KellyFraction).Mul(kellyFraction(profits))
+	return budget.Mul(decimal.Min(fraction, decimal.NewFromFloat(s.MaxFraction))), nil
+}
+
+// kellyFraction returns the full Kelly bet W - (1-W)/R, zero without an edge
+func kellyFraction(profits []decimal.Decimal) decimal.Decimal {
+	var wins, losses int64
+	won, lost := decimal.Zero, decimal.Zero
+	for _, pnl := range profits {
+		switch {
+		case pnl.IsPositive():
+			wins++
+			won = won.Add(pnl)
+		case pnl.IsNegative():
+			losses++
+			lost = lost.Sub(pnl)
+		}
+	}
+	if wins == 0 {
+		return decimal.Zero
+	}
+	if losses == 0 {
+		return decimal.NewFromInt(1)
+	}
+
+	winRate := decimal.NewFromInt(wins).Div(decimal.NewFromInt(wins + losses))
+	payoff := won.Div(decimal.NewFromInt(wins)).Div(lost.Div(decimal.NewFromInt(losses)))
+	return decimal.Max(winRate.Sub(decimal.NewFromInt(1).Sub(winRate).Div(payoff)), decimal.Zero)
+}
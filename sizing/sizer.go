@@ -0,0 +1,146 @@
+package sizing
+
+import (
+	"binance_bot/models"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"math"
+)
+
+// Sizer types selectable from the config
+const (
+	FixedQuoteType    = "fixed_quote"
+	FixedFractionType = "fixed_fraction"
+	VolatilityType    = "volatility"
+	KellyType         = "kelly"
+)
+
+// PositionSizer decides how much of the quote asset a BUY spends
+type PositionSizer interface {
+	Name() string
+	Size(ctx Context) (decimal.Decimal, error)
+}
+
+// Context holds what a sizer can base the size of a BUY on
+type Context struct {
+	Symbol       string
+	Price        decimal.Decimal
+	Candles      []models.CandleStick // Closed candles, newest last
+	QuoteBalance decimal.Decimal      // Free quote balance
+	Equity       decimal.Decimal      // Account value in the quote asset
+	Pairs        int                  // Pairs trading from the same quote balance
+}
+
+// budget returns the equity a sizer works with, split evenly over the pairs when asked to
+func (c Context) budget(split bool) decimal.Decimal {
+	if split && c.Pairs > 1 {
+		return c.Equity.Div(decimal.NewFromInt(int64(c.Pairs)))
+	}
+	return c.Equity
+}
+
+// Config selects a sizer and holds its settings, settings left at 0 use the defaults of the sizer
+type Config struct {
+	Type          string  `json:"type"`           // fixed_quote, fixed_fraction, volatility or kelly
+	Amount        float64 `json:"amount"`         // fixed_quote: quote amount per BUY
+	Fraction      float64 `json:"fraction"`       // fixed_fraction: fraction of equity per BUY, kelly: used until enough trades are known
+	RiskPerTrade  float64 `json:"risk_per_trade"` // volatility: fraction of equity lost when the price falls atr_multiple ATRs
+	ATRPeriod     int     `json:"atr_period"`     // volatility: candles in the average true range
+	ATRMultiple   float64 `json:"atr_multiple"`   // volatility: ATRs to the assumed exit
+	KellyFraction float64 `json:"kelly_fraction"` // kelly: share of the full Kelly bet
+	Lookback      int     `json:"lookback"`       // kelly: latest completed trades used for the win rate
+	MinTrades     int     `json:"min_trades"`     // kelly: completed trades needed before the win rate is trusted
+	MaxFraction   float64 `json:"max_fraction"`   // volatility and kelly: largest fraction of equity per BUY
+	SplitPairs    bool    `json:"split_pairs"`    // Divide the equity by the number of pairs before sizing
+}
+
+// New creates the sizer selected by a config
+func New(cfg Config) (PositionSizer, error) {
+	switch cfg.Type {
+	case FixedQuoteType:
+		if cfg.Amount <= 0 {
+			return nil, fmt.Errorf("%s: amount must be positive, got %v", cfg.Type, cfg.Amount)
+		}
+		return &FixedQuote{Amount: cfg.Amount}, nil
+	case FixedFractionType:
+		fraction := orDefault(cfg.Fraction, 0.25)
+		if err := checkFraction(cfg.Type, "fraction", fraction); err != nil {
+			return nil, err
+		}
+		return &FixedFraction{Fraction: fraction, SplitPairs: cfg.SplitPairs}, nil
+	case VolatilityType:
+		sizer := &Volatility{
+			RiskPerTrade: orDefault(cfg.RiskPerTrade, 0.01),
+			ATRPeriod:    int(orDefault(float64(cfg.ATRPeriod), 14)),
+			ATRMultiple:  orDefault(cfg.ATRMultiple, 2),
+			MaxFraction:  orDefault(cfg.MaxFraction, 0.25),
+			SplitPairs:   cfg.SplitPairs,
+		}
+		if err := checkFraction(cfg.Type, "risk_per_trade", sizer.RiskPerTrade); err != nil {
+			return nil, err
+		}
+		if err := checkFraction(cfg.Type, "max_fraction", sizer.MaxFraction); err != nil {
+			return nil, err
+		}
+		if sizer.ATRPeriod < 2 || sizer.ATRMultiple <= 0 {
+			return nil, fmt.Errorf("%s: atr_period must be at least 2 and atr_multiple positive", cfg.Type)
+		}
+		return sizer, nil
+	case KellyType:
+		sizer := &Kelly{
+			KellyFraction: orDefault(cfg.KellyFraction, 0.5),
+			Lookback:      int(orDefault(float64(cfg.Lookback), 100)),
+			MinTrades:     int(orDefault(float64(cfg.MinTrades), 20)),
+			Fallback:      orDefault(cfg.Fraction, 0.05),
+			MaxFraction:   orDefault(cfg.MaxFraction, 0.25),
+			SplitPairs:    cfg.SplitPairs,
+		}
+		if err := checkFraction(cfg.Type, "kelly_fraction", sizer.KellyFraction); err != nil {
+			return nil, err
+		}
+		if err := checkFraction(cfg.Type, "fraction", sizer.Fallback); err != nil {
+			return nil, err
+		}
+		if err := checkFraction(cfg.Type, "max_fraction", sizer.MaxFraction); err != nil {
+			return nil, err
+		}
+		if sizer.MinTrades < 1 || sizer.Lookback < sizer.MinTrades {
+			return nil, fmt.Errorf("%s: min_trades must be at least 1 and lookback at least min_trades", cfg.Type)
+		}
+		return sizer, nil
+	}
+	return nil, fmt.Errorf("unknown sizer type %q, available: %s, %s, %s, %s", cfg.Type, FixedQuoteType, FixedFractionType, VolatilityType, KellyType)
+}
+
+// Table holds the sizer of every pair
+type Table struct {
+	def   PositionSizer
+	pairs map[string]PositionSizer
+}
+
+// NewTable creates a table that uses def for pairs without a sizer of their own
+func NewTable(def PositionSizer, pairs map[string]PositionSizer) *Table {
+	return &Table{def: def, pairs: pairs}
+}
+
+// For returns the sizer of a pair
+func (t *Table) For(symbol string) PositionSizer {
+	if sizer, ok := t.pairs[symbol]; ok {
+		return sizer
+	}
+	return t.def
+}
+
+func orDefault(value, def float64) float64 {
+	if value == 0 {
+		return def
+	}
+	return value
+}
+
+func checkFraction(sizer, name string, value float64) error {
+	if value <= 0 || value > 1 || math.IsNaN(value) {
+		return fmt.Errorf("%s: %s must be above 0 and at most 1, got %v", sizer, name, value)
+	}
+	return nil
+}
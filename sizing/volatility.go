@@ -0,0 +1,37 @@
+package sizing
+
+import (
+	"binance_bot/indicators"
+	"fmt"
+	"github.com/shopspring/decimal"
+)
+
+// Volatility sizes a BUY so that a fall of ATRMultiple average true ranges loses RiskPerTrade of
+// the equity. Volatile pairs get smaller positions and calm pairs larger ones, up to MaxFraction.
+type Volatility struct {
+	RiskPerTrade float64
+	ATRPeriod    int
+	ATRMultiple  float64
+	MaxFraction  float64
+	SplitPairs   bool
+}
+
+func (s *Volatility) Name() string {
+	return fmt.Sprintf("%s(risk=%v atr=%dx%v)", VolatilityType, s.RiskPerTrade, s.ATRPeriod, s.ATRMultiple)
+}
+
+func (s *Volatility) Size(ctx Context) (decimal.Decimal, error) {
+	atrs, err := indicators.ATRSeries(ctx.Candles, s.ATRPeriod)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	atr := atrs[len(atrs)-1]
+	if atr <= 0 || !ctx.Price.IsPositive() {
+		return decimal.Zero, fmt.Errorf("cannot size %s with ATR %v and price %s", ctx.Symbol, atr, ctx.Price)
+	}
+
+	budget := ctx.budget(s.SplitPairs)
+	loss := decimal.NewFromFloat(atr).Mul(decimal.NewFromFloat(s.ATRMultiple))
+	quantity := budget.Mul(decimal.NewFromFloat(s.RiskPerTrade)).Div(loss)
+	return decimal.Min(quantity.Mul(ctx.Price), budget.Mul(decimal.NewFromFloat(s.MaxFraction))), nil
+}
@@ -0,0 +1,147 @@
+package strategies
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/indicators"
+	"binance_bot/logger"
+	"binance_bot/models"
+	"fmt"
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	Register(Definition{
+		Name:        BollingerStrategyType.String(),
+		Description: "Buys a close below the lower Bollinger Band confirmed by an oversold RSI, sells at the middle or upper band",
+		Execution:   CandleExecution,
+		Params: []ParamSpec{
+			{Name: "period", Kind: IntParam, Default: 20, Min: 2, Max: 500, Description: "Candles of the moving average and standard deviation"},
+			{Name: "std_dev", Kind: FloatParam, Default: 2, Min: 0.1, Max: 10, Description: "Distance of the bands from the middle in standard deviations"},
+			{Name: "rsi_period", Kind: IntParam, Default: 14, Min: 2, Max: 500, Description: "RSI period"},
+			{Name: "rsi_oversold", Kind: IntParam, Default: 30, Min: 1, Max: 99, Description: "RSI below which a close under the lower band is bought"},
+			{Name: "exit_at_upper", Kind: BoolParam, Default: 0, Description: "Sell at the upper band instead of the middle band"},
+			{Name: "trend_filter", Kind: BoolParam, Default: 1, Description: "Only buy in an uptrend"},
+			{Name: "squeeze_lookback", Kind: IntParam, Default: 50, Min: 1, Max: 1000, Description: "Candles the band width is averaged over"},
+			{Name: "squeeze_ratio", Kind: FloatParam, Default: 0.5, Min: 0, Max: 1, Description: "Skip entries while the band width is below this fraction of its average, 0 disables"},
+			{Name: "expansion_ratio", Kind: FloatParam, Default: 2, Min: 0, Max: 100, Description: "Skip entries while the band width is above this multiple of its average, 0 disables"},
+			{Name: "stop_loss_percent", Kind: FloatParam, Default: 3, Min: 0, Max: 99, Description: "Stop of a position this percent below the entry, 0 leaves it to the exit rules"},
+		},
+		New: func(p Params) (Strategy, error) {
+			if p.Float("expansion_ratio") > 0 && p.Float("expansion_ratio") <= p.Float("squeeze_ratio") {
+				return nil, fmt.Errorf("expansion_ratio (%v) must be above squeeze_ratio (%v)", p.Float("expansion_ratio"), p.Float("squeeze_ratio"))
+			}
+			return &BollingerStrategy{
+				Period:          p.Int("period"),
+				StdDev:          p.Float("std_dev"),
+				RSIPeriod:       p.Int("rsi_period"),
+				RSIOversold:     p.Int("rsi_oversold"),
+				ExitAtUpper:     p.Bool("exit_at_upper"),
+				TrendFilter:     p.Bool("trend_filter"),
+				SqueezeLookback: p.Int("squeeze_lookback"),
+				SqueezeRatio:    p.Float("squeeze_ratio"),
+				ExpansionRatio:  p.Float("expansion_ratio"),
+				StopLossPercent: p.Float("stop_loss_percent"),
+			}, nil
+		},
+	})
+}
+
+// BollingerStrategy trades the return of the price to its mean. A close below the lower band with
+// an oversold RSI is bought and the position is sold once the close reaches the middle band, or
+// the upper band with ExitAtUpper. Entries are skipped while the bands are squeezed or expanding,
+// as a close outside the bands then tends to be the start of a breakout rather than an overshoot.
+type BollingerStrategy struct {
+	Period          int
+	StdDev          float64
+	RSIPeriod       int
+	RSIOversold     int
+	ExitAtUpper     bool
+	TrendFilter     bool    // Only buy when the bot reports an uptrend
+	SqueezeLookback int     // Candles the band width is averaged over
+	SqueezeRatio    float64 // Smallest band width to buy at as a fraction of the average, 0 for any
+	ExpansionRatio  float64 // Largest band width to buy at as a multiple of the average, 0 for any
+	StopLossPercent float64
+}
+
+func (b *BollingerStrategy) GetStrategyType() StrategyType {
+	return BollingerStrategyType
+}
+
+func (b *BollingerStrategy) Calculate(candles []models.CandleStick, pair string, trend bool) (models.Signal, error) {
+	closes := indicators.Closes(candles)
+	bands, err := indicators.BollingerSeries(closes, b.Period, b.StdDev)
+	if err != nil {
+		return models.HoldSignal(""), err
+	}
+	if len(bands) <= b.SqueezeLookback {
+		return models.HoldSignal(""), fmt.Errorf("not enough data to average the band width: need %d candles, got %d", b.Period+b.SqueezeLookback, len(candles))
+	}
+	rsi, err := indicators.RSISeries(closes, b.RSIPeriod)
+	if err != nil {
+		return models.HoldSignal(""), err
+	}
+
+	price := closes[len(closes)-1]
+	band := bands[len(bands)-1]
+	latestRSI := rsi[len(rsi)-1]
+	averageWidth := 0.0
+	for _, previous := range bands[len(bands)-1-b.SqueezeLookback : len(bands)-1] {
+		averageWidth += previous.Width() / float64(b.SqueezeLookback)
+	}
+	values := map[string]float64{
+		"bb_lower":     band.Lower,
+		"bb_middle":    band.Middle,
+		"bb_upper":     band.Upper,
+		"bb_width":     band.Width(),
+		"bb_avg_width": averageWidth,
+		"rsi":          latestRSI,
+	}
+
+	// An open position is sold once the close is back at the target band
+	target, targetName := band.Middle, "middle"
+	if b.ExitAtUpper {
+		target, targetName = band.Upper, "upper"
+	}
+	if trade, _ := db2.SQLiteDB.GetActiveTrade(pair); trade != nil {
+		if price >= target {
+			return models.Signal{
+				Action:     models.ActionSell,
+				Strength:   1,
+				Reason:     fmt.Sprintf("close reached the %s band", targetName),
+				Indicators: values,
+			}, nil
+		}
+		return models.HoldSignal("waiting for the " + targetName + " band"), nil
+	}
+
+	if price >= band.Lower {
+		return models.HoldSignal("close above the lower band"), nil
+	}
+	var skip string
+	switch {
+	case latestRSI >= float64(b.RSIOversold):
+		skip = fmt.Sprintf("RSI %.2f is not below %d", latestRSI, b.RSIOversold)
+	case b.TrendFilter && !trend:
+		skip = "not in an uptrend"
+	case band.Width() < averageWidth*b.SqueezeRatio:
+		skip = fmt.Sprintf("bands squeezed to %.2f%% of their average width", band.Width()/averageWidth*100)
+	case b.ExpansionRatio > 0 && band.Width() > averageWidth*b.ExpansionRatio:
+		skip = fmt.Sprintf("bands expanded to %.2f%% of their average width", band.Width()/averageWidth*100)
+	}
+	if skip != "" {
+		logger.Debugf("%s closed below the lower band, not buying: %s", pair, skip)
+		return models.HoldSignal(skip), nil
+	}
+
+	signal := models.Signal{
+		Action:     models.ActionBuy,
+		Strength:   1,
+		TakeProfit: decimal.NewFromFloat(target),
+		Reason:     fmt.Sprintf("close below the lower band with RSI %.2f", latestRSI),
+		Indicators: values,
+	}
+	if b.StopLossPercent > 0 {
+		signal.StopLoss = decimal.NewFromFloat(price).Mul(decimal.NewFromFloat(1 - b.StopLossPercent/100))
+	}
+	return signal, nil
+}
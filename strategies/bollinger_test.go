@@ -0,0 +1,207 @@
+package strategies
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/models"
+	"github.com/shopspring/decimal"
+	"strings"
+	"testing"
+)
+
+// swing returns closes alternating between mid+amp and mid-amp, bands of an even width
+func swing(n int, mid, amp float64, after ...float64) []float64 {
+	closes := make([]float64, n, n+len(after))
+	for i := range closes {
+		closes[i] = mid + amp
+		if i%2 == 1 {
+			closes[i] = mid - amp
+		}
+	}
+	return append(closes, after...)
+}
+
+// dip swings around 100 and falls to 92, below a lower band of 93.20 with an RSI of 15.23 and
+// bands at 1.65 times their average width. The closes after the dip follow.
+func dip(after ...float64) []float64 {
+	return swing(12, 100, 2, append([]float64{100, 99, 98, 92}, after...)...)
+}
+
+func closeCandles(closes []float64) []models.CandleStick {
+	candles := make([]models.CandleStick, len(closes))
+	for i, c := range closes {
+		candles[i] = models.CandleStick{Open: c, High: c, Low: c, Close: c}
+	}
+	return candles
+}
+
+func TestBollingerStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   map[string]interface{} // On top of bands of 5 candles at 1.5 deviations, a 3 candle RSI and widths averaged over 5 bands
+		closes   []float64
+		trend    bool
+		position bool // An ETHUSDT lot is open
+		action   models.SignalAction
+		reason   string
+		target   string // Take-profit of a BUY
+		stop     string // Stop-loss of a BUY
+		wantErr  string
+	}{
+		{
+			name:   "close below the lower band with an oversold RSI",
+			closes: dip(), trend: true,
+			action: models.ActionBuy,
+			reason: "close below the lower band with RSI 15.23",
+			target: "97.40", stop: "89.24",
+		},
+		{
+			name:   "target at the upper band",
+			params: map[string]interface{}{"exit_at_upper": true, "stop_loss_percent": 0.0},
+			closes: dip(), trend: true,
+			action: models.ActionBuy,
+			target: "101.60", stop: "0.00",
+		},
+		{
+			name:   "RSI not oversold",
+			params: map[string]interface{}{"rsi_oversold": 15.0},
+			closes: dip(), trend: true,
+			action: models.ActionHold,
+			reason: "RSI 15.23 is not below 15",
+		},
+		{
+			name:   "close above the lower band",
+			closes: dip(96), trend: true,
+			action: models.ActionHold,
+			reason: "close above the lower band",
+		},
+		{
+			name:   "outside an uptrend",
+			closes: dip(),
+			action: models.ActionHold,
+			reason: "not in an uptrend",
+		},
+		{
+			name:   "trend filter off",
+			params: map[string]interface{}{"trend_filter": false},
+			closes: dip(),
+			action: models.ActionBuy,
+		},
+		{
+			// Wide swings calm down before a small dip below the narrow bands
+			name:   "squeezed bands",
+			params: map[string]interface{}{"rsi_oversold": 40.0},
+			closes: swing(12, 100, 6, 100.5, 100.3, 100.1, 99.9, 99.7, 99.5, 98), trend: true,
+			action: models.ActionHold,
+			reason: "bands squeezed to 32.79% of their average width",
+		},
+		{
+			name:   "squeeze filter off",
+			params: map[string]interface{}{"rsi_oversold": 40.0, "squeeze_ratio": 0.0},
+			closes: swing(12, 100, 6, 100.5, 100.3, 100.1, 99.9, 99.7, 99.5, 98), trend: true,
+			action: models.ActionBuy,
+		},
+		{
+			// Calm swings break down far below the bands
+			name:   "expanding bands",
+			closes: swing(12, 100, 0.5, 100, 99, 97, 92), trend: true,
+			action: models.ActionHold,
+			reason: "bands expanded to 476.",
+		},
+		{
+			name:   "expansion below the ratio",
+			params: map[string]interface{}{"expansion_ratio": 1.5},
+			closes: dip(), trend: true,
+			action: models.ActionHold,
+			reason: "bands expanded to 164.",
+		},
+		{
+			name:   "expansion filter off",
+			params: map[string]interface{}{"expansion_ratio": 0.0},
+			closes: swing(12, 100, 0.5, 100, 99, 97, 92), trend: true,
+			action: models.ActionBuy,
+		},
+		{
+			name:   "position below the middle band",
+			closes: dip(96), position: true,
+			action: models.ActionHold,
+			reason: "waiting for the middle band",
+		},
+		{
+			name:   "position at the middle band",
+			closes: dip(99), position: true,
+			action: models.ActionSell,
+			reason: "close reached the middle band",
+		},
+		{
+			name:   "position past the middle band waits for the upper band",
+			params: map[string]interface{}{"exit_at_upper": true},
+			closes: dip(104), position: true,
+			action: models.ActionHold,
+			reason: "waiting for the upper band",
+		},
+		{
+			name:   "position at the upper band",
+			params: map[string]interface{}{"exit_at_upper": true},
+			closes: dip(106), position: true,
+			action: models.ActionSell,
+			reason: "close reached the upper band",
+		},
+		{
+			name:    "too few bands to average their width",
+			closes:  swing(9, 100, 2),
+			wantErr: "need 10 candles, got 9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := db2.InitDBAt(t.TempDir() + "/bollinger.db"); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db2.SQLiteDB.DB.Close() })
+			if tt.position {
+				if err := db2.SQLiteDB.LogActiveTrade("ETHUSDT", decimal.NewFromInt(95), decimal.NewFromInt(1)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			params := map[string]interface{}{"period": 5.0, "std_dev": 1.5, "rsi_period": 3.0, "squeeze_lookback": 5.0}
+			for name, value := range tt.params {
+				params[name] = value
+			}
+			def, _ := Lookup(BollingerStrategyType.String())
+			strategy, err := def.Build(params)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			signal, err := strategy.Calculate(closeCandles(tt.closes), "ETHUSDT", tt.trend)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signal.Action != tt.action || !strings.Contains(signal.Reason, tt.reason) {
+				t.Errorf("got %v %q, want %v %q", signal.Action, signal.Reason, tt.action, tt.reason)
+			}
+			if tt.target != "" && signal.TakeProfit.StringFixed(2) != tt.target {
+				t.Errorf("got take-profit %s, want %s", signal.TakeProfit.StringFixed(2), tt.target)
+			}
+			if tt.stop != "" && signal.StopLoss.StringFixed(2) != tt.stop {
+				t.Errorf("got stop-loss %s, want %s", signal.StopLoss.StringFixed(2), tt.stop)
+			}
+		})
+	}
+}
+
+func TestBollingerParams(t *testing.T) {
+	def, _ := Lookup(BollingerStrategyType.String())
+	params := map[string]interface{}{"squeeze_ratio": 0.5, "expansion_ratio": 0.5}
+	if _, err := def.Build(params); err == nil || !strings.Contains(err.Error(), "expansion_ratio (0.5) must be above squeeze_ratio (0.5)") {
+		t.Errorf("got error %v, want the expansion ratio rejected", err)
+	}
+}
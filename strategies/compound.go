@@ -2,33 +2,117 @@ package strategies
 
 import (
 	db2 "binance_bot/db"
+	"binance_bot/exits"
 	"binance_bot/logger"
 	"binance_bot/models"
-	"sync"
+	"fmt"
 )
 
-var highestPrices sync.Map
+func init() {
+	Register(Definition{
+		Name:        RSIMACDStrategyType.String(),
+		Description: "Buys when RSI and MACD, and optionally the stochastic oscillator, agree, sells at the desired profit or on a fall from the highest price",
+		Execution:   CandleExecution,
+		Params: []ParamSpec{
+			{Name: "rsi_overbought", Kind: IntParam, Default: 65, Min: 1, Max: 99, Description: "RSI level that counts as overbought"},
+			{Name: "rsi_oversold", Kind: IntParam, Default: 40, Min: 1, Max: 99, Description: "RSI level that counts as oversold"},
+			{Name: "rsi_period", Kind: IntParam, Default: 18, Min: 2, Max: 500, Description: "RSI period"},
+			{Name: "macd_fast_period", Kind: IntParam, Default: 15, Min: 1, Max: 500, Description: "Short-term EMA"},
+			{Name: "macd_slow_period", Kind: IntParam, Default: 30, Min: 1, Max: 500, Description: "Long-term EMA"},
+			{Name: "macd_signal_period", Kind: IntParam, Default: 10, Min: 1, Max: 500, Description: "Signal line EMA"},
+			{Name: "stochastic", Kind: BoolParam, Default: 0, Description: "Require the stochastic oscillator to confirm as well"},
+			{Name: "stoch_period", Kind: IntParam, Default: 14, Min: 1, Max: 500, Description: "Candles of the stochastic %K range"},
+			{Name: "stoch_k_smoothing", Kind: IntParam, Default: 3, Min: 1, Max: 100, Description: "SMA of %K, 1 for the fast oscillator"},
+			{Name: "stoch_d_period", Kind: IntParam, Default: 3, Min: 1, Max: 100, Description: "SMA of %K giving %D"},
+			{Name: "stoch_overbought", Kind: IntParam, Default: 80, Min: 1, Max: 99, Description: "Stochastic level that counts as overbought"},
+			{Name: "stoch_oversold", Kind: IntParam, Default: 20, Min: 1, Max: 99, Description: "Stochastic level that counts as oversold"},
+			{Name: "stoch_cross", Kind: BoolParam, Default: 1, Description: "Require %K to cross %D"},
+			{Name: "stoch_zone", Kind: BoolParam, Default: 1, Description: "Require the stochastic to be oversold for a BUY and overbought for a SELL"},
+			{Name: "fee_rate", Kind: FloatParam, Default: 0.001, Min: 0, Max: 0.1, Description: "Fee rate for selling"},
+			{Name: "desired_profit", Kind: FloatParam, Default: 50, Min: 0, Max: 10000, Description: "Profit in percent before selling"},
+			{Name: "highest_price_fall_off_margin", Kind: FloatParam, Default: 2, Min: 0, Max: 99, Description: "Sell on a fall of this percent from the highest price, 0 disables"},
+			{Name: "intra_candle", Kind: BoolParam, Default: 0, Description: "Evaluate the forming candle instead of closed candles"},
+		},
+		New: func(p Params) (Strategy, error) {
+			if p.Int("rsi_oversold") >= p.Int("rsi_overbought") {
+				return nil, fmt.Errorf("rsi_oversold (%d) must be below rsi_overbought (%d)", p.Int("rsi_oversold"), p.Int("rsi_overbought"))
+			}
+			if p.Int("macd_fast_period") >= p.Int("macd_slow_period") {
+				return nil, fmt.Errorf("macd_fast_period (%d) must be below macd_slow_period (%d)", p.Int("macd_fast_period"), p.Int("macd_slow_period"))
+			}
+			var stochastic *StochasticOscillator
+			if p.Bool("stochastic") {
+				var err error
+				if stochastic, err = newStochasticOscillator(p, "stoch_"); err != nil {
+					return nil, err
+				}
+			}
+			return Legacy(&CompoundStrategy{
+				RSI: &RSIStrategy{
+					Overbought: p.Int("rsi_overbought"),
+					Oversold:   p.Int("rsi_oversold"),
+					Period:     p.Int("rsi_period"),
+				},
+				MACD: &MACDStrategy{
+					FastPeriod:   p.Int("macd_fast_period"),
+					SlowPeriod:   p.Int("macd_slow_period"),
+					SignalPeriod: p.Int("macd_signal_period"),
+				},
+				Stochastic:                stochastic,
+				FeeRate:                   p.Float("fee_rate"),
+				DesiredProfit:             p.Float("desired_profit"),
+				HighestPriceFallOffMargin: p.Float("highest_price_fall_off_margin"),
+				EvaluateIntraCandle:       p.Bool("intra_candle"),
+			}), nil
+		},
+	})
+}
 
-// CompoundStrategy represents the type of strategy, all fields are required to be filled
-// TODO: Deal with RSI or MACD strategy being nil
-// TODO: Implement StochasticOscillator strategy
+// CompoundStrategy represents the type of strategy, RSI and MACD are required to be filled.
+// Other combinations of strategies are voted on by the EnsembleStrategy.
 type CompoundStrategy struct {
 	StrategyType StrategyType
 	RSI          *RSIStrategy
 	MACD         *MACDStrategy
+	// Third confirmation of the RSI and MACD signals, nil to trade on RSI and MACD alone
+	Stochastic *StochasticOscillator
 	// Fee rate for selling
 	FeeRate float64
 	// Desired profit margin before selling
 	DesiredProfit float64
 	// Sell if price falls below highest price since sale was made by a certain margin
 	HighestPriceFallOffMargin float64
+	// Evaluate the forming candle every few seconds instead of once per closed candle
+	EvaluateIntraCandle bool
 }
 
 func (cs *CompoundStrategy) GetStrategyType() StrategyType {
 	return RSIMACDStrategyType
 }
 
+// ExitRules sells the position at the desired profit or on a fall from the highest price since entry
+func (cs *CompoundStrategy) ExitRules() exits.Config {
+	desiredProfit := 5.0
+	if cs.DesiredProfit != 0 {
+		desiredProfit = cs.DesiredProfit
+	}
+	return exits.Config{
+		TakeProfit:      []exits.Target{{Percent: desiredProfit, Fraction: 1}},
+		TrailingPercent: cs.HighestPriceFallOffMargin,
+		FeeRate:         cs.FeeRate,
+	}
+}
+
+// IntraCandle reports whether the strategy opted in to intra-candle evaluation
+func (cs *CompoundStrategy) IntraCandle() bool {
+	return cs.EvaluateIntraCandle
+}
+
 func (cs *CompoundStrategy) Calculate(candles []models.CandleStick, pair string, trend bool) (int, error) {
+	if cs.RSI == nil || cs.MACD == nil {
+		return 0, fmt.Errorf("%s strategy needs both RSI and MACD", RSIMACDStrategyType)
+	}
+
 	var macdColor string
 	var rsiColor string
 	var trendColor string
@@ -45,6 +129,16 @@ func (cs *CompoundStrategy) Calculate(candles []models.CandleStick, pair string,
 		return 0, err
 	}
 
+	// Without the stochastic leg it agrees with every signal
+	stochSignal, stochLines := 0, ""
+	if cs.Stochastic != nil {
+		if stochLines, stochSignal, err = cs.Stochastic.Calculate(candles); err != nil {
+			return 0, err
+		}
+	}
+	buy := rsiSignal > 0 && macdSignal > 0 && (cs.Stochastic == nil || stochSignal > 0)
+	sell := rsiSignal < 0 && macdSignal < 0 && (cs.Stochastic == nil || stochSignal < 0)
+
 	if macdVal > signalLine && histogram > 0 {
 		macdColor = "\033[32m"
 	} else {
@@ -72,63 +166,21 @@ func (cs *CompoundStrategy) Calculate(candles []models.CandleStick, pair string,
 		trendText = "Downtrend"
 	}
 	logger.Infof("%s | HOLD | %s%.6f\033[0m %s%.6f\033[0m\n | %s%.v\u001B[0m \n", pair, rsiColor, rsiVal, macdColor, macdVal, trendColor, trendText)
+	if cs.Stochastic != nil {
+		logger.Infof("%s | Stochastic | %s", pair, stochLines)
+	}
 
-	// Fetch current price and buy price
-	currentPrice := candles[len(candles)-1].Close
-	trade, _ := db2.SQLiteDB.GetActiveTrade(pair) // Fetch active trade from DB
-
-	if trade != nil && !(rsiSignal > 0 && macdSignal > 0) { // If trade is active and not a strong buy signal
-		logger.Infof("Monitoring trade ID: %d | Pair: %s | Price: %.2f | Quantity %.2f", trade.ID, trade.Symbol, trade.BuyPrice, trade.Quantity)
-		breakevenPrice := trade.BuyPrice * (1 + cs.FeeRate)
-		profitMargin := (currentPrice - trade.BuyPrice) / trade.BuyPrice * 100
-
-		// Get or update the new high price since the trade was filled
-		athPrice, ok := highestPrices.Load(pair)
-		if !ok || currentPrice > athPrice.(float64) {
-			highestPrices.Store(pair, currentPrice)
-			athPrice = currentPrice
-			logger.Infof("New HIGH price for %s: %.2f\n", pair, currentPrice)
-		} else {
-			athPrice = athPrice.(float64)
-		}
-
-		// Calculate profit margin relative to ATH
-		profitMarginATH := (currentPrice - athPrice.(float64)) / athPrice.(float64) * 100
-
-		// Sell if price falls below highest price by a certain margin
-		if cs.HighestPriceFallOffMargin != 0 {
-			if profitMarginATH < -cs.HighestPriceFallOffMargin {
-				logger.Infof("Selling %s: Current price (%.2f) is 5%% below ATH (%.2f). \n", pair, currentPrice, athPrice)
-				highestPrices.Delete(pair)
-				return -1, nil // Sell signal
-			}
-		}
-
-		//Check if current price is below breakeven
-		if currentPrice < breakevenPrice {
-			//logger.Infof("Skipping sell: Current price (%.2f) is below breakeven (%.2f).", currentPrice, breakevenPrice)
-			return 0, nil // Hold
-		}
-
-		//Check if profit margin is sufficient
-		desiredProfit := 5.0 // 25% profit margin
-		if cs.DesiredProfit != 0 {
-			desiredProfit = cs.DesiredProfit
-		}
-		if profitMargin > desiredProfit {
-			logger.Infof("Selling %s: Current profit margin = %.2f%%. \n", pair, profitMargin)
-			highestPrices.Delete(pair)
-			return -1, nil // Sell signal
-		} else {
-			logger.Warnf("Skipping sell: Current profit margin = %.2f%%. | Desired profit margin = %.2f%% \n", profitMargin, cs.DesiredProfit)
-			return 0, nil // Hold
-		}
+	// The exit rules sell an open position, only a strong buy adds to it
+	trade, _ := db2.SQLiteDB.GetActiveTrade(pair)
+	if trade != nil && !buy {
+		logger.Infof("Monitoring trade ID: %d | Pair: %s | Price: %s | Quantity %s", trade.ID, trade.Symbol, trade.BuyPrice, trade.Quantity)
+		return 0, nil // Hold
 	}
 
-	if rsiSignal > 0 && macdSignal > 0 {
+	if buy {
 		logger.Info(pair, "Strong Buy |", rsiVal, macdVal, "\n")
 		return 1, nil // Strong BUY
-	} else if rsiSignal < 0 && macdSignal < 0 {
+	} else if sell {
 		logger.Info(pair, "Strong Sell |", rsiVal, macdVal, "\n")
 		return -1, nil // Strong Sell
 	} else if rsiSignal > 0 && macdSignal < 0 {
@@ -0,0 +1,197 @@
+package strategies
+
+import (
+	"binance_bot/exits"
+	"binance_bot/logger"
+	"binance_bot/models"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"math"
+	"strings"
+)
+
+// Voting rules of the ensemble strategy
+const (
+	MajorityVoting  = "majority"
+	UnanimousVoting = "unanimous"
+	WeightedVoting  = "weighted"
+)
+
+func init() {
+	Register(Definition{
+		Name:        EnsembleStrategyType.String(),
+		Description: "Trades when the votes of its child strategies agree, by majority, unanimously or by weighted score",
+		Execution:   CandleExecution,
+		Params: []ParamSpec{
+			{Name: "strategies", Kind: StrategyParam, Description: "Child strategies: type, params, and optionally name, weight and min_strength"},
+			{Name: "voting", Kind: ChoiceParam, Choices: []string{MajorityVoting, UnanimousVoting, WeightedVoting}, Description: "How the votes are counted"},
+			{Name: "buy_threshold", Kind: FloatParam, Default: 0.5, Min: 0, Max: 1, Description: "weighted: score a BUY needs"},
+			{Name: "sell_threshold", Kind: FloatParam, Default: 0.5, Min: 0, Max: 1, Description: "weighted: negative score a SELL needs"},
+			{Name: "min_votes", Kind: IntParam, Default: 1, Min: 1, Max: 100, Description: "Children that must return a signal, fewer holds"},
+		},
+		New: func(p Params) (Strategy, error) {
+			children := p.Children("strategies")
+			if len(children) == 0 {
+				return nil, fmt.Errorf("strategies: at least one child strategy is required")
+			}
+			if p.Int("min_votes") > len(children) {
+				return nil, fmt.Errorf("min_votes (%d) must not exceed the %d child strategies", p.Int("min_votes"), len(children))
+			}
+			return &EnsembleStrategy{
+				Children:      children,
+				Voting:        p.String("voting"),
+				BuyThreshold:  p.Float("buy_threshold"),
+				SellThreshold: p.Float("sell_threshold"),
+				MinVotes:      p.Int("min_votes"),
+			}, nil
+		},
+	})
+}
+
+// EnsembleStrategy asks its child strategies for a signal and trades when their votes agree:
+//   - majority: more than half of the children that voted for a side
+//   - unanimous: every child that voted chose the same side
+//   - weighted: the weighted sum of the votes, BUY +1 and SELL -1 times their strength, divided by
+//     the weight of the children that voted reaches the threshold
+//
+// Children that fail, such as those still short of candles, do not vote. The ensemble holds while
+// fewer than MinVotes children voted.
+type EnsembleStrategy struct {
+	Children      []Child
+	Voting        string
+	BuyThreshold  float64
+	SellThreshold float64
+	MinVotes      int
+}
+
+// vote is the signal of a child that voted
+type vote struct {
+	child  Child
+	signal models.Signal
+}
+
+func (e *EnsembleStrategy) GetStrategyType() StrategyType {
+	return EnsembleStrategyType
+}
+
+// ExitRules returns the exit rules of the first child that brings its own
+func (e *EnsembleStrategy) ExitRules() exits.Config {
+	for _, child := range e.Children {
+		if provider, ok := child.Strategy.(interface{ ExitRules() exits.Config }); ok {
+			if rules := provider.ExitRules(); !rules.Empty() {
+				return rules
+			}
+		}
+	}
+	return exits.Config{}
+}
+
+func (e *EnsembleStrategy) Calculate(candles []models.CandleStick, pair string, trend bool) (models.Signal, error) {
+	var votes []vote
+	for _, child := range e.Children {
+		if child.Strategy == nil {
+			continue
+		}
+		signal, err := child.Strategy.Calculate(candles, pair, trend)
+		if err != nil {
+			logger.Infof("%s ensemble: %s did not vote: %v", pair, child.Name, err)
+			continue
+		}
+		if signal.Action != models.ActionHold && signalStrength(signal) < child.MinStrength {
+			logger.Infof("%s ensemble: %s voted HOLD, its %s is weaker than %.2f: %s", pair, child.Name, signal.Action, child.MinStrength, signal)
+			signal = models.HoldSignal(signal.Reason)
+		} else {
+			logger.Infof("%s ensemble: %s voted %s (weight %v): %s", pair, child.Name, signal.Action, child.Weight, signal)
+		}
+		votes = append(votes, vote{child: child, signal: signal})
+	}
+	if len(votes) < e.MinVotes {
+		return models.HoldSignal(fmt.Sprintf("%d of %d strategies voted, %d needed", len(votes), len(e.Children), e.MinVotes)), nil
+	}
+
+	action, strength, summary := e.count(votes)
+	logger.Infof("%s ensemble: %s by %s vote (%s)", pair, action, e.Voting, summary)
+	if action == models.ActionHold {
+		return models.HoldSignal(summary), nil
+	}
+	return merge(votes, action, strength, fmt.Sprintf("%s vote: %s", e.Voting, summary)), nil
+}
+
+// count decides the action of the votes and the strength of the decision
+func (e *EnsembleStrategy) count(votes []vote) (models.SignalAction, float64, string) {
+	var buys, sells int
+	var score, weight float64
+	for _, v := range votes {
+		switch v.signal.Action {
+		case models.ActionBuy:
+			buys++
+			score += v.child.Weight * signalStrength(v.signal)
+		case models.ActionSell:
+			sells++
+			score -= v.child.Weight * signalStrength(v.signal)
+		}
+		weight += v.child.Weight
+	}
+	if weight > 0 {
+		score /= weight
+	}
+	summary := fmt.Sprintf("%d BUY, %d SELL, %d HOLD, score %.2f", buys, sells, len(votes)-buys-sells, score)
+	total := float64(len(votes))
+
+	switch e.Voting {
+	case UnanimousVoting:
+		if buys == len(votes) {
+			return models.ActionBuy, 1, summary
+		}
+		if sells == len(votes) {
+			return models.ActionSell, 1, summary
+		}
+	case WeightedVoting:
+		if score > 0 && score >= e.BuyThreshold {
+			return models.ActionBuy, math.Min(score, 1), summary
+		}
+		if score < 0 && -score >= e.SellThreshold {
+			return models.ActionSell, math.Min(-score, 1), summary
+		}
+	default:
+		if float64(buys) > total/2 {
+			return models.ActionBuy, float64(buys) / total, summary
+		}
+		if float64(sells) > total/2 {
+			return models.ActionSell, float64(sells) / total, summary
+		}
+	}
+	return models.ActionHold, 0, summary
+}
+
+// merge combines the signals of the children that voted for the action. The tightest stop-loss and
+// take-profit of a BUY are kept and the indicators are prefixed with the name of their child.
+func merge(votes []vote, action models.SignalAction, strength float64, reason string) models.Signal {
+	signal := models.Signal{Action: action, Strength: strength, Indicators: make(map[string]float64)}
+	var names []string
+	for _, v := range votes {
+		for name, value := range v.signal.Indicators {
+			signal.Indicators[v.child.Name+"."+name] = value
+		}
+		if v.signal.Action != action {
+			continue
+		}
+		names = append(names, v.child.Name)
+		if v.signal.StopLoss.IsPositive() {
+			signal.StopLoss = decimal.Max(signal.StopLoss, v.signal.StopLoss)
+		}
+		if v.signal.TakeProfit.IsPositive() && (signal.TakeProfit.IsZero() || v.signal.TakeProfit.LessThan(signal.TakeProfit)) {
+			signal.TakeProfit = v.signal.TakeProfit
+		}
+	}
+	signal.Reason = fmt.Sprintf("%s from %s", reason, strings.Join(names, ", "))
+	return signal
+}
+
+// signalStrength returns the strength of a signal, 0 is treated as 1
+func signalStrength(signal models.Signal) float64 {
+	if signal.Strength <= 0 {
+		return 1
+	}
+	return signal.Strength
+}
@@ -0,0 +1,217 @@
+package strategies
+
+import (
+	"binance_bot/models"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// voter returns the same signal, or error, on every evaluation
+type voter struct {
+	signal models.Signal
+	err    error
+}
+
+func (v voter) GetStrategyType() StrategyType {
+	return EnsembleStrategyType
+}
+
+func (v voter) Calculate(candles []models.CandleStick, pair string, trend bool) (models.Signal, error) {
+	return v.signal, v.err
+}
+
+func buy(strength float64) models.Signal {
+	return models.Signal{Action: models.ActionBuy, Strength: strength}
+}
+
+func sell(strength float64) models.Signal {
+	return models.Signal{Action: models.ActionSell, Strength: strength}
+}
+
+// voters returns children named a, b, c... of weight 1 voting the signals
+func voters(signals ...models.Signal) []Child {
+	children := make([]Child, len(signals))
+	for i, signal := range signals {
+		children[i] = Child{Name: string(rune('a' + i)), Strategy: voter{signal: signal}, Weight: 1}
+	}
+	return children
+}
+
+// with changes a child of a list
+func with(children []Child, i int, change func(c *Child)) []Child {
+	change(&children[i])
+	return children
+}
+
+func TestEnsembleStrategy(t *testing.T) {
+	failing := func(c *Child) { c.Strategy = voter{err: fmt.Errorf("not enough data")} }
+	levels := func(stop, target string) func(c *Child) {
+		return func(c *Child) {
+			v := c.Strategy.(voter)
+			v.signal.StopLoss, v.signal.TakeProfit = decimal.RequireFromString(stop), decimal.RequireFromString(target)
+			v.signal.Indicators = map[string]float64{"rsi": 25}
+			c.Strategy = v
+		}
+	}
+
+	tests := []struct {
+		name      string
+		ensemble  EnsembleStrategy
+		action    models.SignalAction
+		strength  float64
+		reason    string
+		stop      string // Stop-loss and take-profit of the merged signal
+		target    string
+		indicator []string
+	}{
+		{
+			name:     "majority BUY",
+			ensemble: EnsembleStrategy{Voting: MajorityVoting, Children: voters(buy(1), buy(0.5), sell(1))},
+			action:   models.ActionBuy, strength: 2.0 / 3,
+			reason: "majority vote: 2 BUY, 1 SELL, 0 HOLD, score 0.17 from a, b",
+		},
+		{
+			name:     "majority SELL",
+			ensemble: EnsembleStrategy{Voting: MajorityVoting, Children: voters(sell(1), buy(1), sell(0.2))},
+			action:   models.ActionSell, strength: 2.0 / 3,
+			reason: "from a, c",
+		},
+		{
+			name:     "half is no majority",
+			ensemble: EnsembleStrategy{Voting: MajorityVoting, Children: voters(buy(1), buy(1), sell(1), models.HoldSignal(""))},
+			action:   models.ActionHold,
+			reason:   "2 BUY, 1 SELL, 1 HOLD, score 0.25",
+		},
+		{
+			name:     "unanimous BUY",
+			ensemble: EnsembleStrategy{Voting: UnanimousVoting, Children: voters(buy(0.5), buy(1))},
+			action:   models.ActionBuy, strength: 1,
+			reason: "unanimous vote: 2 BUY, 0 SELL, 0 HOLD, score 0.75 from a, b",
+		},
+		{
+			name:     "unanimous with a HOLD",
+			ensemble: EnsembleStrategy{Voting: UnanimousVoting, Children: voters(buy(1), buy(1), models.HoldSignal(""))},
+			action:   models.ActionHold,
+			reason:   "2 BUY, 0 SELL, 1 HOLD",
+		},
+		{
+			name:     "failing child does not vote",
+			ensemble: EnsembleStrategy{Voting: UnanimousVoting, Children: with(voters(sell(1), sell(1), buy(1)), 2, failing)},
+			action:   models.ActionSell, strength: 1,
+			reason: "0 BUY, 2 SELL, 0 HOLD",
+		},
+		{
+			name:     "nil child does not vote",
+			ensemble: EnsembleStrategy{Voting: UnanimousVoting, Children: with(voters(buy(1), sell(1)), 1, func(c *Child) { c.Strategy = nil })},
+			action:   models.ActionBuy, strength: 1,
+			reason: "1 BUY, 0 SELL, 0 HOLD",
+		},
+		{
+			name: "weighted BUY",
+			ensemble: EnsembleStrategy{Voting: WeightedVoting, BuyThreshold: 0.5, SellThreshold: 0.5,
+				Children: with(voters(buy(1), sell(1)), 0, func(c *Child) { c.Weight = 3 })},
+			action: models.ActionBuy, strength: 0.5,
+			reason: "weighted vote: 1 BUY, 1 SELL, 0 HOLD, score 0.50 from a",
+		},
+		{
+			name: "weighted score below the threshold",
+			ensemble: EnsembleStrategy{Voting: WeightedVoting, BuyThreshold: 0.6, SellThreshold: 0.5,
+				Children: with(voters(buy(1), sell(1)), 0, func(c *Child) { c.Weight = 3 })},
+			action: models.ActionHold,
+			reason: "score 0.50",
+		},
+		{
+			name: "weighted SELL by strength",
+			ensemble: EnsembleStrategy{Voting: WeightedVoting, BuyThreshold: 0.5, SellThreshold: 0.5,
+				Children: with(voters(sell(0.9), buy(0.3)), 0, func(c *Child) { c.Weight = 2 })},
+			action: models.ActionSell, strength: 0.5,
+			reason: "score -0.50 from a",
+		},
+		{
+			name:     "HOLD votes dilute the weighted score",
+			ensemble: EnsembleStrategy{Voting: WeightedVoting, BuyThreshold: 0.5, SellThreshold: 0.5, Children: voters(buy(1), models.HoldSignal(""), models.HoldSignal(""))},
+			action:   models.ActionHold,
+			reason:   "score 0.33",
+		},
+		{
+			name:     "signal below min_strength votes HOLD",
+			ensemble: EnsembleStrategy{Voting: MajorityVoting, Children: with(voters(buy(0.4), buy(1), sell(1)), 0, func(c *Child) { c.MinStrength = 0.5 })},
+			action:   models.ActionHold,
+			reason:   "1 BUY, 1 SELL, 1 HOLD",
+		},
+		{
+			name:     "signal without a strength passes min_strength",
+			ensemble: EnsembleStrategy{Voting: MajorityVoting, Children: with(voters(buy(0), buy(1), sell(1)), 0, func(c *Child) { c.MinStrength = 0.5 })},
+			action:   models.ActionBuy, strength: 2.0 / 3,
+			reason: "2 BUY, 1 SELL, 0 HOLD",
+		},
+		{
+			name:     "fewer votes than min_votes",
+			ensemble: EnsembleStrategy{Voting: MajorityVoting, MinVotes: 3, Children: with(voters(buy(1), buy(1), buy(1)), 2, failing)},
+			action:   models.ActionHold,
+			reason:   "2 of 3 strategies voted, 3 needed",
+		},
+		{
+			name:     "min_votes reached",
+			ensemble: EnsembleStrategy{Voting: MajorityVoting, MinVotes: 2, Children: with(voters(buy(1), buy(1), buy(1)), 2, failing)},
+			action:   models.ActionBuy, strength: 1,
+		},
+		{
+			// The highest stop and the lowest target of the BUY voters, the SELL voter only adds its indicators
+			name: "tightest levels of the winning side",
+			ensemble: EnsembleStrategy{Voting: MajorityVoting, Children: with(with(with(
+				voters(buy(1), buy(1), buy(1), sell(1)),
+				0, levels("95", "110")), 1, levels("97", "105")), 3, levels("99", "101"))},
+			action: models.ActionBuy, strength: 0.75,
+			stop: "97", target: "105",
+			indicator: []string{"a.rsi", "b.rsi", "d.rsi"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signal, err := tt.ensemble.Calculate(nil, "ETHUSDT", true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signal.Action != tt.action || fmt.Sprintf("%.4f", signal.Strength) != fmt.Sprintf("%.4f", tt.strength) {
+				t.Errorf("got %v of strength %v, want %v of strength %v", signal.Action, signal.Strength, tt.action, tt.strength)
+			}
+			if !strings.Contains(signal.Reason, tt.reason) {
+				t.Errorf("got reason %q, want %q", signal.Reason, tt.reason)
+			}
+			if tt.stop != "" && (signal.StopLoss.String() != tt.stop || signal.TakeProfit.String() != tt.target) {
+				t.Errorf("got stop-loss %s take-profit %s, want %s and %s", signal.StopLoss, signal.TakeProfit, tt.stop, tt.target)
+			}
+			var indicators []string
+			for name := range signal.Indicators {
+				indicators = append(indicators, name)
+			}
+			sort.Strings(indicators)
+			if fmt.Sprint(indicators) != fmt.Sprint(tt.indicator) {
+				t.Errorf("got indicators %v, want %v", indicators, tt.indicator)
+			}
+		})
+	}
+}
+
+func TestEnsembleParams(t *testing.T) {
+	child := map[string]interface{}{"type": BollingerStrategyType.String(), "params": map[string]interface{}{}}
+	tests := []struct {
+		params  map[string]interface{}
+		wantErr string
+	}{
+		{map[string]interface{}{"voting": MajorityVoting}, "at least one child strategy is required"},
+		{map[string]interface{}{"voting": MajorityVoting, "strategies": []interface{}{child}, "min_votes": 2.0}, "min_votes (2) must not exceed the 1 child strategies"},
+	}
+
+	def, _ := Lookup(EnsembleStrategyType.String())
+	for _, tt := range tests {
+		if _, err := def.Build(tt.params); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("got error %v, want %q", err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package strategies
+
+import (
+	"binance_bot/exits"
+	"binance_bot/models"
+)
+
+// LegacyStrategy is a strategy that reports its signal as -1 (sell), 0 (hold) or 1 (buy)
+type LegacyStrategy interface {
+	GetStrategyType() StrategyType
+	Calculate(candles []models.CandleStick, pair string, trend bool) (int, error)
+}
+
+// LegacyAdapter lets a LegacyStrategy be used where a Signal returning strategy is expected
+type LegacyAdapter struct {
+	Strategy LegacyStrategy
+}
+
+// Legacy wraps a LegacyStrategy
+func Legacy(strategy LegacyStrategy) *LegacyAdapter {
+	return &LegacyAdapter{Strategy: strategy}
+}
+
+func (l *LegacyAdapter) GetStrategyType() StrategyType {
+	return l.Strategy.GetStrategyType()
+}
+
+// Calculate converts the integer signal of the wrapped strategy to a Signal
+func (l *LegacyAdapter) Calculate(candles []models.CandleStick, pair string, trend bool) (models.Signal, error) {
+	signal, err := l.Strategy.Calculate(candles, pair, trend)
+	if err != nil {
+		return models.HoldSignal(""), err
+	}
+
+	s := models.SignalFromInt(signal)
+	if s.Action != models.ActionHold {
+		s.Reason = l.Strategy.GetStrategyType().String() + " signal"
+	}
+	return s, nil
+}
+
+// IntraCandle forwards the intra-candle opt-in of the wrapped strategy
+func (l *LegacyAdapter) IntraCandle() bool {
+	if s, ok := l.Strategy.(interface{ IntraCandle() bool }); ok {
+		return s.IntraCandle()
+	}
+	return false
+}
+
+// ExitRules forwards the exit rules of the wrapped strategy, none when it has no rules of its own
+func (l *LegacyAdapter) ExitRules() exits.Config {
+	if s, ok := l.Strategy.(interface{ ExitRules() exits.Config }); ok {
+		return s.ExitRules()
+	}
+	return exits.Config{}
+}
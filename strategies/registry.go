@@ -0,0 +1,313 @@
+package strategies
+
+import (
+	"binance_bot/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Strategy is implemented by every registered strategy, it matches interfaces.Strategy
+type Strategy interface {
+	GetStrategyType() StrategyType
+	Calculate(candles []models.CandleStick, pair string, trend bool) (models.Signal, error)
+}
+
+// ExecutionModel tells the bot how a strategy is driven
+type ExecutionModel int
+
+const (
+	// CandleExecution evaluates the strategy on candles
+	CandleExecution ExecutionModel = iota
+	// TickExecution evaluates the strategy on every price update, the forming candle closing at the price
+	TickExecution
+)
+
+// String returns the name of the execution model
+func (e ExecutionModel) String() string {
+	if e == TickExecution {
+		return "tick"
+	}
+	return "candle"
+}
+
+// ParamKind is the value type of a strategy parameter
+type ParamKind int
+
+const (
+	IntParam ParamKind = iota
+	FloatParam
+	BoolParam
+	ChoiceParam   // One of Choices
+	StrategyParam // List of child strategies, see ChildSpec
+)
+
+// ParamSpec describes a strategy parameter
+type ParamSpec struct {
+	Name        string
+	Kind        ParamKind
+	Default     float64 // Bool parameters use 0 or 1, choice parameters the index of the choice
+	Min         float64
+	Max         float64 // 0 means no upper bound
+	Choices     []string
+	Description string
+}
+
+// ChildSpec is an element of a strategy list parameter as written in the config, a registered
+// strategy with its parameters
+type ChildSpec struct {
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name"`         // Name in the logs, the type when empty
+	Params      map[string]interface{} `json:"params"`       // Parameters of the strategy
+	Weight      float64                `json:"weight"`       // Weight of its vote, 1 when 0
+	MinStrength float64                `json:"min_strength"` // Signals below this strength count as HOLD
+}
+
+// Child is a built child strategy of a strategy list parameter
+type Child struct {
+	Name        string
+	Strategy    Strategy
+	Weight      float64
+	MinStrength float64
+}
+
+// Definition describes a registered strategy
+type Definition struct {
+	Name        string
+	Description string
+	Execution   ExecutionModel
+	Params      []ParamSpec
+	New         func(params Params) (Strategy, error)
+}
+
+// Params holds the resolved parameters of a strategy
+type Params map[string]interface{}
+
+// Int returns an integer parameter
+func (p Params) Int(name string) int {
+	v, _ := p[name].(int)
+	return v
+}
+
+// Float returns a number parameter
+func (p Params) Float(name string) float64 {
+	switch v := p[name].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+	return 0
+}
+
+// Bool returns a boolean parameter
+func (p Params) Bool(name string) bool {
+	v, _ := p[name].(bool)
+	return v
+}
+
+// String returns a choice parameter
+func (p Params) String(name string) string {
+	v, _ := p[name].(string)
+	return v
+}
+
+// Children returns the strategies of a strategy list parameter
+func (p Params) Children(name string) []Child {
+	v, _ := p[name].([]Child)
+	return v
+}
+
+var (
+	registry   = make(map[string]*Definition)
+	registryMu sync.RWMutex
+)
+
+// Register adds a strategy to the registry, it is meant to be called from init
+func Register(def Definition) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if def.Name == "" || def.New == nil {
+		panic("strategies: Register requires a name and a constructor")
+	}
+	if _, exists := registry[def.Name]; exists {
+		panic(fmt.Sprintf("strategies: strategy %q registered twice", def.Name))
+	}
+	registry[def.Name] = &def
+}
+
+// Lookup returns the definition of a registered strategy
+func Lookup(name string) (*Definition, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	def, ok := registry[name]
+	return def, ok
+}
+
+// Registered returns the definitions of all registered strategies sorted by name
+func Registered() []*Definition {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	defs := make([]*Definition, 0, len(registry))
+	for _, def := range registry {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
+// Build resolves the parameters and creates the strategy
+func (d *Definition) Build(raw map[string]interface{}) (Strategy, error) {
+	params, err := d.Resolve(raw)
+	if err != nil {
+		return nil, err
+	}
+	return d.New(params)
+}
+
+// Resolve checks raw parameters, as decoded from JSON, against the schema and fills in defaults
+func (d *Definition) Resolve(raw map[string]interface{}) (Params, error) {
+	params := make(Params, len(d.Params))
+	known := make(map[string]bool, len(d.Params))
+
+	for _, spec := range d.Params {
+		known[spec.Name] = true
+		value, ok := raw[spec.Name]
+		if !ok {
+			value = spec.defaultValue()
+		}
+
+		resolved, err := spec.resolve(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", d.Name, spec.Name, err)
+		}
+		params[spec.Name] = resolved
+	}
+
+	for name := range raw {
+		if !known[name] {
+			return nil, fmt.Errorf("%s: unknown parameter %q", d.Name, name)
+		}
+	}
+	return params, nil
+}
+
+// Usage describes the parameters of the strategy
+func (d *Definition) Usage() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s): %s\n", d.Name, d.Execution, d.Description)
+	for _, spec := range d.Params {
+		fmt.Fprintf(&b, "  %-32s %-8v %s\n", spec.Name, spec.defaultValue(), spec.Description)
+	}
+	return b.String()
+}
+
+func (s ParamSpec) defaultValue() interface{} {
+	switch s.Kind {
+	case IntParam:
+		return int(s.Default)
+	case BoolParam:
+		return s.Default != 0
+	case ChoiceParam:
+		return s.Choices[int(s.Default)]
+	case StrategyParam:
+		return []interface{}{}
+	}
+	return s.Default
+}
+
+func (s ParamSpec) resolve(value interface{}) (interface{}, error) {
+	switch s.Kind {
+	case BoolParam:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("must be true or false, got %v", value)
+		}
+		return b, nil
+	case ChoiceParam:
+		for _, choice := range s.Choices {
+			if value == choice {
+				return choice, nil
+			}
+		}
+		return nil, fmt.Errorf("must be one of %s, got %v", strings.Join(s.Choices, ", "), value)
+	case StrategyParam:
+		return resolveChildren(value)
+	}
+
+	var f float64
+	switch v := value.(type) {
+	case float64:
+		f = v
+	case int:
+		f = float64(v)
+	default:
+		return nil, fmt.Errorf("must be a number, got %v", value)
+	}
+
+	if f < s.Min || (s.Max != 0 && f > s.Max) {
+		if s.Max != 0 {
+			return nil, fmt.Errorf("must be between %v and %v, got %v", s.Min, s.Max, f)
+		}
+		return nil, fmt.Errorf("must be at least %v, got %v", s.Min, f)
+	}
+
+	if s.Kind == IntParam {
+		if f != math.Trunc(f) {
+			return nil, fmt.Errorf("must be a whole number, got %v", f)
+		}
+		return int(f), nil
+	}
+	return f, nil
+}
+
+// resolveChildren builds the strategies of a strategy list, as decoded from JSON
+func resolveChildren(value interface{}) ([]Child, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("must be a list of strategies: %v", err)
+	}
+	var specs []ChildSpec
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&specs); err != nil {
+		return nil, fmt.Errorf("must be a list of strategies: %v", err)
+	}
+
+	children := make([]Child, 0, len(specs))
+	names := make(map[string]bool, len(specs))
+	for i, spec := range specs {
+		def, ok := Lookup(spec.Type)
+		if !ok {
+			return nil, fmt.Errorf("[%d]: unknown strategy type %q", i, spec.Type)
+		}
+		name := spec.Name
+		if name == "" {
+			name = spec.Type
+		}
+		if names[name] {
+			return nil, fmt.Errorf("[%d]: duplicate name %q, set a name to tell the strategies apart", i, name)
+		}
+		names[name] = true
+		if spec.Weight < 0 || spec.MinStrength < 0 || spec.MinStrength > 1 {
+			return nil, fmt.Errorf("[%d]: weight must not be negative and min_strength must be between 0 and 1", i)
+		}
+
+		strategy, err := def.Build(spec.Params)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %v", i, err)
+		}
+		weight := spec.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		children = append(children, Child{Name: name, Strategy: strategy, Weight: weight, MinStrength: spec.MinStrength})
+	}
+	return children, nil
+}
@@ -1,6 +1,7 @@
 package strategies
 
 import (
+	"binance_bot/exits"
 	"binance_bot/models"
 	"fmt"
 	"log"
@@ -15,10 +16,33 @@ type SpikeStrategy struct {
 	VolumeThreshold float64 // Minimum volume to confirm spike
 }
 
+func init() {
+	Register(Definition{
+		Name:        SpikeDetectionStrategyType.String(),
+		Description: "Buys on sudden price spikes and sells on reversals, follows the price every second",
+		Execution:   TickExecution,
+		Params: []ParamSpec{
+			{Name: "avg_period", Kind: IntParam, Default: 20, Min: 1, Max: 500, Description: "Number of candles to calculate average size"},
+			{Name: "volume_threshold", Kind: FloatParam, Default: 5000, Min: 0, Description: "Minimum volume to confirm spike"},
+		},
+		New: func(p Params) (Strategy, error) {
+			return Legacy(&SpikeStrategy{
+				AvgPeriod:       p.Int("avg_period"),
+				VolumeThreshold: p.Float("volume_threshold"),
+			}), nil
+		},
+	})
+}
+
 func (s *SpikeStrategy) GetStrategyType() StrategyType {
 	return SpikeDetectionStrategyType
 }
 
+// ExitRules sells a position on a fall of 0.5% from the highest price since entry
+func (s *SpikeStrategy) ExitRules() exits.Config {
+	return exits.Config{TrailingPercent: 0.5}
+}
+
 func (s *SpikeStrategy) Calculate(candles []models.CandleStick, pair string, trend bool) (int, error) {
 	if len(candles) < s.AvgPeriod+1 {
 		return 0, fmt.Errorf("not enough candles to calculate spike")
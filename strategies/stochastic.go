@@ -1,70 +1,129 @@
 package strategies
 
 import (
+	db2 "binance_bot/db"
+	"binance_bot/indicators"
 	"binance_bot/models"
 	"fmt"
 )
 
+func init() {
+	Register(Definition{
+		Name:        StochasticStrategyType.String(),
+		Description: "Buys when the stochastic oscillator turns up from oversold and sells when it turns down from overbought",
+		Execution:   CandleExecution,
+		Params: []ParamSpec{
+			{Name: "period", Kind: IntParam, Default: 14, Min: 1, Max: 500, Description: "Candles of the %K range"},
+			{Name: "k_smoothing", Kind: IntParam, Default: 3, Min: 1, Max: 100, Description: "SMA of %K, 1 for the fast oscillator"},
+			{Name: "d_period", Kind: IntParam, Default: 3, Min: 1, Max: 100, Description: "SMA of %K giving %D"},
+			{Name: "overbought", Kind: IntParam, Default: 80, Min: 1, Max: 99, Description: "Level above which the oscillator is overbought"},
+			{Name: "oversold", Kind: IntParam, Default: 20, Min: 1, Max: 99, Description: "Level below which the oscillator is oversold"},
+			{Name: "cross", Kind: BoolParam, Default: 1, Description: "Require %K to cross %D"},
+			{Name: "zone", Kind: BoolParam, Default: 1, Description: "Require the oscillator to be oversold for a BUY and overbought for a SELL"},
+			{Name: "trend_filter", Kind: BoolParam, Default: 0, Description: "Only buy in an uptrend"},
+		},
+		New: func(p Params) (Strategy, error) {
+			oscillator, err := newStochasticOscillator(p, "")
+			if err != nil {
+				return nil, err
+			}
+			return &StochasticStrategy{Oscillator: oscillator, TrendFilter: p.Bool("trend_filter")}, nil
+		},
+	})
+}
+
+// newStochasticOscillator builds an oscillator from the parameters starting with prefix
+func newStochasticOscillator(p Params, prefix string) (*StochasticOscillator, error) {
+	s := &StochasticOscillator{
+		Overbought: p.Int(prefix + "overbought"),
+		Oversold:   p.Int(prefix + "oversold"),
+		Period:     p.Int(prefix + "period"),
+		KSmoothing: p.Int(prefix + "k_smoothing"),
+		DPeriod:    p.Int(prefix + "d_period"),
+		Cross:      p.Bool(prefix + "cross"),
+		Zone:       p.Bool(prefix + "zone"),
+	}
+	if s.Oversold >= s.Overbought {
+		return nil, fmt.Errorf("%soversold (%d) must be below %soverbought (%d)", prefix, s.Oversold, prefix, s.Overbought)
+	}
+	if !s.Cross && !s.Zone {
+		return nil, fmt.Errorf("%scross and %szone cannot both be false", prefix, prefix)
+	}
+	return s, nil
+}
+
+// StochasticOscillator turns the stochastic oscillator into a -1/0/1 signal. With Cross a BUY needs
+// %K to cross above %D and a SELL below it, with Zone a BUY needs the oscillator oversold and a
+// SELL overbought. With both the lines must have been in the zone on the candle before the cross.
 type StochasticOscillator struct {
-	Overbought int // Overbought threshold (e.g., 80)
-	Oversold   int // Oversold threshold (e.g., 20)
-	Period     int // Lookback period
+	Overbought int  // Overbought threshold (e.g., 80)
+	Oversold   int  // Oversold threshold (e.g., 20)
+	Period     int  // Lookback period of %K
+	KSmoothing int  // SMA of %K, 1 for the fast oscillator
+	DPeriod    int  // SMA of %K giving %D
+	Cross      bool // Require %K to cross %D
+	Zone       bool // Require the oscillator to be oversold or overbought
 }
 
 // Calculate generates a signal based on the stochastic oscillator
 func (s *StochasticOscillator) Calculate(candles []models.CandleStick) (string, int, error) {
-	k, d, err := calculateStochasticOscillator(candles, s.Period)
+	values, err := indicators.StochasticSeries(candles, s.Period, s.KSmoothing, s.DPeriod)
 	if err != nil {
 		return "", 0, err
 	}
+	if len(values) < 2 {
+		return "", 0, fmt.Errorf("not enough data to detect a stochastic cross: need %d candles, got %d", s.Period+s.KSmoothing+s.DPeriod-1, len(candles))
+	}
+	prev, latest := values[len(values)-2], values[len(values)-1]
 
-	str := fmt.Sprintf("K: %.2f D: %.2f", k, d)
+	str := fmt.Sprintf("K: %.2f D: %.2f", latest.K, latest.D)
 
-	if k > float64(s.Overbought) && d > float64(s.Overbought) {
+	// Without a cross the zone is that of the latest lines, with one that of the lines before it
+	zone := latest
+	if s.Cross {
+		zone = prev
+	}
+	oversold := !s.Zone || (zone.K < float64(s.Oversold) && zone.D < float64(s.Oversold))
+	overbought := !s.Zone || (zone.K > float64(s.Overbought) && zone.D > float64(s.Overbought))
+	crossUp := !s.Cross || (prev.K <= prev.D && latest.K > latest.D)
+	crossDown := !s.Cross || (prev.K >= prev.D && latest.K < latest.D)
+
+	if overbought && crossDown {
 		return str, -1, nil // Sell signal
-	} else if k < float64(s.Oversold) && d < float64(s.Oversold) {
+	} else if oversold && crossUp {
 		return str, 1, nil // Buy signal
 	}
 
 	return str, 0, nil // Hold
 }
 
-func calculateStochasticOscillator(candles []models.CandleStick, period int) (float64, float64, error) {
-	if len(candles) < period {
-		return 0, 0, fmt.Errorf("not enough data to calculate stochastic oscillator: need %d candles, got %d", period, len(candles))
-	}
-
-	var highestHigh, lowestLow float64
-	highestHigh = candles[len(candles)-period].High
-	lowestLow = candles[len(candles)-period].Low
-
-	for i := len(candles) - period; i < len(candles); i++ {
-		if candles[i].High > highestHigh {
-			highestHigh = candles[i].High
-		}
-		if candles[i].Low < lowestLow {
-			lowestLow = candles[i].Low
-		}
-	}
+// StochasticStrategy trades the signals of a stochastic oscillator, SELL signals only close an
+// open position
+type StochasticStrategy struct {
+	Oscillator  *StochasticOscillator
+	TrendFilter bool // Only buy when the bot reports an uptrend
+}
 
-	// %K calculation
-	lastClose := candles[len(candles)-1].Close
-	k := (lastClose - lowestLow) / (highestHigh - lowestLow) * 100
+func (s *StochasticStrategy) GetStrategyType() StrategyType {
+	return StochasticStrategyType
+}
 
-	// %D calculation (3-period SMA of %K)
-	if len(candles) < period+3 {
-		return k, k, nil // Not enough data for %D, return %K as %D
+func (s *StochasticStrategy) Calculate(candles []models.CandleStick, pair string, trend bool) (models.Signal, error) {
+	lines, signal, err := s.Oscillator.Calculate(candles)
+	if err != nil {
+		return models.HoldSignal(""), err
 	}
 
-	var sumK float64
-	for i := len(candles) - 3; i < len(candles); i++ {
-		cls := candles[i].Close
-		low := candles[i-period].Low
-		high := candles[i-period].High
-		percentK := (cls - low) / (high - low) * 100
-		sumK += percentK
+	switch {
+	case signal > 0 && s.TrendFilter && !trend:
+		return models.HoldSignal("stochastic BUY outside an uptrend, " + lines), nil
+	case signal > 0:
+		return models.Signal{Action: models.ActionBuy, Strength: 1, Reason: "stochastic turned up, " + lines}, nil
+	case signal < 0:
+		if trade, _ := db2.SQLiteDB.GetActiveTrade(pair); trade == nil {
+			return models.HoldSignal("stochastic SELL without a position, " + lines), nil
+		}
+		return models.Signal{Action: models.ActionSell, Strength: 1, Reason: "stochastic turned down, " + lines}, nil
 	}
-	d := sumK / 3
-
-	return k, d, nil
+	return models.HoldSignal(lines), nil
 }
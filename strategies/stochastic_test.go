@@ -0,0 +1,160 @@
+package strategies
+
+import (
+	db2 "binance_bot/db"
+	"binance_bot/models"
+	"github.com/shopspring/decimal"
+	"strings"
+	"testing"
+)
+
+// zigzag is a series whose highs and lows are not at the edges of the %K windows, so %K is only
+// right when it takes the range over the whole window
+var zigzag = struct{ high, low, close []float64 }{
+	high:  []float64{101, 106, 102, 107, 103, 100, 104, 97, 101, 93, 98, 93, 95, 93, 91, 94, 99, 105, 100, 107},
+	low:   []float64{98, 103, 97, 105, 99, 96, 101, 94, 96, 91, 94, 89, 92, 90, 86, 92, 95, 101, 97, 104},
+	close: []float64{100, 104, 99, 106, 101, 97, 103, 95, 98, 92, 96, 90, 94, 91, 88, 93, 97, 102, 99, 105},
+}
+
+// zigzagCandles returns the first n candles of the zigzag series
+func zigzagCandles(n int) []models.CandleStick {
+	candles := make([]models.CandleStick, n)
+	for i := range candles {
+		candles[i] = models.CandleStick{Open: zigzag.close[i], High: zigzag.high[i], Low: zigzag.low[i], Close: zigzag.close[i]}
+	}
+	return candles
+}
+
+func TestStochasticStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   map[string]interface{} // On top of a 5 candle %K smoothed over 3 and a %D of 3
+		candles  int
+		trend    bool
+		position bool // An ETHUSDT lot is open
+		action   models.SignalAction
+		reason   string
+		wantErr  string
+	}{
+		{
+			// Both lines were oversold before %K crossed above %D
+			name:    "cross up from oversold",
+			params:  map[string]interface{}{"overbought": 70.0, "oversold": 30.0},
+			candles: 13, trend: true,
+			action: models.ActionBuy,
+			reason: "stochastic turned up, K: 29.49 D: 25.46",
+		},
+		{
+			name:    "cross up outside the oversold zone",
+			candles: 13, trend: true,
+			action: models.ActionHold,
+			reason: "K: 29.49 D: 25.46",
+		},
+		{
+			name:    "cross down while oversold",
+			params:  map[string]interface{}{"overbought": 70.0, "oversold": 30.0},
+			candles: 12, trend: true,
+			action: models.ActionHold,
+			reason: "K: 18.16 D: 21.78",
+		},
+		{
+			name:    "oversold without a cross",
+			params:  map[string]interface{}{"overbought": 70.0, "oversold": 30.0, "cross": false},
+			candles: 12, trend: true,
+			action: models.ActionBuy,
+			reason: "K: 18.16 D: 21.78",
+		},
+		{
+			name:    "cross only",
+			params:  map[string]interface{}{"zone": false},
+			candles: 13, trend: true,
+			action: models.ActionBuy,
+		},
+		{
+			name:    "BUY outside an uptrend",
+			params:  map[string]interface{}{"overbought": 70.0, "oversold": 30.0, "trend_filter": true},
+			candles: 13,
+			action:  models.ActionHold,
+			reason:  "stochastic BUY outside an uptrend",
+		},
+		{
+			name:    "cross down from overbought",
+			params:  map[string]interface{}{"overbought": 70.0, "oversold": 30.0},
+			candles: 20, position: true,
+			action: models.ActionSell,
+			reason: "stochastic turned down, K: 79.77 D: 80.35",
+		},
+		{
+			name:    "SELL without a position",
+			params:  map[string]interface{}{"overbought": 70.0, "oversold": 30.0},
+			candles: 20,
+			action:  models.ActionHold,
+			reason:  "stochastic SELL without a position",
+		},
+		{
+			name:    "one value is not a cross",
+			candles: 9,
+			wantErr: "need 10 candles, got 9",
+		},
+		{
+			name:    "too few candles for a value",
+			candles: 8,
+			wantErr: "not enough data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := db2.InitDBAt(t.TempDir() + "/stochastic.db"); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db2.SQLiteDB.DB.Close() })
+			if tt.position {
+				if err := db2.SQLiteDB.LogActiveTrade("ETHUSDT", decimal.NewFromInt(95), decimal.NewFromInt(1)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			params := map[string]interface{}{"period": 5.0}
+			for name, value := range tt.params {
+				params[name] = value
+			}
+			def, _ := Lookup(StochasticStrategyType.String())
+			strategy, err := def.Build(params)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			signal, err := strategy.Calculate(zigzagCandles(tt.candles), "ETHUSDT", tt.trend)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signal.Action != tt.action || !strings.Contains(signal.Reason, tt.reason) {
+				t.Errorf("got %v %q, want %v %q", signal.Action, signal.Reason, tt.action, tt.reason)
+			}
+		})
+	}
+}
+
+func TestStochasticParams(t *testing.T) {
+	tests := []struct {
+		params  map[string]interface{}
+		wantErr string
+	}{
+		{map[string]interface{}{"overbought": 30.0, "oversold": 30.0}, "oversold (30) must be below overbought (30)"},
+		{map[string]interface{}{"cross": false, "zone": false}, "cross and zone cannot both be false"},
+	}
+
+	def, _ := Lookup(StochasticStrategyType.String())
+	for _, tt := range tests {
+		if _, err := def.Build(tt.params); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("got error %v, want %q", err, tt.wantErr)
+		}
+	}
+}
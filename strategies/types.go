@@ -1,5 +1,10 @@
 package strategies
 
+import (
+	"fmt"
+	"strings"
+)
+
 // StrategyType defines a type-safe enum-like structure for strategies
 type StrategyType struct {
 	value string
@@ -9,6 +14,9 @@ type StrategyType struct {
 var (
 	RSIMACDStrategyType        = StrategyType{"rsi-macd"}
 	SpikeDetectionStrategyType = StrategyType{"spike-detection"}
+	BollingerStrategyType      = StrategyType{"bollinger"}
+	StochasticStrategyType     = StrategyType{"stochastic"}
+	EnsembleStrategyType       = StrategyType{"ensemble"}
 )
 
 // String returns the string representation of the StrategyType
@@ -16,12 +24,26 @@ func (s StrategyType) String() string {
 	return s.value
 }
 
-// IsValid checks if a given value is a valid StrategyType
+// NewStrategyType creates the StrategyType of a strategy registered under name
+func NewStrategyType(name string) StrategyType {
+	return StrategyType{name}
+}
+
+// IsValid checks if a given value is a registered StrategyType
 func (s StrategyType) IsValid() bool {
-	switch s {
-	case RSIMACDStrategyType, SpikeDetectionStrategyType:
-		return true
-	default:
-		return false
+	_, ok := Lookup(s.value)
+	return ok
+}
+
+// ParseStrategyType converts a strategy name to its StrategyType
+func ParseStrategyType(name string) (StrategyType, error) {
+	st := StrategyType{name}
+	if !st.IsValid() {
+		names := make([]string, 0)
+		for _, def := range Registered() {
+			names = append(names, def.Name)
+		}
+		return StrategyType{}, fmt.Errorf("unknown strategy type %q, available: %s", name, strings.Join(names, ", "))
 	}
+	return st, nil
 }
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"binance_bot/models"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// candleHeader is the column layout of stored candle files
+var candleHeader = []string{"Timestamp", "Open", "High", "Low", "Close", "Volume"}
+
+// WriteCandlesCSV stores candles in a CSV file, replacing any existing content.
+func WriteCandlesCSV(filename string, candles []models.CandleStick) error {
+	// Ensure the directory exists
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %v", dir, err)
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV file: %v", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if err := writer.Write(candleHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %v", err)
+	}
+
+	for _, c := range candles {
+		record := []string{
+			c.Timestamp.UTC().Format(time.RFC3339),
+			strconv.FormatFloat(c.Open, 'f', -1, 64),
+			strconv.FormatFloat(c.High, 'f', -1, 64),
+			strconv.FormatFloat(c.Low, 'f', -1, 64),
+			strconv.FormatFloat(c.Close, 'f', -1, 64),
+			strconv.FormatFloat(c.Volume, 'f', -1, 64),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV record: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// ReadCandlesCSV loads candles stored by WriteCandlesCSV.
+// Timestamps may be RFC3339 or Unix milliseconds, as exported by Binance.
+func ReadCandlesCSV(filename string) ([]models.CandleStick, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %v", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	var candles []models.CandleStick
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV record: %v", err)
+		}
+		line++
+
+		// Skip the header
+		if line == 1 && record[0] == candleHeader[0] {
+			continue
+		}
+		if len(record) < len(candleHeader) {
+			return nil, fmt.Errorf("line %d: expected %d columns, got %d", line, len(candleHeader), len(record))
+		}
+
+		candle, err := parseCandleRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		candles = append(candles, candle)
+	}
+
+	return candles, nil
+}
+
+func parseCandleRecord(record []string) (models.CandleStick, error) {
+	var candle models.CandleStick
+
+	ts, err := time.Parse(time.RFC3339, record[0])
+	if err != nil {
+		ms, msErr := strconv.ParseInt(record[0], 10, 64)
+		if msErr != nil {
+			return candle, fmt.Errorf("invalid timestamp %q", record[0])
+		}
+		ts = time.UnixMilli(ms)
+	}
+	candle.Timestamp = ts
+
+	values := make([]float64, 5)
+	for i := range values {
+		values[i], err = strconv.ParseFloat(record[i+1], 64)
+		if err != nil {
+			return candle, fmt.Errorf("invalid %s value %q", candleHeader[i+1], record[i+1])
+		}
+	}
+	candle.Open, candle.High, candle.Low, candle.Close, candle.Volume = values[0], values[1], values[2], values[3], values[4]
+
+	return candle, nil
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// IntervalDuration converts a kline interval such as 15m or 4h to its length.
+// Monthly candles have no fixed length and are rejected.
+func IntervalDuration(interval string) (time.Duration, error) {
+	if len(interval) < 2 {
+		return 0, fmt.Errorf("invalid interval %q", interval)
+	}
+
+	n, err := strconv.Atoi(interval[:len(interval)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid interval %q", interval)
+	}
+
+	switch interval[len(interval)-1] {
+	case 's':
+		return time.Duration(n) * time.Second, nil
+	case 'm':
+		return time.Duration(n) * time.Minute, nil
+	case 'h':
+		return time.Duration(n) * time.Hour, nil
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, nil
+	case 'w':
+		return time.Duration(n) * 7 * 24 * time.Hour, nil
+	}
+	return 0, fmt.Errorf("interval %q has no fixed length", interval)
+}
+
+// NextCandleClose returns the close time of the candle that contains t.
+// Candles are aligned to UTC like on Binance: weekly candles open on Monday
+// and monthly candles on the first day of the month.
+func NextCandleClose(interval string, t time.Time) (time.Time, error) {
+	t = t.UTC()
+
+	if interval == "1M" {
+		return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+
+	d, err := IntervalDuration(interval)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Candles are counted from the Unix epoch, which is a Thursday. Weekly candles start on Monday.
+	var offset int64
+	if interval[len(interval)-1] == 'w' {
+		offset = int64(4 * 24 * time.Hour / time.Millisecond)
+	}
+
+	step := d.Milliseconds()
+	ms := t.UnixMilli() - offset
+	openMs := ms - ((ms%step)+step)%step
+	open := time.UnixMilli(openMs + offset).UTC()
+	return open.Add(d), nil
+}
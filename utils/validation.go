@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validation collects the problems found in settings, so all of them are reported at once
+type Validation struct {
+	errs []error
+}
+
+// Check records a problem when ok is false
+func (v *Validation) Check(ok bool, format string, args ...interface{}) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf(format, args...))
+	}
+}
+
+// Add records the problems of nested settings under their name, a nil error is ignored
+func (v *Validation) Add(name string, err error) {
+	if err != nil {
+		v.errs = append(v.errs, fmt.Errorf("%s: %v", name, err))
+	}
+}
+
+// Merge records the problems of embedded settings as they are, a nil error is ignored
+func (v *Validation) Merge(err error) {
+	if err != nil {
+		v.errs = append(v.errs, err)
+	}
+}
+
+// Err returns the recorded problems joined into one error, nil when there are none
+func (v *Validation) Err() error {
+	return errors.Join(v.errs...)
+}